@@ -39,6 +39,7 @@ const (
 	MimetypeDataWithValidator = "data/validator"
 	MimetypeTypedData         = "data/typed"
 	MimetypeClique            = "application/x-clique-header"
+	MimetypeThoraPreCommit    = "application/x-thora-precommit"
 	MimetypeTextPlain         = "text/plain"
 )
 
@@ -33,8 +33,11 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/era"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
@@ -77,6 +80,8 @@ if one is set.  Otherwise it prints the genesis from the datadir.`,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
+			utils.StateSchemeFlag,
+			utils.StateHistoryFlag,
 			utils.SnapshotFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
@@ -118,6 +123,39 @@ Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing. If the file ends with .gz, the output will
 be gzipped.`,
+	}
+	importHistoryCommand = &cli.Command{
+		Action:    importHistory,
+		Name:      "import-history",
+		Usage:     "Import an Era archive",
+		ArgsUsage: "<dir>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+			utils.TxLookupLimitFlag,
+		}, utils.DatabasePathFlags),
+		Description: `
+The import-history command imports blocks and their corresponding receipts from
+the Era1 archives in the given directory, as written by export-history.
+
+The archives are checked against their checksums and accumulator roots, blocks
+already present in the local chain must match the archived ones. Only the block
+history is imported, the state is left to be synced from the network.`,
+	}
+	exportHistoryCommand = &cli.Command{
+		Action:    exportHistory,
+		Name:      "export-history",
+		Usage:     "Export blockchain history to Era archives",
+		ArgsUsage: "<dir> <first> <last>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+		}, utils.DatabasePathFlags),
+		Description: `
+The export-history command exports the blocks, receipts and total difficulties
+of the given range into the specified directory as Era1 archives of 8192 blocks
+each, along with a checksums.txt file. The range is extended to the start of
+the epoch containing the first block.`,
 	}
 	importPreimagesCommand = &cli.Command{
 		Action:    importPreimages,
@@ -165,6 +203,28 @@ It's deprecated, please use "{{.GETHCmd}} db export" instead.
 This command dumps out the state for a given block (or latest, if none provided).
 `,
 	}
+	witnessFileFlag = &cli.StringFlag{
+		Name:     "witness",
+		Usage:    "Execution witness file, as returned by debug_executionWitness",
+		Required: true,
+	}
+	verifyBlockCommand = &cli.Command{
+		Action:    verifyBlock,
+		Name:      "verify-block",
+		Usage:     "Re-execute a block statelessly from its execution witness",
+		ArgsUsage: "",
+		Flags:     flags.Merge([]cli.Flag{witnessFileFlag, utils.DataDirFlag}, utils.NetworkFlags),
+		Description: `
+The verify-block command executes the block contained in an execution witness
+on top of the parent state carried by the witness alone, and checks the gas
+used, the receipts and the resulting state root against the block header.
+
+The witness file holds the result of debug_executionWitness, either bare or as
+a full JSON-RPC response. Witnesses are produced on demand by re-executing the
+block on the serving node, which therefore needs the parent state of the block
+(an archive node for older blocks). The chain configuration is taken from the network
+preset if one is set, otherwise from the genesis stored in the datadir.`,
+	}
 )
 
 // initGenesis will initialise the given JSON format genesis file and writes it as
@@ -247,6 +307,67 @@ func dumpGenesis(ctx *cli.Context) error {
 	return nil
 }
 
+func verifyBlock(ctx *cli.Context) error {
+	blob, err := os.ReadFile(ctx.String(witnessFileFlag.Name))
+	if err != nil {
+		return err
+	}
+	// Accept both the bare witness and a JSON-RPC response wrapping it
+	var response struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(blob, &response); err == nil && len(response.Result) > 0 {
+		blob = response.Result
+	}
+	witness := new(stateless.Witness)
+	if err := json.Unmarshal(blob, witness); err != nil {
+		return fmt.Errorf("invalid witness: %v", err)
+	}
+	config, err := loadChainConfig(ctx)
+	if err != nil {
+		return err
+	}
+	engine, err := ethconfig.CreateConsensusEngine(config, rawdb.NewMemoryDatabase())
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	var (
+		block = witness.Block
+		start = time.Now()
+	)
+	if err := core.ExecuteStateless(config, engine, witness); err != nil {
+		return fmt.Errorf("block #%d [%x] failed verification: %v", block.NumberU64(), block.Hash(), err)
+	}
+	log.Info("Block verified", "number", block.NumberU64(), "hash", block.Hash(), "root", block.Root(),
+		"txs", len(block.Transactions()), "headers", len(witness.Headers), "codes", len(witness.Codes),
+		"nodes", len(witness.State), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// loadChainConfig returns the chain configuration of the network preset if one
+// is set, or the one stored along the genesis in the datadir otherwise.
+func loadChainConfig(ctx *cli.Context) (*params.ChainConfig, error) {
+	if utils.IsNetworkPreset(ctx) {
+		return utils.MakeGenesis(ctx).Config, nil
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db, err := stack.OpenDatabase("chaindata", 0, 0, "", true)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	config := rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0))
+	if config == nil {
+		return nil, errors.New("no chain configuration in the datadir, initialize it or select a network")
+	}
+	return config, nil
+}
+
 func importChain(ctx *cli.Context) error {
 	if ctx.Args().Len() < 1 {
 		utils.Fatalf("This command requires an argument.")
@@ -364,6 +485,67 @@ func exportChain(ctx *cli.Context) error {
 	return nil
 }
 
+// importHistory imports the Era1 archives of the chain's network from the
+// specified directory.
+func importHistory(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
+	}
+
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack, false)
+	defer db.Close()
+	defer chain.Stop()
+
+	var (
+		start   = time.Now()
+		dir     = ctx.Args().Get(0)
+		network = utils.HistoryNetwork(chain.Config())
+	)
+	if err := utils.ImportHistory(chain, db, dir, network); err != nil {
+		return err
+	}
+	fmt.Printf("Import done in %v\n", time.Since(start))
+	return nil
+}
+
+// exportHistory exports the chain history in Era1 archives to the specified
+// directory.
+func exportHistory(ctx *cli.Context) error {
+	if ctx.Args().Len() != 3 {
+		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
+	}
+
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, _ := utils.MakeChain(ctx, stack, true)
+	start := time.Now()
+
+	var (
+		dir         = ctx.Args().Get(0)
+		first, ferr = strconv.ParseInt(ctx.Args().Get(1), 10, 64)
+		last, lerr  = strconv.ParseInt(ctx.Args().Get(2), 10, 64)
+	)
+	if ferr != nil || lerr != nil {
+		utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
+	}
+	if first < 0 || last < 0 {
+		utils.Fatalf("Export error: block number must be greater than 0\n")
+	}
+	if head := chain.CurrentSnapBlock(); uint64(last) > head.Number.Uint64() {
+		utils.Fatalf("Export error: block number %d larger than head block %d\n", uint64(last), head.Number.Uint64())
+	}
+	err := utils.ExportHistory(chain, dir, uint64(first), uint64(last), uint64(era.MaxEra1Size))
+	if err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
 // importPreimages imports preimage data from the specified file.
 func importPreimages(ctx *cli.Context) error {
 	if ctx.Args().Len() < 1 {
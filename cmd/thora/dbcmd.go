@@ -18,10 +18,13 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -30,7 +33,9 @@ import (
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/thora"
 	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -69,17 +74,42 @@ Remove blockchain and state databases`,
 			dbExportCmd,
 			dbMetadataCmd,
 			dbCheckStateContentCmd,
+			dbThoraSnapshotsCmd,
+			dbPruneHistoryCmd,
+			dbBackupCmd,
+			dbRestoreCmd,
+			dbMigrateCmd,
 		},
 	}
+	dbInspectJSONFlag = &cli.BoolFlag{
+		Name:  "json",
+		Usage: "Output the statistics in JSON format",
+	}
+	dbInspectIncrementalFlag = &cli.BoolFlag{
+		Name:  "incremental",
+		Usage: "Continue the incremental inspection where the previous one left off",
+	}
+	dbInspectLimitFlag = &cli.IntFlag{
+		Name:  "limit",
+		Usage: "Maximum number of entries to inspect in incremental mode (0 = rest of the pass)",
+	}
 	dbInspectCmd = &cli.Command{
 		Action:    inspect,
 		Name:      "inspect",
 		ArgsUsage: "<prefix> <start>",
 		Flags: flags.Merge([]cli.Flag{
 			utils.SyncModeFlag,
+			dbInspectJSONFlag,
+			dbInspectIncrementalFlag,
+			dbInspectLimitFlag,
 		}, utils.NetworkFlags, utils.DatabasePathFlags),
-		Usage:       "Inspect the storage size for each type of data in the database",
-		Description: `This commands iterates the entire database. If the optional 'prefix' and 'start' arguments are provided, then the iteration is limited to the given subset of data.`,
+		Usage: "Inspect the storage size for each type of data in the database",
+		Description: `This commands iterates the entire database. If the optional 'prefix' and 'start' arguments are provided, then the iteration is limited to the given subset of data.
+
+In incremental mode, the inspection continues where the previous one left off and
+stops after the given number of entries. The statistics of every key prefix are
+stored in the database and replaced once all its entries were inspected, and the
+statistics of every completed pass are recorded to track the growth of the data.`,
 	}
 	dbCheckStateContentCmd = &cli.Command{
 		Action:    checkStateContent,
@@ -193,6 +223,119 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabasePathFlags),
 		Description: "Shows metadata about the chain status.",
 	}
+	dbPruneHistoryCmd = &cli.Command{
+		Action: pruneHistory,
+		Name:   "prune-history",
+		Usage:  "Delete the bodies and receipts of old blocks from the ancient store",
+		Flags: flags.Merge([]cli.Flag{
+			utils.HistoryRetainFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command deletes the block bodies and receipts of all frozen blocks
+except the most recent ones specified by --history.retain, along with their
+transaction indices. Headers and canonical hashes are kept, so the chain can
+still be verified, but the pruned blocks can no longer be served to peers or
+over RPC.`,
+	}
+	dbBackupCmd = &cli.Command{
+		Action:    dbBackup,
+		Name:      "backup",
+		Usage:     "Create a consistent backup of the chain database",
+		ArgsUsage: "<dir>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command writes a point-in-time copy of the key-value store and of the
+ancient store into the given directory, which must not exist yet, along with
+metadata which is validated when the backup is restored. A running node can be
+backed up with the admin_backup RPC method instead.`,
+	}
+	dbRestoreCmd = &cli.Command{
+		Action:    dbRestore,
+		Name:      "restore",
+		Usage:     "Restore the chain database from a backup",
+		ArgsUsage: "<dir>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command verifies the backup in the given directory against its metadata
+and copies it into the data directory. The chain database must not exist yet.`,
+	}
+	dbMigrateToFlag = &cli.StringFlag{
+		Name:  "to",
+		Usage: "Database engine to migrate the key-value store to ('leveldb' or 'pebble')",
+		Value: "pebble",
+	}
+	dbMigrateCmd = &cli.Command{
+		Action: dbMigrate,
+		Name:   "migrate",
+		Usage:  "Migrate the key-value store to another database engine",
+		Flags: flags.Merge([]cli.Flag{
+			dbMigrateToFlag,
+			utils.CacheFlag,
+			utils.CacheDatabaseFlag,
+			utils.FDLimitFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command copies all entries of the key-value store into a new store of
+the given engine next to it, verifying the number of entries and the checksum of
+every key category once done. An interrupted migration resumes where it left off
+when the command is invoked again.
+
+The new store then replaces the old one, which is kept with an '.old' suffix and
+can be deleted once the node runs fine on the migrated database.`,
+	}
+	thoraSnapshotKeepFlag = &cli.Uint64Flag{
+		Name:  "keep",
+		Usage: "Number of most recent Thora checkpoint snapshots to retain",
+		Value: 16,
+	}
+	thoraSnapshotFromFlag = &cli.Uint64Flag{
+		Name:  "from",
+		Usage: "Block number from which to recompute Thora checkpoint snapshots",
+	}
+	dbThoraSnapshotsCmd = &cli.Command{
+		Name:  "thora-snapshots",
+		Usage: "Inspect and repair the Thora voting snapshot checkpoints",
+		Subcommands: []*cli.Command{
+			{
+				Name:        "list",
+				Usage:       "List the stored checkpoint snapshots",
+				Action:      thoraSnapshotsList,
+				Flags:       flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: "This command lists the number and hash of every Thora checkpoint snapshot stored in the database.",
+			},
+			{
+				Name:        "show",
+				Usage:       "Show the content of a checkpoint snapshot",
+				ArgsUsage:   "<hash>",
+				Action:      thoraSnapshotsShow,
+				Flags:       flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: "This command prints the decoded checkpoint snapshot (signers, votes and tally) stored for the given block hash as JSON.",
+			},
+			{
+				Name:   "prune",
+				Usage:  "Remove old checkpoint snapshots",
+				Action: thoraSnapshotsPrune,
+				Flags: flags.Merge([]cli.Flag{
+					thoraSnapshotKeepFlag,
+				}, utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `This command deletes all Thora checkpoint snapshots apart from the
+most recent ones specified by --keep. Checkpoints that cannot be decoded are
+deleted as well.`,
+			},
+			{
+				Name:   "rebuild",
+				Usage:  "Recompute checkpoint snapshots from the stored headers",
+				Action: thoraSnapshotsRebuild,
+				Flags: flags.Merge([]cli.Flag{
+					thoraSnapshotFromFlag,
+				}, utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `This command discards all Thora checkpoint snapshots at or above the
+block specified by --from, recomputes them from the canonical headers and
+writes them back to the database. It can be used to recover from corrupted or
+stale checkpoints without resyncing the chain.`,
+			},
+		},
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -277,13 +420,36 @@ func inspect(ctx *cli.Context) error {
 			start = d
 		}
 	}
+	incremental := ctx.Bool(dbInspectIncrementalFlag.Name)
+	if incremental && ctx.NArg() > 0 {
+		return errors.New("incremental inspection covers the entire database")
+	}
 	stack, _ := makeConfigNode(ctx)
 	defer stack.Close()
 
-	db := utils.MakeChainDatabase(ctx, stack, true)
+	db := utils.MakeChainDatabase(ctx, stack, !incremental)
 	defer db.Close()
 
-	return rawdb.InspectDatabase(db, prefix, start)
+	var (
+		stats *rawdb.DatabaseStats
+		err   error
+	)
+	if incremental {
+		stats, err = rawdb.InspectDatabaseIncremental(db, ctx.Int(dbInspectLimitFlag.Name))
+	} else {
+		stats, err = rawdb.InspectDatabaseStats(db, prefix, start)
+	}
+	if err != nil {
+		return err
+	}
+	if ctx.Bool(dbInspectJSONFlag.Name) {
+		return json.NewEncoder(os.Stdout).Encode(stats)
+	}
+	stats.Render(os.Stdout)
+	if !stats.Complete {
+		log.Warn("Incremental inspection pass in progress, statistics are incomplete")
+	}
+	return nil
 }
 
 func checkStateContent(ctx *cli.Context) error {
@@ -717,3 +883,299 @@ func showMetaData(ctx *cli.Context) error {
 	table.Render()
 	return nil
 }
+
+// thoraSnapshotEntry is a single checkpoint snapshot found in the database.
+type thoraSnapshotEntry struct {
+	key  []byte
+	snap *thora.Snapshot // Nil if the stored blob failed to decode
+}
+
+// readThoraSnapshots collects all the Thora checkpoint snapshots from the
+// database, sorted by block number. Undecodable entries are placed first.
+func readThoraSnapshots(db ethdb.Database) ([]thoraSnapshotEntry, error) {
+	it := db.NewIterator(rawdb.ThoraSnapshotPrefix, nil)
+	defer it.Release()
+
+	var entries []thoraSnapshotEntry
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(rawdb.ThoraSnapshotPrefix)+common.HashLength {
+			continue
+		}
+		entry := thoraSnapshotEntry{key: common.CopyBytes(key)}
+		snap := new(thora.Snapshot)
+		if err := json.Unmarshal(it.Value(), snap); err == nil {
+			entry.snap = snap
+		}
+		entries = append(entries, entry)
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		switch {
+		case entries[i].snap == nil:
+			return entries[j].snap != nil
+		case entries[j].snap == nil:
+			return false
+		}
+		return entries[i].snap.Number < entries[j].snap.Number
+	})
+	return entries, nil
+}
+
+func thoraSnapshotsList(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	entries, err := readThoraSnapshots(db)
+	if err != nil {
+		return err
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Number", "Hash", "Signers", "Votes"})
+	for _, entry := range entries {
+		hash := common.BytesToHash(entry.key[len(rawdb.ThoraSnapshotPrefix):])
+		if entry.snap == nil {
+			table.Append([]string{"corrupted", hash.Hex(), "-", "-"})
+			continue
+		}
+		table.Append([]string{
+			strconv.FormatUint(entry.snap.Number, 10),
+			hash.Hex(),
+			strconv.Itoa(len(entry.snap.Signers)),
+			strconv.Itoa(len(entry.snap.Votes)),
+		})
+	}
+	table.SetFooter([]string{"", "Total", strconv.Itoa(len(entries)), ""})
+	table.Render()
+	return nil
+}
+
+func thoraSnapshotsShow(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	blob, err := hexutil.Decode(ctx.Args().First())
+	if err != nil || len(blob) != common.HashLength {
+		return fmt.Errorf("invalid block hash %q", ctx.Args().First())
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	snap, err := thora.LoadSnapshot(db, common.BytesToHash(blob))
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+func thoraSnapshotsPrune(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	entries, err := readThoraSnapshots(db)
+	if err != nil {
+		return err
+	}
+	keep := int(ctx.Uint64(thoraSnapshotKeepFlag.Name))
+	if keep >= len(entries) {
+		log.Info("Nothing to prune", "checkpoints", len(entries), "keep", keep)
+		return nil
+	}
+	batch := db.NewBatch()
+	for _, entry := range entries[:len(entries)-keep] {
+		hash := common.BytesToHash(entry.key[len(rawdb.ThoraSnapshotPrefix):])
+		if err := thora.DeleteSnapshot(batch, hash); err != nil {
+			return err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Pruned Thora checkpoint snapshots", "deleted", len(entries)-keep, "kept", keep)
+	return nil
+}
+
+func pruneHistory(ctx *cli.Context) error {
+	retain := ctx.Uint64(utils.HistoryRetainFlag.Name)
+	if retain == 0 {
+		return fmt.Errorf("missing or zero --%s", utils.HistoryRetainFlag.Name)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	head := rawdb.ReadHeadBlockHash(db)
+	number := rawdb.ReadHeaderNumber(db, head)
+	if number == nil {
+		return errors.New("head block not found")
+	}
+	if *number < retain {
+		log.Info("Nothing to prune", "head", *number, "retain", retain)
+		return nil
+	}
+	tail, err := rawdb.PruneHistory(db, *number-retain+1)
+	if err != nil {
+		return err
+	}
+	log.Info("History pruning completed", "head", *number, "tail", tail)
+	return nil
+}
+
+func dbBackup(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	meta, err := rawdb.Backup(db, ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backed up chain at block #%d [%x], %d ancients\n", meta.HeadNumber, meta.HeadHash, meta.Ancients)
+	return nil
+}
+
+func dbRestore(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	var (
+		chaindata = stack.ResolvePath("chaindata")
+		ancient   = stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+	)
+	meta, err := rawdb.RestoreBackup(ctx.Args().First(), chaindata, ancient)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored chain at block #%d [%x], %d ancients\n", meta.HeadNumber, meta.HeadHash, meta.Ancients)
+	return nil
+}
+
+func dbMigrate(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	var (
+		target    = ctx.String(dbMigrateToFlag.Name)
+		chaindata = stack.ResolvePath("chaindata")
+		migrated  = chaindata + ".migrate"
+		ancient   = stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+		cache     = ctx.Int(utils.CacheFlag.Name) * ctx.Int(utils.CacheDatabaseFlag.Name) / 100
+		handles   = utils.MakeDatabaseHandles(ctx.Int(utils.FDLimitFlag.Name))
+	)
+	if target != "leveldb" && target != "pebble" {
+		return fmt.Errorf("unknown database engine %q", target)
+	}
+	// A previous run might have been interrupted while swapping the directories
+	if !common.FileExist(chaindata) && common.FileExist(migrated) {
+		return os.Rename(migrated, chaindata)
+	}
+	source := rawdb.PreexistingDatabase(chaindata)
+	if source == "" {
+		return fmt.Errorf("no database found at %s", chaindata)
+	}
+	if source == target {
+		return fmt.Errorf("database already uses %s", target)
+	}
+	if engine := rawdb.PreexistingDatabase(migrated); engine != "" && engine != target {
+		return fmt.Errorf("found pre-existing %s database at %s", engine, migrated)
+	}
+	log.Info("Migrating key-value store", "from", source, "to", target, "database", chaindata)
+
+	src, err := rawdb.Open(rawdb.OpenOptions{Type: source, Directory: chaindata, Cache: cache / 2, Handles: handles / 2, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	dst, err := rawdb.Open(rawdb.OpenOptions{Type: target, Directory: migrated, Cache: cache / 2, Handles: handles / 2})
+	if err != nil {
+		src.Close()
+		return err
+	}
+	err = rawdb.MigrateKeyValueStore(src, dst)
+	src.Close()
+	dst.Close()
+	if err != nil {
+		return err
+	}
+	return swapDatabase(chaindata, migrated, ancient)
+}
+
+// swapDatabase replaces the key-value store at chaindata with the migrated one,
+// moving the ancient store along if it lives within the old directory. Every
+// step is a single rename, so an interrupted swap never loses a store.
+func swapDatabase(chaindata, migrated, ancient string) error {
+	old := chaindata + ".old"
+	if common.FileExist(old) {
+		return fmt.Errorf("backup directory %s already exists", old)
+	}
+	if rel, err := filepath.Rel(chaindata, ancient); err == nil && !strings.HasPrefix(rel, "..") && common.FileExist(ancient) {
+		dest := filepath.Join(migrated, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(ancient, dest); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(chaindata, old); err != nil {
+		return err
+	}
+	if err := os.Rename(migrated, chaindata); err != nil {
+		return err
+	}
+	log.Info("Swapped database directories", "database", chaindata, "old", old)
+	return nil
+}
+
+func thoraSnapshotsRebuild(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	config, err := core.LoadChainConfig(db, utils.MakeGenesis(ctx))
+	if err != nil {
+		return err
+	}
+	if config.Thora == nil {
+		return errors.New("chain is not running the Thora consensus engine")
+	}
+	engine := thora.New(config.Thora, db)
+	chain, err := core.NewHeaderChain(db, config, engine, func() bool { return false })
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	written, err := engine.RebuildSnapshots(chain, ctx.Uint64(thoraSnapshotFromFlag.Name))
+	if err != nil {
+		return err
+	}
+	log.Info("Rebuilt Thora checkpoint snapshots", "written", written, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
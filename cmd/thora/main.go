@@ -82,12 +82,20 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolPrioritySendersFlag,
+		utils.TxPoolPriorityContractsFlag,
+		utils.TxPoolPrioritySlotsFlag,
 		utils.SyncModeFlag,
 		utils.SyncTargetFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
+		utils.StateSchemeFlag,
+		utils.StateTrieFlag,
+		utils.StateHistoryFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.HistoryRetainFlag,
+		utils.AddressIndexFlag,
 		//utils.LightServeFlag,
 		//utils.LightIngressFlag,
 		//utils.LightEgressFlag,
@@ -99,6 +107,7 @@ var (
 		//utils.UltraLightOnlyAnnounceFlag,
 		//utils.LightNoSyncServeFlag,
 		utils.EthRequiredBlocksFlag,
+		utils.ThoraPreCommitsFlag,
 		//utils.LegacyWhitelistFlag,
 		utils.BloomFilterSizeFlag,
 		utils.CacheFlag,
@@ -124,6 +133,8 @@ var (
 		utils.MinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNewPayloadTimeout,
+		utils.MinerPriorityGasFlag,
+		utils.MinerOrderingFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV4Flag,
@@ -174,6 +185,11 @@ var (
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalEVMTimeoutFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.TraceCacheFlag,
+		utils.TraceCacheDBFlag,
+		utils.TraceCacheDBBlocksFlag,
+		utils.TracePretraceFlag,
+		utils.TraceFilterRangeFlag,
 		utils.AllowUnprotectedTxs,
 		utils.BatchRequestLimit,
 		utils.BatchResponseMaxSize,
@@ -208,11 +224,14 @@ func init() {
 		initCommand,
 		importCommand,
 		exportCommand,
+		importHistoryCommand,
+		exportHistoryCommand,
 		importPreimagesCommand,
 		exportPreimagesCommand,
 		removedbCommand,
 		dumpCommand,
 		dumpGenesisCommand,
+		verifyBlockCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -19,16 +19,23 @@ package main
 import (
 	"bytes"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/ethereum/go-ethereum/params"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 	"github.com/gballet/go-verkle"
 	cli "github.com/urfave/cli/v2"
 )
@@ -62,6 +69,32 @@ This command takes a root commitment and attempts to rebuild the tree.
 {{.GETHCmd}} verkle dump <state-root> <key 1> [<key 2> ...]
 This command will produce a dot file representing the tree, rooted at <root>.
 in which key1, key2, ... are expanded.
+ `),
+			},
+			{
+				Name:      "convert",
+				Usage:     "Convert the state snapshot of a MPT into a verkle tree",
+				ArgsUsage: "[<root>]",
+				Action:    convertVerkle,
+				Flags:     flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: params.WaterMarkText(`
+{{.GETHCmd}} verkle convert [<state-root>]
+This command iterates the state snapshot at the given root (or the head block
+root if none is given) and writes the equivalent verkle tree into the database.
+The original addresses and storage slots are recovered from the preimage store,
+so the node must have been synced with --cache.preimages enabled.
+ `),
+			},
+			{
+				Name:      "witness",
+				Usage:     "Print the verkle witness generated for a block",
+				ArgsUsage: "<number|hash>",
+				Action:    printVerkleWitness,
+				Flags:     flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: params.WaterMarkText(`
+{{.GETHCmd}} verkle witness <block-number|block-hash>
+This command prints the witness stored for the given block as JSON, the witness
+is only generated by nodes running with --state.trie=verkle.
  `),
 			},
 		},
@@ -211,3 +244,183 @@ func expandVerkle(ctx *cli.Context) error {
 	}
 	return nil
 }
+
+// verkleConvertFlushLimit is the number of leaves inserted into the verkle tree
+// after which the in-memory nodes are flushed into the database.
+const verkleConvertFlushLimit = 500_000
+
+func convertVerkle(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	if ctx.NArg() > 1 {
+		return errors.New("too many arguments")
+	}
+	chaindb := utils.MakeChainDatabase(ctx, stack, false)
+	defer chaindb.Close()
+
+	var (
+		root common.Hash
+		err  error
+	)
+	if ctx.NArg() == 1 {
+		root, err = parseRoot(ctx.Args().First())
+		if err != nil {
+			log.Error("Failed to resolve state root", "error", err)
+			return err
+		}
+	} else {
+		headBlock := rawdb.ReadHeadBlock(chaindb)
+		if headBlock == nil {
+			log.Error("Failed to load head block")
+			return errors.New("no head block")
+		}
+		root = headBlock.Root()
+	}
+	snapConfig := snapshot.Config{
+		CacheSize:  256,
+		Recovery:   false,
+		NoBuild:    true,
+		AsyncBuild: false,
+	}
+	snaptree, err := snapshot.New(snapConfig, chaindb, trie.NewDatabase(chaindb), root)
+	if err != nil {
+		return err
+	}
+	accIt, err := snaptree.AccountIterator(root, common.Hash{})
+	if err != nil {
+		return err
+	}
+	defer accIt.Release()
+
+	vt, err := trie.NewVerkleTrie(types.EmptyRootHash, trie.NewDatabaseWithConfig(chaindb, &trie.Config{Verkle: true}))
+	if err != nil {
+		return err
+	}
+	log.Info("Verkle conversion started", "root", root)
+	var (
+		start    = time.Now()
+		logged   = time.Now()
+		accounts uint64
+		slots    uint64
+		pending  int
+	)
+	for accIt.Next() {
+		account, err := types.FullAccount(accIt.Account())
+		if err != nil {
+			return err
+		}
+		preimage := rawdb.ReadPreimage(chaindb, accIt.Hash())
+		if len(preimage) != common.AddressLength {
+			return fmt.Errorf("missing preimage of account %x, was the node run with --%s?", accIt.Hash(), utils.CachePreimagesFlag.Name)
+		}
+		addr := common.BytesToAddress(preimage)
+
+		if err := vt.UpdateAccount(addr, account); err != nil {
+			return err
+		}
+		if codeHash := common.BytesToHash(account.CodeHash); codeHash != types.EmptyCodeHash {
+			code := rawdb.ReadCode(chaindb, codeHash)
+			if len(code) == 0 {
+				return fmt.Errorf("missing code %x of account %x", codeHash, addr)
+			}
+			if err := vt.UpdateContractCode(addr, codeHash, code); err != nil {
+				return err
+			}
+			pending += (len(code) + 30) / 31
+		}
+		stIt, err := snaptree.StorageIterator(root, accIt.Hash(), common.Hash{})
+		if err != nil {
+			return err
+		}
+		for stIt.Next() {
+			slot := rawdb.ReadPreimage(chaindb, stIt.Hash())
+			if len(slot) != common.HashLength {
+				stIt.Release()
+				return fmt.Errorf("missing preimage of slot %x in account %x, was the node run with --%s?", stIt.Hash(), addr, utils.CachePreimagesFlag.Name)
+			}
+			_, value, _, err := rlp.Split(stIt.Slot())
+			if err != nil {
+				stIt.Release()
+				return err
+			}
+			if err := vt.UpdateStorage(addr, slot, value); err != nil {
+				stIt.Release()
+				return err
+			}
+			slots++
+			pending++
+		}
+		err = stIt.Error()
+		stIt.Release()
+		if err != nil {
+			return err
+		}
+		accounts++
+		pending += 5
+
+		if pending >= verkleConvertFlushLimit {
+			if _, _, err := vt.Commit(false); err != nil {
+				return err
+			}
+			pending = 0
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verkle conversion in progress", "at", accIt.Hash(), "accounts", accounts, "slots", slots,
+				"elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := accIt.Error(); err != nil {
+		return err
+	}
+	vroot, _, err := vt.Commit(false)
+	if err != nil {
+		return err
+	}
+	log.Info("Verkle conversion complete", "root", root, "verkle", vroot, "accounts", accounts, "slots", slots,
+		"elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func printVerkleWitness(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("need <number|hash> arg")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, true)
+	defer chaindb.Close()
+
+	var (
+		arg    = ctx.Args().First()
+		hash   common.Hash
+		number uint64
+	)
+	if n, err := strconv.ParseUint(arg, 10, 64); err == nil {
+		number, hash = n, rawdb.ReadCanonicalHash(chaindb, n)
+	} else {
+		if err := hash.UnmarshalText([]byte(arg)); err != nil {
+			return fmt.Errorf("invalid block number or hash %q", arg)
+		}
+		num := rawdb.ReadHeaderNumber(chaindb, hash)
+		if num == nil {
+			return fmt.Errorf("unknown block %x", hash)
+		}
+		number = *num
+	}
+	blob := rawdb.ReadVerkleWitness(chaindb, hash, number)
+	if len(blob) == 0 {
+		return fmt.Errorf("no verkle witness stored for block #%d [%x]", number, hash)
+	}
+	witness := new(trie.VerkleWitness)
+	if err := rlp.DecodeBytes(blob, witness); err != nil {
+		return err
+	}
+	log.Info("Loaded verkle witness", "number", number, "hash", hash, "stems", len(witness.StateDiff), "size", common.StorageSize(len(blob)))
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(witness)
+}
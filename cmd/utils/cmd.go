@@ -20,11 +20,15 @@ package utils
 import (
 	"bufio"
 	"compress/gzip"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"syscall"
@@ -35,12 +39,15 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/era"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/debug"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 	"github.com/urfave/cli/v2"
 )
 
@@ -296,6 +303,317 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 	return nil
 }
 
+// ExportHistory exports the blockchain history into the specified directory as
+// Era1 archives of step blocks each, along with a checksums.txt file holding
+// the sha256 checksums of the archives. The exported range is extended to the
+// start of the epoch containing first, so every archive covers whole epochs
+// with the exception of the one containing last.
+func ExportHistory(bc *core.BlockChain, dir string, first, last, step uint64) error {
+	log.Info("Exporting blockchain history", "dir", dir)
+	if head := bc.CurrentSnapBlock().Number.Uint64(); head < last {
+		log.Warn("Last block beyond head, setting last = head", "head", head, "last", last)
+		last = head
+	}
+	if first > last {
+		return fmt.Errorf("export failed: first (%d) is greater than last (%d)", first, last)
+	}
+	network := HistoryNetwork(bc.Config())
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+	var (
+		start     = time.Now()
+		reported  = time.Now()
+		checksums []string
+	)
+	for i := first / step * step; i <= last; i += step {
+		err := func() error {
+			filename := filepath.Join(dir, era.Filename(network, int(i/step), common.Hash{}))
+			f, err := os.Create(filename)
+			if err != nil {
+				return fmt.Errorf("could not create era file: %w", err)
+			}
+			defer f.Close()
+
+			w := era.NewBuilder(f)
+			for j := uint64(0); j < step && j <= last-i; j++ {
+				var (
+					n     = i + j
+					block = bc.GetBlockByNumber(n)
+				)
+				if block == nil {
+					return fmt.Errorf("export failed on #%d: not found", n)
+				}
+				receipts := bc.GetReceiptsByHash(block.Hash())
+				if receipts == nil {
+					return fmt.Errorf("export failed on #%d: receipts not found", n)
+				}
+				td := bc.GetTd(block.Hash(), block.NumberU64())
+				if td == nil {
+					return fmt.Errorf("export failed on #%d: total difficulty not found", n)
+				}
+				if err := w.Add(block, receipts, td); err != nil {
+					return err
+				}
+			}
+			root, err := w.Finalize()
+			if err != nil {
+				return fmt.Errorf("export failed to finalize %d: %w", i/step, err)
+			}
+			// Set correct filename with root.
+			final := filepath.Join(dir, era.Filename(network, int(i/step), root))
+			if err := os.Rename(filename, final); err != nil {
+				return err
+			}
+			// Compute checksum of entire Era1.
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			h := sha256.New()
+			if _, err := io.Copy(h, f); err != nil {
+				return fmt.Errorf("unable to calculate checksum: %w", err)
+			}
+			checksums = append(checksums, common.BytesToHash(h.Sum(nil)).Hex())
+			return nil
+		}()
+		if err != nil {
+			return err
+		}
+		if time.Since(reported) >= 8*time.Second {
+			log.Info("Exporting blocks", "exported", i, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "checksums.txt"), []byte(strings.Join(checksums, "\n")), os.ModePerm); err != nil {
+		return fmt.Errorf("unable to write checksums.txt: %w", err)
+	}
+
+	log.Info("Exported blockchain to", "dir", dir)
+	return nil
+}
+
+// HistoryNetwork returns the network name the Era1 archives of a chain are
+// named after.
+func HistoryNetwork(config *params.ChainConfig) string {
+	if name, ok := params.NetworkNames[config.ChainID.String()]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ImportHistory imports the Era1 archives of the given network from the
+// specified directory. The archives are checked against checksums.txt and
+// their accumulators, and every block is verified against the local chain:
+// blocks already present must match, missing ones are inserted along with
+// their receipts after validating their headers and bodies.
+func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string, network string) error {
+	if chain.CurrentSnapBlock().Number.BitLen() != 0 {
+		log.Warn("Importing history into a non-empty chain, existing blocks are verified only")
+	}
+	entries, err := era.ReadDir(dir, network)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", dir, err)
+	}
+	checksums, err := readList(filepath.Join(dir, "checksums.txt"))
+	if err != nil {
+		return fmt.Errorf("unable to read checksums.txt: %w", err)
+	}
+	if len(checksums) != len(entries) {
+		return fmt.Errorf("expected equal number of checksums and entries, have: %d checksums, %d entries", len(checksums), len(entries))
+	}
+	var (
+		start    = time.Now()
+		reported = time.Now()
+		imported = 0
+	)
+	for i, filename := range entries {
+		err := func() error {
+			f, err := os.Open(filepath.Join(dir, filename))
+			if err != nil {
+				return fmt.Errorf("unable to open era: %w", err)
+			}
+			defer f.Close()
+
+			// Validate checksum.
+			h := sha256.New()
+			if _, err := io.Copy(h, f); err != nil {
+				return fmt.Errorf("unable to recalculate checksum: %w", err)
+			}
+			if have, want := common.BytesToHash(h.Sum(nil)).Hex(), checksums[i]; have != want {
+				return fmt.Errorf("checksum mismatch: have %s, want %s", have, want)
+			}
+			e, err := era.From(f)
+			if err != nil {
+				return fmt.Errorf("error opening era: %w", err)
+			}
+			if err := verifyAccumulator(e); err != nil {
+				return err
+			}
+			// Import all block data from Era1.
+			n, err := importEra(chain, db, e)
+			imported += n
+			return err
+		}()
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		if time.Since(reported) >= 8*time.Second {
+			log.Info("Importing Era files", "head", chain.CurrentSnapBlock().Number, "imported", imported, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+	}
+	log.Info("Imported blockchain history", "head", chain.CurrentSnapBlock().Number, "imported", imported, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// verifyAccumulator recomputes the accumulator of an Era1 archive and checks
+// it against the root stored in the archive.
+func verifyAccumulator(e *era.Era) error {
+	var (
+		hashes []common.Hash
+		tds    []*big.Int
+		it     = era.NewIterator(e)
+	)
+	for it.Next() {
+		hashes = append(hashes, it.Block().Hash())
+		tds = append(tds, it.TotalDifficulty())
+	}
+	if err := it.Error(); err != nil {
+		return fmt.Errorf("error reading block %d: %w", it.Number()+1, err)
+	}
+	want, err := e.Accumulator()
+	if err != nil {
+		return fmt.Errorf("error reading accumulator: %w", err)
+	}
+	have, err := era.ComputeAccumulator(hashes, tds)
+	if err != nil {
+		return fmt.Errorf("error computing accumulator: %w", err)
+	}
+	if have != want {
+		return fmt.Errorf("accumulator mismatch: have %x, want %x", have, want)
+	}
+	return nil
+}
+
+// importEra verifies the blocks of an Era1 archive against the local chain and
+// inserts the missing ones, returning the number of blocks inserted.
+func importEra(chain *core.BlockChain, db ethdb.Database, e *era.Era) (int, error) {
+	var (
+		blocks   types.Blocks
+		receipts []types.Receipts
+		tds      []*big.Int
+		imported int
+	)
+	flush := func() error {
+		if len(blocks) == 0 {
+			return nil
+		}
+		headers := make([]*types.Header, len(blocks))
+		for i, block := range blocks {
+			headers[i] = block.Header()
+		}
+		if n, err := chain.InsertHeaderChain(headers); err != nil {
+			return fmt.Errorf("invalid header %d: %w", headers[n].Number, err)
+		}
+		last := blocks[len(blocks)-1]
+		if td := chain.GetTd(last.Hash(), last.NumberU64()); td == nil || td.Cmp(tds[len(tds)-1]) != 0 {
+			return fmt.Errorf("total difficulty mismatch at block %d: have %v, want %v", last.NumberU64(), td, tds[len(tds)-1])
+		}
+		// Store the blocks straight in the ancient store if it is contiguous
+		// with them, otherwise leave them to the freezer.
+		ancientLimit := uint64(0)
+		if frozen, err := db.Ancients(); err == nil {
+			if first := blocks[0].NumberU64(); frozen == first || (frozen == 0 && first == 1) {
+				ancientLimit = math.MaxUint64
+			}
+		}
+		if _, err := chain.InsertReceiptChain(blocks, receipts, ancientLimit); err != nil {
+			return fmt.Errorf("error inserting body %d: %w", blocks[0].NumberU64(), err)
+		}
+		imported += len(blocks)
+		blocks, receipts, tds = blocks[:0], receipts[:0], tds[:0]
+		return nil
+	}
+	it := era.NewIterator(e)
+	for it.Next() {
+		var (
+			block = it.Block()
+			rs    = it.Receipts()
+			td    = it.TotalDifficulty()
+			n     = block.NumberU64()
+		)
+		if n == 0 {
+			if block.Hash() != chain.Genesis().Hash() {
+				return imported, fmt.Errorf("genesis mismatch: have %x, want %x", block.Hash(), chain.Genesis().Hash())
+			}
+			continue
+		}
+		if err := verifyBlockContents(block, rs); err != nil {
+			return imported, fmt.Errorf("invalid block %d: %w", n, err)
+		}
+		// Blocks already present in the local chain must match the archive.
+		if local := chain.GetCanonicalHash(n); local != (common.Hash{}) {
+			if local != block.Hash() {
+				return imported, fmt.Errorf("block %d mismatch: have %x, local %x", n, block.Hash(), local)
+			}
+			if chain.HasBlock(local, n) {
+				if have := chain.GetTd(local, n); have == nil || have.Cmp(td) != 0 {
+					return imported, fmt.Errorf("total difficulty mismatch at block %d: have %v, want %v", n, have, td)
+				}
+				if err := flush(); err != nil {
+					return imported, err
+				}
+				continue
+			}
+		}
+		blocks, receipts, tds = append(blocks, block), append(receipts, rs), append(tds, td)
+		if len(blocks) >= importBatchSize {
+			if err := flush(); err != nil {
+				return imported, err
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return imported, fmt.Errorf("error reading block %d: %w", it.Number()+1, err)
+	}
+	return imported, flush()
+}
+
+// verifyBlockContents checks the body and the receipts of a block against the
+// commitments in its header.
+func verifyBlockContents(block *types.Block, receipts types.Receipts) error {
+	if hash := types.CalcUncleHash(block.Uncles()); hash != block.UncleHash() {
+		return fmt.Errorf("uncle root hash mismatch: have %x, want %x", hash, block.UncleHash())
+	}
+	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != block.TxHash() {
+		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, block.TxHash())
+	}
+	if want := block.Header().WithdrawalsHash; want != nil {
+		if hash := types.DeriveSha(block.Withdrawals(), trie.NewStackTrie(nil)); hash != *want {
+			return fmt.Errorf("withdrawals root hash mismatch: have %x, want %x", hash, *want)
+		}
+	}
+	if hash := types.DeriveSha(receipts, trie.NewStackTrie(nil)); hash != block.ReceiptHash() {
+		return fmt.Errorf("receipt root hash mismatch: have %x, want %x", hash, block.ReceiptHash())
+	}
+	return nil
+}
+
+// readList reads the non-empty lines of a file.
+func readList(filename string) ([]string, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var list []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			list = append(list, line)
+		}
+	}
+	return list, nil
+}
+
 // ImportPreimages imports a batch of exported hash preimages into the database.
 // It's a part of the deprecated functionality, should be removed in the future.
 func ImportPreimages(db ethdb.Database, fn string) error {
@@ -78,6 +78,7 @@ import (
 	pcsclite "github.com/gballet/go-libpcsclite"
 	gopsutil "github.com/shirou/gopsutil/mem"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/exp/slices"
 )
 
 // These are all the command line flags we support.
@@ -111,6 +112,17 @@ var (
 		Usage:    "Root directory for ancient data (default = inside chaindata)",
 		Category: flags.EthCategory,
 	}
+	AncientRemoteFlag = &cli.StringFlag{
+		Name:     "datadir.ancient.remote",
+		Usage:    "Object store URL to offload sealed ancient data segments to (file:///path or s3://bucket/prefix?endpoint=...)",
+		Category: flags.EthCategory,
+	}
+	AncientRemoteCacheFlag = &cli.IntFlag{
+		Name:     "datadir.ancient.remote.cache",
+		Usage:    "Disk space in megabytes for caching offloaded ancient data segments",
+		Value:    node.DefaultConfig.AncientRemoteCache,
+		Category: flags.EthCategory,
+	}
 	MinFreeDiskSpaceFlag = &flags.DirectoryFlag{
 		Name:     "datadir.minfreedisk",
 		Usage:    "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
@@ -226,6 +238,23 @@ var (
 		Value:    "full",
 		Category: flags.EthCategory,
 	}
+	StateSchemeFlag = &cli.StringFlag{
+		Name:     "state.scheme",
+		Usage:    `Scheme to use for storing ethereum state ("hash" or "path", default = stored scheme or "hash")`,
+		Category: flags.EthCategory,
+	}
+	StateTrieFlag = &cli.StringFlag{
+		Name:     "state.trie",
+		Usage:    `Tree to store ethereum state in ("mpt" or the experimental "verkle", developer mode only)`,
+		Value:    "mpt",
+		Category: flags.EthCategory,
+	}
+	StateHistoryFlag = &cli.Uint64Flag{
+		Name:     "state.history",
+		Usage:    "Number of recent blocks to retain state history for, path scheme only (default = 90,000 blocks, 0 = entire chain)",
+		Value:    ethconfig.Defaults.StateHistory,
+		Category: flags.EthCategory,
+	}
 	SnapshotFlag = &cli.BoolFlag{
 		Name:     "snapshot",
 		Usage:    `Enables snapshot-database mode (default = enable)`,
@@ -238,6 +267,16 @@ var (
 		Value:    ethconfig.Defaults.TxLookupLimit,
 		Category: flags.EthCategory,
 	}
+	AddressIndexFlag = &cli.BoolFlag{
+		Name:     "addressindex",
+		Usage:    "Enables indexing the transactions sent by, sent to or creating each address",
+		Category: flags.EthCategory,
+	}
+	HistoryRetainFlag = &cli.Uint64Flag{
+		Name:     "history.retain",
+		Usage:    "Number of recent blocks to retain bodies and receipts for (0 = entire chain)",
+		Category: flags.EthCategory,
+	}
 	LightKDFFlag = &cli.BoolFlag{
 		Name:     "lightkdf",
 		Usage:    "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -248,6 +287,11 @@ var (
 		Usage:    "Comma separated block number-to-hash mappings to require for peering (<number>=<hash>)",
 		Category: flags.EthCategory,
 	}
+	ThoraPreCommitsFlag = &cli.BoolFlag{
+		Name:     "thora.precommits",
+		Usage:    "Gossip signer pre-commits to provide soft finality on Thora networks",
+		Category: flags.EthCategory,
+	}
 	BloomFilterSizeFlag = &cli.Uint64Flag{
 		Name:     "bloomfilter.size",
 		Usage:    "Megabytes of memory allocated to bloom-filter for pruning",
@@ -381,6 +425,22 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolPrioritySendersFlag = &cli.StringFlag{
+		Name:     "txpool.prioritysenders",
+		Usage:    "Comma separated accounts whose transactions are kept in the priority lane",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPriorityContractsFlag = &cli.StringFlag{
+		Name:     "txpool.prioritycontracts",
+		Usage:    "Comma separated contracts whose inbound transactions are kept in the priority lane",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPrioritySlotsFlag = &cli.Uint64Flag{
+		Name:     "txpool.priorityslots",
+		Usage:    "Maximum number of transaction slots reserved for the priority lane",
+		Value:    ethconfig.Defaults.TxPool.PrioritySlots,
+		Category: flags.TxPoolCategory,
+	}
 	// Performance tuning settings
 	CacheFlag = &cli.IntFlag{
 		Name:     "cache",
@@ -480,6 +540,18 @@ var (
 		Value:    ethconfig.Defaults.Miner.NewPayloadTimeout,
 		Category: flags.MinerCategory,
 	}
+	MinerPriorityGasFlag = &cli.Uint64Flag{
+		Name:     "miner.prioritygas",
+		Usage:    "Gas reserved at the top of each block for the transaction pool's priority lane (0 = disabled)",
+		Value:    ethconfig.Defaults.Miner.PriorityGas,
+		Category: flags.MinerCategory,
+	}
+	MinerOrderingFlag = &cli.StringFlag{
+		Name:     "miner.ordering",
+		Usage:    "Transaction ordering policy within sealed blocks (price, fcfs, roundrobin)",
+		Value:    miner.OrderingPrice,
+		Category: flags.MinerCategory,
+	}
 
 	// Account settings
 	UnlockedAccountFlag = &cli.StringFlag{
@@ -532,6 +604,33 @@ var (
 		Value:    ethconfig.Defaults.RPCTxFeeCap,
 		Category: flags.APICategory,
 	}
+	TraceCacheFlag = &cli.IntFlag{
+		Name:     "trace.cache",
+		Usage:    "Number of block traces to cache for the tracing APIs (0 = disabled)",
+		Category: flags.APICategory,
+	}
+	TraceCacheDBFlag = &cli.BoolFlag{
+		Name:     "trace.cache.db",
+		Usage:    "Persist the cached block traces into a separate database",
+		Category: flags.APICategory,
+	}
+	TraceCacheDBBlocksFlag = &cli.Uint64Flag{
+		Name:     "trace.cache.db.blocks",
+		Usage:    "Number of recent blocks whose traces are persisted (0 = all)",
+		Value:    ethconfig.Defaults.TraceCacheDBBlocks,
+		Category: flags.APICategory,
+	}
+	TracePretraceFlag = &cli.StringFlag{
+		Name:     "trace.pretrace",
+		Usage:    "Comma separated list of tracers to run on new canonical blocks, filling the trace cache (e.g. callTracer)",
+		Category: flags.APICategory,
+	}
+	TraceFilterRangeFlag = &cli.Uint64Flag{
+		Name:     "trace.filter.range",
+		Usage:    "Maximum number of blocks a trace_filter request may span (0 = no limit)",
+		Value:    ethconfig.Defaults.TraceFilterRange,
+		Category: flags.APICategory,
+	}
 	// Authenticated RPC HTTP settings
 	AuthListenFlag = &cli.StringFlag{
 		Name:     "authrpc.addr",
@@ -938,6 +1037,8 @@ var (
 	DatabasePathFlags = []cli.Flag{
 		DataDirFlag,
 		AncientFlag,
+		AncientRemoteFlag,
+		AncientRemoteCacheFlag,
 		RemoteDBFlag,
 		HttpHeaderFlag,
 	}
@@ -1434,6 +1535,12 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		log.Info(fmt.Sprintf("Using %s as db engine", dbEngine))
 		cfg.DBEngine = dbEngine
 	}
+	if ctx.IsSet(AncientRemoteFlag.Name) {
+		cfg.AncientRemote = ctx.String(AncientRemoteFlag.Name)
+	}
+	if ctx.IsSet(AncientRemoteCacheFlag.Name) {
+		cfg.AncientRemoteCache = ctx.Int(AncientRemoteCacheFlag.Name)
+	}
 }
 
 func setSmartCard(ctx *cli.Context, cfg *node.Config) {
@@ -1528,6 +1635,28 @@ func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	if ctx.IsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.Duration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.IsSet(TxPoolPrioritySendersFlag.Name) {
+		cfg.PrioritySenders = parseAddressList(ctx, TxPoolPrioritySendersFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPriorityContractsFlag.Name) {
+		cfg.PriorityContracts = parseAddressList(ctx, TxPoolPriorityContractsFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPrioritySlotsFlag.Name) {
+		cfg.PrioritySlots = ctx.Uint64(TxPoolPrioritySlotsFlag.Name)
+	}
+}
+
+// parseAddressList parses a comma separated list of accounts from a flag.
+func parseAddressList(ctx *cli.Context, name string) []common.Address {
+	var addrs []common.Address
+	for _, account := range strings.Split(ctx.String(name), ",") {
+		trimmed := strings.TrimSpace(account)
+		if !common.IsHexAddress(trimmed) {
+			Fatalf("Invalid account in --%s: %s", name, trimmed)
+		}
+		addrs = append(addrs, common.HexToAddress(trimmed))
+	}
+	return addrs
 }
 
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
@@ -1546,6 +1675,16 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.IsSet(MinerNewPayloadTimeout.Name) {
 		cfg.NewPayloadTimeout = ctx.Duration(MinerNewPayloadTimeout.Name)
 	}
+	if ctx.IsSet(MinerPriorityGasFlag.Name) {
+		cfg.PriorityGas = ctx.Uint64(MinerPriorityGasFlag.Name)
+	}
+	if ctx.IsSet(MinerOrderingFlag.Name) {
+		ordering := ctx.String(MinerOrderingFlag.Name)
+		if !slices.Contains(miner.OrderingPolicies(), ordering) {
+			Fatalf("Invalid --%s %q, available: %v", MinerOrderingFlag.Name, ordering, miner.OrderingPolicies())
+		}
+		cfg.Ordering = ordering
+	}
 }
 
 func setRequiredBlocks(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1680,6 +1819,21 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(CacheNoPrefetchFlag.Name) {
 		cfg.NoPrefetch = ctx.Bool(CacheNoPrefetchFlag.Name)
 	}
+	if ctx.IsSet(StateSchemeFlag.Name) {
+		cfg.StateScheme = parseStateScheme(ctx)
+		if cfg.StateScheme == rawdb.PathScheme && cfg.NoPruning {
+			Fatalf("--%s=path is not compatible with --%s=archive", StateSchemeFlag.Name, GCModeFlag.Name)
+		}
+	}
+	if ctx.IsSet(StateTrieFlag.Name) {
+		cfg.StateTrie = parseStateTrie(ctx)
+		if cfg.StateTrie == "verkle" && cfg.StateScheme == rawdb.PathScheme {
+			Fatalf("--%s=verkle is not compatible with --%s=path", StateTrieFlag.Name, StateSchemeFlag.Name)
+		}
+	}
+	if ctx.IsSet(StateHistoryFlag.Name) {
+		cfg.StateHistory = ctx.Uint64(StateHistoryFlag.Name)
+	}
 	// Read the value from the flag no matter if it's set or not.
 	cfg.Preimages = ctx.Bool(CachePreimagesFlag.Name)
 	if cfg.NoPruning && !cfg.Preimages {
@@ -1689,6 +1843,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.Uint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.IsSet(HistoryRetainFlag.Name) {
+		cfg.HistoryRetain = ctx.Uint64(HistoryRetainFlag.Name)
+	}
+	if ctx.IsSet(AddressIndexFlag.Name) {
+		cfg.AddressIndex = ctx.Bool(AddressIndexFlag.Name)
+	}
+	if ctx.IsSet(ThoraPreCommitsFlag.Name) {
+		cfg.ThoraPreCommits = ctx.Bool(ThoraPreCommitsFlag.Name)
+	}
 	if ctx.IsSet(CacheFlag.Name) || ctx.IsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.Int(CacheFlag.Name) * ctx.Int(CacheTrieFlag.Name) / 100
 	}
@@ -1732,8 +1895,26 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.Float64(RPCGlobalTxFeeCapFlag.Name)
 	}
+	if ctx.IsSet(TraceCacheFlag.Name) {
+		cfg.TraceCache = ctx.Int(TraceCacheFlag.Name)
+	}
+	if ctx.IsSet(TraceCacheDBFlag.Name) {
+		cfg.TraceCacheDB = ctx.Bool(TraceCacheDBFlag.Name)
+	}
+	if ctx.IsSet(TraceCacheDBBlocksFlag.Name) {
+		cfg.TraceCacheDBBlocks = ctx.Uint64(TraceCacheDBBlocksFlag.Name)
+	}
+	if ctx.IsSet(TracePretraceFlag.Name) {
+		cfg.TracePretrace = SplitAndTrim(ctx.String(TracePretraceFlag.Name))
+	}
+	if ctx.IsSet(TraceFilterRangeFlag.Name) {
+		cfg.TraceFilterRange = ctx.Uint64(TraceFilterRangeFlag.Name)
+	}
+	if len(cfg.TracePretrace) > 0 && cfg.TraceCache == 0 {
+		Fatalf("--%s requires the trace cache to be enabled with --%s", TracePretraceFlag.Name, TraceCacheFlag.Name)
+	}
 	if ctx.IsSet(NoDiscoverFlag.Name) {
-		cfg.EthDiscoveryURLs, cfg.SnapDiscoveryURLs = []string{}, []string{}
+		cfg.EthDiscoveryURLs, cfg.SnapDiscoveryURLs, cfg.TpcDiscoveryURLs = []string{}, []string{}, []string{}
 	} else if ctx.IsSet(DNSDiscoveryFlag.Name) {
 		urls := ctx.String(DNSDiscoveryFlag.Name)
 		if urls == "" {
@@ -1865,7 +2046,7 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 		if err != nil {
 			Fatalf("Failed to register the %s service: %v", params.PlatformChainInfo.PlatformShortName, err)
 		}
-		stack.RegisterAPIs(tracers.APIs(backend.ApiBackend))
+		stack.RegisterAPIs(tracers.APIs(backend.ApiBackend, nil, cfg.TraceFilterRange))
 		if err := lescatalyst.Register(stack, backend); err != nil {
 			Fatalf("Failed to register the Engine API service: %v", err)
 		}
@@ -1881,10 +2062,32 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 			Fatalf("Failed to create the LES server: %v", err)
 		}
 	}
-	stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
+	stack.RegisterAPIs(tracers.APIs(backend.APIBackend, registerTraceCache(stack, cfg, backend.APIBackend), cfg.TraceFilterRange))
 	return backend.APIBackend, backend
 }
 
+// registerTraceCache creates the block trace cache of the tracing APIs along with
+// its pretracer, if enabled by the config. It returns nil if the cache is disabled.
+func registerTraceCache(stack *node.Node, cfg *ethconfig.Config, backend tracers.PretraceBackend) *tracers.Cache {
+	if cfg.TraceCache <= 0 {
+		return nil
+	}
+	var db ethdb.KeyValueStore
+	if cfg.TraceCacheDB {
+		var err error
+		db, err = stack.OpenDatabase("tracecache", 16, 16, "eth/db/tracecache/", false)
+		if err != nil {
+			Fatalf("Failed to open the trace cache database: %v", err)
+		}
+	}
+	cache := tracers.NewCache(cfg.TraceCache, db, cfg.TraceCacheDBBlocks)
+	if len(cfg.TracePretrace) > 0 {
+		stack.RegisterLifecycle(tracers.NewPretracer(backend, cache, cfg.TracePretrace))
+	}
+	log.Info("Enabled trace cache", "blocks", cfg.TraceCache, "persistent", cfg.TraceCacheDB, "retained", cfg.TraceCacheDBBlocks, "pretrace", cfg.TracePretrace)
+	return cache
+}
+
 // RegisterEthStatsService configures the Ethereum Stats daemon and adds it to the node.
 func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url string) {
 	if err := ethstats.New(stack, backend, backend.Engine(), url); err != nil {
@@ -2084,6 +2287,36 @@ func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	return genesis
 }
 
+// parseStateScheme resolves the state scheme identifier from the CLI flag.
+func parseStateScheme(ctx *cli.Context) string {
+	switch scheme := ctx.String(StateSchemeFlag.Name); scheme {
+	case "hash":
+		return rawdb.HashScheme
+	case "path":
+		return rawdb.PathScheme
+	default:
+		Fatalf("--%s must be either 'hash' or 'path', got %q", StateSchemeFlag.Name, scheme)
+	}
+	return ""
+}
+
+// parseStateTrie resolves the state tree identifier from the CLI flag. The
+// verkle tree is experimental and only allowed on developer chains.
+func parseStateTrie(ctx *cli.Context) string {
+	switch tree := ctx.String(StateTrieFlag.Name); tree {
+	case "mpt":
+		return tree
+	case "verkle":
+		if !ctx.IsSet(DeveloperFlag.Name) {
+			Fatalf("--%s=verkle is only supported in developer mode (--%s)", StateTrieFlag.Name, DeveloperFlag.Name)
+		}
+		return tree
+	default:
+		Fatalf("--%s must be either 'mpt' or 'verkle', got %q", StateTrieFlag.Name, tree)
+	}
+	return ""
+}
+
 // MakeChain creates a chain manager from set command line flags.
 func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockChain, ethdb.Database) {
 	var (
@@ -2109,12 +2342,32 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		TrieTimeLimit:       ethconfig.Defaults.TrieTimeout,
 		SnapshotLimit:       ethconfig.Defaults.SnapshotCache,
 		Preimages:           ctx.Bool(CachePreimagesFlag.Name),
+		StateHistory:        ctx.Uint64(StateHistoryFlag.Name),
+		HistoryRetain:       ctx.Uint64(HistoryRetainFlag.Name),
+	}
+	var provided string
+	if ctx.IsSet(StateSchemeFlag.Name) {
+		provided = parseStateScheme(ctx)
+	}
+	scheme, err := rawdb.ParseStateScheme(provided, chainDb)
+	if err != nil {
+		Fatalf("%v", err)
+	}
+	cache.StateScheme = scheme
+	if scheme == rawdb.PathScheme && cache.TrieDirtyDisabled {
+		Fatalf("--%s=path is not compatible with --%s=archive", StateSchemeFlag.Name, GCModeFlag.Name)
+	}
+	if ctx.IsSet(StateTrieFlag.Name) && parseStateTrie(ctx) == "verkle" {
+		if scheme != rawdb.HashScheme {
+			Fatalf("--%s=verkle is not compatible with --%s=path", StateTrieFlag.Name, StateSchemeFlag.Name)
+		}
+		cache.Verkle = true
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
 		log.Info("Enabling recording of key preimages since archive mode is used")
 	}
-	if !ctx.Bool(SnapshotFlag.Name) {
+	if !ctx.Bool(SnapshotFlag.Name) || cache.Verkle {
 		cache.SnapshotLimit = 0 // Disabled
 	}
 	// If we're in readonly, do not bother generating snapshot data.
@@ -0,0 +1,192 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/era"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	historyKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	historyAddr    = crypto.PubkeyToAddress(historyKey.PublicKey)
+	historyFunds   = big.NewInt(1000000000000000000)
+	historyGenesis = &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{historyAddr: {Balance: historyFunds}},
+	}
+)
+
+// makeHistoryChain creates a chain of n blocks with a transfer in each, using
+// the given coinbase.
+func makeHistoryChain(t *testing.T, n int, coinbase common.Address) (*core.BlockChain, ethdb.Database) {
+	_, blocks, _ := core.GenerateChainWithGenesis(historyGenesis, ethash.NewFaker(), n, func(i int, g *core.BlockGen) {
+		g.SetCoinbase(coinbase)
+		tx, _ := types.SignTx(types.NewTransaction(g.TxNonce(historyAddr), common.Address{0xaa}, big.NewInt(1000), params.TxGas, g.BaseFee(), nil), types.LatestSigner(historyGenesis.Config), historyKey)
+		g.AddTx(tx)
+	})
+	db := rawdb.NewMemoryDatabase()
+	chain, err := core.NewBlockChain(db, nil, historyGenesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("error inserting chain: %v", err)
+	}
+	return chain, db
+}
+
+func TestHistoryImportAndExport(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		count    = 100
+		step     = uint64(16)
+		chain, _ = makeHistoryChain(t, count, common.Address{})
+	)
+	defer chain.Stop()
+
+	// Export history to temp directory.
+	if err := ExportHistory(chain, dir, 0, uint64(count), step); err != nil {
+		t.Fatalf("error exporting history: %v", err)
+	}
+	// Read checksums.
+	b, err := os.ReadFile(filepath.Join(dir, "checksums.txt"))
+	if err != nil {
+		t.Fatalf("failed to read checksums: %v", err)
+	}
+	checksums := strings.Split(string(b), "\n")
+
+	// Verify each Era.
+	entries, err := era.ReadDir(dir, "mainnet")
+	if err != nil {
+		t.Fatalf("error reading era dir: %v", err)
+	}
+	if want := (count + int(step)) / int(step); len(entries) != want || len(checksums) != want {
+		t.Fatalf("archive count mismatch: have %d files, %d checksums, want %d", len(entries), len(checksums), want)
+	}
+	for i, filename := range entries {
+		e, err := era.Open(filepath.Join(dir, filename))
+		if err != nil {
+			t.Fatalf("error opening era file: %v", err)
+		}
+		if e.Start() != uint64(i)*step {
+			t.Fatalf("era %d start mismatch: have %d, want %d", i, e.Start(), uint64(i)*step)
+		}
+		it := era.NewIterator(e)
+		for it.Next() {
+			want := chain.GetBlockByNumber(it.Number())
+			if want.Hash() != it.Block().Hash() {
+				t.Fatalf("block %d hash mismatch", it.Number())
+			}
+			if td := chain.GetTd(want.Hash(), want.NumberU64()); td.Cmp(it.TotalDifficulty()) != 0 {
+				t.Fatalf("block %d td mismatch", it.Number())
+			}
+			if types.DeriveSha(it.Receipts(), trie.NewStackTrie(nil)) != want.ReceiptHash() {
+				t.Fatalf("block %d receipts mismatch", it.Number())
+			}
+		}
+		if err := it.Error(); err != nil {
+			t.Fatalf("error iterating era %d: %v", i, err)
+		}
+		e.Close()
+	}
+	// Now import Era into a fresh chain, backed by an ancient store.
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer db.Close()
+
+	imported, err := core.NewBlockChain(db, nil, historyGenesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	defer imported.Stop()
+
+	if err := ImportHistory(imported, db, dir, "mainnet"); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	if have, want := imported.CurrentSnapBlock().Hash(), chain.CurrentBlock().Hash(); have != want {
+		t.Fatalf("imported chain does not match expected, have (%d, %s) want (%d, %s)", imported.CurrentSnapBlock().Number, have, chain.CurrentBlock().Number, want)
+	}
+	for n := uint64(1); n <= uint64(count); n++ {
+		want := chain.GetBlockByNumber(n)
+		block := imported.GetBlockByNumber(n)
+		if block == nil || block.Hash() != want.Hash() {
+			t.Fatalf("imported block %d mismatch", n)
+		}
+		if len(imported.GetReceiptsByHash(want.Hash())) != len(want.Transactions()) {
+			t.Fatalf("imported receipts %d missing", n)
+		}
+	}
+	// Importing again only verifies the existing blocks.
+	if err := ImportHistory(imported, db, dir, "mainnet"); err != nil {
+		t.Fatalf("failed to reimport chain: %v", err)
+	}
+}
+
+func TestHistoryImportVerification(t *testing.T) {
+	dir := t.TempDir()
+	chain, _ := makeHistoryChain(t, 40, common.Address{})
+	defer chain.Stop()
+
+	if err := ExportHistory(chain, dir, 0, 40, 16); err != nil {
+		t.Fatalf("error exporting history: %v", err)
+	}
+	// A chain diverging from the archives is rejected.
+	forked, db := makeHistoryChain(t, 20, common.Address{0xff})
+	defer forked.Stop()
+
+	if err := ImportHistory(forked, db, dir, "mainnet"); err == nil || !strings.Contains(err.Error(), "block 1 mismatch") {
+		t.Fatalf("expected mismatch error importing into diverging chain, have %v", err)
+	}
+	// A corrupted archive is rejected.
+	entries, err := era.ReadDir(dir, "mainnet")
+	if err != nil {
+		t.Fatalf("error reading era dir: %v", err)
+	}
+	path := filepath.Join(dir, entries[1])
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read era file: %v", err)
+	}
+	blob[len(blob)/2] ^= 0xff
+	if err := os.WriteFile(path, blob, 0644); err != nil {
+		t.Fatalf("failed to write era file: %v", err)
+	}
+	fresh, db := makeHistoryChain(t, 0, common.Address{})
+	defer fresh.Stop()
+
+	if err := ImportHistory(fresh, db, dir, "mainnet"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum error importing corrupted archive, have %v", err)
+	}
+}
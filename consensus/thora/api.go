@@ -17,7 +17,9 @@
 package thora
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -123,6 +125,42 @@ func (api *API) Discard(address common.Address) {
 	delete(api.thora.proposals, address)
 }
 
+// GetConfirmations retrieves the signer pre-commits collected for a block and
+// whether they reached a quorum of the current signers.
+func (api *API) GetConfirmations(hash common.Hash) (*Confirmations, error) {
+	return api.thora.Confirmations(api.chain, hash)
+}
+
+// Confirmations creates a subscription that fires each time a block reaches a
+// quorum of signer pre-commits.
+func (api *API) Confirmations(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	events := make(chan ConfirmationEvent, 16)
+	sub := api.thora.SubscribeConfirmations(events)
+	if sub == nil {
+		return nil, errors.New("consensus engine closed")
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case event := <-events:
+				notifier.Notify(rpcSub.ID, event)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 type status struct {
 	InturnPercent float64                `json:"inturnPercent"`
 	SigningStatus map[common.Address]int `json:"sealerActivity"`
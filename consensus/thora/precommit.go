@@ -0,0 +1,249 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package thora
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	inmemoryPreCommits  = 256 // Number of recent blocks to track pre-commits for
+	maxFuturePreCommits = 16  // Maximum number of blocks a pre-commit may be ahead of the local head
+)
+
+var (
+	// errInvalidPreCommit is returned if a pre-commit signature cannot be recovered.
+	errInvalidPreCommit = errors.New("invalid pre-commit signature")
+
+	// errStalePreCommit is returned if a pre-commit references a block too far
+	// behind or ahead of the local chain head.
+	errStalePreCommit = errors.New("pre-commit out of tracked range")
+
+	// errMismatchingPreCommit is returned if a pre-commit references a known block
+	// hash with a different number.
+	errMismatchingPreCommit = errors.New("pre-commit number mismatch")
+
+	// errMissingSigner is returned if a pre-commit is requested from an engine
+	// without any signing credentials.
+	errMissingSigner = errors.New("no signer authorized")
+)
+
+// PreCommit is a statement signed by an authorized signer that it accepted a
+// freshly sealed block. Once a quorum of the current signers pre-committed the
+// same block, it is considered softly final ahead of the next block.
+type PreCommit struct {
+	Number    uint64      // Number of the pre-committed block
+	Hash      common.Hash // Hash of the pre-committed block
+	Signature []byte      // Signature of the signer over the number and hash
+}
+
+// preCommitSigData returns the data a signer needs to sign to pre-commit a block.
+// It is domain separated from header seals so the two can never be mixed up.
+func preCommitSigData(number uint64, hash common.Hash) []byte {
+	blob, err := rlp.EncodeToBytes([]interface{}{"thora-precommit", number, hash})
+	if err != nil {
+		panic("can't encode: " + err.Error())
+	}
+	return blob
+}
+
+// Signer recovers the address of the account that signed the pre-commit.
+func (p *PreCommit) Signer() (common.Address, error) {
+	if len(p.Signature) != crypto.SignatureLength {
+		return common.Address{}, errInvalidPreCommit
+	}
+	pubkey, err := crypto.Ecrecover(crypto.Keccak256(preCommitSigData(p.Number, p.Hash)), p.Signature)
+	if err != nil {
+		return common.Address{}, errInvalidPreCommit
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	return signer, nil
+}
+
+// ID returns a unique identifier of the pre-commit, used to track which ones
+// have already been seen by a remote peer.
+func (p *PreCommit) ID() common.Hash {
+	return crypto.Keccak256Hash(p.Signature)
+}
+
+// ConfirmationEvent is posted when a quorum of the current signers pre-committed
+// a block.
+type ConfirmationEvent struct {
+	Number  uint64           `json:"number"`
+	Hash    common.Hash      `json:"hash"`
+	Signers []common.Address `json:"signers"`
+}
+
+// Confirmations is the pre-commit status of a block.
+type Confirmations struct {
+	Number    uint64           `json:"number"`
+	Hash      common.Hash      `json:"hash"`
+	Signers   []common.Address `json:"signers"`   // Signers that pre-committed the block
+	Quorum    int              `json:"quorum"`    // Number of pre-commits needed to confirm the block
+	Confirmed bool             `json:"confirmed"` // Whether a quorum was reached
+}
+
+// preCommits is the set of pre-commits collected for a single block.
+type preCommits struct {
+	number    uint64
+	signers   map[common.Address]struct{}
+	confirmed bool
+}
+
+// SignPreCommit creates a pre-commit for the given block with the local signing
+// credentials.
+func (c *Thora) SignPreCommit(number uint64, hash common.Hash) (*PreCommit, error) {
+	c.lock.RLock()
+	signer, signFn := c.signer, c.signFn
+	c.lock.RUnlock()
+
+	if signFn == nil {
+		return nil, errMissingSigner
+	}
+	sig, err := signFn(accounts.Account{Address: signer}, accounts.MimetypeThoraPreCommit, preCommitSigData(number, hash))
+	if err != nil {
+		return nil, err
+	}
+	return &PreCommit{Number: number, Hash: hash, Signature: sig}, nil
+}
+
+// AddPreCommit validates a pre-commit against the signers authorized at the
+// pre-committed block and tracks it. It returns whether the pre-commit was not
+// yet known, in which case it is worth relaying to other peers.
+//
+// Pre-commits of blocks not yet imported are rejected, the block needs to be
+// known locally to resolve its signers.
+func (c *Thora) AddPreCommit(chain consensus.ChainHeaderReader, pc *PreCommit) (bool, error) {
+	// Discard anything out of the tracked range before doing any expensive work
+	number := chain.CurrentHeader().Number.Uint64()
+	if pc.Number+inmemoryPreCommits < number || pc.Number > number+maxFuturePreCommits {
+		return false, errStalePreCommit
+	}
+	header := chain.GetHeaderByHash(pc.Hash)
+	if header == nil {
+		return false, errUnknownBlock
+	}
+	if header.Number.Uint64() != pc.Number {
+		return false, errMismatchingPreCommit
+	}
+	signer, err := pc.Signer()
+	if err != nil {
+		return false, err
+	}
+	snap, err := c.snapshot(chain, pc.Number, pc.Hash, nil)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := snap.Signers[signer]; !ok {
+		return false, errUnauthorizedSigner
+	}
+	c.precommitLock.Lock()
+	set, ok := c.precommits.Get(pc.Hash)
+	if !ok {
+		set = &preCommits{number: pc.Number, signers: make(map[common.Address]struct{})}
+		c.precommits.Add(pc.Hash, set)
+	}
+	if set.number != pc.Number {
+		c.precommitLock.Unlock()
+		return false, errMismatchingPreCommit
+	}
+	if _, ok := set.signers[signer]; ok {
+		c.precommitLock.Unlock()
+		return false, nil
+	}
+	set.signers[signer] = struct{}{}
+
+	var event *ConfirmationEvent
+	if !set.confirmed && len(set.signers) >= preCommitQuorum(snap) {
+		set.confirmed = true
+		event = &ConfirmationEvent{Number: set.number, Hash: pc.Hash, Signers: sortedSigners(set.signers)}
+	}
+	c.precommitLock.Unlock()
+
+	if event != nil {
+		c.confirmFeed.Send(*event)
+	}
+	return true, nil
+}
+
+// Confirmations returns the pre-commits collected for the given block hash,
+// along with the quorum required by the signers at that block.
+func (c *Thora) Confirmations(chain consensus.ChainHeaderReader, hash common.Hash) (*Confirmations, error) {
+	header := chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := c.snapshot(chain, header.Number.Uint64(), hash, nil)
+	if err != nil {
+		return nil, err
+	}
+	res := &Confirmations{
+		Number:  header.Number.Uint64(),
+		Hash:    hash,
+		Signers: []common.Address{},
+		Quorum:  preCommitQuorum(snap),
+	}
+	c.precommitLock.Lock()
+	if set, ok := c.precommits.Get(hash); ok {
+		res.Signers = sortedSigners(set.signers)
+		res.Confirmed = set.confirmed
+	}
+	c.precommitLock.Unlock()
+
+	return res, nil
+}
+
+// IsInvalidPreCommit reports whether a pre-commit was rejected for a bad
+// signature, an unauthorized signer or a mismatching block number, as opposed
+// to merely being stale or referencing a block not yet known. Such pre-commits
+// can never become valid, so peers relaying them are misbehaving.
+func IsInvalidPreCommit(err error) bool {
+	return errors.Is(err, errInvalidPreCommit) || errors.Is(err, errUnauthorizedSigner) || errors.Is(err, errMismatchingPreCommit)
+}
+
+// IsPendingPreCommit reports whether a pre-commit was rejected only because the
+// block it references is not yet known locally. Such pre-commits may become
+// valid once the block is imported, so they are worth retrying until then.
+func IsPendingPreCommit(err error) bool {
+	return errors.Is(err, errUnknownBlock)
+}
+
+// SubscribeConfirmations registers a subscription for blocks reaching a quorum
+// of signer pre-commits.
+func (c *Thora) SubscribeConfirmations(ch chan<- ConfirmationEvent) event.Subscription {
+	return c.scope.Track(c.confirmFeed.Subscribe(ch))
+}
+
+// preCommitQuorum returns the number of pre-commits required to confirm a block,
+// a strict majority of the signers in the snapshot.
+func preCommitQuorum(snap *Snapshot) int {
+	return len(snap.Signers)/2 + 1
+}
+
+// sortedSigners returns the signers of a pre-commit set in ascending order.
+func sortedSigners(signers map[common.Address]struct{}) []common.Address {
+	snap := &Snapshot{Signers: signers}
+	return snap.signers()
+}
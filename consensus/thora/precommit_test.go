@@ -0,0 +1,111 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package thora
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that pre-commits are only accepted from authorized signers, are
+// deduplicated and confirm a block once a strict majority signed it.
+func TestPreCommitQuorum(t *testing.T) {
+	var (
+		pool  = newTesterAccountPool()
+		chain = &testerChainReader{config: params.TestChainConfig}
+	)
+	genesis := &types.Header{
+		Number:     new(big.Int),
+		Difficulty: new(big.Int),
+		Extra:      make([]byte, extraVanity+3*common.AddressLength+extraSeal),
+	}
+	pool.checkpoint(genesis, []string{"A", "B", "C"})
+	chain.headers = append(chain.headers, genesis)
+
+	engine := New(&params.ThoraConfig{Epoch: 30000}, rawdb.NewMemoryDatabase())
+	defer engine.Close()
+
+	events := make(chan ConfirmationEvent, 1)
+	sub := engine.SubscribeConfirmations(events)
+	defer sub.Unsubscribe()
+
+	// Sign pre-commits for the genesis block from all accounts
+	hash := genesis.Hash()
+	sign := func(signer string) *PreCommit {
+		engine.Authorize(pool.address(signer), func(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+			return crypto.Sign(crypto.Keccak256(data), pool.accounts[signer])
+		}, nil)
+		pc, err := engine.SignPreCommit(0, hash)
+		if err != nil {
+			t.Fatalf("failed to sign pre-commit: %v", err)
+		}
+		if have, err := pc.Signer(); err != nil || have != pool.address(signer) {
+			t.Fatalf("signer mismatch: have %x, want %x, err %v", have, pool.address(signer), err)
+		}
+		return pc
+	}
+	if _, err := engine.AddPreCommit(chain, sign("D")); !errors.Is(err, errUnauthorizedSigner) || !IsInvalidPreCommit(err) {
+		t.Fatalf("unauthorized pre-commit error mismatch: have %v, want %v", err, errUnauthorizedSigner)
+	}
+	// Pre-commits with a wrong number are invalid, unknown blocks are not yet
+	mismatch := sign("A")
+	mismatch.Number = 1
+	if _, err := engine.AddPreCommit(chain, mismatch); !errors.Is(err, errMismatchingPreCommit) || !IsInvalidPreCommit(err) {
+		t.Fatalf("mismatching pre-commit error mismatch: have %v, want %v", err, errMismatchingPreCommit)
+	}
+	unknown := &PreCommit{Number: 1, Hash: common.Hash{0x01}, Signature: mismatch.Signature}
+	if _, err := engine.AddPreCommit(chain, unknown); !errors.Is(err, errUnknownBlock) || IsInvalidPreCommit(err) || !IsPendingPreCommit(err) {
+		t.Fatalf("unknown pre-commit error mismatch: have %v, want %v", err, errUnknownBlock)
+	}
+	a := sign("A")
+	if added, err := engine.AddPreCommit(chain, a); err != nil || !added {
+		t.Fatalf("pre-commit not added: added %v, err %v", added, err)
+	}
+	if added, err := engine.AddPreCommit(chain, a); err != nil || added {
+		t.Fatalf("duplicate pre-commit added: added %v, err %v", added, err)
+	}
+	if res, err := engine.Confirmations(chain, hash); err != nil || res.Confirmed || res.Quorum != 2 || len(res.Signers) != 1 {
+		t.Fatalf("premature confirmation: %+v, err %v", res, err)
+	}
+	if added, err := engine.AddPreCommit(chain, sign("B")); err != nil || !added {
+		t.Fatalf("pre-commit not added: added %v, err %v", added, err)
+	}
+	select {
+	case ev := <-events:
+		if ev.Hash != hash || len(ev.Signers) != 2 {
+			t.Fatalf("confirmation event mismatch: %+v", ev)
+		}
+	default:
+		t.Fatalf("no confirmation event fired")
+	}
+	if res, err := engine.Confirmations(chain, hash); err != nil || !res.Confirmed {
+		t.Fatalf("block not confirmed: %+v, err %v", res, err)
+	}
+	// Pre-commits too far ahead of the head should be rejected
+	future := &PreCommit{Number: maxFuturePreCommits + 1, Hash: common.Hash{0x01}, Signature: a.Signature}
+	if _, err := engine.AddPreCommit(chain, future); err == nil {
+		t.Fatalf("future pre-commit accepted")
+	}
+}
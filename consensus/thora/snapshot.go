@@ -97,6 +97,19 @@ func loadSnapshot(config *params.ThoraConfig, sigcache *sigLRU, db ethdb.Databas
 	return snap, nil
 }
 
+// LoadSnapshot loads an existing checkpoint snapshot from the database without
+// attaching it to a running engine. It is meant for offline database tooling,
+// the returned snapshot cannot be used to apply further headers.
+func LoadSnapshot(db ethdb.Database, hash common.Hash) (*Snapshot, error) {
+	return loadSnapshot(nil, nil, db, hash)
+}
+
+// DeleteSnapshot removes the checkpoint snapshot of the given block hash from
+// the database.
+func DeleteSnapshot(db ethdb.KeyValueWriter, hash common.Hash) error {
+	return db.Delete(append(rawdb.ThoraSnapshotPrefix, hash[:]...))
+}
+
 // store inserts the snapshot into the database.
 func (s *Snapshot) store(db ethdb.Database) error {
 	blob, err := json.Marshal(s)
@@ -559,3 +559,92 @@ func makePlatformChain2(genesis *core.Genesis, blocks []*types.Block, accounts *
 
 	return newBlocks
 }
+
+// testerChainReader implements consensus.ChainHeaderReader on top of a plain
+// list of canonical headers.
+type testerChainReader struct {
+	config  *params.ChainConfig
+	headers []*types.Header
+}
+
+func (r *testerChainReader) Config() *params.ChainConfig  { return r.config }
+func (r *testerChainReader) CurrentHeader() *types.Header { return r.headers[len(r.headers)-1] }
+func (r *testerChainReader) GetTd(common.Hash, uint64) *big.Int {
+	return nil
+}
+func (r *testerChainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := r.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+func (r *testerChainReader) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(r.headers)) {
+		return nil
+	}
+	return r.headers[number]
+}
+func (r *testerChainReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range r.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+
+// Tests that checkpoint snapshots can be rebuilt from the canonical headers,
+// replacing corrupted entries and leaving older checkpoints untouched.
+func TestRebuildSnapshots(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.ThoraConfig{Epoch: 30000}
+		chain    = &testerChainReader{config: params.TestChainConfig}
+	)
+	genesis := &types.Header{
+		Number:     new(big.Int),
+		Difficulty: new(big.Int),
+		Extra:      make([]byte, extraVanity+common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, []string{"A"})
+	chain.headers = append(chain.headers, genesis)
+
+	for i := 1; i <= 2*checkpointInterval+5; i++ {
+		header := &types.Header{
+			ParentHash: chain.headers[i-1].Hash(),
+			Number:     big.NewInt(int64(i)),
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		accounts.sign(header, "A")
+		chain.headers = append(chain.headers, header)
+	}
+	db := rawdb.NewMemoryDatabase()
+	if n, err := New(config, db).RebuildSnapshots(chain, 0); err != nil || n != 3 {
+		t.Fatalf("rebuild failed: written %d, err %v", n, err)
+	}
+	for _, number := range []uint64{0, checkpointInterval, 2 * checkpointInterval} {
+		snap, err := LoadSnapshot(db, chain.headers[number].Hash())
+		if err != nil {
+			t.Fatalf("checkpoint %d missing: %v", number, err)
+		}
+		if snap.Number != number {
+			t.Fatalf("checkpoint number mismatch: have %d, want %d", snap.Number, number)
+		}
+	}
+	// Corrupt the most recent checkpoint and rebuild only the tail
+	last := chain.headers[2*checkpointInterval].Hash()
+	if err := db.Put(append(rawdb.ThoraSnapshotPrefix, last[:]...), []byte("corrupt")); err != nil {
+		t.Fatalf("failed to corrupt checkpoint: %v", err)
+	}
+	if n, err := New(config, db).RebuildSnapshots(chain, checkpointInterval+1); err != nil || n != 1 {
+		t.Fatalf("rebuild failed: written %d, err %v", n, err)
+	}
+	snap, err := LoadSnapshot(db, last)
+	if err != nil {
+		t.Fatalf("checkpoint not rebuilt: %v", err)
+	}
+	if signers := snap.signers(); len(signers) != 1 || signers[0] != accounts.address("A") {
+		t.Fatalf("signers mismatch: have %x", signers)
+	}
+}
@@ -19,6 +19,7 @@ package thora
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -33,10 +34,12 @@ import (
 	lru "github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -178,6 +181,11 @@ type Thora struct {
 
 	proposals map[common.Address]bool // Current list of proposals we are pushing
 
+	precommits    *lru.Cache[common.Hash, *preCommits] // Signer pre-commits collected for recent blocks
+	precommitLock sync.Mutex                           // Protects the pre-commit sets from concurrent updates
+	confirmFeed   event.Feed                           // Event feed to notify about blocks reaching a pre-commit quorum
+	scope         event.SubscriptionScope              // Subscription scope tracking the confirmation subscribers
+
 	signer        common.Address // Ethereum address of the signing key
 	signFn        SignerFn       // Signer function to authorize hashes with
 	onSignerFnErr OnSignerFnErr  // On Signer function error
@@ -205,6 +213,7 @@ func New(config *params.ThoraConfig, db ethdb.Database) *Thora {
 		recents:    recents,
 		signatures: signatures,
 		proposals:  make(map[common.Address]bool),
+		precommits: lru.NewCache[common.Hash, *preCommits](inmemoryPreCommits),
 	}
 }
 
@@ -214,6 +223,25 @@ func (c *Thora) Author(header *types.Header) (common.Address, error) {
 	return ecrecover(header, c.signatures)
 }
 
+// NextSealer returns the signer expected to seal the block following the given
+// parent, which is the account credited with the transaction fees of the block.
+// It is the local signer if authorized at the parent, otherwise the in-turn one.
+func (c *Thora) NextSealer(chain consensus.ChainHeaderReader, parent *types.Header) (common.Address, error) {
+	snap, err := c.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	c.lock.RLock()
+	signer := c.signer
+	c.lock.RUnlock()
+
+	if _, authorized := snap.Signers[signer]; authorized {
+		return signer, nil
+	}
+	signers := snap.signers()
+	return signers[(parent.Number.Uint64()+1)%uint64(len(signers))], nil
+}
+
 // VerifyHeader checks whether a header conforms to the consensus rules.
 func (c *Thora) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header) error {
 	return c.verifyHeader(chain, header, nil)
@@ -449,6 +477,65 @@ func (c *Thora) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 	return snap, err
 }
 
+// RebuildSnapshots recomputes the checkpoint snapshots of the canonical chain
+// from the given block number up to the current head and writes them to disk.
+// Any checkpoint already stored at or above that number, as well as any blob
+// that fails to decode, is discarded beforehand so stale or corrupted entries
+// cannot leak into the rebuilt ones. The number of written checkpoints is
+// returned.
+func (c *Thora) RebuildSnapshots(chain consensus.ChainHeaderReader, from uint64) (int, error) {
+	it := c.db.NewIterator(rawdb.ThoraSnapshotPrefix, nil)
+	batch := c.db.NewBatch()
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(rawdb.ThoraSnapshotPrefix)+common.HashLength {
+			continue
+		}
+		snap := new(Snapshot)
+		if err := json.Unmarshal(it.Value(), snap); err == nil && snap.Number < from {
+			continue
+		}
+		if err := batch.Delete(key); err != nil {
+			it.Release()
+			return 0, err
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return 0, err
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	c.recents.Purge()
+
+	// Walk the canonical chain checkpoint by checkpoint, each step reusing the
+	// in-memory snapshot of the previous one.
+	var (
+		head    = chain.CurrentHeader().Number.Uint64()
+		number  = (from + checkpointInterval - 1) / checkpointInterval * checkpointInterval
+		written int
+	)
+	for ; number <= head; number += checkpointInterval {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return written, fmt.Errorf("missing canonical header #%d", number)
+		}
+		snap, err := c.snapshot(chain, number, header.Hash(), nil)
+		if err != nil {
+			return written, err
+		}
+		// The genesis and trusted checkpoints are stored by snapshot, but if the
+		// snapshot was already in memory it isn't, so make sure it hits the disk.
+		if err := snap.store(c.db); err != nil {
+			return written, err
+		}
+		written++
+		log.Info("Rebuilt voting snapshot", "number", snap.Number, "hash", snap.Hash)
+	}
+	return written, nil
+}
+
 // VerifyUncles implements consensus.Engine, always returning an error for any
 // uncles as this consensus mechanism doesn't permit uncles.
 func (c *Thora) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
@@ -692,8 +779,10 @@ func (c *Thora) SealHash(header *types.Header) common.Hash {
 	return SealHash(header)
 }
 
-// Close implements consensus.Engine. It's a noop for thora as there are no background threads.
+// Close implements consensus.Engine. There are no background threads, only the
+// pre-commit confirmation subscriptions are terminated.
 func (c *Thora) Close() error {
+	c.scope.Close()
 	return nil
 }
 
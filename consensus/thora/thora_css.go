@@ -1,6 +1,8 @@
 package thora
 
 import (
+	"bytes"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -50,3 +52,44 @@ func (c *Thora) Finalize(chain consensus.ChainHeaderReader, header *types.Header
 		}
 	}
 }
+
+// CheckpointSigners returns the list of authorized signers embedded into the
+// extra-data of a checkpoint header, or nil if the header carries none.
+func CheckpointSigners(header *types.Header) []common.Address {
+	if len(header.Extra) <= extraVanity+extraSeal {
+		return nil
+	}
+	payload := header.Extra[extraVanity : len(header.Extra)-extraSeal]
+	if len(payload)%common.AddressLength != 0 {
+		return nil
+	}
+	signers := make([]common.Address, len(payload)/common.AddressLength)
+	for i := 0; i < len(signers); i++ {
+		copy(signers[i][:], payload[i*common.AddressLength:])
+	}
+	return signers
+}
+
+// HeaderVote returns the authorization vote cast by the sealer of the header.
+// The last return value is false if the header doesn't carry any vote.
+func HeaderVote(header *types.Header) (address common.Address, authorize bool, ok bool) {
+	if header.Coinbase == (common.Address{}) {
+		return common.Address{}, false, false
+	}
+	return header.Coinbase, bytes.Equal(header.Nonce[:], nonceAuthVote), true
+}
+
+// IsInTurn reports whether the header was sealed by the in-turn signer.
+func IsInTurn(header *types.Header) bool {
+	return header.Difficulty != nil && header.Difficulty.Cmp(diffInTurn) == 0
+}
+
+// FromEngine returns the Thora engine backing the given consensus engine,
+// unwrapping it from a beacon engine if needed.
+func FromEngine(engine consensus.Engine) (*Thora, bool) {
+	if wrapper, ok := engine.(interface{ InnerEngine() consensus.Engine }); ok {
+		engine = wrapper.InnerEngine()
+	}
+	c, ok := engine.(*Thora)
+	return c, ok
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// addressThrottling is the time to wait between processing two consecutive
+	// index sections. It's useful during chain upgrades to prevent disk overload.
+	addressThrottling = 100 * time.Millisecond
+)
+
+// AddressIndexer implements a core.ChainIndexer, building up an index of the
+// canonical transactions sent by, sent to or creating each address.
+type AddressIndexer struct {
+	size    uint64                                       // section size to generate the index for
+	db      ethdb.Database                               // database instance to write index data and metadata into
+	config  *params.ChainConfig                          // chain config to derive transaction signers from
+	section uint64                                       // Section is the section number being processed currently
+	entries map[common.Address][]rawdb.AddressIndexEntry // Entries gathered for the current section
+}
+
+// NewAddressIndexer returns a chain indexer that generates the address index
+// for the canonical chain.
+func NewAddressIndexer(db ethdb.Database, config *params.ChainConfig, size, confirms uint64) *ChainIndexer {
+	backend := &AddressIndexer{
+		db:     db,
+		config: config,
+		size:   size,
+	}
+	table := rawdb.NewTable(db, string(rawdb.AddressIndexPrefix))
+
+	return NewChainIndexer(db, table, backend, size, confirms, addressThrottling, "address")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new address index
+// section. Any entries previously stored for the section belong to a chain
+// that has since been reorged out, so they are unwound first.
+func (a *AddressIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	rawdb.DeleteAddressIndexSection(a.db, section, a.size)
+	a.section, a.entries = section, make(map[common.Address][]rawdb.AddressIndexEntry)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the transactions of a new
+// block into the index.
+func (a *AddressIndexer) Process(ctx context.Context, header *types.Header) error {
+	number := header.Number.Uint64()
+	body := rawdb.ReadBody(a.db, header.Hash(), number)
+	if body == nil {
+		// Bodies below the history tail are pruned, there's nothing to index
+		if number < rawdb.ReadHistoryTail(a.db) {
+			return nil
+		}
+		return fmt.Errorf("block body #%d [%x..] not found", number, header.Hash().Bytes()[:4])
+	}
+	signer := types.MakeSigner(a.config, header.Number, header.Time)
+	for i, tx := range body.Transactions {
+		addrs, err := TransactionAddresses(signer, tx)
+		if err != nil {
+			return fmt.Errorf("transaction %d of block #%d: %v", i, number, err)
+		}
+		for addr, roles := range addrs {
+			a.entries[addr] = append(a.entries[addr], rawdb.AddressIndexEntry{Number: number, Index: uint32(i), Roles: roles})
+		}
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing the gathered section out
+// into the database.
+func (a *AddressIndexer) Commit() error {
+	batch := a.db.NewBatch()
+	rawdb.WriteAddressIndexSection(batch, a.section, a.entries)
+	return batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (a *AddressIndexer) Prune(threshold uint64) error {
+	return nil
+}
+
+// TransactionAddresses returns the addresses the address index records for a
+// transaction, along with the roles they have in it.
+func TransactionAddresses(signer types.Signer, tx *types.Transaction) (map[common.Address]uint8, error) {
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, err
+	}
+	addrs := map[common.Address]uint8{from: rawdb.AddressRoleSender}
+	if to := tx.To(); to != nil {
+		addrs[*to] |= rawdb.AddressRoleRecipient
+	} else {
+		addrs[crypto.CreateAddress(from, tx.Nonce())] |= rawdb.AddressRoleCreation
+	}
+	return addrs, nil
+}
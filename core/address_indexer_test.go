@@ -0,0 +1,127 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the address index records senders, recipients and created contracts
+// and that reindexing a section after a reorg unwinds the stale entries.
+func TestAddressIndexer(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr1  = crypto.PubkeyToAddress(key.PublicKey)
+		addr2  = common.Address{0x02}
+		addr3  = common.Address{0x03}
+		gspec  = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{addr1: {Balance: big.NewInt(100000000000000000)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer  = types.LatestSigner(gspec.Config)
+		engine  = ethash.NewFaker()
+		created = crypto.CreateAddress(addr1, 1)
+	)
+	transfer := func(to common.Address) func(int, *BlockGen) {
+		return func(i int, block *BlockGen) {
+			var tx *types.Transaction
+			switch {
+			case to == addr2 && i == 1:
+				tx = types.NewContractCreation(block.TxNonce(addr1), new(big.Int), 100000, block.header.BaseFee, nil)
+			case to == addr2 && i%2 == 1:
+				return
+			default:
+				tx = types.NewTransaction(block.TxNonce(addr1), to, big.NewInt(1000), params.TxGas, block.header.BaseFee, nil)
+			}
+			signed, err := types.SignTx(tx, signer, key)
+			if err != nil {
+				panic(err)
+			}
+			block.AddTx(signed)
+		}
+	}
+	genDb, blocks, _ := GenerateChainWithGenesis(gspec, engine, 8, transfer(addr2))
+	forks, _ := GenerateChain(gspec.Config, blocks[3], engine, genDb, 5, transfer(addr3))
+
+	db := rawdb.NewMemoryDatabase()
+	chain, err := NewBlockChain(db, nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	indexer := &AddressIndexer{db: db, config: gspec.Config, size: 4}
+	process := func(section uint64) {
+		t.Helper()
+
+		if err := indexer.Reset(context.Background(), section, common.Hash{}); err != nil {
+			t.Fatalf("failed to reset section %d: %v", section, err)
+		}
+		for number := section * 4; number < (section+1)*4; number++ {
+			if err := indexer.Process(context.Background(), chain.GetHeaderByNumber(number)); err != nil {
+				t.Fatalf("failed to process block #%d: %v", number, err)
+			}
+		}
+		if err := indexer.Commit(); err != nil {
+			t.Fatalf("failed to commit section %d: %v", section, err)
+		}
+	}
+	check := func(addr common.Address, want map[uint64]uint8) {
+		t.Helper()
+
+		have := rawdb.ReadAddressIndex(db, addr, 0, 7, 0)
+		if len(have) != len(want) {
+			t.Fatalf("entry count mismatch for %x: have %d, want %d", addr, len(have), len(want))
+		}
+		for _, entry := range have {
+			if roles, ok := want[entry.Number]; !ok || roles != entry.Roles || entry.Index != 0 {
+				t.Fatalf("unexpected entry for %x: %+v", addr, entry)
+			}
+		}
+	}
+	process(0)
+	process(1)
+
+	check(addr1, map[uint64]uint8{1: rawdb.AddressRoleSender, 2: rawdb.AddressRoleSender, 3: rawdb.AddressRoleSender, 5: rawdb.AddressRoleSender, 7: rawdb.AddressRoleSender})
+	check(addr2, map[uint64]uint8{1: rawdb.AddressRoleRecipient, 3: rawdb.AddressRoleRecipient, 5: rawdb.AddressRoleRecipient, 7: rawdb.AddressRoleRecipient})
+	check(created, map[uint64]uint8{2: rawdb.AddressRoleCreation})
+	check(addr3, nil)
+
+	// Reorg the second section and ensure reindexing it drops the stale entries
+	if _, err := chain.InsertChain(forks); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	process(1)
+
+	check(addr1, map[uint64]uint8{1: rawdb.AddressRoleSender, 2: rawdb.AddressRoleSender, 3: rawdb.AddressRoleSender, 5: rawdb.AddressRoleSender, 6: rawdb.AddressRoleSender, 7: rawdb.AddressRoleSender})
+	check(addr2, map[uint64]uint8{1: rawdb.AddressRoleRecipient, 3: rawdb.AddressRoleRecipient})
+	check(created, map[uint64]uint8{2: rawdb.AddressRoleCreation})
+	check(addr3, map[uint64]uint8{5: rawdb.AddressRoleRecipient, 6: rawdb.AddressRoleRecipient, 7: rawdb.AddressRoleRecipient})
+}
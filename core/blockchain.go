@@ -47,6 +47,7 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/triedb/pathdb"
 	"golang.org/x/exp/slices"
 )
 
@@ -85,6 +86,10 @@ var (
 	blockPrefetchExecuteTimer   = metrics.NewRegisteredTimer("chain/prefetch/executes", nil)
 	blockPrefetchInterruptMeter = metrics.NewRegisteredMeter("chain/prefetch/interrupts", nil)
 
+	verkleWitnessTimer     = metrics.NewRegisteredTimer("chain/verkle/witness", nil)
+	verkleWitnessSizeHist  = metrics.NewRegisteredHistogram("chain/verkle/witness/size", nil, metrics.NewExpDecaySample(1028, 0.015))
+	verkleWitnessLeafsHist = metrics.NewRegisteredHistogram("chain/verkle/witness/leafs", nil, metrics.NewExpDecaySample(1028, 0.015))
+
 	errInsertionInterrupted = errors.New("insertion is interrupted")
 	errChainStopped         = errors.New("blockchain is stopped")
 	errInvalidOldChain      = errors.New("invalid old chain")
@@ -100,6 +105,11 @@ const (
 	maxTimeFutureBlocks = 30
 	TriesInMemory       = 128
 
+	// prunedTxUnindexBatch is the minimum number of pruned blocks whose
+	// transaction indices are removed at once, each removal iterating the
+	// whole transaction index.
+	prunedTxUnindexBatch = 100000
+
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	//
 	// Changelog:
@@ -136,6 +146,10 @@ type CacheConfig struct {
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
 	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages           bool          // Whether to store preimage of trie key to the disk
+	StateScheme         string        // Scheme used to store ethereum states and merkle tree nodes on top
+	StateHistory        uint64        // Number of blocks from head whose state histories are reserved, path scheme only
+	HistoryRetain       uint64        // Number of blocks from head whose bodies and receipts are retained, zero retains all
+	Verkle              bool          // Whether the state is kept in an experimental verkle tree, hash scheme only
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
@@ -151,6 +165,21 @@ var defaultCacheConfig = &CacheConfig{
 	SnapshotWait:   true,
 }
 
+// triedbConfig derives the configuration for the trie database.
+func (c *CacheConfig) triedbConfig() *trie.Config {
+	config := &trie.Config{
+		Cache:     c.TrieCleanLimit,
+		Preimages: c.Preimages,
+		Verkle:    c.Verkle,
+	}
+	if c.StateScheme == rawdb.PathScheme {
+		config.PathDB = &pathdb.Config{
+			StateHistory: c.StateHistory,
+		}
+	}
+	return config
+}
+
 // BlockChain represents the canonical chain given a database with a genesis
 // block. The Blockchain manages chain imports, reverts, chain reorganisations.
 //
@@ -185,6 +214,10 @@ type BlockChain struct {
 	//  * nil: disable tx reindexer/deleter, but still index new blocks
 	txLookupLimit uint64
 
+	// historyLock serializes the transaction indexer and the history pruner,
+	// preventing the indexer from reading the bodies being pruned.
+	historyLock sync.Mutex
+
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
 	chainFeed     event.Feed
@@ -234,10 +267,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		cacheConfig = defaultCacheConfig
 	}
 	// Open trie database with provided config
-	triedb := trie.NewDatabaseWithConfig(db, &trie.Config{
-		Cache:     cacheConfig.TrieCleanLimit,
-		Preimages: cacheConfig.Preimages,
-	})
+	triedb := trie.NewDatabaseWithConfig(db, cacheConfig.triedbConfig())
 	// Setup the genesis block, commit the provided genesis specification
 	// to database if the genesis block is not present yet, or load the
 	// stored one from database.
@@ -425,6 +455,15 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		bc.wg.Add(1)
 		go bc.maintainTxIndex()
 	}
+	// Start the history pruner if required.
+	if bc.cacheConfig.HistoryRetain > 0 {
+		if _, err := bc.db.Ancients(); err != nil {
+			log.Warn("History pruning requires an ancient store, disabling", "err", err)
+		} else {
+			bc.wg.Add(1)
+			go bc.maintainHistory()
+		}
+	}
 	return bc, nil
 }
 
@@ -631,7 +670,7 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 					if root != (common.Hash{}) && !beyondRoot && newHeadBlock.Root() == root {
 						beyondRoot, rootNumber = true, newHeadBlock.NumberU64()
 					}
-					if !bc.HasState(newHeadBlock.Root()) {
+					if !bc.HasState(newHeadBlock.Root()) && !bc.stateRecoverable(newHeadBlock.Root()) {
 						log.Trace("Block state missing, rewinding further", "number", newHeadBlock.NumberU64(), "hash", newHeadBlock.Hash())
 						if pivot == nil || newHeadBlock.NumberU64() > *pivot {
 							parent := bc.GetBlock(newHeadBlock.ParentHash(), newHeadBlock.NumberU64()-1)
@@ -647,6 +686,14 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 						}
 					}
 					if beyondRoot || newHeadBlock.NumberU64() == 0 {
+						if !bc.HasState(newHeadBlock.Root()) && bc.stateRecoverable(newHeadBlock.Root()) {
+							// Rewind to a block with recoverable state. If the state is
+							// missing, run the state recovery here.
+							if err := bc.triedb.Recover(newHeadBlock.Root()); err != nil {
+								log.Crit("Failed to rollback state", "err", err) // Shouldn't happen
+							}
+							log.Debug("Rewound to block with recovered state", "number", newHeadBlock.NumberU64(), "hash", newHeadBlock.Hash())
+						}
 						if newHeadBlock.NumberU64() == 0 {
 							// Recommit the genesis state into disk in case the rewinding destination
 							// is genesis block and the relevant state is gone. In the future this
@@ -654,6 +701,13 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 							// if the historical chain pruning is enabled. In that case the logic
 							// needs to be improved here.
 							if !bc.HasState(bc.genesisBlock.Root()) {
+								// The path-based database can only hold a single persistent
+								// state, wipe the stale one before recommitting the genesis.
+								if bc.triedb.Scheme() == rawdb.PathScheme {
+									if err := bc.triedb.Reset(types.EmptyRootHash); err != nil {
+										log.Crit("Failed to clean state", "err", err)
+									}
+								}
 								if err := CommitGenesisState(bc.db, bc.triedb, bc.genesisBlock.Hash()); err != nil {
 									log.Crit("Failed to commit genesis state", "err", err)
 								}
@@ -771,7 +825,13 @@ func (bc *BlockChain) SnapSyncCommitHead(hash common.Hash) error {
 	if block == nil {
 		return fmt.Errorf("non existent block [%x..]", hash[:4])
 	}
+	// Reset the trie database with the fresh snap synced state.
 	root := block.Root()
+	if bc.triedb.Scheme() == rawdb.PathScheme {
+		if err := bc.triedb.Reset(root); err != nil {
+			return err
+		}
+	}
 	if !bc.HasState(root) {
 		return fmt.Errorf("non existent state [%x..]", root[:4])
 	}
@@ -938,11 +998,16 @@ func (bc *BlockChain) Stop() {
 	}
 
 	// Ensure the state of a recent block is also stored to disk before exiting.
-	// We're writing three different states to catch different restart scenarios:
+	// The path-based database journals all its in-memory layers, otherwise
+	// we're writing three different states to catch different restart scenarios:
 	//  - HEAD:     So we don't need to reprocess any blocks in the general case
 	//  - HEAD-1:   So we don't do large reorgs if our HEAD becomes an uncle
 	//  - HEAD-127: So we have a hard limit on the number of blocks reexecuted
-	if !bc.cacheConfig.TrieDirtyDisabled {
+	if bc.triedb.Scheme() == rawdb.PathScheme {
+		if err := bc.triedb.Journal(bc.CurrentBlock().Root); err != nil {
+			log.Info("Failed to journal in-memory trie nodes", "err", err)
+		}
+	} else if !bc.cacheConfig.TrieDirtyDisabled {
 		triedb := bc.triedb
 
 		for _, offset := range []uint64{0, 1, TriesInMemory - 1} {
@@ -1332,6 +1397,9 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	rawdb.WriteBlock(blockBatch, block)
 	rawdb.WriteReceipts(blockBatch, block.Hash(), block.NumberU64(), receipts)
 	rawdb.WritePreimages(blockBatch, state.Preimages())
+	if bc.triedb.IsVerkle() {
+		bc.writeVerkleWitness(blockBatch, block, state)
+	}
 	if err := blockBatch.Write(); err != nil {
 		log.Crit("Failed to write block into disk", "err", err)
 	}
@@ -1340,6 +1408,11 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	if err != nil {
 		return err
 	}
+	// If node is running in path mode, skip explicit gc operation
+	// which is unnecessary in this mode.
+	if bc.triedb.Scheme() == rawdb.PathScheme {
+		return nil
+	}
 	// If we're running an archive node, always flush
 	if bc.cacheConfig.TrieDirtyDisabled {
 		return bc.triedb.Commit(root, false)
@@ -1395,6 +1468,37 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	return nil
 }
 
+// writeVerkleWitness generates the witness of all the state accessed by a block
+// and stores it along with the block, so that witness sizes can be measured on
+// real workloads. Failures are only logged, since the witness isn't needed for
+// processing the chain.
+func (bc *BlockChain) writeVerkleWitness(db ethdb.KeyValueWriter, block *types.Block, state *state.StateDB) {
+	start := time.Now()
+	witness, err := state.VerkleWitness()
+	if err != nil {
+		log.Error("Failed to generate verkle witness", "number", block.Number(), "hash", block.Hash(), "err", err)
+		return
+	}
+	blob, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		log.Error("Failed to encode verkle witness", "number", block.Number(), "hash", block.Hash(), "err", err)
+		return
+	}
+	rawdb.WriteVerkleWitness(db, block.Hash(), block.NumberU64(), blob)
+
+	var leafs int
+	for _, stem := range witness.StateDiff {
+		leafs += len(stem.SuffixDiffs)
+	}
+	verkleWitnessTimer.UpdateSince(start)
+	verkleWitnessSizeHist.Update(int64(len(blob)))
+	verkleWitnessLeafsHist.Update(int64(leafs))
+
+	log.Debug("Generated verkle witness", "number", block.Number(), "hash", block.Hash(),
+		"stems", len(witness.StateDiff), "leafs", leafs, "size", common.StorageSize(len(blob)),
+		"elapsed", common.PrettyDuration(time.Since(start)))
+}
+
 // WriteBlockAndSetHead writes the given block and all associated state to the database,
 // and applies the block as the new chain head.
 func (bc *BlockChain) WriteBlockAndSetHead(block *types.Block, receipts []*types.Receipt, logs []*types.Log, state *state.StateDB, emitHeadEvent bool) (status WriteStatus, err error) {
@@ -2331,6 +2435,12 @@ func (bc *BlockChain) skipBlock(err error, it *insertIterator) bool {
 func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{}) {
 	defer func() { close(done) }()
 
+	bc.historyLock.Lock()
+	defer bc.historyLock.Unlock()
+
+	// The bodies below the history tail are pruned, they can't be indexed.
+	floor := rawdb.ReadHistoryTail(bc.db)
+
 	// The tail flag is not existent, it means the node is just initialized
 	// and all blocks(may from ancient store) are not indexed yet.
 	if tail == nil {
@@ -2338,12 +2448,15 @@ func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{})
 		if bc.txLookupLimit != 0 && head >= bc.txLookupLimit {
 			from = head - bc.txLookupLimit + 1
 		}
+		if from < floor {
+			from = floor
+		}
 		rawdb.IndexTransactions(bc.db, from, head+1, bc.quit)
 		return
 	}
 	// The tail flag is existent, but the whole chain is required to be indexed.
 	if bc.txLookupLimit == 0 || head < bc.txLookupLimit {
-		if *tail > 0 {
+		if *tail > floor {
 			// It can happen when chain is rewound to a historical point which
 			// is even lower than the indexes tail, recap the indexing target
 			// to new head to avoid reading non-existent block bodies.
@@ -2351,17 +2464,39 @@ func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{})
 			if end > head+1 {
 				end = head + 1
 			}
-			rawdb.IndexTransactions(bc.db, 0, end, bc.quit)
+			rawdb.IndexTransactions(bc.db, floor, end, bc.quit)
 		}
 		return
 	}
 	// Update the transaction index to the new chain state
-	if head-bc.txLookupLimit+1 < *tail {
+	if from := head - bc.txLookupLimit + 1; from < *tail {
 		// Reindex a part of missing indices and rewind index tail to HEAD-limit
-		rawdb.IndexTransactions(bc.db, head-bc.txLookupLimit+1, *tail, bc.quit)
+		if from < floor {
+			from = floor
+		}
+		rawdb.IndexTransactions(bc.db, from, *tail, bc.quit)
 	} else {
 		// Unindex a part of stale indices and forward index tail to HEAD-limit
-		rawdb.UnindexTransactions(bc.db, *tail, head-bc.txLookupLimit+1, bc.quit)
+		from, to := *tail, head-bc.txLookupLimit+1
+		if from < floor {
+			// The bodies of the pruned blocks are gone, so their indices can only
+			// be removed by iterating the whole index. Do it in large batches.
+			end := to
+			if end > floor {
+				end = floor
+			}
+			if end < floor && end-from < prunedTxUnindexBatch {
+				return
+			}
+			rawdb.UnindexPrunedTransactions(bc.db, from, end, bc.quit)
+			select {
+			case <-bc.quit:
+				return
+			default:
+			}
+			from = end
+		}
+		rawdb.UnindexTransactions(bc.db, from, to, bc.quit)
 	}
 }
 
@@ -2408,6 +2543,64 @@ func (bc *BlockChain) maintainTxIndex() {
 	}
 }
 
+// maintainHistory is responsible for pruning the bodies and receipts of the
+// blocks older than the configured history retention from the ancient store.
+// The headers, canonical hashes and total difficulties are retained.
+func (bc *BlockChain) maintainHistory() {
+	defer bc.wg.Done()
+
+	var (
+		done   chan struct{}                  // Non-nil if background pruning is active.
+		headCh = make(chan ChainHeadEvent, 1) // Buffered to avoid locking up the event feed
+	)
+	sub := bc.SubscribeChainHeadEvent(headCh)
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case head := <-headCh:
+			if done == nil {
+				done = make(chan struct{})
+				go bc.pruneHistory(head.Block.NumberU64(), done)
+			}
+		case <-done:
+			done = nil
+		case <-bc.quit:
+			if done != nil {
+				log.Info("Waiting background history pruner to exit")
+				<-done
+			}
+			return
+		}
+	}
+}
+
+// pruneHistory prunes the bodies and receipts of the blocks beyond the history
+// retention of the given head. Only frozen blocks are pruned.
+func (bc *BlockChain) pruneHistory(head uint64, done chan struct{}) {
+	defer func() { close(done) }()
+
+	retain := bc.cacheConfig.HistoryRetain
+	if head < retain {
+		return
+	}
+	bc.historyLock.Lock()
+	defer bc.historyLock.Unlock()
+
+	// The indexer may have held the lock until shutdown.
+	select {
+	case <-bc.quit:
+		return
+	default:
+	}
+	if _, err := rawdb.PruneHistory(bc.db, head-retain+1); err != nil {
+		log.Warn("Failed to prune chain history", "err", err)
+	}
+}
+
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
 	rawdb.WriteBadBlock(bc.db, block)
@@ -282,6 +282,17 @@ func (bc *BlockChain) HasState(hash common.Hash) bool {
 	return err == nil
 }
 
+// stateRecoverable checks if the specified state is recoverable.
+// Note, this function assumes the state is not present, because
+// state is not treated as recoverable if it's available, thus
+// false will be returned in this case.
+func (bc *BlockChain) stateRecoverable(root common.Hash) bool {
+	if bc.triedb.Scheme() == rawdb.HashScheme {
+		return false
+	}
+	return bc.triedb.Recoverable(root)
+}
+
 // HasBlockAndState checks if a block and associated state trie is fully present
 // in the database or not, caching it if present.
 func (bc *BlockChain) HasBlockAndState(hash common.Hash, number uint64) bool {
@@ -376,6 +387,18 @@ func (bc *BlockChain) TxLookupLimit() uint64 {
 	return bc.txLookupLimit
 }
 
+// HistoryTail retrieves the number of the first block whose body and receipts
+// are retained, the history of the blocks below it has been pruned.
+func (bc *BlockChain) HistoryTail() uint64 {
+	return rawdb.ReadHistoryTail(bc.db)
+}
+
+// HistoryPruned reports whether the body and receipts of the given block have
+// been pruned. The genesis block is always retained.
+func (bc *BlockChain) HistoryPruned(number uint64) bool {
+	return number > 0 && number < bc.HistoryTail()
+}
+
 // TrieDB retrieves the low level trie database used for data storage.
 func (bc *BlockChain) TrieDB() *trie.Database {
 	return bc.triedb
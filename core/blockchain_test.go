@@ -17,6 +17,7 @@
 package core
 
 import (
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math/big"
@@ -4341,3 +4342,268 @@ func TestEIP3651(t *testing.T) {
 		t.Fatalf("sender balance incorrect: expected %d, got %d", expected, actual)
 	}
 }
+
+// newPathSchemeChain creates a blockchain storing its state in the path-based
+// scheme, keeping the histories of the given number of recent states. Snapshots
+// are disabled to not interfere with the state recovery.
+func newPathSchemeChain(t *testing.T, db ethdb.Database, gspec *Genesis, history uint64) *BlockChain {
+	config := *defaultCacheConfig
+	config.SnapshotLimit = 0
+	config.StateScheme = rawdb.PathScheme
+	config.StateHistory = history
+
+	chain, err := NewBlockChain(db, &config, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	return chain
+}
+
+// makePathSchemeBlocks generates a chain of blocks transferring funds to fresh
+// accounts, so that every block modifies the state. Blocks from the given fork
+// number on differ from the ones of other chains.
+func makePathSchemeBlocks(gspec *Genesis, key *ecdsa.PrivateKey, n int, fork int) []*types.Block {
+	signer := types.LatestSigner(gspec.Config)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), n, func(i int, b *BlockGen) {
+		seed := byte(1)
+		if i >= fork {
+			seed = 2
+		}
+		b.SetCoinbase(common.Address{seed})
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(crypto.PubkeyToAddress(key.PublicKey)), common.Address{seed, byte(i), byte(i >> 8)}, big.NewInt(1000), params.TxGas, b.header.BaseFee, nil), signer, key)
+		b.AddTx(tx)
+	})
+	return blocks
+}
+
+func newPathSchemeGenesis() (*Genesis, *ecdsa.PrivateKey) {
+	key, _ := crypto.GenerateKey()
+	return &Genesis{
+		Config:  params.AllEthashProtocolChanges,
+		BaseFee: big.NewInt(params.InitialBaseFee),
+		Alloc:   GenesisAlloc{crypto.PubkeyToAddress(key.PublicKey): {Balance: big.NewInt(params.Ether)}},
+	}, key
+}
+
+// Tests that the path-based scheme serves the states of recent blocks, also
+// across reorgs, while keeping a single state on disk.
+func TestPathSchemeReorg(t *testing.T) {
+	gspec, key := newPathSchemeGenesis()
+	chain := newPathSchemeChain(t, rawdb.NewMemoryDatabase(), gspec, 0)
+	defer chain.Stop()
+
+	blocks := makePathSchemeBlocks(gspec, key, 2*TriesInMemory, 2*TriesInMemory)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for _, block := range blocks[len(blocks)-TriesInMemory:] {
+		if !chain.HasState(block.Root()) {
+			t.Fatalf("missing state of block %d", block.NumberU64())
+		}
+	}
+	if chain.HasState(blocks[len(blocks)-TriesInMemory-2].Root()) {
+		t.Fatal("stale state is still available")
+	}
+	// Reorg to a longer chain forking off a recent block
+	fork := makePathSchemeBlocks(gspec, key, 2*TriesInMemory+2, 2*TriesInMemory-16)
+	if _, err := chain.InsertChain(fork[2*TriesInMemory-16:]); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != fork[len(fork)-1].Hash() {
+		t.Fatalf("head mismatch: have %d, want %d", head.Number, len(fork))
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatalf("failed to open head state: %v", err)
+	}
+	if statedb.GetBalance(common.Address{2, byte(len(fork) - 1), byte((len(fork) - 1) >> 8)}).Uint64() != 1000 {
+		t.Fatal("head state mismatch")
+	}
+}
+
+// Tests that the in-memory states of the path-based scheme survive a restart,
+// and that the chain is rewound to the persistent state after a crash.
+func TestPathSchemeRestart(t *testing.T) {
+	gspec, key := newPathSchemeGenesis()
+	db := rawdb.NewMemoryDatabase()
+	chain := newPathSchemeChain(t, db, gspec, 0)
+
+	blocks := makePathSchemeBlocks(gspec, key, 2*TriesInMemory, 2*TriesInMemory)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	chain.Stop()
+
+	// Graceful restart, all the recent states are restored from the journal
+	chain = newPathSchemeChain(t, db, gspec, 0)
+	if head := chain.CurrentBlock(); head.Number.Uint64() != uint64(len(blocks)) {
+		t.Fatalf("head mismatch after restart: have %d, want %d", head.Number, len(blocks))
+	}
+	for _, block := range blocks[len(blocks)-TriesInMemory:] {
+		if !chain.HasState(block.Root()) {
+			t.Fatalf("missing state of block %d", block.NumberU64())
+		}
+	}
+	more := makePathSchemeBlocks(gspec, key, 2*TriesInMemory+8, 2*TriesInMemory+8)
+	if _, err := chain.InsertChain(more[len(blocks):]); err != nil {
+		t.Fatalf("failed to extend chain: %v", err)
+	}
+	// Crash without journaling, the chain is rewound to the persistent state
+	chain.stopWithoutSaving()
+	chain = newPathSchemeChain(t, db, gspec, 0)
+	defer chain.Stop()
+
+	if head := chain.CurrentBlock(); head.Number.Uint64() != uint64(len(more)-TriesInMemory) {
+		t.Fatalf("head mismatch after crash: have %d, want %d", head.Number, len(more)-TriesInMemory)
+	}
+	if _, err := chain.InsertChain(more[len(more)-TriesInMemory:]); err != nil {
+		t.Fatalf("failed to reimport chain: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != more[len(more)-1].Hash() {
+		t.Fatalf("head mismatch after reimport: have %d, want %d", head.Number, len(more))
+	}
+}
+
+// Tests that the path-based scheme rolls back the persistent state with the
+// retained histories when rewinding the chain, and that rewinding beyond the
+// history window falls back to the genesis state.
+func TestPathSchemeSetHead(t *testing.T) {
+	gspec, key := newPathSchemeGenesis()
+	chain := newPathSchemeChain(t, rawdb.NewMemoryDatabase(), gspec, 16)
+	defer chain.Stop()
+
+	blocks := makePathSchemeBlocks(gspec, key, 2*TriesInMemory, 2*TriesInMemory)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Rewind below the persistent state, within the history window
+	disk := uint64(len(blocks) - TriesInMemory)
+	target := disk - 8
+	if chain.HasState(blocks[target-1].Root()) {
+		t.Fatal("state below the disk layer is available")
+	}
+	if err := chain.SetHead(target); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Number.Uint64() != target {
+		t.Fatalf("head mismatch: have %d, want %d", head.Number, target)
+	}
+	if !chain.HasState(blocks[target-1].Root()) {
+		t.Fatal("rewound state not recovered")
+	}
+	if _, err := chain.InsertChain(blocks[target:]); err != nil {
+		t.Fatalf("failed to reimport chain: %v", err)
+	}
+	// Rewind beyond the history window, the genesis state is recommitted
+	if err := chain.SetHead(disk - 32); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Number.Uint64() != 0 {
+		t.Fatalf("head mismatch: have %d, want 0", head.Number)
+	}
+	if !chain.HasState(chain.Genesis().Root()) {
+		t.Fatal("genesis state not recommitted")
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to reimport chain: %v", err)
+	}
+}
+
+func TestHistoryPruning(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{address: {Balance: big.NewInt(100000000000000000)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 128, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x00}, big.NewInt(1000), params.TxGas, block.header.BaseFee, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	ancientDb, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create temp freezer db: %v", err)
+	}
+	defer ancientDb.Close()
+	rawdb.WriteAncientBlocks(ancientDb, append([]*types.Block{gspec.ToBlock()}, blocks...), append([]types.Receipts{{}}, receipts...), big.NewInt(0))
+
+	config := *defaultCacheConfig
+	config.HistoryRetain = 32
+
+	limit := uint64(0)
+	chain, err := NewBlockChain(ancientDb, &config, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, &limit)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	chain.indexBlocks(rawdb.ReadTxIndexTail(ancientDb), 128, make(chan struct{}))
+	chain.pruneHistory(128, make(chan struct{}))
+
+	check := func(chain *BlockChain) {
+		t.Helper()
+
+		if tail := chain.HistoryTail(); tail != 97 {
+			t.Fatalf("history tail mismatch: have %d, want 97", tail)
+		}
+		if tail := rawdb.ReadTxIndexTail(ancientDb); tail == nil || *tail != 0 {
+			t.Fatalf("tx index tail mismatch: have %v, want 0", tail)
+		}
+		if chain.GetBlockByNumber(0) == nil {
+			t.Fatalf("genesis block pruned")
+		}
+		for _, block := range blocks {
+			number := block.NumberU64()
+			if chain.GetHeaderByNumber(number) == nil {
+				t.Fatalf("header %d pruned", number)
+			}
+			pruned := number < 97
+			if have := chain.GetBlockByNumber(number) == nil; have != pruned {
+				t.Fatalf("block %d pruned mismatch: have %v, want %v", number, have, pruned)
+			}
+			if have := chain.GetReceiptsByHash(block.Hash()) == nil; have != pruned {
+				t.Fatalf("receipts %d pruned mismatch: have %v, want %v", number, have, pruned)
+			}
+			if rawdb.ReadTxLookupEntry(ancientDb, block.Transactions()[0].Hash()) == nil {
+				t.Fatalf("tx lookup %d pruned", number)
+			}
+		}
+	}
+	check(chain)
+
+	// The indexer must not try to index the pruned blocks
+	chain.indexBlocks(rawdb.ReadTxIndexTail(ancientDb), 128, make(chan struct{}))
+	check(chain)
+	chain.Stop()
+
+	// The pruned chain must be reopenable
+	chain, err = NewBlockChain(ancientDb, &config, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, &limit)
+	if err != nil {
+		t.Fatalf("failed to reopen tester chain: %v", err)
+	}
+	defer chain.Stop()
+	check(chain)
+
+	// The indices of the pruned blocks are only removed in large batches, or
+	// once all of them fall out of the lookup limit
+	chain.SetTxLookupLimit(64)
+	chain.indexBlocks(rawdb.ReadTxIndexTail(ancientDb), 128, make(chan struct{}))
+	check(chain)
+
+	chain.SetTxLookupLimit(16)
+	chain.indexBlocks(rawdb.ReadTxIndexTail(ancientDb), 128, make(chan struct{}))
+	if tail := rawdb.ReadTxIndexTail(ancientDb); tail == nil || *tail != 113 {
+		t.Fatalf("tx index tail mismatch: have %v, want 113", tail)
+	}
+	for _, block := range blocks {
+		number := block.NumberU64()
+		if have, want := rawdb.ReadTxLookupEntry(ancientDb, block.Transactions()[0].Hash()) != nil, number >= 113; have != want {
+			t.Fatalf("tx lookup %d presence mismatch: have %v, want %v", number, have, want)
+		}
+	}
+}
@@ -434,6 +434,21 @@ func isCanon(reader ethdb.AncientReaderOp, number uint64, hash common.Hash) bool
 	return bytes.Equal(h, hash[:])
 }
 
+// ErrHistoryPruned is returned when the body or the receipts of a block are
+// requested, which have been pruned from the ancient store.
+var ErrHistoryPruned = errors.New("pruned history unavailable")
+
+// ReadHistoryTail retrieves the number of the first block whose body and
+// receipts are retained in the database. The headers, canonical hashes and
+// total difficulties of all blocks are retained regardless.
+func ReadHistoryTail(db ethdb.AncientReaderOp) uint64 {
+	tail, err := db.Tail()
+	if err != nil {
+		return 0
+	}
+	return tail
+}
+
 // ReadBodyRLP retrieves the block body (transactions and uncles) in RLP encoding.
 func ReadBodyRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
 	// First try to look up the data in ancient database. Extra hash
@@ -444,7 +459,11 @@ func ReadBodyRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue
 		// Check if the data is in ancients
 		if isCanon(reader, number, hash) {
 			data, _ = reader.Ancient(ChainFreezerBodiesTable, number)
-			return nil
+			if len(data) > 0 {
+				return nil
+			}
+			// The body may have been pruned from the ancients, in which case
+			// only the genesis is retained in leveldb.
 		}
 		// If not, try reading from leveldb
 		data, _ = db.Get(blockBodyKey(number, hash))
@@ -481,7 +500,7 @@ func WriteBodyRLP(db ethdb.KeyValueWriter, hash common.Hash, number uint64, rlp
 
 // HasBody verifies the existence of a block body corresponding to the hash.
 func HasBody(db ethdb.Reader, hash common.Hash, number uint64) bool {
-	if isCanon(db, number, hash) {
+	if isCanon(db, number, hash) && number >= ReadHistoryTail(db) {
 		return true
 	}
 	if has, err := db.Has(blockBodyKey(number, hash)); !has || err != nil {
@@ -571,7 +590,7 @@ func DeleteTd(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 // HasReceipts verifies the existence of all the transaction receipts belonging
 // to a block.
 func HasReceipts(db ethdb.Reader, hash common.Hash, number uint64) bool {
-	if isCanon(db, number, hash) {
+	if isCanon(db, number, hash) && number >= ReadHistoryTail(db) {
 		return true
 	}
 	if has, err := db.Has(blockReceiptsKey(number, hash)); !has || err != nil {
@@ -587,7 +606,11 @@ func ReadReceiptsRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawVa
 		// Check if the data is in ancients
 		if isCanon(reader, number, hash) {
 			data, _ = reader.Ancient(ChainFreezerReceiptTable, number)
-			return nil
+			if len(data) > 0 {
+				return nil
+			}
+			// The receipts may have been pruned from the ancients, in which
+			// case only the genesis is retained in leveldb.
 		}
 		// If not, try reading from leveldb
 		data, _ = db.Get(blockReceiptsKey(number, hash))
@@ -818,6 +841,7 @@ func DeleteBlock(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	DeleteHeader(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
+	DeleteVerkleWitness(db, hash, number)
 }
 
 // DeleteBlockWithoutNumber removes all block data associated with a hash, except
@@ -827,6 +851,7 @@ func DeleteBlockWithoutNumber(db ethdb.KeyValueWriter, hash common.Hash, number
 	deleteHeaderWithoutNumber(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
+	DeleteVerkleWitness(db, hash, number)
 }
 
 const badBlockToKeep = 10
@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -32,6 +33,12 @@ import (
 // hash to allow retrieving the transaction or receipt by hash.
 func ReadTxLookupEntry(db ethdb.Reader, hash common.Hash) *uint64 {
 	data, _ := db.Get(txLookupKey(hash))
+	return readTxLookupEntry(db, hash, data)
+}
+
+// readTxLookupEntry decodes the block number from a transaction lookup entry of
+// any database version.
+func readTxLookupEntry(db ethdb.Reader, hash common.Hash, data []byte) *uint64 {
 	if len(data) == 0 {
 		return nil
 	}
@@ -106,7 +113,11 @@ func ReadTransaction(db ethdb.Reader, hash common.Hash) (*types.Transaction, com
 	}
 	body := ReadBody(db, blockHash, *blockNumber)
 	if body == nil {
-		log.Error("Transaction referenced missing", "number", *blockNumber, "hash", blockHash)
+		if *blockNumber < ReadHistoryTail(db) {
+			log.Debug("Transaction referenced pruned history", "number", *blockNumber, "hash", blockHash)
+		} else {
+			log.Error("Transaction referenced missing", "number", *blockNumber, "hash", blockHash)
+		}
 		return nil, common.Hash{}, 0, 0
 	}
 	for txIndex, tx := range body.Transactions {
@@ -134,6 +145,10 @@ func ReadReceipt(db ethdb.Reader, hash common.Hash, config *params.ChainConfig)
 	if blockHeader == nil {
 		return nil, common.Hash{}, 0, 0
 	}
+	if *blockNumber < ReadHistoryTail(db) {
+		log.Debug("Receipt referenced pruned history", "number", *blockNumber, "hash", blockHash)
+		return nil, common.Hash{}, 0, 0
+	}
 	// Read all the receipts from the block and return the one with the matching hash
 	receipts := ReadReceipts(db, blockHash, *blockNumber, blockHeader.Time, config)
 	for receiptIndex, receipt := range receipts {
@@ -179,3 +194,110 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// Roles of an address within a transaction, as recorded by the address index.
+const (
+	AddressRoleSender    uint8 = 1 << iota // Address signed the transaction
+	AddressRoleRecipient                   // Address is the recipient of the transaction
+	AddressRoleCreation                    // Address is the contract created by the transaction
+)
+
+// AddressIndexEntry is a positional reference to a transaction involving an
+// indexed address.
+type AddressIndexEntry struct {
+	Number uint64 // Number of the block containing the transaction
+	Index  uint32 // Index of the transaction within the block
+	Roles  uint8  // Bitmask of the roles the address has in the transaction
+}
+
+// ReadAddressIndex retrieves the address index entries of the given address
+// within the block range [from, to], in ascending order. If limit is non-zero,
+// at most limit entries are returned.
+func ReadAddressIndex(db ethdb.Iteratee, address common.Address, from uint64, to uint64, limit int) []AddressIndexEntry {
+	entries, _ := ReadAddressIndexPage(db, address, from, to, 0, limit)
+	return entries
+}
+
+// ReadAddressIndexPage retrieves the address index entries of the given address
+// like ReadAddressIndex, but first skips over the given number of entries without
+// retaining them. The number of entries actually skipped is returned too, which
+// is less than requested if the range runs out first.
+func ReadAddressIndexPage(db ethdb.Iteratee, address common.Address, from uint64, to uint64, skip int, limit int) ([]AddressIndexEntry, int) {
+	prefix := append(addressIndexPrefix, address.Bytes()...)
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	var (
+		entries []AddressIndexEntry
+		skipped int
+	)
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+12 || len(it.Value()) != 1 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number > to {
+			break
+		}
+		if skipped < skip {
+			skipped++
+			continue
+		}
+		entries = append(entries, AddressIndexEntry{
+			Number: number,
+			Index:  binary.BigEndian.Uint32(key[len(prefix)+8:]),
+			Roles:  it.Value()[0],
+		})
+		if limit > 0 && len(entries) >= limit {
+			break
+		}
+	}
+	if it.Error() != nil {
+		log.Error("Failed to read address index", "address", address, "err", it.Error())
+	}
+	return entries, skipped
+}
+
+// WriteAddressIndexSection stores the address index entries of a section along
+// with the journal of indexed addresses needed to unwind it.
+func WriteAddressIndexSection(db ethdb.KeyValueWriter, section uint64, entries map[common.Address][]AddressIndexEntry) {
+	journal := make([]byte, 0, len(entries)*common.AddressLength)
+	for address, list := range entries {
+		for _, entry := range list {
+			if err := db.Put(addressIndexKey(address, entry.Number, entry.Index), []byte{entry.Roles}); err != nil {
+				log.Crit("Failed to store address index entry", "err", err)
+			}
+		}
+		journal = append(journal, address.Bytes()...)
+	}
+	if err := db.Put(addressJournalKey(section), journal); err != nil {
+		log.Crit("Failed to store address index journal", "err", err)
+	}
+}
+
+// DeleteAddressIndexSection removes all address index entries belonging to
+// the given section of the given size, as recorded in the section journal.
+func DeleteAddressIndexSection(db ethdb.KeyValueStore, section uint64, size uint64) {
+	journal, _ := db.Get(addressJournalKey(section))
+	if len(journal) == 0 {
+		return
+	}
+	batch := db.NewBatch()
+	for i := 0; i+common.AddressLength <= len(journal); i += common.AddressLength {
+		address := common.BytesToAddress(journal[i : i+common.AddressLength])
+		for _, entry := range ReadAddressIndex(db, address, section*size, (section+1)*size-1, 0) {
+			batch.Delete(addressIndexKey(address, entry.Number, entry.Index))
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to delete address index entries", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	batch.Delete(addressJournalKey(section))
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete address index entries", "err", err)
+	}
+}
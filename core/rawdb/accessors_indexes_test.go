@@ -155,6 +155,59 @@ func TestDeleteBloomBits(t *testing.T) {
 	check(1, 1, params.PlatformTestNetGenesisHash, true)
 }
 
+// Tests that address index sections can be stored, queried and unwound.
+func TestAddressIndexStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		addr1 = common.HexToAddress("0x01")
+		addr2 = common.HexToAddress("0x02")
+	)
+	WriteAddressIndexSection(db, 0, map[common.Address][]AddressIndexEntry{
+		addr1: {{Number: 1, Index: 0, Roles: AddressRoleSender}, {Number: 3, Index: 2, Roles: AddressRoleRecipient}},
+		addr2: {{Number: 1, Index: 0, Roles: AddressRoleRecipient}},
+	})
+	WriteAddressIndexSection(db, 1, map[common.Address][]AddressIndexEntry{
+		addr1: {{Number: 4, Index: 1, Roles: AddressRoleSender | AddressRoleRecipient}},
+	})
+	check := func(addr common.Address, from, to uint64, limit int, want []AddressIndexEntry) {
+		t.Helper()
+		have := ReadAddressIndex(db, addr, from, to, limit)
+		if len(have) != len(want) {
+			t.Fatalf("entry count mismatch: have %d, want %d", len(have), len(want))
+		}
+		for i := range have {
+			if have[i] != want[i] {
+				t.Fatalf("entry %d mismatch: have %+v, want %+v", i, have[i], want[i])
+			}
+		}
+	}
+	check(addr1, 0, 7, 0, []AddressIndexEntry{
+		{Number: 1, Index: 0, Roles: AddressRoleSender},
+		{Number: 3, Index: 2, Roles: AddressRoleRecipient},
+		{Number: 4, Index: 1, Roles: AddressRoleSender | AddressRoleRecipient},
+	})
+	check(addr1, 2, 3, 0, []AddressIndexEntry{{Number: 3, Index: 2, Roles: AddressRoleRecipient}})
+	check(addr1, 0, 7, 1, []AddressIndexEntry{{Number: 1, Index: 0, Roles: AddressRoleSender}})
+	check(addr2, 0, 7, 0, []AddressIndexEntry{{Number: 1, Index: 0, Roles: AddressRoleRecipient}})
+
+	// Skip over leading entries, running out of them if the range is too short
+	if have, skipped := ReadAddressIndexPage(db, addr1, 0, 7, 2, 0); skipped != 2 || len(have) != 1 || have[0].Number != 4 {
+		t.Fatalf("entries after skipping mismatch: have %+v, skipped %d", have, skipped)
+	}
+	if have, skipped := ReadAddressIndexPage(db, addr1, 2, 7, 5, 0); skipped != 2 || len(have) != 0 {
+		t.Fatalf("entries after skipping mismatch: have %+v, skipped %d", have, skipped)
+	}
+
+	// Unwind the first section and ensure the second one is left intact
+	DeleteAddressIndexSection(db, 0, 4)
+	check(addr1, 0, 7, 0, []AddressIndexEntry{{Number: 4, Index: 1, Roles: AddressRoleSender | AddressRoleRecipient}})
+	check(addr2, 0, 7, 0, nil)
+	if has, _ := db.Has(addressJournalKey(0)); has {
+		t.Fatalf("section journal not deleted")
+	}
+}
+
 //func TestDeleteBloomBits(t *testing.T) {
 //	// Prepare testing data
 //	db := NewMemoryDatabase()
@@ -17,6 +17,8 @@
 package rawdb
 
 import (
+	"encoding/binary"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
@@ -92,3 +94,116 @@ func DeleteCode(db ethdb.KeyValueWriter, hash common.Hash) {
 		log.Crit("Failed to delete contract code", "err", err)
 	}
 }
+
+// ReadStateID retrieves the state id with the provided state root.
+func ReadStateID(db ethdb.KeyValueReader, root common.Hash) *uint64 {
+	data, err := db.Get(stateIDKey(root))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteStateID writes the provided state lookup to database.
+func WriteStateID(db ethdb.KeyValueWriter, root common.Hash, id uint64) {
+	var buff [8]byte
+	binary.BigEndian.PutUint64(buff[:], id)
+	if err := db.Put(stateIDKey(root), buff[:]); err != nil {
+		log.Crit("Failed to store state ID", "err", err)
+	}
+}
+
+// DeleteStateID deletes the specified state lookup from the database.
+func DeleteStateID(db ethdb.KeyValueWriter, root common.Hash) {
+	if err := db.Delete(stateIDKey(root)); err != nil {
+		log.Crit("Failed to delete state ID", "err", err)
+	}
+}
+
+// ReadPersistentStateID retrieves the id of the persistent state from the database.
+func ReadPersistentStateID(db ethdb.KeyValueReader) uint64 {
+	data, _ := db.Get(persistentStateIDKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WritePersistentStateID stores the id of the persistent state into database.
+func WritePersistentStateID(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(persistentStateIDKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the persistent state ID", "err", err)
+	}
+}
+
+// ReadTrieJournal retrieves the serialized in-memory trie nodes of layers saved at
+// the last shutdown.
+func ReadTrieJournal(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(trieJournalKey)
+	return data
+}
+
+// WriteTrieJournal stores the serialized in-memory trie nodes of layers to save at
+// shutdown.
+func WriteTrieJournal(db ethdb.KeyValueWriter, journal []byte) {
+	if err := db.Put(trieJournalKey, journal); err != nil {
+		log.Crit("Failed to store tries journal", "err", err)
+	}
+}
+
+// DeleteTrieJournal deletes the serialized in-memory trie nodes of layers saved at
+// the last shutdown.
+func DeleteTrieJournal(db ethdb.KeyValueWriter) {
+	if err := db.Delete(trieJournalKey); err != nil {
+		log.Crit("Failed to remove tries journal", "err", err)
+	}
+}
+
+// ReadTrieHistory retrieves the trie node history with the given state id, which
+// holds the original trie nodes overwritten by the corresponding state transition.
+func ReadTrieHistory(db ethdb.KeyValueReader, id uint64) []byte {
+	data, _ := db.Get(trieHistoryKey(id))
+	return data
+}
+
+// HasTrieHistory verifies the existence of the trie node history with the given
+// state id.
+func HasTrieHistory(db ethdb.KeyValueReader, id uint64) bool {
+	ok, _ := db.Has(trieHistoryKey(id))
+	return ok
+}
+
+// WriteTrieHistory stores the trie node history with the given state id.
+func WriteTrieHistory(db ethdb.KeyValueWriter, id uint64, history []byte) {
+	if err := db.Put(trieHistoryKey(id), history); err != nil {
+		log.Crit("Failed to store trie history", "err", err)
+	}
+}
+
+// DeleteTrieHistory deletes the trie node history with the given state id.
+func DeleteTrieHistory(db ethdb.KeyValueWriter, id uint64) {
+	if err := db.Delete(trieHistoryKey(id)); err != nil {
+		log.Crit("Failed to delete trie history", "err", err)
+	}
+}
+
+// ReadVerkleWitness retrieves the verkle state witness of the given block.
+func ReadVerkleWitness(db ethdb.KeyValueReader, hash common.Hash, number uint64) []byte {
+	data, _ := db.Get(verkleWitnessKey(number, hash))
+	return data
+}
+
+// WriteVerkleWitness stores the verkle state witness of the given block.
+func WriteVerkleWitness(db ethdb.KeyValueWriter, hash common.Hash, number uint64, witness []byte) {
+	if err := db.Put(verkleWitnessKey(number, hash), witness); err != nil {
+		log.Crit("Failed to store verkle witness", "err", err)
+	}
+}
+
+// DeleteVerkleWitness deletes the verkle state witness of the given block.
+func DeleteVerkleWitness(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(verkleWitnessKey(number, hash)); err != nil {
+		log.Crit("Failed to delete verkle witness", "err", err)
+	}
+}
@@ -261,3 +261,103 @@ func DeleteTrieNode(db ethdb.KeyValueWriter, owner common.Hash, path []byte, has
 		panic(fmt.Sprintf("Unknown scheme %v", scheme))
 	}
 }
+
+// ReadStateScheme reads the state scheme of the persistent state, or none if
+// the state is not present in database.
+func ReadStateScheme(db ethdb.Reader) string {
+	// Check if the state in path-based scheme is present
+	blob, _ := ReadAccountTrieNode(db, nil)
+	if len(blob) != 0 {
+		return PathScheme
+	}
+	// In the hash-based scheme, the genesis state is always stored on disk,
+	// so the scheme of the persistent state can be inferred from it.
+	header := ReadHeader(db, ReadCanonicalHash(db, 0), 0)
+	if header == nil {
+		return "" // empty datadir
+	}
+	blob = ReadLegacyTrieNode(db, header.Root)
+	if len(blob) == 0 {
+		return "" // no state in disk
+	}
+	return HashScheme
+}
+
+// DeletePathTrieNodes deletes all the trie nodes stored in the path-based scheme.
+func DeletePathTrieNodes(db ethdb.KeyValueStore) error {
+	err := deletePrefix(db, trieNodeAccountPrefix, func(key []byte) bool {
+		ok, _ := IsAccountTrieNode(key)
+		return ok
+	})
+	if err != nil {
+		return err
+	}
+	return deletePrefix(db, trieNodeStoragePrefix, func(key []byte) bool {
+		ok, _, _ := IsStorageTrieNode(key)
+		return ok
+	})
+}
+
+// DeleteTrieHistories deletes all the trie histories of the path-based scheme,
+// along with the state lookups associated with them.
+func DeleteTrieHistories(db ethdb.KeyValueStore) error {
+	err := deletePrefix(db, stateIDPrefix, func(key []byte) bool {
+		return len(key) == len(stateIDPrefix)+common.HashLength
+	})
+	if err != nil {
+		return err
+	}
+	return deletePrefix(db, trieHistoryPrefix, func(key []byte) bool {
+		return len(key) == len(trieHistoryPrefix)+8
+	})
+}
+
+// deletePrefix deletes all the database entries with the given prefix which are
+// accepted by the filter.
+func deletePrefix(db ethdb.KeyValueStore, prefix []byte, filter func(key []byte) bool) error {
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	batch := db.NewBatch()
+	for it.Next() {
+		if !filter(it.Key()) {
+			continue
+		}
+		if err := batch.Delete(it.Key()); err != nil {
+			return err
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// ParseStateScheme checks if the specified state scheme is compatible with
+// the stored state. If the scheme is not specified, the scheme of the stored
+// state is used, or the hash-based scheme for a fresh database.
+func ParseStateScheme(provided string, disk ethdb.Database) (string, error) {
+	stored := ReadStateScheme(disk)
+	if provided == "" {
+		if stored == "" {
+			log.Info("State scheme set to default", "scheme", HashScheme)
+			return HashScheme, nil
+		}
+		log.Info("State scheme set to already existing", "scheme", stored)
+		return stored, nil
+	}
+	if provided != HashScheme && provided != PathScheme {
+		return "", fmt.Errorf("unknown state scheme %q", provided)
+	}
+	if stored == "" || provided == stored {
+		log.Info("State scheme set by user", "scheme", provided)
+		return provided, nil
+	}
+	return "", fmt.Errorf("incompatible state scheme, stored: %s, provided: %s", stored, provided)
+}
@@ -34,14 +34,21 @@ const (
 	ChainFreezerDifficultyTable = "diffs"
 )
 
-// chainFreezerNoSnappy configures whether compression is disabled for the ancient-tables.
-// Hashes and difficulties don't compress well.
-var chainFreezerNoSnappy = map[string]bool{
-	ChainFreezerHeaderTable:     false,
-	ChainFreezerHashTable:       true,
-	ChainFreezerBodiesTable:     false,
-	ChainFreezerReceiptTable:    false,
-	ChainFreezerDifficultyTable: true,
+// freezerTableConfig contains the settings for a freezer table.
+type freezerTableConfig struct {
+	noSnappy bool // disables item compression
+	prunable bool // true for tables that can be pruned by TruncateTail
+}
+
+// chainFreezerTableConfigs configures the settings for tables in the chain freezer.
+// Hashes and difficulties don't compress well. Only the block bodies and receipts
+// can be pruned, the headers, hashes and difficulties are kept for the full chain.
+var chainFreezerTableConfigs = map[string]freezerTableConfig{
+	ChainFreezerHeaderTable:     {noSnappy: false, prunable: false},
+	ChainFreezerHashTable:       {noSnappy: true, prunable: false},
+	ChainFreezerBodiesTable:     {noSnappy: false, prunable: true},
+	ChainFreezerReceiptTable:    {noSnappy: false, prunable: true},
+	ChainFreezerDifficultyTable: {noSnappy: true, prunable: false},
 }
 
 // The list of identifiers of ancient stores.
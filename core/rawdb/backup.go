@@ -0,0 +1,297 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	backupKeyValueDir = "chaindata"   // Directory of the key-value store in a backup
+	backupAncientDir  = "ancient"     // Directory of the ancient store in a backup
+	backupMetaFile    = "BACKUP.json" // File of the backup metadata
+	backupVersion     = 1             // Version of the backup layout
+)
+
+// errCheckpointUnsupported is returned if the backing key-value store can't
+// create consistent copies of itself.
+var errCheckpointUnsupported = errors.New("database does not support checkpoints")
+
+// BackupMeta is the metadata of a database backup, recorded when the backup is
+// created and validated before it's restored.
+type BackupMeta struct {
+	Version    uint64           `json:"version"`
+	Time       uint64           `json:"time"`       // Unix time of the backup
+	Engine     string           `json:"engine"`     // Engine of the key-value store
+	Genesis    common.Hash      `json:"genesis"`    // Hash of the genesis block
+	HeadHash   common.Hash      `json:"headHash"`   // Hash of the head block
+	HeadNumber uint64           `json:"headNumber"` // Number of the head block
+	Ancients   uint64           `json:"ancients"`   // Number of items in the chain freezer
+	Tail       uint64           `json:"tail"`       // Number of the first item in the chain freezer
+	Files      map[string]int64 `json:"files"`      // Sizes of the backed up files
+}
+
+// Backup writes a consistent point-in-time copy of the database, which may be
+// in use, into the given directory along with its metadata. The directory must
+// not exist yet.
+func Backup(db ethdb.Database, dir string) (*BackupMeta, error) {
+	if common.FileExist(dir) {
+		return nil, fmt.Errorf("backup directory %s already exists", dir)
+	}
+	cp, ok := db.(ethdb.Checkpointer)
+	if !ok {
+		return nil, errCheckpointUnsupported
+	}
+	start := time.Now()
+	if err := cp.Checkpoint(dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	// Don't leave an incomplete backup behind if it can't be sealed
+	meta, err := sealBackup(dir, start)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	log.Info("Created database backup", "dir", dir, "head", meta.HeadNumber, "ancients", meta.Ancients, "elapsed", common.PrettyDuration(time.Since(start)))
+	return meta, nil
+}
+
+// sealBackup writes the metadata of the checkpoint in the given directory,
+// started at the given time.
+func sealBackup(dir string, start time.Time) (*BackupMeta, error) {
+	files, err := backupFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := inspectBackup(dir)
+	if err != nil {
+		return nil, err
+	}
+	meta.Version = backupVersion
+	meta.Time = uint64(start.Unix())
+	meta.Files = files
+
+	blob, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, backupMetaFile), blob, 0644); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// VerifyBackup checks that the backup in the given directory is complete and
+// matches its metadata.
+func VerifyBackup(dir string) (*BackupMeta, error) {
+	blob, err := os.ReadFile(filepath.Join(dir, backupMetaFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup metadata: %v", err)
+	}
+	meta := new(BackupMeta)
+	if err := json.Unmarshal(blob, meta); err != nil {
+		return nil, fmt.Errorf("invalid backup metadata: %v", err)
+	}
+	if meta.Version != backupVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", meta.Version)
+	}
+	for name, size := range meta.Files {
+		stat, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, fmt.Errorf("backup file missing: %v", err)
+		}
+		if stat.Size() != size {
+			return nil, fmt.Errorf("backup file %s size mismatch: have %d, want %d", name, stat.Size(), size)
+		}
+	}
+	have, err := inspectBackup(dir)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case have.Engine != meta.Engine:
+		return nil, fmt.Errorf("backup engine mismatch: have %s, want %s", have.Engine, meta.Engine)
+	case have.Genesis != meta.Genesis:
+		return nil, fmt.Errorf("backup genesis mismatch: have %x, want %x", have.Genesis, meta.Genesis)
+	case have.HeadHash != meta.HeadHash || have.HeadNumber != meta.HeadNumber:
+		return nil, fmt.Errorf("backup head mismatch: have #%d [%x], want #%d [%x]", have.HeadNumber, have.HeadHash, meta.HeadNumber, meta.HeadHash)
+	case have.Ancients != meta.Ancients || have.Tail != meta.Tail:
+		return nil, fmt.Errorf("backup ancients mismatch: have [%d, %d), want [%d, %d)", have.Tail, have.Ancients, meta.Tail, meta.Ancients)
+	}
+	return meta, nil
+}
+
+// RestoreBackup verifies the backup in the given directory and copies it into
+// the database directories, which must not exist yet.
+func RestoreBackup(dir string, chaindata string, ancient string) (*BackupMeta, error) {
+	meta, err := VerifyBackup(dir)
+	if err != nil {
+		return nil, err
+	}
+	freezer := filepath.Join(ancient, chainFreezerName)
+	for _, path := range []string{chaindata, freezer} {
+		if common.FileExist(path) {
+			return nil, fmt.Errorf("database directory %s already exists", path)
+		}
+	}
+	// Restore into temporary directories first and move them into place once
+	// complete. The ancient store is nested in the key-value store by default.
+	var (
+		start  = time.Now()
+		tmp    = chaindata + ".restore"
+		ftmp   string
+		nested bool
+	)
+	if rel, err := filepath.Rel(chaindata, freezer); err == nil && !strings.HasPrefix(rel, "..") {
+		ftmp, nested = filepath.Join(tmp, rel), true
+	} else {
+		ftmp = freezer + ".restore"
+		defer os.RemoveAll(ftmp)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := os.RemoveAll(tmp); err != nil {
+		return nil, err
+	}
+	if err := copyDir(filepath.Join(dir, backupKeyValueDir), tmp); err != nil {
+		return nil, err
+	}
+	if src := filepath.Join(dir, backupAncientDir, chainFreezerName); common.FileExist(src) {
+		if err := os.RemoveAll(ftmp); err != nil {
+			return nil, err
+		}
+		if err := copyDir(src, ftmp); err != nil {
+			return nil, err
+		}
+		if !nested {
+			if err := os.Rename(ftmp, freezer); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := os.Rename(tmp, chaindata); err != nil {
+		return nil, err
+	}
+	log.Info("Restored database backup", "dir", dir, "head", meta.HeadNumber, "ancients", meta.Ancients, "elapsed", common.PrettyDuration(time.Since(start)))
+	return meta, nil
+}
+
+// inspectBackup opens the database in a backup directory read-only and reads
+// the chain metadata from it.
+func inspectBackup(dir string) (*BackupMeta, error) {
+	var (
+		kvdir  = filepath.Join(dir, backupKeyValueDir)
+		engine = PreexistingDatabase(kvdir)
+	)
+	if engine == "" {
+		return nil, errors.New("backup key-value store missing")
+	}
+	kvdb, err := openKeyValueDatabase(OpenOptions{Type: engine, Directory: kvdir, Cache: 16, Handles: 16, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	db := NewDatabase(kvdb)
+	if ancient := filepath.Join(dir, backupAncientDir); common.FileExist(ancient) {
+		if db, err = NewDatabaseWithFreezer(kvdb, ancient, "", true); err != nil {
+			kvdb.Close()
+			return nil, err
+		}
+	}
+	defer db.Close()
+
+	meta := &BackupMeta{
+		Engine:   engine,
+		Genesis:  ReadCanonicalHash(db, 0),
+		HeadHash: ReadHeadBlockHash(db),
+	}
+	if meta.Genesis == (common.Hash{}) {
+		return nil, errors.New("backup genesis missing")
+	}
+	number := ReadHeaderNumber(db, meta.HeadHash)
+	if number == nil || ReadHeader(db, meta.HeadHash, *number) == nil {
+		return nil, errors.New("backup head block missing")
+	}
+	meta.HeadNumber = *number
+	meta.Ancients, _ = db.Ancients()
+	meta.Tail, _ = db.Tail()
+	return meta, nil
+}
+
+// backupFiles returns the sizes of all the data files in a backup directory.
+// Lock and log files are skipped, they may be changed by simply opening the
+// databases.
+func backupFiles(dir string) (map[string]int64, error) {
+	files := make(map[string]int64)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch d.Name() {
+		case "LOCK", "FLOCK", "LOG", "LOG.old", backupMetaFile:
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	return files, err
+}
+
+// copyDir recursively copies the regular files of a directory into a new one.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return copyPrefix(f, target, -1)
+	})
+}
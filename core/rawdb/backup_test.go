@@ -0,0 +1,125 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupLevelDB(t *testing.T) { testBackup(t, dbLeveldb) }
+
+func TestBackupPebble(t *testing.T) {
+	if !PebbleEnabled {
+		t.Skip("pebble not supported on this platform")
+	}
+	testBackup(t, dbPebble)
+}
+
+func testBackup(t *testing.T, engine string) {
+	var (
+		dir      = t.TempDir()
+		backup   = filepath.Join(dir, "backup")
+		restored = filepath.Join(dir, "restored")
+		blocks   = makeTestBlocks(21, 2)
+	)
+	open := func(path string) *freezerdb {
+		t.Helper()
+		db, err := Open(OpenOptions{
+			Type:              engine,
+			Directory:         path,
+			AncientsDirectory: filepath.Join(path, "ancient"),
+			Cache:             16,
+			Handles:           16,
+		})
+		require.NoError(t, err)
+		return db.(*freezerdb)
+	}
+	// Backups which can't be sealed must not be left behind
+	db := open(filepath.Join(dir, "chaindata"))
+	if _, err := Backup(db, backup); err == nil {
+		t.Fatal("backup of an empty database succeeded")
+	}
+	if common.FileExist(backup) {
+		t.Fatal("failed backup left behind")
+	}
+	// Fill the database with half of the blocks frozen and back it up
+	_, err := WriteAncientBlocks(db, blocks[:10], makeTestReceipts(10, 1), big.NewInt(100))
+	require.NoError(t, err)
+	for _, block := range blocks[10:20] {
+		WriteBlock(db, block)
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	}
+	WriteHeadBlockHash(db, blocks[19].Hash())
+
+	meta, err := Backup(db, backup)
+	require.NoError(t, err)
+	require.Equal(t, blocks[0].Hash(), meta.Genesis)
+	require.Equal(t, blocks[19].Hash(), meta.HeadHash)
+	require.Equal(t, uint64(19), meta.HeadNumber)
+	require.Equal(t, uint64(10), meta.Ancients)
+	require.Equal(t, engine, meta.Engine)
+
+	if _, err := Backup(db, backup); err == nil {
+		t.Fatal("backup overwrote an existing directory")
+	}
+	// Changes after the backup must not leak into it
+	WriteBlock(db, blocks[20])
+	WriteCanonicalHash(db, blocks[20].Hash(), 20)
+	WriteHeadBlockHash(db, blocks[20].Hash())
+	require.NoError(t, db.Close())
+
+	// Restore the backup and check its content
+	_, err = RestoreBackup(backup, restored, filepath.Join(restored, "ancient"))
+	require.NoError(t, err)
+	if _, err := RestoreBackup(backup, restored, filepath.Join(restored, "ancient")); err == nil {
+		t.Fatal("restore overwrote an existing database")
+	}
+	db = open(restored)
+	if frozen, _ := db.Ancients(); frozen != 10 {
+		t.Fatalf("restored ancients mismatch: have %d, want 10", frozen)
+	}
+	if head := ReadHeadBlockHash(db); head != blocks[19].Hash() {
+		t.Fatalf("restored head mismatch: have %x, want %x", head, blocks[19].Hash())
+	}
+	for _, block := range blocks[:20] {
+		if have := ReadBlock(db, block.Hash(), block.NumberU64()); have == nil || have.Hash() != block.Hash() {
+			t.Fatalf("restored block %d missing", block.NumberU64())
+		}
+	}
+	if ReadCanonicalHash(db, 20) != (common.Hash{}) {
+		t.Fatal("post-backup block restored")
+	}
+	require.NoError(t, db.Close())
+
+	// Damaged backups must be rejected
+	for name, size := range meta.Files {
+		if strings.HasPrefix(name, "ancient/") && size > 0 {
+			require.NoError(t, os.Truncate(filepath.Join(backup, name), size-1))
+			break
+		}
+	}
+	if _, err := VerifyBackup(backup); err == nil {
+		t.Fatal("damaged backup accepted")
+	}
+}
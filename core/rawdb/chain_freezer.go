@@ -51,9 +51,10 @@ type chainFreezer struct {
 	trigger chan chan struct{} // Manual blocking freeze trigger, test determinism
 }
 
-// newChainFreezer initializes the freezer for ancient chain data.
-func newChainFreezer(datadir string, namespace string, readonly bool) (*chainFreezer, error) {
-	freezer, err := NewChainFreezer(datadir, namespace, readonly)
+// newChainFreezer initializes the freezer for ancient chain data, offloading
+// the sealed data files into an object store if remote is non-nil.
+func newChainFreezer(datadir string, namespace string, readonly bool, remote *RemoteAncients) (*chainFreezer, error) {
+	freezer, err := newFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerTableConfigs, remote)
 	if err != nil {
 		return nil, err
 	}
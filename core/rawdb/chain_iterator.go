@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"errors"
 	"runtime"
 	"sync/atomic"
 	"time"
@@ -353,3 +354,120 @@ func UnindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 func unindexTransactionsForTesting(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
 	unindexTransactions(db, from, to, interrupt, hook)
 }
+
+// PruneHistory removes the bodies and receipts of the blocks below the given
+// number from the ancient store, retaining their headers, canonical hashes and
+// total difficulties. The genesis body and receipts are preserved in the
+// key-value store.
+//
+// The transaction indices of the pruned blocks are retained, so that their
+// transactions are reported as pruned instead of unknown. As the bodies are
+// gone, they can only be removed by UnindexPrunedTransactions afterwards.
+//
+// Only frozen blocks can be pruned, the returned number is the new tail of the
+// retained history.
+func PruneHistory(db ethdb.Database, tail uint64) (uint64, error) {
+	frozen, err := db.Ancients()
+	if err != nil {
+		return 0, err
+	}
+	if tail > frozen {
+		tail = frozen
+	}
+	old, err := db.Tail()
+	if err != nil {
+		return 0, err
+	}
+	if tail <= old {
+		return old, nil
+	}
+	// Move the genesis out of the ancients before it's pruned for the first
+	// time, the chain can't be opened without it.
+	if old == 0 {
+		hash := ReadCanonicalHash(db, 0)
+		if hash == (common.Hash{}) {
+			return 0, errors.New("genesis not found")
+		}
+		batch := db.NewBatch()
+		WriteBodyRLP(batch, hash, 0, ReadBodyRLP(db, hash, 0))
+		if err := batch.Put(blockReceiptsKey(0, hash), ReadReceiptsRLP(db, hash, 0)); err != nil {
+			return 0, err
+		}
+		if err := batch.Write(); err != nil {
+			return 0, err
+		}
+	}
+	if err := db.TruncateTail(tail); err != nil {
+		return old, err
+	}
+	log.Info("Pruned chain history", "tail", tail, "pruned", tail-old)
+	return tail, nil
+}
+
+// UnindexPrunedTransactions removes the txlookup indices of the specified block
+// range, whose bodies have been pruned. Lacking the bodies, the whole index is
+// iterated to find the transactions of the range. The from is included while to
+// is excluded.
+//
+// There is a passed channel, the whole procedure will be interrupted if any
+// signal received, leaving the indexing tail unchanged.
+func UnindexPrunedTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}) {
+	// short circuit for invalid range
+	if from >= to {
+		return
+	}
+	var (
+		it     = db.NewIterator(txLookupPrefix, nil)
+		batch  = db.NewBatch()
+		start  = time.Now()
+		logged = start
+		// for stats reporting
+		entries, txs = 0, 0
+	)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(txLookupPrefix)+common.HashLength {
+			continue
+		}
+		entries++
+		if entries%10000 == 0 {
+			select {
+			case <-interrupt:
+				log.Debug("Pruned transaction unindexing interrupted", "txs", txs, "elapsed", common.PrettyDuration(time.Since(start)))
+				return
+			default:
+			}
+		}
+		number := readTxLookupEntry(db, common.BytesToHash(key[len(txLookupPrefix):]), it.Value())
+		if number == nil || *number < from || *number >= to {
+			continue
+		}
+		DeleteTxLookupEntry(batch, common.BytesToHash(key[len(txLookupPrefix):]))
+		txs++
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed writing batch to db", "error", err)
+				return
+			}
+			batch.Reset()
+		}
+		// If we've spent too much time already, notify the user of what we're doing
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Unindexing pruned transactions", "entries", entries, "txs", txs, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		log.Error("Failed to iterate transaction indices", "err", err)
+		return
+	}
+	WriteTxIndexTail(batch, to)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed writing batch to db", "error", err)
+		return
+	}
+	log.Debug("Unindexed pruned transactions", "txs", txs, "tail", to, "elapsed", common.PrettyDuration(time.Since(start)))
+}
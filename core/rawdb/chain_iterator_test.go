@@ -208,3 +208,88 @@ func TestIndexTransactions(t *testing.T) {
 	verify(8, 11, true, 8)
 	verify(0, 8, false, 8)
 }
+
+func TestPruneHistory(t *testing.T) {
+	// Construct a test chain, frozen into the ancient store
+	chainDb, err := NewDatabaseWithFreezer(NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	defer chainDb.Close()
+
+	var (
+		to       = common.BytesToAddress([]byte{0x11})
+		blocks   []*types.Block
+		receipts []types.Receipts
+		txs      []*types.Transaction
+	)
+	genesis := types.NewBlock(&types.Header{Number: big.NewInt(0)}, nil, nil, nil, newHasher())
+	blocks, receipts = append(blocks, genesis), append(receipts, nil)
+	for i := uint64(1); i <= 10; i++ {
+		tx := types.NewTx(&types.LegacyTx{Nonce: i, GasPrice: big.NewInt(11111), Gas: 1111, To: &to, Value: big.NewInt(111)})
+		receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 1111, Logs: []*types.Log{}}
+		block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(i), ParentHash: blocks[i-1].Hash()}, []*types.Transaction{tx}, nil, []*types.Receipt{receipt}, newHasher())
+		blocks, receipts, txs = append(blocks, block), append(receipts, types.Receipts{receipt}), append(txs, tx)
+	}
+	if _, err := WriteAncientBlocks(chainDb, blocks, receipts, big.NewInt(0)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	IndexTransactions(chainDb, 0, 11, nil)
+
+	// Prune the history below block 5, beyond the frozen blocks the limit is capped
+	if tail, err := PruneHistory(chainDb, 5); err != nil || tail != 5 {
+		t.Fatalf("failed to prune history: tail %d, err %v", tail, err)
+	}
+	for i, block := range blocks[5:] {
+		if !HasBody(chainDb, block.Hash(), block.NumberU64()) || ReadTxLookupEntry(chainDb, txs[i+4].Hash()) == nil {
+			t.Fatalf("block %d history pruned", block.NumberU64())
+		}
+	}
+	if tail, err := PruneHistory(chainDb, 100); err != nil || tail != 11 {
+		t.Fatalf("failed to prune history: tail %d, err %v", tail, err)
+	}
+	if tail := ReadHistoryTail(chainDb); tail != 11 {
+		t.Fatalf("history tail mismatch: have %d, want 11", tail)
+	}
+	if tail := ReadTxIndexTail(chainDb); tail == nil || *tail != 0 {
+		t.Fatalf("tx index tail mismatch: have %v, want 0", tail)
+	}
+	// The genesis and the headers of all blocks are retained
+	if body := ReadBody(chainDb, genesis.Hash(), 0); body == nil {
+		t.Fatalf("genesis body pruned")
+	}
+	if rs := ReadReceiptsRLP(chainDb, genesis.Hash(), 0); len(rs) == 0 {
+		t.Fatalf("genesis receipts pruned")
+	}
+	for i, block := range blocks[1:] {
+		number := block.NumberU64()
+		if header := ReadHeader(chainDb, block.Hash(), number); header == nil || header.Hash() != block.Hash() {
+			t.Fatalf("header %d pruned", number)
+		}
+		if hash := ReadCanonicalHash(chainDb, number); hash != block.Hash() {
+			t.Fatalf("canonical hash %d pruned", number)
+		}
+		if ReadBody(chainDb, block.Hash(), number) != nil || HasBody(chainDb, block.Hash(), number) {
+			t.Fatalf("body %d retained", number)
+		}
+		if ReadRawReceipts(chainDb, block.Hash(), number) != nil || HasReceipts(chainDb, block.Hash(), number) {
+			t.Fatalf("receipts %d retained", number)
+		}
+		if ReadTxLookupEntry(chainDb, txs[i].Hash()) == nil {
+			t.Fatalf("tx lookup %d pruned", number)
+		}
+		if tx, _, _, _ := ReadTransaction(chainDb, txs[i].Hash()); tx != nil {
+			t.Fatalf("tx %d retained", number)
+		}
+	}
+	// The indices of the pruned blocks can be removed without their bodies
+	UnindexPrunedTransactions(chainDb, 0, 6, nil)
+	if tail := ReadTxIndexTail(chainDb); tail == nil || *tail != 6 {
+		t.Fatalf("tx index tail mismatch: have %v, want 6", tail)
+	}
+	for i, tx := range txs {
+		if have, want := ReadTxLookupEntry(chainDb, tx.Hash()) != nil, i+1 >= 6; have != want {
+			t.Fatalf("tx lookup %d presence mismatch: have %v, want %v", i+1, have, want)
+		}
+	}
+}
@@ -24,14 +24,12 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/leveldb"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/olekukonko/tablewriter"
 )
 
 // freezerdb is a database wrapper that enabled freezer data retrievals.
@@ -62,6 +60,20 @@ func (frdb *freezerdb) Close() error {
 	return nil
 }
 
+// Checkpoint implements ethdb.Checkpointer, writing a copy of the key-value store
+// and of the chain freezer into the given directory. Freezing is paused while
+// the copy is made, so that the two are consistent with each other.
+func (frdb *freezerdb) Checkpoint(dir string) error {
+	kvdb, ok := checkpointer(frdb.KeyValueStore)
+	if !ok {
+		return errCheckpointUnsupported
+	}
+	freezer := frdb.AncientStore.(*chainFreezer)
+	return freezer.checkpoint(filepath.Join(dir, backupAncientDir, chainFreezerName), func() error {
+		return kvdb.Checkpoint(filepath.Join(dir, backupKeyValueDir))
+	})
+}
+
 // Freeze is a helper method used for external testing to trigger and block until
 // a freeze cycle completes, without having to sleep for a minute to trigger the
 // automatic background run.
@@ -164,6 +176,26 @@ func (db *nofreezedb) AncientDatadir() (string, error) {
 	return "", errNotSupported
 }
 
+// Checkpoint implements ethdb.Checkpointer, writing a copy of the key-value store
+// into the given directory.
+func (db *nofreezedb) Checkpoint(dir string) error {
+	kvdb, ok := checkpointer(db.KeyValueStore)
+	if !ok {
+		return errCheckpointUnsupported
+	}
+	return kvdb.Checkpoint(filepath.Join(dir, backupKeyValueDir))
+}
+
+// checkpointer returns the checkpointing capability of a key-value store, looking
+// through the wrapper which turns it into a database without a freezer.
+func checkpointer(kvdb ethdb.KeyValueStore) (ethdb.Checkpointer, bool) {
+	if db, ok := kvdb.(*nofreezedb); ok {
+		kvdb = db.KeyValueStore
+	}
+	cp, ok := kvdb.(ethdb.Checkpointer)
+	return cp, ok
+}
+
 // NewDatabase creates a high level database on top of a given key-value data
 // store without a freezer moving immutable chain segments into cold storage.
 func NewDatabase(db ethdb.KeyValueStore) ethdb.Database {
@@ -198,8 +230,15 @@ func resolveChainFreezerDir(ancient string) string {
 // storage. The passed ancient indicates the path of root ancient directory
 // where the chain freezer can be opened.
 func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace string, readonly bool) (ethdb.Database, error) {
+	return NewDatabaseWithRemoteFreezer(db, ancient, namespace, readonly, nil)
+}
+
+// NewDatabaseWithRemoteFreezer creates a high level database like
+// NewDatabaseWithFreezer, offloading the sealed segments of the chain freezer
+// into an object store if remote is non-nil.
+func NewDatabaseWithRemoteFreezer(db ethdb.KeyValueStore, ancient string, namespace string, readonly bool, remote *RemoteAncients) (ethdb.Database, error) {
 	// Create the idle freezer instance
-	frdb, err := newChainFreezer(resolveChainFreezerDir(ancient), namespace, readonly)
+	frdb, err := newChainFreezer(resolveChainFreezerDir(ancient), namespace, readonly, remote)
 	if err != nil {
 		printChainMetadata(db)
 		return nil, err
@@ -326,10 +365,10 @@ const (
 	dbLeveldb = "leveldb"
 )
 
-// hasPreexistingDb checks the given data directory whether a database is already
+// PreexistingDatabase checks the given data directory whether a database is already
 // instantiated at that location, and if so, returns the type of database (or the
 // empty string).
-func hasPreexistingDb(path string) string {
+func PreexistingDatabase(path string) string {
 	if _, err := os.Stat(filepath.Join(path, "CURRENT")); err != nil {
 		return "" // No pre-existing db
 	}
@@ -352,6 +391,10 @@ type OpenOptions struct {
 	Cache             int    // the capacity(in megabytes) of the data caching
 	Handles           int    // number of files to be open simultaneously
 	ReadOnly          bool
+
+	// AncientsRemote optionally offloads the sealed chain freezer segments
+	// into an object store.
+	AncientsRemote *RemoteAncients
 }
 
 // openKeyValueDatabase opens a disk-based key-value database, e.g. leveldb or pebble.
@@ -367,7 +410,7 @@ func openKeyValueDatabase(o OpenOptions) (ethdb.Database, error) {
 	}
 	// Retrieve any pre-existing database's type and use that or the requested one
 	// as long as there's no conflict between the two types
-	existingDb := hasPreexistingDb(o.Directory)
+	existingDb := PreexistingDatabase(o.Directory)
 	if len(existingDb) != 0 && len(o.Type) != 0 && o.Type != existingDb {
 		return nil, fmt.Errorf("db.engine choice was %v but found pre-existing %v database in specified data directory", o.Type, existingDb)
 	}
@@ -407,7 +450,7 @@ func Open(o OpenOptions) (ethdb.Database, error) {
 	if len(o.AncientsDirectory) == 0 {
 		return kvdb, nil
 	}
-	frdb, err := NewDatabaseWithFreezer(kvdb, o.AncientsDirectory, o.Namespace, o.ReadOnly)
+	frdb, err := NewDatabaseWithRemoteFreezer(kvdb, o.AncientsDirectory, o.Namespace, o.ReadOnly, o.AncientsRemote)
 	if err != nil {
 		kvdb.Close()
 		return nil, err
@@ -445,171 +488,160 @@ func (s *stat) Count() string {
 	return s.count.String()
 }
 
-// InspectDatabase traverses the entire database and checks the size
-// of all different categories of data.
-func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
-	it := db.NewIterator(keyPrefix, keyStart)
-	defer it.Release()
-
-	var (
-		count  int64
-		start  = time.Now()
-		logged = time.Now()
-
-		// Key-value store statistics
-		headers         stat
-		bodies          stat
-		receipts        stat
-		tds             stat
-		numHashPairings stat
-		hashNumPairings stat
-		tries           stat
-		codes           stat
-		txLookups       stat
-		accountSnaps    stat
-		storageSnaps    stat
-		preimages       stat
-		bloomBits       stat
-		beaconHeaders   stat
-		cliqueSnaps     stat
-		thoraSnaps      stat
-
-		// Les statistic
-		chtTrieNodes   stat
-		bloomTrieNodes stat
-
-		// Meta- and unaccounted data
-		metadata    stat
-		unaccounted stat
-
-		// Totals
-		total common.StorageSize
-	)
-	// Inspect key-value database first.
-	for it.Next() {
-		var (
-			key  = it.Key()
-			size = common.StorageSize(len(key) + len(it.Value()))
-		)
-		total += size
-		switch {
-		case bytes.HasPrefix(key, headerPrefix) && len(key) == (len(headerPrefix)+8+common.HashLength):
-			headers.Add(size)
-		case bytes.HasPrefix(key, blockBodyPrefix) && len(key) == (len(blockBodyPrefix)+8+common.HashLength):
-			bodies.Add(size)
-		case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == (len(blockReceiptsPrefix)+8+common.HashLength):
-			receipts.Add(size)
-		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerTDSuffix):
-			tds.Add(size)
-		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerHashSuffix):
-			numHashPairings.Add(size)
-		case bytes.HasPrefix(key, headerNumberPrefix) && len(key) == (len(headerNumberPrefix)+common.HashLength):
-			hashNumPairings.Add(size)
-		case len(key) == common.HashLength:
-			tries.Add(size)
-		case bytes.HasPrefix(key, CodePrefix) && len(key) == len(CodePrefix)+common.HashLength:
-			codes.Add(size)
-		case bytes.HasPrefix(key, txLookupPrefix) && len(key) == (len(txLookupPrefix)+common.HashLength):
-			txLookups.Add(size)
-		case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == (len(SnapshotAccountPrefix)+common.HashLength):
-			accountSnaps.Add(size)
-		case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == (len(SnapshotStoragePrefix)+2*common.HashLength):
-			storageSnaps.Add(size)
-		case bytes.HasPrefix(key, PreimagePrefix) && len(key) == (len(PreimagePrefix)+common.HashLength):
-			preimages.Add(size)
-		case bytes.HasPrefix(key, configPrefix) && len(key) == (len(configPrefix)+common.HashLength):
-			metadata.Add(size)
-		case bytes.HasPrefix(key, genesisPrefix) && len(key) == (len(genesisPrefix)+common.HashLength):
-			metadata.Add(size)
-		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == (len(bloomBitsPrefix)+10+common.HashLength):
-			bloomBits.Add(size)
-		case bytes.HasPrefix(key, BloomBitsIndexPrefix):
-			bloomBits.Add(size)
-		case bytes.HasPrefix(key, skeletonHeaderPrefix) && len(key) == (len(skeletonHeaderPrefix)+8):
-			beaconHeaders.Add(size)
-		case bytes.HasPrefix(key, CliqueSnapshotPrefix) && len(key) == 7+common.HashLength:
-			cliqueSnaps.Add(size)
-		case bytes.HasPrefix(key, ThoraSnapshotPrefix) && len(key) == 7+common.HashLength:
-			thoraSnaps.Add(size)
-		case bytes.HasPrefix(key, ChtTablePrefix) ||
-			bytes.HasPrefix(key, ChtIndexTablePrefix) ||
-			bytes.HasPrefix(key, ChtPrefix): // Canonical hash trie
-			chtTrieNodes.Add(size)
-		case bytes.HasPrefix(key, BloomTrieTablePrefix) ||
-			bytes.HasPrefix(key, BloomTrieIndexPrefix) ||
-			bytes.HasPrefix(key, BloomTriePrefix): // Bloomtrie sub
-			bloomTrieNodes.Add(size)
-		default:
-			var accounted bool
-			for _, meta := range [][]byte{
-				databaseVersionKey, headHeaderKey, headBlockKey, headFastBlockKey, headFinalizedBlockKey,
-				lastPivotKey, fastTrieProgressKey, snapshotDisabledKey, SnapshotRootKey, snapshotJournalKey,
-				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
-				uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
-			} {
-				if bytes.Equal(key, meta) {
-					metadata.Add(size)
-					accounted = true
-					break
-				}
-			}
-			if !accounted {
-				unaccounted.Add(size)
-			}
-		}
-		count++
-		if count%1000 == 0 && time.Since(logged) > 8*time.Second {
-			log.Info("Inspecting database", "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
-			logged = time.Now()
-		}
+// keyCategory is a category of key-value store entries, as reported by
+// InspectDatabase.
+type keyCategory int
+
+const (
+	categoryHeaders keyCategory = iota
+	categoryBodies
+	categoryReceipts
+	categoryTds
+	categoryNumHashPairings
+	categoryHashNumPairings
+	categoryTxLookups
+	categoryBloomBits
+	categoryAddressIndex
+	categoryVerkleWitnesses
+	categoryCodes
+	categoryTries
+	categoryPathTries
+	categoryStateLookups
+	categoryTrieHistories
+	categoryPreimages
+	categoryAccountSnaps
+	categoryStorageSnaps
+	categoryBeaconHeaders
+	categoryCliqueSnaps
+	categoryThoraSnaps
+	categoryMetadata
+	categoryChtTrieNodes
+	categoryBloomTrieNodes
+	categoryUnaccounted
+
+	numKeyCategories
+)
+
+// keyCategoryNames contains the database and display name of each key category.
+var keyCategoryNames = [numKeyCategories][2]string{
+	categoryHeaders:         {"Key-Value store", "Headers"},
+	categoryBodies:          {"Key-Value store", "Bodies"},
+	categoryReceipts:        {"Key-Value store", "Receipt lists"},
+	categoryTds:             {"Key-Value store", "Difficulties"},
+	categoryNumHashPairings: {"Key-Value store", "Block number->hash"},
+	categoryHashNumPairings: {"Key-Value store", "Block hash->number"},
+	categoryTxLookups:       {"Key-Value store", "Transaction index"},
+	categoryBloomBits:       {"Key-Value store", "Bloombit index"},
+	categoryAddressIndex:    {"Key-Value store", "Address index"},
+	categoryVerkleWitnesses: {"Key-Value store", "Verkle witnesses"},
+	categoryCodes:           {"Key-Value store", "Contract codes"},
+	categoryTries:           {"Key-Value store", "Trie nodes"},
+	categoryPathTries:       {"Key-Value store", "Path trie nodes"},
+	categoryStateLookups:    {"Key-Value store", "Path trie state lookups"},
+	categoryTrieHistories:   {"Key-Value store", "Path trie histories"},
+	categoryPreimages:       {"Key-Value store", "Trie preimages"},
+	categoryAccountSnaps:    {"Key-Value store", "Account snapshot"},
+	categoryStorageSnaps:    {"Key-Value store", "Storage snapshot"},
+	categoryBeaconHeaders:   {"Key-Value store", "Beacon sync headers"},
+	categoryCliqueSnaps:     {"Key-Value store", "Clique snapshots"},
+	categoryThoraSnaps:      {"Key-Value store", "Thora snapshots"},
+	categoryMetadata:        {"Key-Value store", "Singleton metadata"},
+	categoryChtTrieNodes:    {"Light client", "CHT trie nodes"},
+	categoryBloomTrieNodes:  {"Light client", "Bloom trie nodes"},
+	categoryUnaccounted:     {"Key-Value store", "Unaccounted"},
+}
+
+// inspectKey returns the category a key-value store entry belongs to.
+func inspectKey(key []byte) keyCategory {
+	switch {
+	case bytes.HasPrefix(key, headerPrefix) && len(key) == (len(headerPrefix)+8+common.HashLength):
+		return categoryHeaders
+	case bytes.HasPrefix(key, blockBodyPrefix) && len(key) == (len(blockBodyPrefix)+8+common.HashLength):
+		return categoryBodies
+	case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == (len(blockReceiptsPrefix)+8+common.HashLength):
+		return categoryReceipts
+	case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerTDSuffix):
+		return categoryTds
+	case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerHashSuffix):
+		return categoryNumHashPairings
+	case bytes.HasPrefix(key, headerNumberPrefix) && len(key) == (len(headerNumberPrefix)+common.HashLength):
+		return categoryHashNumPairings
+	case len(key) == common.HashLength:
+		return categoryTries
+	case bytes.HasPrefix(key, stateIDPrefix) && len(key) == len(stateIDPrefix)+common.HashLength:
+		return categoryStateLookups
+	case bytes.HasPrefix(key, trieHistoryPrefix) && len(key) == len(trieHistoryPrefix)+8:
+		return categoryTrieHistories
+	case bytes.HasPrefix(key, CodePrefix) && len(key) == len(CodePrefix)+common.HashLength:
+		return categoryCodes
+	case bytes.HasPrefix(key, txLookupPrefix) && len(key) == (len(txLookupPrefix)+common.HashLength):
+		return categoryTxLookups
+	case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == (len(SnapshotAccountPrefix)+common.HashLength):
+		return categoryAccountSnaps
+	case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == (len(SnapshotStoragePrefix)+2*common.HashLength):
+		return categoryStorageSnaps
+	case bytes.HasPrefix(key, PreimagePrefix) && len(key) == (len(PreimagePrefix)+common.HashLength):
+		return categoryPreimages
+	case bytes.HasPrefix(key, configPrefix) && len(key) == (len(configPrefix)+common.HashLength):
+		return categoryMetadata
+	case bytes.HasPrefix(key, genesisPrefix) && len(key) == (len(genesisPrefix)+common.HashLength):
+		return categoryMetadata
+	case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == (len(bloomBitsPrefix)+10+common.HashLength):
+		return categoryBloomBits
+	case bytes.HasPrefix(key, BloomBitsIndexPrefix):
+		return categoryBloomBits
+	case bytes.HasPrefix(key, addressIndexPrefix) && len(key) == (len(addressIndexPrefix)+common.AddressLength+12):
+		return categoryAddressIndex
+	case bytes.HasPrefix(key, addressJournalPrefix) && len(key) == (len(addressJournalPrefix)+8):
+		return categoryAddressIndex
+	case bytes.HasPrefix(key, AddressIndexPrefix):
+		return categoryAddressIndex
+	case bytes.HasPrefix(key, verkleWitnessPrefix) && len(key) == (len(verkleWitnessPrefix)+8+common.HashLength):
+		return categoryVerkleWitnesses
+	case bytes.HasPrefix(key, skeletonHeaderPrefix) && len(key) == (len(skeletonHeaderPrefix)+8):
+		return categoryBeaconHeaders
+	case bytes.HasPrefix(key, CliqueSnapshotPrefix) && len(key) == (len(CliqueSnapshotPrefix)+common.HashLength):
+		return categoryCliqueSnaps
+	case bytes.HasPrefix(key, ThoraSnapshotPrefix) && len(key) == (len(ThoraSnapshotPrefix)+common.HashLength):
+		return categoryThoraSnaps
+	case bytes.HasPrefix(key, ChtTablePrefix) ||
+		bytes.HasPrefix(key, ChtIndexTablePrefix) ||
+		bytes.HasPrefix(key, ChtPrefix): // Canonical hash trie
+		return categoryChtTrieNodes
+	case bytes.HasPrefix(key, BloomTrieTablePrefix) ||
+		bytes.HasPrefix(key, BloomTrieIndexPrefix) ||
+		bytes.HasPrefix(key, BloomTriePrefix): // Bloomtrie sub
+		return categoryBloomTrieNodes
 	}
-	// Display the database statistic of key-value store.
-	stats := [][]string{
-		{"Key-Value store", "Headers", headers.Size(), headers.Count()},
-		{"Key-Value store", "Bodies", bodies.Size(), bodies.Count()},
-		{"Key-Value store", "Receipt lists", receipts.Size(), receipts.Count()},
-		{"Key-Value store", "Difficulties", tds.Size(), tds.Count()},
-		{"Key-Value store", "Block number->hash", numHashPairings.Size(), numHashPairings.Count()},
-		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
-		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
-		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
-		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
-		{"Key-Value store", "Trie nodes", tries.Size(), tries.Count()},
-		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
-		{"Key-Value store", "Account snapshot", accountSnaps.Size(), accountSnaps.Count()},
-		{"Key-Value store", "Storage snapshot", storageSnaps.Size(), storageSnaps.Count()},
-		{"Key-Value store", "Beacon sync headers", beaconHeaders.Size(), beaconHeaders.Count()},
-		{"Key-Value store", "Clique snapshots", cliqueSnaps.Size(), cliqueSnaps.Count()},
-		{"Key-Value store", "Thora snapshots", thoraSnaps.Size(), thoraSnaps.Count()},
-		{"Key-Value store", "Singleton metadata", metadata.Size(), metadata.Count()},
-		{"Light client", "CHT trie nodes", chtTrieNodes.Size(), chtTrieNodes.Count()},
-		{"Light client", "Bloom trie nodes", bloomTrieNodes.Size(), bloomTrieNodes.Count()},
+	if ok, _ := IsAccountTrieNode(key); ok {
+		return categoryPathTries
 	}
-	// Inspect all registered append-only file store then.
-	ancients, err := inspectFreezers(db)
-	if err != nil {
-		return err
+	if ok, _, _ := IsStorageTrieNode(key); ok {
+		return categoryPathTries
 	}
-	for _, ancient := range ancients {
-		for _, table := range ancient.sizes {
-			stats = append(stats, []string{
-				fmt.Sprintf("Ancient store (%s)", strings.Title(ancient.name)),
-				strings.Title(table.name),
-				table.size.String(),
-				fmt.Sprintf("%d", ancient.count()),
-			})
+	for _, meta := range [][]byte{
+		databaseVersionKey, headHeaderKey, headBlockKey, headFastBlockKey, headFinalizedBlockKey,
+		lastPivotKey, fastTrieProgressKey, snapshotDisabledKey, SnapshotRootKey, snapshotJournalKey,
+		snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
+		uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
+		persistentStateIDKey, trieJournalKey, migrationProgressKey, databaseStatsKey,
+		databaseStatsHistoryKey,
+	} {
+		if bytes.Equal(key, meta) {
+			return categoryMetadata
 		}
-		total += ancient.size()
 	}
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Database", "Category", "Size", "Items"})
-	table.SetFooter([]string{"", "Total", total.String(), " "})
-	table.AppendBulk(stats)
-	table.Render()
-
-	if unaccounted.size > 0 {
-		log.Error("Database contains unaccounted data", "size", unaccounted.size, "count", unaccounted.count)
+	return categoryUnaccounted
+}
+
+// InspectDatabase traverses the entire database and checks the size
+// of all different categories of data.
+func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
+	stats, err := InspectDatabaseStats(db, keyPrefix, keyStart)
+	if err != nil {
+		return err
 	}
+	stats.Render(os.Stdout)
 	return nil
 }
 
@@ -0,0 +1,391 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/olekukonko/tablewriter"
+)
+
+// dbStatsHistoryLimit is the maximum number of database statistics retained in
+// the growth history.
+const dbStatsHistoryLimit = 128
+
+// dbStatsLock serializes the incremental database inspections.
+var dbStatsLock sync.Mutex
+
+// DatabaseStat is the storage size and number of entries of a category of data.
+type DatabaseStat struct {
+	Database string `json:"database"`
+	Category string `json:"category"`
+	Size     uint64 `json:"size"`
+	Count    uint64 `json:"count"`
+}
+
+// DatabaseStats is a snapshot of the storage used by every category of data.
+type DatabaseStats struct {
+	Time     uint64         `json:"time"`     // Unix time the statistics were assembled at
+	Complete bool           `json:"complete"` // Whether all key prefixes were inspected
+	Total    uint64         `json:"total"`
+	Stats    []DatabaseStat `json:"stats"`
+}
+
+// DatabaseGrowth is the change in storage size and number of entries of a
+// category of data over a period of time.
+type DatabaseGrowth struct {
+	Database string `json:"database"`
+	Category string `json:"category"`
+	Size     int64  `json:"size"`
+	Count    int64  `json:"count"`
+	Period   uint64 `json:"period"` // Seconds elapsed between the compared statistics
+}
+
+// Growth returns the change of every category of data since the given older
+// statistics.
+func (s *DatabaseStats) Growth(old *DatabaseStats) []DatabaseGrowth {
+	prev := make(map[[2]string]DatabaseStat)
+	for _, stat := range old.Stats {
+		prev[[2]string{stat.Database, stat.Category}] = stat
+	}
+	var period uint64
+	if s.Time > old.Time {
+		period = s.Time - old.Time
+	}
+	growth := make([]DatabaseGrowth, 0, len(s.Stats))
+	for _, stat := range s.Stats {
+		p := prev[[2]string{stat.Database, stat.Category}]
+		growth = append(growth, DatabaseGrowth{
+			Database: stat.Database,
+			Category: stat.Category,
+			Size:     int64(stat.Size) - int64(p.Size),
+			Count:    int64(stat.Count) - int64(p.Count),
+			Period:   period,
+		})
+	}
+	return growth
+}
+
+// Render displays the database statistics as a table, reporting unaccounted data
+// separately.
+func (s *DatabaseStats) Render(w io.Writer) {
+	var rows [][]string
+	for i, stat := range s.Stats {
+		if i == int(categoryUnaccounted) {
+			continue
+		}
+		rows = append(rows, []string{stat.Database, stat.Category, common.StorageSize(stat.Size).String(), fmt.Sprintf("%d", stat.Count)})
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Database", "Category", "Size", "Items"})
+	table.SetFooter([]string{"", "Total", common.StorageSize(s.Total).String(), " "})
+	table.AppendBulk(rows)
+	table.Render()
+
+	if unaccounted := s.Stats[categoryUnaccounted]; unaccounted.Size > 0 {
+		log.Error("Database contains unaccounted data", "size", common.StorageSize(unaccounted.Size), "count", unaccounted.Count)
+	}
+}
+
+// newDatabaseStats assembles the database statistics out of the key-value store
+// statistics of every key category and the statistics of the ancient stores.
+func newDatabaseStats(kv *[numKeyCategories]stat, ancients []freezerInfo) *DatabaseStats {
+	stats := &DatabaseStats{
+		Time:     uint64(time.Now().Unix()),
+		Complete: true,
+	}
+	for category := keyCategory(0); category < numKeyCategories; category++ {
+		names := keyCategoryNames[category]
+		stats.Stats = append(stats.Stats, DatabaseStat{
+			Database: names[0],
+			Category: names[1],
+			Size:     uint64(kv[category].size),
+			Count:    uint64(kv[category].count),
+		})
+		stats.Total += uint64(kv[category].size)
+	}
+	for _, ancient := range ancients {
+		sizes := append([]tableSize{}, ancient.sizes...)
+		sort.Slice(sizes, func(i, j int) bool { return sizes[i].name < sizes[j].name })
+
+		for _, table := range sizes {
+			stats.Stats = append(stats.Stats, DatabaseStat{
+				Database: fmt.Sprintf("Ancient store (%s)", strings.Title(ancient.name)),
+				Category: strings.Title(table.name),
+				Size:     uint64(table.size),
+				Count:    ancient.count(),
+			})
+			stats.Total += uint64(table.size)
+		}
+	}
+	return stats
+}
+
+// inspectAncients inspects the ancient stores of the database, if it has any.
+func inspectAncients(db ethdb.Database) ([]freezerInfo, error) {
+	ancients, err := inspectFreezers(db)
+	if errors.Is(err, errNotSupported) {
+		return nil, nil
+	}
+	return ancients, err
+}
+
+// InspectDatabaseStats traverses the entire database and gathers the size of
+// all different categories of data.
+func InspectDatabaseStats(db ethdb.Database, keyPrefix, keyStart []byte) (*DatabaseStats, error) {
+	it := db.NewIterator(keyPrefix, keyStart)
+	defer it.Release()
+
+	var (
+		count  int64
+		start  = time.Now()
+		logged = time.Now()
+
+		// Key-value store statistics
+		stats [numKeyCategories]stat
+	)
+	for it.Next() {
+		var (
+			key  = it.Key()
+			size = common.StorageSize(len(key) + len(it.Value()))
+		)
+		stats[inspectKey(key)].Add(size)
+
+		count++
+		if count%1000 == 0 && time.Since(logged) > 8*time.Second {
+			log.Info("Inspecting database", "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	// Inspect all registered append-only file store then.
+	ancients, err := inspectAncients(db)
+	if err != nil {
+		return nil, err
+	}
+	return newDatabaseStats(&stats, ancients), nil
+}
+
+// dbStatsBucket is the storage statistics of all keys sharing a first byte.
+type dbStatsBucket struct {
+	Inspected bool     // Whether all keys of the bucket were inspected at least once
+	Sizes     []uint64 // Storage size of every key category
+	Counts    []uint64 // Number of entries of every key category
+}
+
+func newDBStatsBucket() dbStatsBucket {
+	return dbStatsBucket{
+		Sizes:  make([]uint64, numKeyCategories),
+		Counts: make([]uint64, numKeyCategories),
+	}
+}
+
+// valid reports whether the bucket statistics match the current key categories.
+func (b *dbStatsBucket) valid() bool {
+	return len(b.Sizes) == int(numKeyCategories) && len(b.Counts) == int(numKeyCategories)
+}
+
+// dbStatsProgress is the persisted state of the incremental database inspection.
+type dbStatsProgress struct {
+	Cursor  []byte          // Next key to inspect, nil if a new pass is due
+	Partial dbStatsBucket   // Statistics gathered so far for the bucket of the cursor
+	Buckets []dbStatsBucket // Statistics of every bucket, indexed by first key byte
+}
+
+// readDBStatsProgress retrieves the state of the incremental database inspection,
+// starting afresh if there's none or if it's incompatible.
+func readDBStatsProgress(db ethdb.KeyValueReader) *dbStatsProgress {
+	blob, _ := db.Get(databaseStatsKey)
+	if len(blob) > 0 {
+		progress := new(dbStatsProgress)
+		if err := rlp.DecodeBytes(blob, progress); err != nil {
+			log.Warn("Failed to decode database inspection progress", "err", err)
+		} else if progress.valid() {
+			return progress
+		}
+	}
+	progress := &dbStatsProgress{
+		Partial: newDBStatsBucket(),
+		Buckets: make([]dbStatsBucket, 256),
+	}
+	for i := range progress.Buckets {
+		progress.Buckets[i] = newDBStatsBucket()
+	}
+	return progress
+}
+
+// valid reports whether the inspection state matches the current key categories.
+func (p *dbStatsProgress) valid() bool {
+	if len(p.Buckets) != 256 || !p.Partial.valid() {
+		return false
+	}
+	for _, bucket := range p.Buckets {
+		if !bucket.valid() {
+			return false
+		}
+	}
+	return true
+}
+
+// stats assembles the database statistics out of the latest statistics of every
+// bucket and the statistics of the ancient stores.
+func (p *dbStatsProgress) stats(db ethdb.Database) (*DatabaseStats, error) {
+	var (
+		kv       [numKeyCategories]stat
+		complete = true
+	)
+	for _, bucket := range p.Buckets {
+		for i := range kv {
+			kv[i].size += common.StorageSize(bucket.Sizes[i])
+			kv[i].count += counter(bucket.Counts[i])
+		}
+		complete = complete && bucket.Inspected
+	}
+	ancients, err := inspectAncients(db)
+	if err != nil {
+		return nil, err
+	}
+	stats := newDatabaseStats(&kv, ancients)
+	stats.Complete = complete
+	return stats, nil
+}
+
+// ReadDatabaseStats assembles the database statistics gathered by the incremental
+// database inspections so far, without inspecting any keys.
+func ReadDatabaseStats(db ethdb.Database) (*DatabaseStats, error) {
+	dbStatsLock.Lock()
+	defer dbStatsLock.Unlock()
+
+	return readDBStatsProgress(db).stats(db)
+}
+
+// InspectDatabaseIncremental continues the incremental inspection of the key-value
+// store where the previous one left off, inspecting at most limit entries, or the
+// rest of the current pass over the key space if limit is zero.
+//
+// The keys are accounted in buckets by their first byte, and the statistics of a
+// bucket are only replaced once all its keys were inspected, so the returned
+// statistics always cover the whole key space once a single pass completed.
+// Whenever a pass completes, the statistics are appended to the growth history.
+func InspectDatabaseIncremental(db ethdb.Database, limit int) (*DatabaseStats, error) {
+	dbStatsLock.Lock()
+	defer dbStatsLock.Unlock()
+
+	var (
+		progress = readDBStatsProgress(db)
+		bucket   int
+		count    int
+		done     bool
+	)
+	if len(progress.Cursor) > 0 {
+		bucket = int(progress.Cursor[0])
+	}
+	// finish replaces the statistics of the current bucket, as well as those of
+	// the empty buckets up until the next one.
+	finish := func(next int) {
+		progress.Partial.Inspected = true
+		progress.Buckets[bucket] = progress.Partial
+		for i := bucket + 1; i < next; i++ {
+			progress.Buckets[i] = newDBStatsBucket()
+			progress.Buckets[i].Inspected = true
+		}
+		progress.Partial = newDBStatsBucket()
+		bucket = next
+	}
+	it := db.NewIterator(nil, progress.Cursor)
+	for limit == 0 || count < limit {
+		if !it.Next() {
+			done = true
+			break
+		}
+		key := it.Key()
+		if len(key) > 0 && int(key[0]) != bucket {
+			finish(int(key[0]))
+		}
+		category := inspectKey(key)
+		progress.Partial.Sizes[category] += uint64(len(key) + len(it.Value()))
+		progress.Partial.Counts[category]++
+		progress.Cursor = append(common.CopyBytes(key), 0x00)
+		count++
+	}
+	err := it.Error()
+	it.Release()
+	if err != nil {
+		return nil, err
+	}
+	if done {
+		finish(len(progress.Buckets))
+		progress.Cursor = nil
+	}
+	stats, err := progress.stats(db)
+	if err != nil {
+		return nil, err
+	}
+	// Persist the inspection progress and extend the history if a pass completed
+	blob, err := rlp.EncodeToBytes(progress)
+	if err != nil {
+		return nil, err
+	}
+	batch := db.NewBatch()
+	if err := batch.Put(databaseStatsKey, blob); err != nil {
+		return nil, err
+	}
+	if done && stats.Complete {
+		history := append(ReadDatabaseStatsHistory(db), stats)
+		if len(history) > dbStatsHistoryLimit {
+			history = history[len(history)-dbStatsHistoryLimit:]
+		}
+		blob, err := rlp.EncodeToBytes(history)
+		if err != nil {
+			return nil, err
+		}
+		if err := batch.Put(databaseStatsHistoryKey, blob); err != nil {
+			return nil, err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ReadDatabaseStatsHistory retrieves the database statistics recorded at the end
+// of every completed incremental inspection pass, oldest first.
+func ReadDatabaseStatsHistory(db ethdb.KeyValueReader) []*DatabaseStats {
+	blob, _ := db.Get(databaseStatsHistoryKey)
+	if len(blob) == 0 {
+		return nil
+	}
+	var history []*DatabaseStats
+	if err := rlp.DecodeBytes(blob, &history); err != nil {
+		log.Warn("Failed to decode database statistics history", "err", err)
+		return nil
+	}
+	return history
+}
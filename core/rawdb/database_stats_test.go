@@ -0,0 +1,89 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestInspectDatabaseIncremental(t *testing.T) {
+	db := NewMemoryDatabase()
+	fillMigrationSource(t, db)
+
+	full, err := InspectDatabaseStats(db, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to inspect database: %v", err)
+	}
+	// Inspect the database in small steps until a pass completes
+	var (
+		stats *DatabaseStats
+		steps int
+	)
+	for len(ReadDatabaseStatsHistory(db)) == 0 {
+		if stats, err = InspectDatabaseIncremental(db, 100); err != nil {
+			t.Fatalf("failed to inspect database: %v", err)
+		}
+		if steps++; steps > 1000 {
+			t.Fatalf("inspection pass not completing")
+		}
+		if len(ReadDatabaseStatsHistory(db)) == 0 && stats.Complete {
+			t.Fatalf("step %d: statistics complete before the end of the pass", steps)
+		}
+	}
+	if steps < 2 {
+		t.Fatalf("inspection not incremental: %d steps", steps)
+	}
+	check := func(have, want *DatabaseStats) {
+		t.Helper()
+
+		if !have.Complete {
+			t.Fatalf("statistics incomplete")
+		}
+		for i := range want.Stats {
+			// Singleton metadata includes the inspection progress itself
+			if i == int(categoryMetadata) {
+				continue
+			}
+			if have.Stats[i] != want.Stats[i] {
+				t.Fatalf("category %s mismatch: have %+v, want %+v", want.Stats[i].Category, have.Stats[i], want.Stats[i])
+			}
+		}
+	}
+	check(stats, full)
+	if current, err := ReadDatabaseStats(db); err != nil {
+		t.Fatalf("failed to read statistics: %v", err)
+	} else {
+		check(current, full)
+	}
+	// Grow the database and ensure the growth is tracked by the next pass
+	for i := 0; i < 100; i++ {
+		db.Put(codeKey(common.Hash{0xff, byte(i)}), make([]byte, 10))
+	}
+	if _, err := InspectDatabaseIncremental(db, 0); err != nil {
+		t.Fatalf("failed to inspect database: %v", err)
+	}
+	history := ReadDatabaseStatsHistory(db)
+	if len(history) != 2 {
+		t.Fatalf("history length mismatch: have %d, want 2", len(history))
+	}
+	growth := history[1].Growth(history[0])[categoryCodes]
+	if growth.Count != 100 || growth.Size != 100*int64(len(CodePrefix)+common.HashLength+10) {
+		t.Fatalf("code growth mismatch: have %+v", growth)
+	}
+}
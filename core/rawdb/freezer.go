@@ -63,7 +63,7 @@ const freezerTableSize = 2 * 1000 * 1000 * 1000
 //     of Geth, and thus also GC overhead.
 type Freezer struct {
 	frozen atomic.Uint64 // Number of blocks already frozen
-	tail   atomic.Uint64 // Number of the first stored item in the freezer
+	tail   atomic.Uint64 // Number of the first stored item in the prunable tables
 
 	// This lock synchronizes writers and the truncate operation, as well as
 	// the "atomic" (batched) read operations.
@@ -72,22 +72,30 @@ type Freezer struct {
 
 	readonly     bool
 	tables       map[string]*freezerTable // Data tables for storing everything
+	prunable     map[string]bool          // Tables whose tail can be truncated
 	instanceLock *flock.Flock             // File-system lock to prevent double opens
+	remote       *freezerRemote           // Object store the sealed data files are offloaded to (nil = local only)
 	closeOnce    sync.Once
 }
 
 // NewChainFreezer is a small utility method around NewFreezer that sets the
 // default parameters for the chain storage.
 func NewChainFreezer(datadir string, namespace string, readonly bool) (*Freezer, error) {
-	return NewFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy)
+	return NewFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerTableConfigs)
 }
 
 // NewFreezer creates a freezer instance for maintaining immutable ordered
 // data according to the given parameters.
 //
-// The 'tables' argument defines the data tables. If the value of a map
-// entry is true, snappy compression is disabled for the table.
-func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+// The 'tables' argument defines the data tables along with their settings,
+// whether snappy compression is disabled and whether the table can be pruned.
+func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]freezerTableConfig) (*Freezer, error) {
+	return newFreezer(datadir, namespace, readonly, maxTableSize, tables, nil)
+}
+
+// newFreezer creates a freezer instance like NewFreezer, offloading the sealed
+// data files of its tables into an object store if remote is non-nil.
+func newFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]freezerTableConfig, remote *RemoteAncients) (*Freezer, error) {
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -117,20 +125,33 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 	freezer := &Freezer{
 		readonly:     readonly,
 		tables:       make(map[string]*freezerTable),
+		prunable:     make(map[string]bool),
 		instanceLock: lock,
 	}
-
+	if remote != nil {
+		var err error
+		if freezer.remote, err = newFreezerRemote(remote, datadir); err != nil {
+			lock.Unlock()
+			return nil, err
+		}
+	}
 	// Create the tables.
-	for name, disableSnappy := range tables {
-		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, maxTableSize, disableSnappy, readonly)
+	for name, config := range tables {
+		table, err := newRemoteTable(datadir, name, readMeter, writeMeter, sizeGauge, maxTableSize, config.noSnappy, readonly, freezer.remote)
 		if err != nil {
 			for _, table := range freezer.tables {
 				table.Close()
 			}
+			if freezer.remote != nil {
+				freezer.remote.close()
+			}
 			lock.Unlock()
 			return nil, err
 		}
 		freezer.tables[name] = table
+		if config.prunable {
+			freezer.prunable[name] = true
+		}
 	}
 	var err error
 	if freezer.readonly {
@@ -145,6 +166,9 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 		for _, table := range freezer.tables {
 			table.Close()
 		}
+		if freezer.remote != nil {
+			freezer.remote.close()
+		}
 		lock.Unlock()
 		return nil, err
 	}
@@ -152,7 +176,11 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 	// Create the write batch.
 	freezer.writeBatch = newFreezerBatch(freezer)
 
-	log.Info("Opened ancient database", "database", datadir, "readonly", readonly)
+	// Start offloading the sealed data files
+	if freezer.remote != nil && !readonly {
+		freezer.remote.start()
+	}
+	log.Info("Opened ancient database", "database", datadir, "readonly", readonly, "remote", freezer.remote != nil)
 	return freezer, nil
 }
 
@@ -163,6 +191,12 @@ func (f *Freezer) Close() error {
 
 	var errs []error
 	f.closeOnce.Do(func() {
+		// Stop the uploader before the tables are closed beneath it
+		if f.remote != nil {
+			if err := f.remote.close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
 		for _, table := range f.tables {
 			if err := table.Close(); err != nil {
 				errs = append(errs, err)
@@ -178,6 +212,29 @@ func (f *Freezer) Close() error {
 	return nil
 }
 
+// checkpoint copies all the tables as of the current head into the given
+// directory. Modifications are blocked until the copy is complete; fn is run
+// beforehand, allowing the caller to snapshot related data consistently.
+func (f *Freezer) checkpoint(dir string, fn func() error) error {
+	f.writeLock.RLock()
+	defer f.writeLock.RUnlock()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if fn != nil {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	for _, table := range f.tables {
+		if err := table.checkpoint(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // HasAncient returns an indicator whether the specified ancient data exists
 // in the freezer.
 func (f *Freezer) HasAncient(kind string, number uint64) (bool, error) {
@@ -213,7 +270,8 @@ func (f *Freezer) Ancients() (uint64, error) {
 	return f.frozen.Load(), nil
 }
 
-// Tail returns the number of first stored item in the freezer.
+// Tail returns the number of first stored item in the prunable tables of the
+// freezer. The items below it are only retained in the non-prunable tables.
 func (f *Freezer) Tail() (uint64, error) {
 	return f.tail.Load(), nil
 }
@@ -294,7 +352,8 @@ func (f *Freezer) TruncateHead(items uint64) error {
 	return nil
 }
 
-// TruncateTail discards any recent data below the provided threshold number.
+// TruncateTail discards any data below the provided threshold number from the
+// prunable tables. The non-prunable tables always retain all items.
 func (f *Freezer) TruncateTail(tail uint64) error {
 	if f.readonly {
 		return errReadOnly
@@ -305,7 +364,10 @@ func (f *Freezer) TruncateTail(tail uint64) error {
 	if f.tail.Load() >= tail {
 		return nil
 	}
-	for _, table := range f.tables {
+	for kind, table := range f.tables {
+		if !f.prunable[kind] {
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return err
 		}
@@ -328,31 +390,45 @@ func (f *Freezer) Sync() error {
 	return nil
 }
 
-// validate checks that every table has the same boundary.
+// validate checks that every table has the same head, that the prunable tables
+// share the same tail and that the non-prunable ones were never pruned.
 // Used instead of `repair` in readonly mode.
 func (f *Freezer) validate() error {
 	if len(f.tables) == 0 {
 		return nil
 	}
 	var (
-		head uint64
-		tail uint64
-		name string
+		head     uint64
+		name     string
+		tail     uint64
+		tailName string
 	)
 	// Hack to get boundary of any table
 	for kind, table := range f.tables {
 		head = table.items.Load()
-		tail = table.itemHidden.Load()
 		name = kind
 		break
 	}
+	for kind, table := range f.tables {
+		if f.prunable[kind] {
+			tail = table.itemHidden.Load()
+			tailName = kind
+			break
+		}
+	}
 	// Now check every table against those boundaries.
 	for kind, table := range f.tables {
 		if head != table.items.Load() {
 			return fmt.Errorf("freezer tables %s and %s have differing head: %d != %d", kind, name, table.items.Load(), head)
 		}
+		if !f.prunable[kind] {
+			if hidden := table.itemHidden.Load(); hidden != 0 {
+				return fmt.Errorf("non-prunable freezer table %s has non-zero tail: %d", kind, hidden)
+			}
+			continue
+		}
 		if tail != table.itemHidden.Load() {
-			return fmt.Errorf("freezer tables %s and %s have differing tail: %d != %d", kind, name, table.itemHidden.Load(), tail)
+			return fmt.Errorf("freezer tables %s and %s have differing tail: %d != %d", kind, tailName, table.itemHidden.Load(), tail)
 		}
 	}
 	f.frozen.Store(head)
@@ -360,26 +436,36 @@ func (f *Freezer) validate() error {
 	return nil
 }
 
-// repair truncates all data tables to the same length.
+// repair truncates all data tables to the same length and all the prunable
+// tables to the same tail.
 func (f *Freezer) repair() error {
 	var (
 		head = uint64(math.MaxUint64)
 		tail = uint64(0)
 	)
-	for _, table := range f.tables {
+	for kind, table := range f.tables {
 		items := table.items.Load()
 		if head > items {
 			head = items
 		}
+		if !f.prunable[kind] {
+			continue
+		}
 		hidden := table.itemHidden.Load()
 		if hidden > tail {
 			tail = hidden
 		}
 	}
-	for _, table := range f.tables {
+	for kind, table := range f.tables {
 		if err := table.truncateHead(head); err != nil {
 			return err
 		}
+		if !f.prunable[kind] {
+			if hidden := table.itemHidden.Load(); hidden != 0 {
+				return fmt.Errorf("non-prunable freezer table %s has non-zero tail: %d", kind, hidden)
+			}
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return err
 		}
@@ -0,0 +1,505 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb/objstore"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// remoteChunkSize is the size of the segments offloaded data files are fetched
+// and cached in, so reading an item doesn't require downloading its whole file.
+const remoteChunkSize = 4 * 1024 * 1024
+
+// RemoteAncients configures a freezer to offload its sealed data files into an
+// object store, keeping only the table heads, indices and metadata on the local
+// disk.
+type RemoteAncients struct {
+	Store     objstore.Store // Object store to upload the sealed data files to
+	CacheSize uint64         // Maximum size of the fetched data file chunks cached on disk
+}
+
+// offloadTask is a sealed data file of a freezer table waiting to be uploaded.
+type offloadTask struct {
+	table *freezerTable
+	num   uint32
+}
+
+// freezerRemote moves the sealed, immutable data files of freezer tables into
+// an object store in the background. Offloaded files are fetched back in chunks
+// on demand into a local LRU cache when read, or restored into the table
+// directory if the table is truncated into them.
+type freezerRemote struct {
+	store  objstore.Store
+	prefix string       // Key prefix of the objects of the freezer
+	cache  *remoteCache // Local cache of the fetched data files
+
+	lock    sync.Mutex
+	pending []offloadTask // Sealed data files waiting to be uploaded
+	wake    chan struct{} // Notification channel of new offload tasks
+	run     sync.Mutex    // Lock serializing the processing of offload tasks
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newFreezerRemote creates the remote backend of the freezer in datadir. The
+// objects are namespaced by the freezer directory name.
+func newFreezerRemote(config *RemoteAncients, datadir string) (*freezerRemote, error) {
+	quit := make(chan struct{})
+	cache, err := newRemoteCache(config.Store, filepath.Join(datadir, "remotecache"), config.CacheSize, remoteChunkSize, quit)
+	if err != nil {
+		return nil, err
+	}
+	return &freezerRemote{
+		store:  config.Store,
+		prefix: filepath.Base(datadir) + "/",
+		cache:  cache,
+		wake:   make(chan struct{}, 1),
+		quit:   quit,
+	}, nil
+}
+
+// start launches the background uploader.
+func (r *freezerRemote) start() {
+	r.wg.Add(1)
+	go r.loop()
+}
+
+// close terminates the uploader, aborting any running transfer, and drops the
+// local cache.
+func (r *freezerRemote) close() error {
+	close(r.quit)
+	r.wg.Wait()
+	return r.cache.close()
+}
+
+// key returns the object key of a data file of a table.
+func (r *freezerRemote) key(t *freezerTable, num uint32) string {
+	return r.prefix + t.fileName(num)
+}
+
+// loop uploads the scheduled data files until the freezer is closed.
+func (r *freezerRemote) loop() {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-r.wake:
+			r.process()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// schedule queues a sealed data file for uploading.
+func (r *freezerRemote) schedule(t *freezerTable, num uint32) {
+	r.lock.Lock()
+	r.pending = append(r.pending, offloadTask{table: t, num: num})
+	r.lock.Unlock()
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// process uploads all the queued data files. Failed uploads leave the data file
+// on the local disk, it's retried the next time the freezer is opened.
+func (r *freezerRemote) process() {
+	r.run.Lock()
+	defer r.run.Unlock()
+
+	for {
+		select {
+		case <-r.quit:
+			return
+		default:
+		}
+		r.lock.Lock()
+		if len(r.pending) == 0 {
+			r.lock.Unlock()
+			return
+		}
+		task := r.pending[0]
+		r.pending = r.pending[1:]
+		r.lock.Unlock()
+
+		if err := r.offload(task.table, task.num); err != nil {
+			task.table.logger.Warn("Failed to offload freezer data file", "file", task.num, "err", err)
+		}
+	}
+}
+
+// offload uploads a sealed data file of a table into the object store and
+// removes the local copy once the upload is verified.
+func (r *freezerRemote) offload(t *freezerTable, num uint32) error {
+	t.lock.RLock()
+	f, exist := t.files[num]
+	sealed := num >= t.tailId && num < t.headId
+	gen := t.remoteGen
+	t.lock.RUnlock()
+
+	if !exist || !sealed {
+		return nil
+	}
+	path := f.Name()
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	stat, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	key := r.key(t, num)
+	if err := r.store.Put(key, &abortReader{r: src, quit: r.quit}, stat.Size()); err != nil {
+		return err
+	}
+	// Verify the upload before the local copy is deleted
+	size, err := r.store.Size(key)
+	if err != nil {
+		return err
+	}
+	if size != stat.Size() {
+		if err := r.store.Delete(key); err != nil {
+			return err
+		}
+		return fmt.Errorf("uploaded data file size mismatch: have %d, want %d", size, stat.Size())
+	}
+	// The data file was uploaded, swap it out unless the table was truncated
+	// in the meantime, in which case the upload may be stale.
+	t.lock.Lock()
+	if t.remoteGen != gen || num < t.tailId {
+		retry := num >= t.tailId && num < t.headId
+		t.lock.Unlock()
+		if err := r.store.Delete(key); err != nil {
+			return err
+		}
+		if retry {
+			r.schedule(t, num)
+		}
+		return nil
+	}
+	t.releaseFile(num)
+	err = os.Remove(path)
+	t.lock.Unlock()
+
+	t.logger.Debug("Offloaded freezer data file", "file", num, "size", common.StorageSize(stat.Size()))
+	return err
+}
+
+// readAt reads from an offloaded data file of a table through the local cache,
+// fetching only the chunks of the file covering the requested range.
+func (r *freezerRemote) readAt(t *freezerTable, num uint32, p []byte, off int64) error {
+	key := r.key(t, num)
+	for len(p) > 0 {
+		var (
+			index = off / r.cache.chunk
+			start = off - index*r.cache.chunk
+			size  = int64(len(p))
+		)
+		if start+size > r.cache.chunk {
+			size = r.cache.chunk - start
+		}
+		f, err := r.cache.acquire(key, index)
+		if err != nil {
+			return err
+		}
+		n, err := f.file.ReadAt(p[:size], start)
+		r.cache.release(f)
+		if err == io.EOF && int64(n) < size {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		p, off = p[size:], off+size
+	}
+	return nil
+}
+
+// restore downloads an offloaded data file of a table back into the given path,
+// making it writable again. Data files which were never offloaded are ignored.
+func (r *freezerRemote) restore(t *freezerTable, num uint32, path string) error {
+	key := r.key(t, num)
+	src, err := r.store.Get(key)
+	if errors.Is(err, objstore.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	r.cache.invalidate(key)
+	dst, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, &abortReader{r: src, quit: r.quit})
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return err
+	}
+	t.logger.Debug("Restored offloaded freezer data file", "file", num)
+	return os.Rename(dst.Name(), path)
+}
+
+// drop deletes the offloaded data files of a table in the range [from, to).
+// Failures are only logged, the objects are orphaned but never read again.
+func (r *freezerRemote) drop(t *freezerTable, from, to uint32) {
+	for num := from; num < to; num++ {
+		key := r.key(t, num)
+		r.cache.invalidate(key)
+		if err := r.store.Delete(key); err != nil {
+			t.logger.Warn("Failed to delete offloaded freezer data file", "file", num, "err", err)
+		}
+	}
+}
+
+// isLocal reports whether the data file at the given path exists on disk.
+func isLocal(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+// chunkID identifies a chunk of an object in the remote cache.
+type chunkID struct {
+	key   string
+	index int64
+}
+
+// cachedFile is a chunk of a data file fetched into the remote cache.
+type cachedFile struct {
+	id    chunkID
+	elem  *list.Element // Position of the file in the eviction order
+	file  *os.File      // Local copy of the data file
+	size  uint64        // Size of the local copy
+	refs  int           // Number of readers currently using the file
+	gone  bool          // Flag whether the file was removed from the cache
+	ready chan struct{} // Channel closed once the download finished
+	err   error         // Error of a failed download
+}
+
+// remoteCache is a size-limited local disk cache of data file chunks fetched
+// from an object store. Once the limit is exceeded, the least recently used
+// chunks are evicted first; chunks in use are never evicted.
+type remoteCache struct {
+	store objstore.Store
+	dir   string
+	limit uint64
+	chunk int64 // Size of the chunks objects are fetched in
+	quit  chan struct{}
+
+	lock  sync.Mutex
+	files map[chunkID]*cachedFile
+	order *list.List // Cached files, the most recently used at the front
+	size  uint64     // Total size of the cached files
+}
+
+// newRemoteCache creates a cache in the given directory, discarding any files
+// left over from a previous run.
+func newRemoteCache(store objstore.Store, dir string, limit uint64, chunk int64, quit chan struct{}) (*remoteCache, error) {
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &remoteCache{
+		store: store,
+		dir:   dir,
+		limit: limit,
+		chunk: chunk,
+		quit:  quit,
+		files: make(map[chunkID]*cachedFile),
+		order: list.New(),
+	}, nil
+}
+
+// acquire returns the cached copy of a chunk of the given object, downloading it
+// if it's not cached yet. The file must be released after use.
+func (c *remoteCache) acquire(key string, index int64) (*cachedFile, error) {
+	id := chunkID{key: key, index: index}
+
+	c.lock.Lock()
+	if f, ok := c.files[id]; ok {
+		f.refs++
+		c.order.MoveToFront(f.elem)
+		c.lock.Unlock()
+
+		<-f.ready
+		if f.err != nil {
+			c.release(f)
+			return nil, f.err
+		}
+		return f, nil
+	}
+	f := &cachedFile{id: id, refs: 1, ready: make(chan struct{})}
+	f.elem = c.order.PushFront(f)
+	c.files[id] = f
+	c.lock.Unlock()
+
+	file, size, err := c.fetch(id)
+
+	c.lock.Lock()
+	f.file, f.size, f.err = file, size, err
+	if err != nil {
+		c.remove(f)
+	} else if !f.gone {
+		c.size += size
+	}
+	close(f.ready)
+	c.lock.Unlock()
+
+	if err != nil {
+		c.release(f)
+		return nil, err
+	}
+	return f, nil
+}
+
+// release marks a cached file as no longer used by the caller, evicting files
+// if the cache outgrew its limit.
+func (c *remoteCache) release(f *cachedFile) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	f.refs--
+	if f.refs == 0 && f.gone {
+		f.close()
+	}
+	c.evict()
+}
+
+// invalidate removes all chunks of the given object from the cache.
+func (c *remoteCache) invalidate(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for id, f := range c.files {
+		if id.key == key {
+			c.remove(f)
+		}
+	}
+}
+
+// close removes all the cached files.
+func (c *remoteCache) close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, f := range c.files {
+		c.remove(f)
+	}
+	return os.RemoveAll(c.dir)
+}
+
+// fetch downloads a chunk of an object into a new file in the cache directory.
+func (c *remoteCache) fetch(id chunkID) (*os.File, uint64, error) {
+	src, err := c.store.GetRange(id.key, id.index*c.chunk, c.chunk)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp(c.dir, fmt.Sprintf("%s.%d.*", strings.ReplaceAll(id.key, "/", "_"), id.index))
+	if err != nil {
+		return nil, 0, err
+	}
+	n, err := io.Copy(dst, &abortReader{r: src, quit: c.quit})
+	if err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return nil, 0, err
+	}
+	log.Debug("Fetched offloaded freezer data chunk", "key", id.key, "chunk", id.index, "size", common.StorageSize(n))
+	return dst, uint64(n), nil
+}
+
+// remove drops a file from the cache, deleting it immediately if it's not in
+// use. The caller must hold the lock.
+func (c *remoteCache) remove(f *cachedFile) {
+	if !f.gone {
+		delete(c.files, f.id)
+		c.order.Remove(f.elem)
+		if f.file != nil {
+			c.size -= f.size
+		}
+		f.gone = true
+	}
+	if f.refs == 0 {
+		f.close()
+	}
+}
+
+// evict removes the least recently used unreferenced files until the cache
+// fits into its limit. The caller must hold the lock.
+func (c *remoteCache) evict() {
+	for elem := c.order.Back(); elem != nil && c.size > c.limit; {
+		prev := elem.Prev()
+		if f := elem.Value.(*cachedFile); f.refs == 0 && f.file != nil {
+			c.remove(f)
+		}
+		elem = prev
+	}
+}
+
+// close deletes the local copy of the cached file.
+func (f *cachedFile) close() {
+	if f.file != nil {
+		f.file.Close()
+		os.Remove(f.file.Name())
+		f.file = nil
+	}
+}
+
+// abortReader wraps a reader, failing once the quit channel is closed so that
+// long transfers don't block the shutdown of the freezer.
+type abortReader struct {
+	r    io.Reader
+	quit chan struct{}
+}
+
+func (r *abortReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.quit:
+		return 0, errClosed
+	default:
+		return r.r.Read(p)
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/objstore"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that the sealed data files of freezer tables are offloaded into the
+// remote store, and that they're transparently fetched, restored and deleted
+// as the tables are read and truncated.
+func TestFreezerRemote(t *testing.T) {
+	var (
+		dir       = t.TempDir()
+		store, _  = objstore.NewDirectory(t.TempDir())
+		tables    = map[string]freezerTableConfig{"a": {noSnappy: true, prunable: true}}
+		remote    = &RemoteAncients{Store: store, CacheSize: 250}
+		items     = make(map[uint64][]byte)
+		localFile = func(num uint32) string { return filepath.Join(dir, fmt.Sprintf("a.%04d.rdat", num)) }
+		objectKey = func(num uint32) string { return fmt.Sprintf("%s/a.%04d.rdat", filepath.Base(dir), num) }
+	)
+	// Each data file holds 10 items, the head file being the 10th one
+	f, err := newFreezer(dir, "", false, 100, tables, remote)
+	require.NoError(t, err)
+
+	write := func(from, to uint64, salt int) {
+		t.Helper()
+		_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := from; i < to; i++ {
+				items[i] = getChunk(10, int(i)+salt)
+				if err := op.AppendRaw("a", i, items[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		f.remote.process()
+	}
+	check := func(f *Freezer, from, to uint64) {
+		t.Helper()
+
+		// Fetch the data files in chunks not aligned with the items
+		f.remote.cache.chunk = 32
+		for i := from; i < to; i++ {
+			blob, err := f.Ancient("a", i)
+			require.NoError(t, err)
+			if !bytes.Equal(blob, items[i]) {
+				t.Fatalf("item %d mismatch: have %x, want %x", i, blob, items[i])
+			}
+		}
+		if size := f.remote.cache.size; size > remote.CacheSize {
+			t.Fatalf("cache exceeds limit: have %d, want <= %d", size, remote.CacheSize)
+		}
+	}
+	checkFiles := func(offloaded []uint32, local []uint32, dropped []uint32) {
+		t.Helper()
+		for _, num := range offloaded {
+			if isLocal(localFile(num)) {
+				t.Fatalf("data file %d not offloaded", num)
+			}
+			if _, err := store.Size(objectKey(num)); err != nil {
+				t.Fatalf("data file %d missing from the store: %v", num, err)
+			}
+		}
+		for _, num := range local {
+			if !isLocal(localFile(num)) {
+				t.Fatalf("data file %d missing locally", num)
+			}
+		}
+		for _, num := range dropped {
+			if isLocal(localFile(num)) {
+				t.Fatalf("dropped data file %d exists locally", num)
+			}
+			if _, err := store.Size(objectKey(num)); !errors.Is(err, objstore.ErrNotFound) {
+				t.Fatalf("dropped data file %d exists in the store", num)
+			}
+		}
+	}
+	write(0, 100, 0)
+	checkFiles([]uint32{0, 1, 2, 3, 4, 5, 6, 7, 8}, []uint32{9}, nil)
+	check(f, 0, 100)
+
+	// Truncating the head into an offloaded file should restore it
+	require.NoError(t, f.TruncateHead(25))
+	checkFiles([]uint32{0, 1}, []uint32{2}, []uint32{3, 4, 5, 6, 7, 8, 9})
+	check(f, 0, 25)
+
+	write(25, 60, 100)
+	checkFiles([]uint32{0, 1, 2, 3, 4}, []uint32{5}, nil)
+	check(f, 0, 60)
+
+	// Truncating the tail should delete the offloaded files
+	require.NoError(t, f.TruncateTail(30))
+	checkFiles([]uint32{3, 4}, []uint32{5}, []uint32{0, 1, 2})
+	check(f, 30, 60)
+	require.NoError(t, f.Close())
+
+	// The offloaded files should be found after a restart, also read-only
+	f, err = newFreezer(dir, "", false, 100, tables, remote)
+	require.NoError(t, err)
+	check(f, 30, 60)
+	require.NoError(t, f.Close())
+
+	f, err = newFreezer(dir, "", true, 100, tables, remote)
+	require.NoError(t, err)
+	check(f, 30, 60)
+	require.NoError(t, f.Close())
+}
+
+// blockingStore is an object store whose ranged reads block until released.
+type blockingStore struct {
+	objstore.Store
+	fetching chan struct{}
+	release  chan struct{}
+}
+
+func (s *blockingStore) GetRange(key string, offset, length int64) (io.ReadCloser, error) {
+	select {
+	case s.fetching <- struct{}{}:
+	default:
+	}
+	<-s.release
+	return s.Store.GetRange(key, offset, length)
+}
+
+// Tests that reading an offloaded item doesn't hold the table lock while the
+// data is fetched from the remote store.
+func TestFreezerRemoteReadUnlocked(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		local, _ = objstore.NewDirectory(t.TempDir())
+		store    = &blockingStore{Store: local, fetching: make(chan struct{}, 1), release: make(chan struct{})}
+		tables   = map[string]freezerTableConfig{"a": {noSnappy: true, prunable: true}}
+	)
+	f, err := newFreezer(dir, "", false, 100, tables, &RemoteAncients{Store: store, CacheSize: 1000})
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 20; i++ {
+			if err := op.AppendRaw("a", i, getChunk(10, int(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	f.remote.process()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := f.Ancient("a", 0)
+		done <- err
+	}()
+	<-store.fetching
+
+	// Writers must be able to take the table lock during the fetch
+	locked := make(chan struct{})
+	go func() {
+		table := f.tables["a"]
+		table.lock.Lock()
+		table.lock.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("table lock held during remote fetch")
+	}
+	close(store.release)
+	require.NoError(t, <-done)
+}
+
+// Tests that items read from an offloaded data file are re-resolved if the head
+// is truncated into the file during the remote fetch.
+func TestFreezerRemoteReadTruncated(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		local, _ = objstore.NewDirectory(t.TempDir())
+		store    = &blockingStore{Store: local, fetching: make(chan struct{}, 1), release: make(chan struct{})}
+		tables   = map[string]freezerTableConfig{"a": {noSnappy: true, prunable: true}}
+	)
+	f, err := newFreezer(dir, "", false, 100, tables, &RemoteAncients{Store: store, CacheSize: 1000})
+	require.NoError(t, err)
+	defer f.Close()
+
+	write := func(from, to uint64, salt int) {
+		t.Helper()
+		_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := from; i < to; i++ {
+				if err := op.AppendRaw("a", i, getChunk(10, int(i)+salt)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	write(0, 20, 0)
+	f.remote.process()
+
+	type result struct {
+		blob []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		blob, err := f.Ancient("a", 5)
+		done <- result{blob, err}
+	}()
+	<-store.fetching
+
+	// Truncate into the offloaded file and overwrite the item being fetched
+	require.NoError(t, f.TruncateHead(3))
+	write(3, 20, 100)
+
+	close(store.release)
+	res := <-done
+	require.NoError(t, res.err)
+	if want := getChunk(10, 105); !bytes.Equal(res.blob, want) {
+		t.Fatalf("item mismatch: have %x, want %x", res.blob, want)
+	}
+}
+
+// shortStore is an object store which silently drops the last byte of uploads.
+type shortStore struct {
+	objstore.Store
+}
+
+func (s *shortStore) Put(key string, r io.Reader, size int64) error {
+	return s.Store.Put(key, io.LimitReader(r, size-1), size-1)
+}
+
+// Tests that data files are kept locally if their upload is incomplete.
+func TestFreezerRemoteVerifyUpload(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		local, _ = objstore.NewDirectory(t.TempDir())
+		tables   = map[string]freezerTableConfig{"a": {noSnappy: true, prunable: true}}
+	)
+	f, err := newFreezer(dir, "", false, 100, tables, &RemoteAncients{Store: &shortStore{local}, CacheSize: 1000})
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 20; i++ {
+			if err := op.AppendRaw("a", i, getChunk(10, int(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	f.remote.process()
+
+	if !isLocal(filepath.Join(dir, "a.0000.rdat")) {
+		t.Fatal("data file deleted after incomplete upload")
+	}
+	if _, err := local.Size(fmt.Sprintf("%s/a.0000.rdat", filepath.Base(dir))); !errors.Is(err, objstore.ErrNotFound) {
+		t.Fatalf("incomplete upload not deleted: %v", err)
+	}
+	for i := uint64(0); i < 20; i++ {
+		blob, err := f.Ancient("a", i)
+		require.NoError(t, err)
+		if !bytes.Equal(blob, getChunk(10, int(i))) {
+			t.Fatalf("item %d mismatch", i)
+		}
+	}
+}
+
+// Tests that the remote cache evicts the least recently used files which are
+// not in use, and removes invalidated files once released.
+func TestRemoteCache(t *testing.T) {
+	store, _ := objstore.NewDirectory(t.TempDir())
+	for i := 0; i < 4; i++ {
+		require.NoError(t, store.Put(fmt.Sprintf("f%d", i), bytes.NewReader(getChunk(10, i)), 10))
+	}
+	cache, err := newRemoteCache(store, t.TempDir(), 20, 10, make(chan struct{}))
+	require.NoError(t, err)
+
+	acquire := func(key string) *cachedFile {
+		t.Helper()
+		f, err := cache.acquire(key, 0)
+		require.NoError(t, err)
+		return f
+	}
+	cached := func(keys ...string) {
+		t.Helper()
+		if len(cache.files) != len(keys) {
+			t.Fatalf("cached file count mismatch: have %d, want %d", len(cache.files), len(keys))
+		}
+		for _, key := range keys {
+			if _, ok := cache.files[chunkID{key: key}]; !ok {
+				t.Fatalf("file %s not cached", key)
+			}
+		}
+	}
+	// Fill the cache and use the oldest file again
+	cache.release(acquire("f0"))
+	cache.release(acquire("f1"))
+	cache.release(acquire("f0"))
+	cached("f0", "f1")
+
+	// Overflowing should evict the least recently used one
+	cache.release(acquire("f2"))
+	cached("f0", "f2")
+
+	// Files in use must not be evicted, even if the cache overflows
+	f3 := acquire("f3")
+	f0 := acquire("f0")
+	cached("f0", "f2", "f3")
+	cache.release(acquire("f1"))
+	cached("f0", "f3")
+
+	// Invalidated files must stay readable until released
+	cache.invalidate("f3")
+	cached("f0")
+	blob := make([]byte, 10)
+	if _, err := f3.file.ReadAt(blob, 0); err != nil {
+		t.Fatalf("invalidated file unreadable: %v", err)
+	}
+	name := f3.file.Name()
+	cache.release(f3)
+	if isLocal(name) {
+		t.Fatal("invalidated file not removed")
+	}
+	cache.release(f0)
+	cached("f0")
+
+	if _, err := cache.acquire("missing", 0); !errors.Is(err, objstore.ErrNotFound) {
+		t.Fatalf("missing object error mismatch: have %v, want %v", err, objstore.ErrNotFound)
+	}
+	cached("f0")
+	require.NoError(t, cache.close())
+}
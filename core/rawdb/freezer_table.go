@@ -111,6 +111,9 @@ type freezerTable struct {
 	headId uint32              // number of the currently active head file
 	tailId uint32              // number of the earliest file
 
+	remote    *freezerRemote // Object store the sealed data files are offloaded to (nil = local only)
+	remoteGen uint64         // Counter of head truncations, invalidating running offloads
+
 	headBytes  int64         // Number of bytes written to the head file
 	readMeter  metrics.Meter // Meter for measuring the effective amount of data read
 	writeMeter metrics.Meter // Meter for measuring the effective amount of data written
@@ -129,6 +132,12 @@ func newFreezerTable(path, name string, disableSnappy, readonly bool) (*freezerT
 // non-existent. Both files are truncated to the shortest common length to ensure
 // they don't go out of sync.
 func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool) (*freezerTable, error) {
+	return newRemoteTable(path, name, readMeter, writeMeter, sizeGauge, maxFilesize, noCompression, readonly, nil)
+}
+
+// newRemoteTable opens a freezer table like newTable, offloading its sealed data
+// files into the given remote store if it's non-nil.
+func newRemoteTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool, remote *freezerRemote) (*freezerTable, error) {
 	// Ensure the containing directory exists and open the indexEntry file
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
@@ -178,6 +187,7 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 		noCompression: noCompression,
 		readonly:      readonly,
 		maxFileSize:   maxFilesize,
+		remote:        remote,
 	}
 	if err := tab.repair(); err != nil {
 		tab.Close()
@@ -358,11 +368,19 @@ func (t *freezerTable) preopen() (err error) {
 	// The repair might have already opened (some) files
 	t.releaseFilesAfter(0, false)
 
-	// Open all except head in RDONLY
+	// Open all except head in RDONLY. Data files which were offloaded into the
+	// remote store are fetched on demand, the local ones are (re)scheduled for
+	// offloading.
 	for i := t.tailId; i < t.headId; i++ {
+		if t.remote != nil && !isLocal(filepath.Join(t.path, t.fileName(i))) {
+			continue
+		}
 		if _, err = t.openFile(i, openFreezerFileForReadOnly); err != nil {
 			return err
 		}
+		if t.remote != nil && !t.readonly {
+			t.remote.schedule(t, i)
+		}
 	}
 	if t.readonly {
 		t.head, err = t.openFile(t.headId, openFreezerFileForReadOnly)
@@ -417,7 +435,9 @@ func (t *freezerTable) truncateHead(items uint64) error {
 	}
 	// We might need to truncate back to older files
 	if expected.filenum != t.headId {
-		// If already open for reading, force-reopen for writing
+		// If already open for reading, force-reopen for writing. Offloaded
+		// data files are restored from the remote store first.
+		t.remoteGen++
 		t.releaseFile(expected.filenum)
 		newHead, err := t.openFile(expected.filenum, openFreezerFileForAppend)
 		if err != nil {
@@ -426,6 +446,9 @@ func (t *freezerTable) truncateHead(items uint64) error {
 		// Release any files _after the current head -- both the previous head
 		// and any files which may have been opened for reading
 		t.releaseFilesAfter(expected.filenum, true)
+		if t.remote != nil {
+			t.remote.drop(t, expected.filenum, t.headId)
+		}
 		// Set back the historic head
 		t.head = newHead
 		t.headId = expected.filenum
@@ -537,9 +560,13 @@ func (t *freezerTable) truncateTail(items uint64) error {
 		return err
 	}
 	// Release any files before the current tail
+	oldTailId := t.tailId
 	t.tailId = newTailId
 	t.itemOffset.Store(newDeleted)
 	t.releaseFilesBefore(t.tailId, true)
+	if t.remote != nil {
+		t.remote.drop(t, oldTailId, t.tailId)
+	}
 
 	// Retrieve the new size and update the total size counter
 	newSize, err := t.sizeNolock()
@@ -589,17 +616,26 @@ func (t *freezerTable) Close() error {
 	return nil
 }
 
-// openFile assumes that the write-lock is held by the caller
+// fileName returns the name of the data file with the given number.
+func (t *freezerTable) fileName(num uint32) string {
+	if t.noCompression {
+		return fmt.Sprintf("%s.%04d.rdat", t.name, num)
+	}
+	return fmt.Sprintf("%s.%04d.cdat", t.name, num)
+}
+
+// openFile assumes that the write-lock is held by the caller. If the data file
+// was offloaded into the remote store, it's restored to the local disk first.
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
-		var name string
-		if t.noCompression {
-			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
-			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
+		path := filepath.Join(t.path, t.fileName(num))
+		if t.remote != nil && !t.readonly && !isLocal(path) {
+			if err := t.remote.restore(t, num, path); err != nil {
+				return nil, err
+			}
 		}
-		f, err = opener(filepath.Join(t.path, name))
+		f, err = opener(path)
 		if err != nil {
 			return nil, err
 		}
@@ -729,18 +765,68 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 	return output, nil
 }
 
+// remoteRead is a deferred read of an offloaded data file into the output buffer
+// of an item retrieval.
+type remoteRead struct {
+	fileId uint32 // Data file to read from
+	start  uint32 // Offset in the data file to start reading at
+	pos    int    // Offset in the output buffer to read into
+	length int    // Number of bytes to read
+}
+
 // retrieveItems reads up to 'count' items from the table. It reads at least
 // one item, but otherwise avoids reading more than maxBytes bytes. Freezer
 // will ignore the size limitation and continuously allocate memory to store
 // data if maxBytes is 0. It returns the (potentially compressed) data, and
 // the sizes.
 func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []int, error) {
+	for {
+		output, sizes, reads, gen, err := t.retrieveLocalItems(start, count, maxBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(reads) == 0 {
+			return output, sizes, nil
+		}
+		// Offloaded data files are read without holding the table lock, as they
+		// may have to be fetched from the remote store first.
+		for _, read := range reads {
+			if err = t.remote.readAt(t, read.fileId, output[read.pos:read.pos+read.length], int64(read.start)); err != nil {
+				break
+			}
+		}
+		// A head truncation meanwhile may have restored the data files or
+		// deleted them from the remote store, making the resolved positions
+		// stale, so retry. A tail truncation may have deleted them too, but
+		// then the items are out of bounds anyway.
+		t.lock.RLock()
+		stale := t.remoteGen != gen
+		t.lock.RUnlock()
+
+		if stale {
+			continue
+		}
+		if t.itemHidden.Load() > start {
+			return nil, nil, errOutOfBounds
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return output, sizes, nil
+	}
+}
+
+// retrieveLocalItems reads up to 'count' items from the table like retrieveItems,
+// but only from the data files stored locally. The reads of offloaded files are
+// returned to be done once the table lock is released, along with the number of
+// head truncations they are valid for.
+func (t *freezerTable) retrieveLocalItems(start, count, maxBytes uint64) ([]byte, []int, []remoteRead, uint64, error) {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 
 	// Ensure the table and the item are accessible
 	if t.index == nil || t.head == nil || t.meta == nil {
-		return nil, nil, errClosed
+		return nil, nil, nil, 0, errClosed
 	}
 	var (
 		items  = t.items.Load()      // the total items(head + 1)
@@ -749,7 +835,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 	// Ensure the start is written, not deleted from the tail, and that the
 	// caller actually wants something
 	if items <= start || hidden > start || count == 0 {
-		return nil, nil, errOutOfBounds
+		return nil, nil, nil, 0, errOutOfBounds
 	}
 	if start+count > items {
 		count = items - start
@@ -761,10 +847,15 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 		output = make([]byte, 0, 1024) // initial buffer cap
 	}
 	// readData is a helper method to read a single data item from disk.
+	var reads []remoteRead
 	readData := func(fileId, start uint32, length int) error {
 		output = grow(output, length)
 		dataFile, exist := t.files[fileId]
 		if !exist {
+			if t.remote != nil {
+				reads = append(reads, remoteRead{fileId: fileId, start: start, pos: len(output) - length, length: length})
+				return nil
+			}
 			return fmt.Errorf("missing data file %d", fileId)
 		}
 		if _, err := dataFile.ReadAt(output[len(output)-length:], int64(start)); err != nil {
@@ -775,7 +866,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 	// Read all the indexes in one go
 	indices, err := t.getIndices(start, count)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, 0, err
 	}
 	var (
 		sizes      []int               // The sizes for each element
@@ -794,7 +885,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 			// If we have unread data in the first file, we need to do that read now.
 			if unreadSize > 0 {
 				if err := readData(firstIndex.filenum, readStart, unreadSize); err != nil {
-					return nil, nil, err
+					return nil, nil, nil, 0, err
 				}
 				unreadSize = 0
 			}
@@ -805,7 +896,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 			// read this last item, but we need to do the deferred reads now.
 			if unreadSize > 0 {
 				if err := readData(secondIndex.filenum, readStart, unreadSize); err != nil {
-					return nil, nil, err
+					return nil, nil, nil, 0, err
 				}
 			}
 			break
@@ -817,7 +908,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 		if i == len(indices)-2 || (uint64(totalSize) > maxBytes && maxBytes != 0) {
 			// Last item, need to do the read now
 			if err := readData(secondIndex.filenum, readStart, unreadSize); err != nil {
-				return nil, nil, err
+				return nil, nil, nil, 0, err
 			}
 			break
 		}
@@ -825,7 +916,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 
 	// Update metrics.
 	t.readMeter.Mark(int64(totalSize))
-	return output, sizes, nil
+	return output, sizes, reads, t.remoteGen, nil
 }
 
 // has returns an indicator whether the specified number data is still accessible
@@ -874,6 +965,9 @@ func (t *freezerTable) advanceHead() error {
 	}
 	t.releaseFile(t.headId)
 	t.openFile(t.headId, openFreezerFileForReadOnly)
+	if t.remote != nil {
+		t.remote.schedule(t, t.headId)
+	}
 
 	// Swap out the current head.
 	t.head = newHead
@@ -882,6 +976,73 @@ func (t *freezerTable) advanceHead() error {
 	return nil
 }
 
+// checkpoint copies the table as of its current head into the given directory.
+// The caller must ensure that the table is not modified meanwhile. Offloaded
+// data files are fetched from the remote store, making the copy self-contained.
+func (t *freezerTable) checkpoint(dir string) error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil || t.head == nil || t.meta == nil {
+		return errClosed
+	}
+	// Copy the metadata and the index entries up to the current head
+	stat, err := t.meta.Stat()
+	if err != nil {
+		return err
+	}
+	if err := copyPrefix(io.NewSectionReader(t.meta, 0, stat.Size()), filepath.Join(dir, filepath.Base(t.meta.Name())), stat.Size()); err != nil {
+		return err
+	}
+	entries := int64(t.items.Load()-t.itemOffset.Load()) + 1
+	if err := copyPrefix(io.NewSectionReader(t.index, 0, entries*indexEntrySize), filepath.Join(dir, filepath.Base(t.index.Name())), entries*indexEntrySize); err != nil {
+		return err
+	}
+	// Copy the data files up to the end of the last item. The first index
+	// entry is the tail marker, it doesn't reference any data.
+	last := indexEntry{filenum: t.tailId}
+	if entries > 1 {
+		buffer := make([]byte, indexEntrySize)
+		if _, err := t.index.ReadAt(buffer, (entries-1)*indexEntrySize); err != nil {
+			return err
+		}
+		last.unmarshalBinary(buffer)
+	}
+	for num := t.tailId; num <= last.filenum; num++ {
+		size := int64(-1)
+		if num == last.filenum {
+			size = int64(last.offset)
+		}
+		path := filepath.Join(dir, t.fileName(num))
+		if f, exist := t.files[num]; exist {
+			if size < 0 {
+				stat, err := f.Stat()
+				if err != nil {
+					return err
+				}
+				size = stat.Size()
+			}
+			if err := copyPrefix(io.NewSectionReader(f, 0, size), path, size); err != nil {
+				return err
+			}
+			continue
+		}
+		if t.remote == nil {
+			return fmt.Errorf("missing data file %d", num)
+		}
+		src, err := t.remote.store.Get(t.remote.key(t, num))
+		if err != nil {
+			return err
+		}
+		err = copyPrefix(src, path, size)
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Sync pushes any pending data from memory out to disk. This is an expensive
 // operation, so use it with care.
 func (t *freezerTable) Sync() error {
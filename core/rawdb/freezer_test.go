@@ -32,7 +32,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-var freezerTestTableDef = map[string]bool{"test": true}
+var freezerTestTableDef = map[string]freezerTableConfig{"test": {noSnappy: true, prunable: true}}
 
 func TestFreezerModify(t *testing.T) {
 	t.Parallel()
@@ -48,7 +48,7 @@ func TestFreezerModify(t *testing.T) {
 		valuesRLP = append(valuesRLP, iv)
 	}
 
-	tables := map[string]bool{"raw": true, "rlp": false}
+	tables := map[string]freezerTableConfig{"raw": {noSnappy: true, prunable: true}, "rlp": {noSnappy: false, prunable: true}}
 	f, _ := newFreezerForTesting(t, tables)
 	defer f.Close()
 
@@ -112,7 +112,7 @@ func TestFreezerModifyRollback(t *testing.T) {
 	f.Close()
 
 	// Reopen and check that the rolled-back data doesn't reappear.
-	tables := map[string]bool{"test": true}
+	tables := map[string]freezerTableConfig{"test": {noSnappy: true, prunable: true}}
 	f2, err := NewFreezer(dir, "", false, 2049, tables)
 	if err != nil {
 		t.Fatalf("can't reopen freezer after failed ModifyAncients: %v", err)
@@ -250,7 +250,7 @@ func TestFreezerConcurrentModifyTruncate(t *testing.T) {
 }
 
 func TestFreezerReadonlyValidate(t *testing.T) {
-	tables := map[string]bool{"a": true, "b": true}
+	tables := map[string]freezerTableConfig{"a": {noSnappy: true, prunable: true}, "b": {noSnappy: true, prunable: true}}
 	dir := t.TempDir()
 	// Open non-readonly freezer and fill individual tables
 	// with different amount of data.
@@ -283,7 +283,57 @@ func TestFreezerReadonlyValidate(t *testing.T) {
 	}
 }
 
-func newFreezerForTesting(t *testing.T, tables map[string]bool) (*Freezer, string) {
+func TestFreezerTruncateTailPrunable(t *testing.T) {
+	tables := map[string]freezerTableConfig{"a": {noSnappy: true, prunable: true}, "b": {noSnappy: true, prunable: false}}
+	f, dir := newFreezerForTesting(t, tables)
+
+	_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 10; i++ {
+			if err := op.AppendRaw("a", i, []byte{byte(i)}); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("b", i, []byte{byte(i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, f.TruncateTail(5))
+
+	// Only the prunable table should be truncated.
+	check := func(f *Freezer) {
+		t.Helper()
+		if tail, _ := f.Tail(); tail != 5 {
+			t.Fatalf("tail mismatch: have %d, want 5", tail)
+		}
+		for i := uint64(0); i < 10; i++ {
+			if ok, _ := f.HasAncient("a", i); ok != (i >= 5) {
+				t.Fatalf("prunable item %d presence mismatch: have %v, want %v", i, ok, i >= 5)
+			}
+			if ok, _ := f.HasAncient("b", i); !ok {
+				t.Fatalf("non-prunable item %d missing", i)
+			}
+		}
+		checkAncientCount(t, f, "a", 10)
+		checkAncientCount(t, f, "b", 10)
+	}
+	check(f)
+	require.NoError(t, f.Close())
+
+	// The boundaries should survive a restart, both repaired and validated.
+	f, err = NewFreezer(dir, "", false, 2049, tables)
+	require.NoError(t, err)
+	check(f)
+	require.NoError(t, f.Close())
+
+	f, err = NewFreezer(dir, "", true, 2049, tables)
+	require.NoError(t, err)
+	check(f)
+	require.NoError(t, f.Close())
+}
+
+func newFreezerForTesting(t *testing.T, tables map[string]freezerTableConfig) (*Freezer, string) {
 	t.Helper()
 
 	dir := t.TempDir()
@@ -410,7 +460,7 @@ func TestRenameWindows(t *testing.T) {
 
 func TestFreezerCloseSync(t *testing.T) {
 	t.Parallel()
-	f, _ := newFreezerForTesting(t, map[string]bool{"a": true, "b": true})
+	f, _ := newFreezerForTesting(t, map[string]freezerTableConfig{"a": {noSnappy: true, prunable: true}, "b": {noSnappy: true, prunable: true}})
 	defer f.Close()
 
 	// Now, close and sync. This mimics the behaviour if the node is shut down,
@@ -22,6 +22,27 @@ import (
 	"path/filepath"
 )
 
+// copyPrefix writes the first 'size' bytes of the source into a new file at
+// 'destPath', or all of it if size is negative.
+func copyPrefix(src io.Reader, destPath string, size int64) error {
+	f, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		_, err = io.Copy(f, src)
+	} else {
+		_, err = io.CopyN(f, src, size)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
 // copyFrom copies data from 'srcPath' at offset 'offset' into 'destPath'.
 // The 'destPath' is created if it doesn't exist, otherwise it is overwritten.
 // Before the copy is executed, there is a callback can be registered to
@@ -0,0 +1,216 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// migrationProgress is the marker stored in the destination store of a
+// key-value store migration, allowing an interrupted migration to resume.
+type migrationProgress struct {
+	Last  []byte // Last key copied into the destination store
+	Count uint64 // Number of entries copied so far
+	Done  bool   // Whether all entries have been copied
+}
+
+// readMigrationProgress retrieves the migration progress marker of the given
+// destination store, or an empty one if no migration was started yet.
+func readMigrationProgress(db ethdb.KeyValueReader) (*migrationProgress, error) {
+	blob, _ := db.Get(migrationProgressKey)
+	if len(blob) == 0 {
+		return new(migrationProgress), nil
+	}
+	progress := new(migrationProgress)
+	if err := rlp.DecodeBytes(blob, progress); err != nil {
+		return nil, fmt.Errorf("invalid migration progress: %v", err)
+	}
+	return progress, nil
+}
+
+// MigrateKeyValueStore copies all entries of the source key-value store into
+// the destination one in batches and verifies the number of entries and the
+// checksum of every key category afterwards.
+//
+// The progress is committed atomically with each batch, so an interrupted
+// migration resumes where it left off if invoked again with the same stores.
+func MigrateKeyValueStore(src ethdb.KeyValueStore, dst ethdb.KeyValueStore) error {
+	progress, err := readMigrationProgress(dst)
+	if err != nil {
+		return err
+	}
+	if !progress.Done {
+		if err := copyKeyValueStore(src, dst, progress); err != nil {
+			return err
+		}
+	}
+	log.Info("Verifying migrated key-value store", "count", progress.Count)
+	if err := verifyMigration(src, dst); err != nil {
+		return err
+	}
+	return dst.Delete(migrationProgressKey)
+}
+
+// copyKeyValueStore copies the entries of the source store after the last one
+// recorded in the progress marker into the destination store.
+func copyKeyValueStore(src ethdb.KeyValueStore, dst ethdb.KeyValueStore, progress *migrationProgress) error {
+	var start []byte
+	if progress.Last != nil {
+		start = append(common.CopyBytes(progress.Last), 0x00)
+		log.Info("Resuming key-value store migration", "count", progress.Count, "key", hexutil.Encode(progress.Last))
+	}
+	it := src.NewIterator(nil, start)
+	defer it.Release()
+
+	var (
+		batch = dst.NewBatch()
+		size  common.StorageSize
+
+		begin  = time.Now()
+		logged = time.Now()
+	)
+	commit := func() error {
+		blob, err := rlp.EncodeToBytes(progress)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(migrationProgressKey, blob); err != nil {
+			return err
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+		return nil
+	}
+	for it.Next() {
+		if err := batch.Put(it.Key(), it.Value()); err != nil {
+			return err
+		}
+		progress.Last = common.CopyBytes(it.Key())
+		progress.Count++
+		size += common.StorageSize(len(it.Key()) + len(it.Value()))
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := commit(); err != nil {
+				return err
+			}
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Migrating key-value store", "count", progress.Count, "size", size, "key", hexutil.Encode(progress.Last), "elapsed", common.PrettyDuration(time.Since(begin)))
+				logged = time.Now()
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	progress.Done = true
+	if err := commit(); err != nil {
+		return err
+	}
+	log.Info("Copied key-value store", "count", progress.Count, "size", size, "elapsed", common.PrettyDuration(time.Since(begin)))
+	return nil
+}
+
+// migrationDigest is the number of entries and the checksum of a key category.
+type migrationDigest struct {
+	count uint64
+	sum   common.Hash
+}
+
+// digestKeyValueStore iterates over a key-value store and computes the digest
+// of each key category, ignoring the migration progress marker.
+func digestKeyValueStore(db ethdb.KeyValueStore) ([numKeyCategories]migrationDigest, error) {
+	var (
+		digests [numKeyCategories]migrationDigest
+		hashers [numKeyCategories]crypto.KeccakState
+		length  [4]byte
+	)
+	for i := range hashers {
+		hashers[i] = crypto.NewKeccakState()
+	}
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if bytes.Equal(key, migrationProgressKey) {
+			continue
+		}
+		category := inspectKey(key)
+		digests[category].count++
+
+		binary.BigEndian.PutUint32(length[:], uint32(len(key)))
+		hashers[category].Write(length[:])
+		hashers[category].Write(key)
+		binary.BigEndian.PutUint32(length[:], uint32(len(it.Value())))
+		hashers[category].Write(length[:])
+		hashers[category].Write(it.Value())
+	}
+	if err := it.Error(); err != nil {
+		return digests, err
+	}
+	for i := range hashers {
+		hashers[i].Read(digests[i].sum[:])
+	}
+	return digests, nil
+}
+
+// verifyMigration compares the number of entries and the checksum of every key
+// category of the source and destination stores of a migration.
+func verifyMigration(src ethdb.KeyValueStore, dst ethdb.KeyValueStore) error {
+	type result struct {
+		digests [numKeyCategories]migrationDigest
+		err     error
+	}
+	results := make([]chan result, 2)
+	for i, db := range []ethdb.KeyValueStore{src, dst} {
+		results[i] = make(chan result, 1)
+		go func(db ethdb.KeyValueStore, res chan result) {
+			digests, err := digestKeyValueStore(db)
+			res <- result{digests, err}
+		}(db, results[i])
+	}
+	have, want := <-results[1], <-results[0]
+	if want.err != nil {
+		return want.err
+	}
+	if have.err != nil {
+		return have.err
+	}
+	var mismatches []string
+	for category := keyCategory(0); category < numKeyCategories; category++ {
+		if have.digests[category] != want.digests[category] {
+			mismatches = append(mismatches, fmt.Sprintf("%s (have %d entries, want %d)", keyCategoryNames[category][1], have.digests[category].count, want.digests[category].count))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("migrated key-value store mismatch: %s", strings.Join(mismatches, ", "))
+	}
+	return nil
+}
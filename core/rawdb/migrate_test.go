@@ -0,0 +1,175 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// fillMigrationSource populates a key-value store with entries of several key
+// categories.
+func fillMigrationSource(t *testing.T, db ethdb.KeyValueStore) {
+	t.Helper()
+
+	for i := uint64(0); i < 2000; i++ {
+		var seed [8]byte
+		binary.BigEndian.PutUint64(seed[:], i)
+		hash := crypto.Keccak256Hash(seed[:])
+
+		var key []byte
+		switch i % 4 {
+		case 0:
+			key = headerKey(i, hash)
+		case 1:
+			key = blockBodyKey(i, hash)
+		case 2:
+			key = codeKey(hash)
+		case 3:
+			key = hash.Bytes()
+		}
+		if err := db.Put(key, bytes.Repeat(seed[:], int(i%16)+1)); err != nil {
+			t.Fatalf("failed to write entry: %v", err)
+		}
+	}
+	if err := db.Put(headBlockKey, common.Hash{0x01}.Bytes()); err != nil {
+		t.Fatalf("failed to write entry: %v", err)
+	}
+}
+
+// checkMigrated ensures the destination store of a completed migration holds
+// exactly the entries of the source one.
+func checkMigrated(t *testing.T, src, dst ethdb.KeyValueStore) {
+	t.Helper()
+
+	if has, _ := dst.Has(migrationProgressKey); has {
+		t.Fatalf("migration progress not deleted")
+	}
+	var (
+		srcIt = src.NewIterator(nil, nil)
+		dstIt = dst.NewIterator(nil, nil)
+	)
+	defer srcIt.Release()
+	defer dstIt.Release()
+
+	for srcIt.Next() {
+		if !dstIt.Next() {
+			t.Fatalf("missing entry %x", srcIt.Key())
+		}
+		if !bytes.Equal(srcIt.Key(), dstIt.Key()) || !bytes.Equal(srcIt.Value(), dstIt.Value()) {
+			t.Fatalf("entry mismatch: have %x, want %x", dstIt.Key(), srcIt.Key())
+		}
+	}
+	if dstIt.Next() {
+		t.Fatalf("unexpected entry %x", dstIt.Key())
+	}
+}
+
+func TestMigrateKeyValueStore(t *testing.T) {
+	src, dst := NewMemoryDatabase(), NewMemoryDatabase()
+	fillMigrationSource(t, src)
+
+	if err := MigrateKeyValueStore(src, dst); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	checkMigrated(t, src, dst)
+}
+
+func TestMigrateKeyValueStoreResume(t *testing.T) {
+	src, dst := NewMemoryDatabase(), NewMemoryDatabase()
+	fillMigrationSource(t, src)
+
+	// Simulate an interrupted migration which copied part of the entries
+	var (
+		it       = src.NewIterator(nil, nil)
+		progress = new(migrationProgress)
+	)
+	for progress.Count < 1000 && it.Next() {
+		dst.Put(it.Key(), it.Value())
+		progress.Last = common.CopyBytes(it.Key())
+		progress.Count++
+	}
+	it.Release()
+	blob, _ := rlp.EncodeToBytes(progress)
+	dst.Put(migrationProgressKey, blob)
+
+	if err := MigrateKeyValueStore(src, dst); err != nil {
+		t.Fatalf("failed to resume migration: %v", err)
+	}
+	checkMigrated(t, src, dst)
+}
+
+func TestMigrateKeyValueStoreMismatch(t *testing.T) {
+	src, dst := NewMemoryDatabase(), NewMemoryDatabase()
+	fillMigrationSource(t, src)
+
+	// Simulate a completed migration which lost a contract code
+	var (
+		it       = src.NewIterator(nil, nil)
+		progress = &migrationProgress{Done: true}
+		dropped  bool
+	)
+	for it.Next() {
+		if !dropped && inspectKey(it.Key()) == categoryCodes {
+			dropped = true
+			continue
+		}
+		dst.Put(it.Key(), it.Value())
+	}
+	it.Release()
+	blob, _ := rlp.EncodeToBytes(progress)
+	dst.Put(migrationProgressKey, blob)
+
+	err := MigrateKeyValueStore(src, dst)
+	if err == nil || !strings.Contains(err.Error(), keyCategoryNames[categoryCodes][1]) {
+		t.Fatalf("mismatch not detected: %v", err)
+	}
+	if has, _ := dst.Has(migrationProgressKey); !has {
+		t.Fatalf("migration progress deleted despite mismatch")
+	}
+}
+
+func TestMigrateLevelDBToPebble(t *testing.T) {
+	if !PebbleEnabled {
+		t.Skip("pebble not supported on this platform")
+	}
+	dir := t.TempDir()
+	src, err := NewLevelDBDatabase(filepath.Join(dir, "leveldb"), 16, 16, "", false)
+	if err != nil {
+		t.Fatalf("failed to open leveldb: %v", err)
+	}
+	defer src.Close()
+	dst, err := NewPebbleDBDatabase(filepath.Join(dir, "pebble"), 16, 16, "", false)
+	if err != nil {
+		t.Fatalf("failed to open pebble: %v", err)
+	}
+	defer dst.Close()
+
+	fillMigrationSource(t, src)
+	if err := MigrateKeyValueStore(src, dst); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	checkMigrated(t, src, dst)
+}
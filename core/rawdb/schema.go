@@ -58,6 +58,12 @@ var (
 	// snapshotJournalKey tracks the in-memory diff layers across restarts.
 	snapshotJournalKey = []byte("SnapshotJournal")
 
+	// persistentStateIDKey tracks the id of the latest stored state (for path-based only).
+	persistentStateIDKey = []byte("LastStateID")
+
+	// trieJournalKey tracks the in-memory trie node layers across restarts.
+	trieJournalKey = []byte("TrieJournal")
+
 	// snapshotGeneratorKey tracks the snapshot generation marker across restarts.
 	snapshotGeneratorKey = []byte("SnapshotGenerator")
 
@@ -76,6 +82,17 @@ var (
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	fastTxLookupLimitKey = []byte("FastTransactionLookupLimit")
 
+	// migrationProgressKey tracks the progress of a key-value store migration
+	// in the destination store.
+	migrationProgressKey = []byte("MigrationProgress")
+
+	// databaseStatsKey tracks the progress of the incremental database inspection.
+	databaseStatsKey = []byte("DatabaseStats")
+
+	// databaseStatsHistoryKey tracks the database statistics recorded by every
+	// completed incremental inspection pass.
+	databaseStatsHistoryKey = []byte("DatabaseStatsHistory")
+
 	// badBlockKey tracks the list of bad blocks seen by local
 	badBlockKey = []byte("InvalidBlock")
 
@@ -100,10 +117,17 @@ var (
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
 	skeletonHeaderPrefix  = []byte("S") // skeletonHeaderPrefix + num (uint64 big endian) -> header
+	addressIndexPrefix    = []byte("x") // addressIndexPrefix + address + num (uint64 big endian) + tx index (uint32 big endian) -> roles
+	addressJournalPrefix  = []byte("X") // addressJournalPrefix + section (uint64 big endian) -> addresses indexed in the section
+	verkleWitnessPrefix   = []byte("W") // verkleWitnessPrefix + num (uint64 big endian) + hash -> verkle state witness
 
 	// Path-based storage scheme of merkle patricia trie.
 	trieNodeAccountPrefix = []byte("A") // trieNodeAccountPrefix + hexPath -> trie node
 	trieNodeStoragePrefix = []byte("O") // trieNodeStoragePrefix + accountHash + hexPath -> trie node
+	stateIDPrefix         = []byte("L") // stateIDPrefix + state root -> state id
+
+	// Reverse trie node diffs of the path-based scheme, for rolling back the state.
+	trieHistoryPrefix = []byte("trie-history-") // trieHistoryPrefix + state id (uint64 big endian) -> trie node history
 
 	PreimagePrefix = []byte("secure-key-")       // PreimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-")  // config prefix for the db
@@ -112,6 +136,9 @@ var (
 	// BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
 	BloomBitsIndexPrefix = []byte("iB")
 
+	// AddressIndexPrefix is the data table of a chain indexer to track its progress
+	AddressIndexPrefix = []byte("iA")
+
 	ChtPrefix           = []byte("chtRootV2-") // ChtPrefix + chtNum (uint64 big endian) -> trie root hash
 	ChtTablePrefix      = []byte("cht-")
 	ChtIndexTablePrefix = []byte("chtIndexV2-")
@@ -177,6 +204,11 @@ func blockReceiptsKey(number uint64, hash common.Hash) []byte {
 	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+// verkleWitnessKey = verkleWitnessPrefix + num (uint64 big endian) + hash
+func verkleWitnessKey(number uint64, hash common.Hash) []byte {
+	return append(append(verkleWitnessPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // txLookupKey = txLookupPrefix + hash
 func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
@@ -207,6 +239,21 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// addressIndexKey = addressIndexPrefix + address + num (uint64 big endian) + tx index (uint32 big endian)
+func addressIndexKey(address common.Address, number uint64, index uint32) []byte {
+	key := append(append(addressIndexPrefix, address.Bytes()...), make([]byte, 12)...)
+
+	binary.BigEndian.PutUint64(key[len(addressIndexPrefix)+common.AddressLength:], number)
+	binary.BigEndian.PutUint32(key[len(addressIndexPrefix)+common.AddressLength+8:], index)
+
+	return key
+}
+
+// addressJournalKey = addressJournalPrefix + section (uint64 big endian)
+func addressJournalKey(section uint64) []byte {
+	return append(addressJournalPrefix, encodeBlockNumber(section)...)
+}
+
 // skeletonHeaderKey = skeletonHeaderPrefix + num (uint64 big endian)
 func skeletonHeaderKey(number uint64) []byte {
 	return append(skeletonHeaderPrefix, encodeBlockNumber(number)...)
@@ -251,6 +298,16 @@ func storageTrieNodeKey(accountHash common.Hash, path []byte) []byte {
 	return append(append(trieNodeStoragePrefix, accountHash.Bytes()...), path...)
 }
 
+// stateIDKey = stateIDPrefix + root (32 bytes)
+func stateIDKey(root common.Hash) []byte {
+	return append(stateIDPrefix, root.Bytes()...)
+}
+
+// trieHistoryKey = trieHistoryPrefix + id (uint64 big endian)
+func trieHistoryKey(id uint64) []byte {
+	return append(trieHistoryPrefix, encodeBlockNumber(id)...)
+}
+
 // IsLegacyTrieNode reports whether a provided database entry is a legacy trie
 // node. The characteristics of legacy trie node are:
 // - the key length is 32 bytes
@@ -81,6 +81,11 @@ type Pruner struct {
 
 // NewPruner creates the pruner instance.
 func NewPruner(db ethdb.Database, config Config) (*Pruner, error) {
+	// The path-based scheme keeps a single persistent state which is pruned
+	// in place, there's nothing to prune offline.
+	if rawdb.ReadStateScheme(db) == rawdb.PathScheme {
+		return nil, errors.New("offline pruning is not needed for the path-based state scheme")
+	}
 	headBlock := rawdb.ReadHeadBlock(db)
 	if headBlock == nil {
 		return nil, errors.New("failed to load head block")
@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 	"github.com/ethereum/go-ethereum/trie/trienode"
 )
 
@@ -142,7 +143,7 @@ func (s *stateObject) getTrie(db Database) (Trie, error) {
 			s.trie = s.db.prefetcher.trie(s.addrHash, s.data.Root)
 		}
 		if s.trie == nil {
-			tr, err := db.OpenStorageTrie(s.db.originalRoot, s.address, s.data.Root)
+			tr, err := db.OpenStorageTrie(s.db.originalRoot, s.address, s.data.Root, s.db.trie)
 			if err != nil {
 				return nil, err
 			}
@@ -285,8 +286,14 @@ func (s *stateObject) updateTrie(db Database) (Trie, error) {
 		s.db.setError(err)
 		return nil, err
 	}
-	// Insert all the pending updates into the trie
-	usedStorage := make([][]byte, 0, len(s.pendingStorage))
+	// Insert all the pending updates into the trie. Deletions are applied after
+	// all the insertions, otherwise a deletion collapsing a node which a later
+	// insertion expands again would resolve the sibling nodes needlessly, making
+	// the set of nodes accessed depend on the map iteration order.
+	var (
+		usedStorage = make([][]byte, 0, len(s.pendingStorage))
+		deletions   []common.Hash
+	)
 	for key, value := range s.pendingStorage {
 		// Skip noop changes, persist actual changes
 		if value == s.originStorage[key] {
@@ -298,11 +305,7 @@ func (s *stateObject) updateTrie(db Database) (Trie, error) {
 		// rlp-encoded value to be used by the snapshot
 		var snapshotVal []byte
 		if (value == common.Hash{}) {
-			if err := tr.DeleteStorage(s.address, key[:]); err != nil {
-				s.db.setError(err)
-				return nil, err
-			}
-			s.db.StorageDeleted += 1
+			deletions = append(deletions, key)
 		} else {
 			trimmedVal := common.TrimLeftZeroes(value[:])
 			// Encoding []byte cannot fail, ok to ignore the error.
@@ -343,6 +346,13 @@ func (s *stateObject) updateTrie(db Database) (Trie, error) {
 		// Cache the items for preloading
 		usedStorage = append(usedStorage, common.CopyBytes(key[:])) // Copy needed for closure
 	}
+	for _, key := range deletions {
+		if err := tr.DeleteStorage(s.address, key[:]); err != nil {
+			s.db.setError(err)
+			return nil, err
+		}
+		s.db.StorageDeleted += 1
+	}
 	if s.db.prefetcher != nil {
 		s.db.prefetcher.used(s.addrHash, s.data.Root, usedStorage)
 	}
@@ -359,10 +369,14 @@ func (s *stateObject) updateRoot(db Database) {
 	if err != nil {
 		return
 	}
-	// If nothing changed, don't bother with hashing anything
+	// If nothing changed, don't bother with hashing anything. In verkle mode
+	// the storage is part of the account trie, so there's no root to track.
 	if tr == nil {
 		return
 	}
+	if _, ok := tr.(*trie.VerkleTrie); ok {
+		return
+	}
 	// Track the amount of time wasted on hashing the storage trie
 	if metrics.EnabledExpensive {
 		defer func(start time.Time) { s.db.StorageHashes += time.Since(start) }(time.Now())
@@ -376,11 +390,16 @@ func (s *stateObject) commit(db Database) (*trienode.NodeSet, error) {
 	if err != nil {
 		return nil, err
 	}
-	// If nothing changed, don't bother with committing anything
+	// If nothing changed, don't bother with committing anything. The verkle
+	// account trie holding the storage is committed along with the state.
 	if tr == nil {
 		s.origin = s.data.Copy()
 		return nil, nil
 	}
+	if _, ok := tr.(*trie.VerkleTrie); ok {
+		s.origin = s.data.Copy()
+		return nil, nil
+	}
 	// Track the amount of time wasted on committing the storage trie
 	if metrics.EnabledExpensive {
 		defer func(start time.Time) { s.db.StorageCommits += time.Since(start) }(time.Now())
@@ -440,7 +459,13 @@ func (s *stateObject) deepCopy(db *StateDB) *stateObject {
 		data:     s.data,
 	}
 	if s.trie != nil {
-		obj.trie = db.db.CopyTrie(s.trie)
+		// In verkle mode the storage trie is the account trie, which has
+		// already been copied along with the state.
+		if _, ok := s.trie.(*trie.VerkleTrie); ok {
+			obj.trie = db.trie
+		} else {
+			obj.trie = db.db.CopyTrie(s.trie)
+		}
 	}
 	obj.code = s.code
 	obj.dirtyStorage = s.dirtyStorage.Copy()
@@ -368,6 +368,28 @@ func (s *StateDB) GetStorageProof(a common.Address, key common.Hash) ([][]byte,
 	return proof, nil
 }
 
+// VerkleWitness generates a multiproof of all the state accessed since the state
+// was opened, against the tree it was opened at. It is only available with the
+// experimental verkle state and must be called before the state is committed.
+func (s *StateDB) VerkleWitness() (*trie.VerkleWitness, error) {
+	tr, ok := s.trie.(*trie.VerkleTrie)
+	if !ok {
+		return nil, errors.New("witnesses are only supported by the verkle state")
+	}
+	// Code is read from the code store rather than the tree, report all the
+	// loaded contracts so that their chunks are included too.
+	for addr, obj := range s.stateObjects {
+		if len(obj.code) > 0 {
+			tr.TouchCode(addr, len(obj.code))
+		}
+	}
+	parent, err := trie.NewVerkleTrie(s.originalRoot, s.db.TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	return parent.Witness(tr.AccessedKeys())
+}
+
 // GetCommittedState retrieves a value from the given account's committed storage trie.
 func (s *StateDB) GetCommittedState(addr common.Address, hash common.Hash) common.Hash {
 	stateObject := s.getStateObject(addr)
@@ -535,6 +557,11 @@ func (s *StateDB) updateStateObject(obj *stateObject) {
 	if err := s.trie.UpdateAccount(addr, &obj.data); err != nil {
 		s.setError(fmt.Errorf("updateStateObject (%x) error: %v", addr[:], err))
 	}
+	if obj.dirtyCode {
+		if err := s.trie.UpdateContractCode(addr, common.BytesToHash(obj.CodeHash()), obj.code); err != nil {
+			s.setError(fmt.Errorf("updateStateObject (%x) error: %v", addr[:], err))
+		}
+	}
 	// Cache the data until commit. Note, this update mechanism is not symmetric
 	// to the deletion, because whereas it is enough to track account updates
 	// at commit time, deletions need tracking at transaction boundary level to
@@ -964,17 +991,25 @@ func (s *StateDB) IntermediateRoot(deleteEmptyObjects bool) common.Hash {
 			s.trie = trie
 		}
 	}
-	usedAddrs := make([][]byte, 0, len(s.stateObjectsPending))
+	// Apply the deletions after all the updates, so that the trie nodes resolved
+	// to collapse the deleted accounts don't depend on the map iteration order.
+	var (
+		usedAddrs = make([][]byte, 0, len(s.stateObjectsPending))
+		deletions []*stateObject
+	)
 	for addr := range s.stateObjectsPending {
 		if obj := s.stateObjects[addr]; obj.deleted {
-			s.deleteStateObject(obj)
-			s.AccountDeleted += 1
+			deletions = append(deletions, obj)
 		} else {
 			s.updateStateObject(obj)
 			s.AccountUpdated += 1
 		}
 		usedAddrs = append(usedAddrs, common.CopyBytes(addr[:])) // Copy needed for closure
 	}
+	for _, obj := range deletions {
+		s.deleteStateObject(obj)
+		s.AccountDeleted += 1
+	}
 	if prefetcher != nil {
 		prefetcher.used(common.Hash{}, s.originalRoot, usedAddrs)
 	}
@@ -1008,7 +1043,7 @@ func (s *StateDB) clearJournalAndRefund() {
 // slots inside as deleted.
 func (s *StateDB) deleteStorage(addr common.Address, addrHash common.Hash, root common.Hash) (bool, map[common.Hash][]byte, *trienode.NodeSet, error) {
 	start := time.Now()
-	tr, err := s.db.OpenStorageTrie(s.originalRoot, addr, root)
+	tr, err := s.db.OpenStorageTrie(s.originalRoot, addr, root, s.trie)
 	if err != nil {
 		return false, nil, nil, fmt.Errorf("failed to open storage trie, err: %w", err)
 	}
@@ -1171,7 +1206,6 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (common.Hash, error) {
 		// Write any contract code associated with the state object
 		if obj.code != nil && obj.dirtyCode {
 			rawdb.WriteCode(codeWriter, common.BytesToHash(obj.CodeHash()), obj.code)
-			s.trie.UpdateContractCode(obj.Address(), common.BytesToHash(obj.CodeHash()), obj.code)
 			obj.dirtyCode = false
 		}
 		// Write any storage changes in the state object to its storage trie
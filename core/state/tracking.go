@@ -0,0 +1,137 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// TrackingDatabase is a state database wrapper that records all the trie nodes
+// and contract codes accessed through it, so that the state used by a block can
+// be collected into a witness after execution. Only merkle tries are tracked.
+type TrackingDatabase struct {
+	Database
+
+	tries []*trie.StateTrie      // Tries handed out, holding the loaded nodes
+	codes map[common.Hash][]byte // Contract codes loaded, keyed by code hash
+	lock  sync.Mutex
+}
+
+// NewTrackingDatabase wraps a state database to record the state accessed
+// through it.
+func NewTrackingDatabase(db Database) *TrackingDatabase {
+	return &TrackingDatabase{
+		Database: db,
+		codes:    make(map[common.Hash][]byte),
+	}
+}
+
+// OpenTrie opens the main account trie.
+func (db *TrackingDatabase) OpenTrie(root common.Hash) (Trie, error) {
+	tr, err := db.Database.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	db.track(tr)
+	return tr, nil
+}
+
+// OpenStorageTrie opens the storage trie of an account.
+func (db *TrackingDatabase) OpenStorageTrie(stateRoot common.Hash, address common.Address, root common.Hash, self Trie) (Trie, error) {
+	tr, err := db.Database.OpenStorageTrie(stateRoot, address, root, self)
+	if err != nil {
+		return nil, err
+	}
+	db.track(tr)
+	return tr, nil
+}
+
+// CopyTrie returns an independent copy of the given trie.
+func (db *TrackingDatabase) CopyTrie(t Trie) Trie {
+	cpy := db.Database.CopyTrie(t)
+	db.track(cpy)
+	return cpy
+}
+
+// ContractCode retrieves a particular contract's code.
+func (db *TrackingDatabase) ContractCode(address common.Address, codeHash common.Hash) ([]byte, error) {
+	code, err := db.Database.ContractCode(address, codeHash)
+	if err != nil {
+		return nil, err
+	}
+	db.lock.Lock()
+	db.codes[codeHash] = code
+	db.lock.Unlock()
+	return code, nil
+}
+
+// ContractCodeSize retrieves a particular contracts code's size. The whole code
+// is loaded, as it's needed to prove the size without the database.
+func (db *TrackingDatabase) ContractCodeSize(address common.Address, codeHash common.Hash) (int, error) {
+	code, err := db.ContractCode(address, codeHash)
+	if err != nil {
+		return 0, err
+	}
+	return len(code), nil
+}
+
+// track registers a trie handed out by the database for node collection.
+func (db *TrackingDatabase) track(t Trie) {
+	if tr, ok := t.(*trie.StateTrie); ok {
+		db.lock.Lock()
+		db.tries = append(db.tries, tr)
+		db.lock.Unlock()
+	}
+}
+
+// Nodes returns the deduplicated blobs of all the trie nodes loaded from the
+// database by the tries opened through the tracker. The tries must not have
+// been committed, as that resets their access lists.
+func (db *TrackingDatabase) Nodes() [][]byte {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var (
+		seen  = make(map[string]struct{})
+		nodes [][]byte
+	)
+	for _, tr := range db.tries {
+		for _, blob := range tr.Witness() {
+			if _, ok := seen[string(blob)]; ok {
+				continue
+			}
+			seen[string(blob)] = struct{}{}
+			nodes = append(nodes, blob)
+		}
+	}
+	return nodes
+}
+
+// Codes returns all the contract codes loaded through the tracker.
+func (db *TrackingDatabase) Codes() [][]byte {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	codes := make([][]byte, 0, len(db.codes))
+	for _, code := range db.codes {
+		codes = append(codes, code)
+	}
+	return codes
+}
@@ -31,18 +31,25 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 )
 
+// ProcessorChain is the chain access needed to process a block: resolving the
+// ancestor headers for the EVM and for the consensus engine.
+type ProcessorChain interface {
+	ChainContext
+	consensus.ChainHeaderReader
+}
+
 // StateProcessor is a basic Processor, which takes care of transitioning
 // state from one point to another.
 //
 // StateProcessor implements Processor.
 type StateProcessor struct {
 	config *params.ChainConfig // Chain configuration options
-	bc     *BlockChain         // Canonical block chain
+	bc     ProcessorChain      // Canonical block chain
 	engine consensus.Engine    // Consensus engine used for block rewards
 }
 
 // NewStateProcessor initialises a new StateProcessor.
-func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consensus.Engine) *StateProcessor {
+func NewStateProcessor(config *params.ChainConfig, bc ProcessorChain, engine consensus.Engine) *StateProcessor {
 	return &StateProcessor{
 		config: config,
 		bc:     bc,
@@ -0,0 +1,177 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// ExecutionWitness re-executes a block on top of its parent state, recording all
+// the trie nodes, contract codes and ancestor headers it accesses into a witness
+// that is sufficient to execute the block without any chain state.
+//
+// Witnesses are not recorded during chain insertion, they are only produced on
+// demand by this re-execution. The parent state must therefore still be present,
+// so blocks whose parent state was already pruned can't be witnessed.
+func (bc *BlockChain) ExecutionWitness(block *types.Block) (*stateless.Witness, error) {
+	if bc.triedb.IsVerkle() {
+		return nil, errors.New("execution witnesses are not supported on verkle state")
+	}
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	// Snapshots are deliberately not used, all the state has to be read through
+	// the tries for their nodes to be recorded.
+	db := state.NewTrackingDatabase(bc.stateCache)
+	statedb, err := state.New(parent.Root, db, nil)
+	if err != nil {
+		return nil, err
+	}
+	witness := stateless.NewWitness(block)
+	witness.AddHeader(parent)
+
+	chain := &witnessChain{ProcessorChain: bc, witness: witness}
+	receipts, _, usedGas, err := NewStateProcessor(bc.chainConfig, chain, bc.engine).Process(block, statedb, vm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	// Validating the state also hashes the tries, resolving the nodes needed
+	// to collapse the ones deleted by the block.
+	if err := bc.validator.ValidateState(block, statedb, receipts, usedGas); err != nil {
+		return nil, err
+	}
+	witness.AddState(db.Nodes())
+	for _, code := range db.Codes() {
+		witness.AddCode(code)
+	}
+	return witness, nil
+}
+
+// ExecuteStateless executes the block of a witness on top of the parent state
+// contained in it, without access to any chain data, and validates the gas
+// used, the receipts and the resulting state root against the block header.
+func ExecuteStateless(config *params.ChainConfig, engine consensus.Engine, witness *stateless.Witness) error {
+	block := witness.Block
+	parent := witness.Parent()
+	if parent == nil {
+		return fmt.Errorf("witness lacks the parent header %x", block.ParentHash())
+	}
+	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != block.TxHash() {
+		return fmt.Errorf("transaction root hash mismatch (header value %x, calculated %x)", block.TxHash(), hash)
+	}
+	if hash := types.CalcUncleHash(block.Uncles()); hash != block.UncleHash() {
+		return fmt.Errorf("uncle root hash mismatch (header value %x, calculated %x)", block.UncleHash(), hash)
+	}
+	statedb, err := state.New(parent.Root, state.NewDatabase(witness.MakeHashDB()), nil)
+	if err != nil {
+		return err
+	}
+	chain := &statelessChain{config: config, engine: engine, witness: witness}
+	receipts, _, usedGas, err := NewStateProcessor(config, chain, engine).Process(block, statedb, vm.Config{})
+	if err != nil {
+		return err
+	}
+	if err := statedb.Error(); err != nil {
+		return fmt.Errorf("incomplete witness: %w", err)
+	}
+	return NewBlockValidator(config, nil, engine).ValidateState(block, statedb, receipts, usedGas)
+}
+
+// witnessChain wraps a chain to record all the headers accessed during block
+// processing into a witness.
+type witnessChain struct {
+	ProcessorChain
+	witness *stateless.Witness
+}
+
+// GetHeader retrieves a block header by hash and number, recording it.
+func (c *witnessChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.record(c.ProcessorChain.GetHeader(hash, number))
+}
+
+// GetHeaderByNumber retrieves a block header by number, recording it.
+func (c *witnessChain) GetHeaderByNumber(number uint64) *types.Header {
+	return c.record(c.ProcessorChain.GetHeaderByNumber(number))
+}
+
+// GetHeaderByHash retrieves a block header by hash, recording it.
+func (c *witnessChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return c.record(c.ProcessorChain.GetHeaderByHash(hash))
+}
+
+func (c *witnessChain) record(header *types.Header) *types.Header {
+	if header != nil {
+		c.witness.AddHeader(header)
+	}
+	return header
+}
+
+// statelessChain is a chain backed by the headers of a witness alone.
+type statelessChain struct {
+	config  *params.ChainConfig
+	engine  consensus.Engine
+	witness *stateless.Witness
+}
+
+// Config retrieves the chain's fork configuration.
+func (c *statelessChain) Config() *params.ChainConfig { return c.config }
+
+// Engine retrieves the chain's consensus engine.
+func (c *statelessChain) Engine() consensus.Engine { return c.engine }
+
+// CurrentHeader returns the parent of the witness block, the most recent
+// header known to the chain.
+func (c *statelessChain) CurrentHeader() *types.Header { return c.witness.Parent() }
+
+// GetHeader retrieves a block header from the witness by hash and number.
+func (c *statelessChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header := c.witness.Headers[hash]
+	if header == nil || header.Number.Uint64() != number {
+		return nil
+	}
+	return header
+}
+
+// GetHeaderByNumber retrieves a block header from the witness by number.
+func (c *statelessChain) GetHeaderByNumber(number uint64) *types.Header {
+	for _, header := range c.witness.Headers {
+		if header.Number.Uint64() == number {
+			return header
+		}
+	}
+	return nil
+}
+
+// GetHeaderByHash retrieves a block header from the witness by hash.
+func (c *statelessChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return c.witness.Headers[hash]
+}
+
+// GetTd is not available without the chain database and always returns nil.
+func (c *statelessChain) GetTd(hash common.Hash, number uint64) *big.Int { return nil }
@@ -0,0 +1,167 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package stateless implements the witnesses needed to execute a block without
+// access to the state database.
+package stateless
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Witness encompasses everything needed to execute a block on top of its parent
+// statelessly: the trie nodes and contract codes accessed during execution, and
+// the ancestor headers the block depends on.
+type Witness struct {
+	Block   *types.Block                  // Block the witness was generated for
+	Headers map[common.Hash]*types.Header // Parent header and the ancestors accessed via BLOCKHASH
+	Codes   map[string]struct{}           // Contract codes accessed during execution
+	State   map[string]struct{}           // Trie nodes accessed during execution
+}
+
+// NewWitness creates an empty witness for the given block.
+func NewWitness(block *types.Block) *Witness {
+	return &Witness{
+		Block:   block,
+		Headers: make(map[common.Hash]*types.Header),
+		Codes:   make(map[string]struct{}),
+		State:   make(map[string]struct{}),
+	}
+}
+
+// AddHeader adds an ancestor header to the witness.
+func (w *Witness) AddHeader(header *types.Header) {
+	w.Headers[header.Hash()] = header
+}
+
+// AddCode adds a contract code to the witness.
+func (w *Witness) AddCode(code []byte) {
+	if len(code) == 0 {
+		return
+	}
+	w.Codes[string(code)] = struct{}{}
+}
+
+// AddState adds a batch of trie nodes to the witness.
+func (w *Witness) AddState(nodes [][]byte) {
+	for _, node := range nodes {
+		w.State[string(node)] = struct{}{}
+	}
+}
+
+// Parent returns the header of the parent block, or nil if the witness does not
+// contain it.
+func (w *Witness) Parent() *types.Header {
+	return w.Headers[w.Block.ParentHash()]
+}
+
+// MakeHashDB imports the trie nodes and contract codes of the witness into an
+// in-memory database, keyed by their hashes as the hash-based trie database
+// expects them.
+func (w *Witness) MakeHashDB() ethdb.Database {
+	db := rawdb.NewMemoryDatabase()
+	for node := range w.State {
+		blob := []byte(node)
+		rawdb.WriteLegacyTrieNode(db, crypto.Keccak256Hash(blob), blob)
+	}
+	for code := range w.Codes {
+		blob := []byte(code)
+		rawdb.WriteCode(db, crypto.Keccak256Hash(blob), blob)
+	}
+	return db
+}
+
+// extWitness is the external representation of a witness. The block is carried
+// in its consensus encoding, headers are sorted from the most recent and codes
+// and trie nodes are sorted to keep the encoding deterministic.
+type extWitness struct {
+	Block   hexutil.Bytes   `json:"block"`
+	Headers []*types.Header `json:"headers"`
+	Codes   []hexutil.Bytes `json:"codes"`
+	State   []hexutil.Bytes `json:"state"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (w *Witness) MarshalJSON() ([]byte, error) {
+	block, err := rlp.EncodeToBytes(w.Block)
+	if err != nil {
+		return nil, err
+	}
+	ext := &extWitness{
+		Block:   block,
+		Headers: make([]*types.Header, 0, len(w.Headers)),
+		Codes:   sortedBlobs(w.Codes),
+		State:   sortedBlobs(w.State),
+	}
+	for _, header := range w.Headers {
+		ext.Headers = append(ext.Headers, header)
+	}
+	sort.Slice(ext.Headers, func(i, j int) bool {
+		return ext.Headers[i].Number.Cmp(ext.Headers[j].Number) > 0
+	})
+	return json.Marshal(ext)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (w *Witness) UnmarshalJSON(input []byte) error {
+	var ext extWitness
+	if err := json.Unmarshal(input, &ext); err != nil {
+		return err
+	}
+	if len(ext.Block) == 0 {
+		return errors.New("missing block in witness")
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(ext.Block, block); err != nil {
+		return err
+	}
+	*w = *NewWitness(block)
+	for _, header := range ext.Headers {
+		w.AddHeader(header)
+	}
+	for _, code := range ext.Codes {
+		w.AddCode(code)
+	}
+	for _, node := range ext.State {
+		w.State[string(node)] = struct{}{}
+	}
+	return nil
+}
+
+// sortedBlobs returns the keys of a blob set in ascending order.
+func sortedBlobs(set map[string]struct{}) []hexutil.Bytes {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	blobs := make([]hexutil.Bytes, len(keys))
+	for i, key := range keys {
+		blobs[i] = hexutil.Bytes(key)
+	}
+	return blobs
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the witness generated for a block is sufficient to execute it
+// statelessly, and that incomplete witnesses are rejected.
+func TestStatelessExecution(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		config   = params.TestChainConfig
+		signer   = types.LatestSigner(config)
+		gspec    = &Genesis{
+			Config: config,
+			Alloc: GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+				contract: {
+					Balance: common.Big0,
+					// slot0 += 1; slot1 = blockhash(number - 3); slot2 = 0
+					Code: common.FromHex("0x6001600054016000556003430340600155600060025500"),
+					Storage: map[common.Hash]common.Hash{
+						common.HexToHash("0x02"): common.HexToHash("0x01"),
+						common.HexToHash("0x03"): common.HexToHash("0x01"),
+					},
+				},
+			},
+		}
+	)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// Blocks are generated one by one on top of the imported chain, so that the
+	// BLOCKHASH lookups can be served.
+	var (
+		db, _, _ = GenerateChainWithGenesis(gspec, ethash.NewFaker(), 0, nil)
+		parent   = chain.Genesis()
+		blocks   []*types.Block
+	)
+	for i := 0; i < 4; i++ {
+		generated, _ := GenerateChain(config, parent, ethash.NewFaker(), db, 1, func(_ int, gen *BlockGen) {
+			call, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), contract, common.Big0, 100000, gen.header.BaseFee, nil), signer, key)
+			gen.AddTxWithChain(chain, call)
+			transfer, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{byte(i + 1)}, big.NewInt(1000), params.TxGas, gen.header.BaseFee, nil), signer, key)
+			gen.AddTxWithChain(chain, transfer)
+		})
+		if _, err := chain.InsertChain(generated); err != nil {
+			t.Fatalf("failed to insert block %d: %v", i+1, err)
+		}
+		parent = generated[0]
+		blocks = append(blocks, parent)
+	}
+	for _, block := range blocks {
+		witness, err := chain.ExecutionWitness(block)
+		if err != nil {
+			t.Fatalf("block %d: failed to generate witness: %v", block.NumberU64(), err)
+		}
+		if len(witness.Codes) != 1 {
+			t.Errorf("block %d: code count mismatch: have %d, want 1", block.NumberU64(), len(witness.Codes))
+		}
+		// From the third block on, BLOCKHASH walks back to the grandparent which
+		// has to be carried along with the parent.
+		if want := 2; block.NumberU64() >= 3 && len(witness.Headers) != want {
+			t.Errorf("block %d: header count mismatch: have %d, want %d", block.NumberU64(), len(witness.Headers), want)
+		}
+		blob, err := json.Marshal(witness)
+		if err != nil {
+			t.Fatalf("block %d: failed to encode witness: %v", block.NumberU64(), err)
+		}
+		dec := new(stateless.Witness)
+		if err := json.Unmarshal(blob, dec); err != nil {
+			t.Fatalf("block %d: failed to decode witness: %v", block.NumberU64(), err)
+		}
+		if err := ExecuteStateless(config, ethash.NewFaker(), dec); err != nil {
+			t.Fatalf("block %d: stateless execution failed: %v", block.NumberU64(), err)
+		}
+		// Dropping any trie node or the parent header must fail execution
+		for node := range dec.State {
+			incomplete := stateless.NewWitness(dec.Block)
+			for _, header := range dec.Headers {
+				incomplete.AddHeader(header)
+			}
+			for code := range dec.Codes {
+				incomplete.AddCode([]byte(code))
+			}
+			for other := range dec.State {
+				if other != node {
+					incomplete.State[other] = struct{}{}
+				}
+			}
+			if err := ExecuteStateless(config, ethash.NewFaker(), incomplete); err == nil {
+				t.Fatalf("block %d: stateless execution succeeded without node %x", block.NumberU64(), crypto.Keccak256([]byte(node)))
+			}
+		}
+		delete(dec.Headers, block.ParentHash())
+		if err := ExecuteStateless(config, ethash.NewFaker(), dec); err == nil {
+			t.Fatalf("block %d: stateless execution succeeded without parent header", block.NumberU64())
+		}
+	}
+}
@@ -103,6 +103,8 @@ var (
 	localGauge   = metrics.NewRegisteredGauge("txpool/local", nil)
 	slotsGauge   = metrics.NewRegisteredGauge("txpool/slots", nil)
 
+	prioritySlotsGauge = metrics.NewRegisteredGauge("txpool/priority/slots", nil)
+
 	reheapTimer = metrics.NewRegisteredTimer("txpool/reheap", nil)
 )
 
@@ -138,6 +140,14 @@ type Config struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	// Transactions from the priority senders or to the priority contracts are
+	// kept in a separate lane of PrioritySlots, outside of the global limits and
+	// exempt from price based eviction. Priority senders are additionally exempt
+	// from the per-account and lifetime based eviction rules, like locals.
+	PrioritySenders   []common.Address `toml:",omitempty"`
+	PriorityContracts []common.Address `toml:",omitempty"`
+	PrioritySlots     uint64           // Maximum number of transaction slots reserved for the priority lane
 }
 
 // DefaultConfig contains the default configurations for the transaction pool.
@@ -154,6 +164,8 @@ var DefaultConfig = Config{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	PrioritySlots: 512,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -192,6 +204,10 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultConfig.Lifetime)
 		conf.Lifetime = DefaultConfig.Lifetime
 	}
+	if conf.PrioritySlots < 1 {
+		log.Warn("Sanitizing invalid txpool priority slots", "provided", conf.PrioritySlots, "updated", DefaultConfig.PrioritySlots)
+		conf.PrioritySlots = DefaultConfig.PrioritySlots
+	}
 	return conf
 }
 
@@ -216,8 +232,9 @@ type LegacyPool struct {
 	currentState  *state.StateDB               // Current state in the blockchain head
 	pendingNonces *noncer                      // Pending state tracking virtual nonces
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *journal    // Journal of local transaction to back up to disk
+	locals   *accountSet          // Set of local transaction to exempt from eviction rules
+	journal  *journal             // Journal of local transaction to back up to disk
+	priority *txpool.PriorityList // Allowlist of senders and contracts routed into the priority lane
 
 	pending map[common.Address]*list     // All currently processable transactions
 	queue   map[common.Address]*list     // Queued but non-processable transactions
@@ -256,6 +273,7 @@ func New(config Config, chain BlockChain) *LegacyPool {
 		queue:           make(map[common.Address]*list),
 		beats:           make(map[common.Address]time.Time),
 		all:             newLookup(),
+		priority:        txpool.NewPriorityList(config.PrioritySenders, config.PriorityContracts),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
 		queueTxEventCh:  make(chan *types.Transaction),
@@ -357,8 +375,8 @@ func (pool *LegacyPool) loop() {
 		case <-evict.C:
 			pool.mu.Lock()
 			for addr := range pool.queue {
-				// Skip local transactions from the eviction mechanism
-				if pool.locals.contains(addr) {
+				// Skip local and priority transactions from the eviction mechanism
+				if pool.exempt(addr) {
 					continue
 				}
 				// Any non-locals old enough should be removed
@@ -540,6 +558,18 @@ func (pool *LegacyPool) Locals() []common.Address {
 	return pool.locals.flatten()
 }
 
+// Priority retrieves the allowlist of senders and contracts whose transactions
+// are tracked in the priority lane of the pool.
+func (pool *LegacyPool) Priority() *txpool.PriorityList {
+	return pool.priority
+}
+
+// exempt returns whether the transactions of an account are shielded from the
+// per-account and lifetime based eviction rules.
+func (pool *LegacyPool) exempt(addr common.Address) bool {
+	return pool.locals.contains(addr) || pool.priority.ContainsSender(addr)
+}
+
 // local retrieves all currently known local transactions, grouped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
@@ -636,8 +666,13 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 	// already validated by this point
 	from, _ := types.Sender(pool.signer, tx)
 
+	// Route allowlisted remote transactions into the priority lane as long as it
+	// has room left. Overflowing ones compete with the rest of the pool.
+	isPriority := !isLocal && pool.priority.Contains(from, tx) &&
+		uint64(pool.all.PrioritySlots()+numSlots(tx)) <= pool.config.PrioritySlots
+
 	// If the transaction pool is full, discard underpriced transactions
-	if uint64(pool.all.Slots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
+	if !isPriority && uint64(pool.all.Slots()-pool.all.PrioritySlots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
 		if !isLocal && pool.priced.Underpriced(tx) {
 			log.Trace("Discarding underpriced transaction", "hash", hash, "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
@@ -657,7 +692,7 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 		// New transaction is better than our worse ones, make room for it.
 		// If it's a local transaction, forcibly discard all available transactions.
 		// Otherwise if we can't make enough room for new one, abort the operation.
-		drop, success := pool.priced.Discard(pool.all.Slots()-int(pool.config.GlobalSlots+pool.config.GlobalQueue)+numSlots(tx), isLocal)
+		drop, success := pool.priced.Discard(pool.all.Slots()-pool.all.PrioritySlots()-int(pool.config.GlobalSlots+pool.config.GlobalQueue)+numSlots(tx), isLocal)
 
 		// Special case, we still can't make the room for the new remote one.
 		if !isLocal && !success {
@@ -709,8 +744,11 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
 		}
-		pool.all.Add(tx, isLocal)
-		pool.priced.Put(tx, isLocal)
+		pool.all.Add(tx, isLocal || isPriority)
+		pool.priced.Put(tx, isLocal || isPriority)
+		if isPriority {
+			pool.all.MarkPriority(tx)
+		}
 		pool.journalTx(from, tx)
 		pool.queueTxEvent(tx)
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
@@ -720,10 +758,13 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 		return old != nil, nil
 	}
 	// New transaction isn't replacing a pending one, push into queue
-	replaced, err = pool.enqueueTx(hash, tx, isLocal, true)
+	replaced, err = pool.enqueueTx(hash, tx, isLocal || isPriority, true)
 	if err != nil {
 		return false, err
 	}
+	if isPriority {
+		pool.all.MarkPriority(tx)
+	}
 	// Mark local addresses and journal local transactions
 	if local && !pool.locals.contains(from) {
 		log.Info("Setting new local account", "address", from)
@@ -1012,6 +1053,20 @@ func (pool *LegacyPool) Has(hash common.Hash) bool {
 	return pool.all.Get(hash) != nil
 }
 
+// Remove drops a transaction from the pool, moving all subsequent transactions
+// of the same account back to the future queue. It returns whether the
+// transaction was found in the pool.
+func (pool *LegacyPool) Remove(hash common.Hash) bool {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.all.Get(hash) == nil {
+		return false
+	}
+	pool.removeTx(hash, true)
+	return true
+}
+
 // removeTx removes a single transaction from the queue, moving all subsequent
 // transactions back to the future queue.
 // Returns the number of transactions removed from the pending queue.
@@ -1383,7 +1438,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 
 		// Drop all transactions over the allowed limit
 		var caps types.Transactions
-		if !pool.locals.contains(addr) {
+		if !pool.exempt(addr) {
 			caps = list.Cap(int(pool.config.AccountQueue))
 			for _, tx := range caps {
 				hash := tx.Hash()
@@ -1424,7 +1479,7 @@ func (pool *LegacyPool) truncatePending() {
 	spammers := prque.New[int64, common.Address](nil)
 	for addr, list := range pool.pending {
 		// Only evict transactions from high rollers
-		if !pool.locals.contains(addr) && uint64(list.Len()) > pool.config.AccountSlots {
+		if !pool.exempt(addr) && uint64(list.Len()) > pool.config.AccountSlots {
 			spammers.Push(addr, int64(list.Len()))
 		}
 	}
@@ -1507,7 +1562,7 @@ func (pool *LegacyPool) truncateQueue() {
 	// Sort all accounts with queued transactions by heartbeat
 	addresses := make(addressesByHeartbeat, 0, len(pool.queue))
 	for addr := range pool.queue {
-		if !pool.locals.contains(addr) { // don't drop locals
+		if !pool.exempt(addr) { // don't drop locals and priority senders
 			addresses = append(addresses, addressByHeartbeat{addr, pool.beats[addr]})
 		}
 	}
@@ -1697,13 +1752,17 @@ type lookup struct {
 	lock    sync.RWMutex
 	locals  map[common.Hash]*types.Transaction
 	remotes map[common.Hash]*types.Transaction
+
+	prioritySlots int
+	priority      map[common.Hash]struct{} // Transactions tracked in the priority lane
 }
 
 // newLookup returns a new lookup structure.
 func newLookup() *lookup {
 	return &lookup{
-		locals:  make(map[common.Hash]*types.Transaction),
-		remotes: make(map[common.Hash]*types.Transaction),
+		locals:   make(map[common.Hash]*types.Transaction),
+		remotes:  make(map[common.Hash]*types.Transaction),
+		priority: make(map[common.Hash]struct{}),
 	}
 }
 
@@ -1789,6 +1848,31 @@ func (t *lookup) Slots() int {
 	return t.slots
 }
 
+// PrioritySlots returns the current number of slots used by the priority lane.
+func (t *lookup) PrioritySlots() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.prioritySlots
+}
+
+// MarkPriority accounts an already added transaction to the priority lane.
+func (t *lookup) MarkPriority(tx *types.Transaction) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	hash := tx.Hash()
+	if _, ok := t.priority[hash]; ok {
+		return
+	}
+	if t.locals[hash] == nil && t.remotes[hash] == nil {
+		return
+	}
+	t.priority[hash] = struct{}{}
+	t.prioritySlots += numSlots(tx)
+	prioritySlotsGauge.Update(int64(t.prioritySlots))
+}
+
 // Add adds a transaction to the lookup.
 func (t *lookup) Add(tx *types.Transaction, local bool) {
 	t.lock.Lock()
@@ -1820,6 +1904,11 @@ func (t *lookup) Remove(hash common.Hash) {
 	t.slots -= numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
 
+	if _, ok := t.priority[hash]; ok {
+		t.prioritySlots -= numSlots(tx)
+		prioritySlotsGauge.Update(int64(t.prioritySlots))
+		delete(t.priority, hash)
+	}
 	delete(t.locals, hash)
 	delete(t.remotes, hash)
 }
@@ -1781,6 +1781,127 @@ func TestUnderpricing(t *testing.T) {
 	}
 }
 
+// Tests that transactions of priority senders are kept in their own lane, which
+// is neither limited nor evictable by the rest of the pool.
+func TestPriorityLane(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the priority lane with
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	config := testTxPoolConfig
+	config.GlobalSlots = 2
+	config.GlobalQueue = 2
+	config.PrioritySenders = []common.Address{crypto.PubkeyToAddress(keys[2].PublicKey)}
+	config.PrioritySlots = 2
+
+	pool := New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock())
+	defer pool.Close()
+
+	for _, key := range keys {
+		testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(100000000))
+	}
+	// Fill up the regular lanes of the pool
+	for i := uint64(0); i < 4; i++ {
+		if err := pool.addRemoteSync(pricedTransaction(i, 100000, big.NewInt(2), keys[0])); err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", i, err)
+		}
+	}
+	// Cheap priority transactions should be admitted until their own lane is full
+	for i := uint64(0); i < 2; i++ {
+		if err := pool.addRemoteSync(pricedTransaction(i, 100000, big.NewInt(1), keys[2])); err != nil {
+			t.Fatalf("failed to add priority transaction %d: %v", i, err)
+		}
+	}
+	if slots := pool.all.PrioritySlots(); slots != 2 {
+		t.Fatalf("priority slots mismatch: have %d, want %d", slots, 2)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(2, 100000, big.NewInt(1), keys[2])); !errors.Is(err, txpool.ErrUnderpriced) {
+		t.Fatalf("adding overflowing priority transaction error mismatch: have %v, want %v", err, txpool.ErrUnderpriced)
+	}
+	// Expensive remote transactions must not evict the priority lane
+	for i := uint64(0); i < 4; i++ {
+		if err := pool.addRemoteSync(pricedTransaction(i, 100000, big.NewInt(10), keys[1])); err != nil {
+			t.Fatalf("failed to add expensive remote transaction %d: %v", i, err)
+		}
+	}
+	pending := pool.Pending(false)
+	if txs := pending[crypto.PubkeyToAddress(keys[2].PublicKey)]; len(txs) != 2 {
+		t.Fatalf("priority transactions evicted: have %d, want %d", len(txs), 2)
+	}
+	if txs := pending[crypto.PubkeyToAddress(keys[0].PublicKey)]; len(txs) != 0 {
+		t.Fatalf("cheap transactions not evicted: have %d, want %d", len(txs), 0)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Split the pending transactions into the priority lane and the rest
+	priority, rest := pool.Priority().Split(pending)
+	if len(priority) != 1 || len(rest) != 1 {
+		t.Fatalf("priority split mismatch: have %d priority and %d other accounts", len(priority), len(rest))
+	}
+}
+
+// Tests that private transactions are hidden from propagation and dropped from
+// the pool once the chain progresses to their maximum block number.
+func TestPrivateTransactionExpiry(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	pool := New(testTxPoolConfig, blockchain)
+	txs, err := txpool.New(new(big.Int).SetUint64(testTxPoolConfig.PriceLimit), blockchain, []txpool.SubPool{pool})
+	if err != nil {
+		t.Fatalf("failed to create tx pool: %v", err)
+	}
+	defer txs.Close()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+
+	tx := transaction(0, 100000, key)
+	if err := txs.AddPrivate(tx, 1); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	if !txs.IsPrivate(tx.Hash()) {
+		t.Fatalf("transaction not tracked as private")
+	}
+	// Building the next block should still include the transaction, but not after
+	pending := txs.Pending(false)
+	txs.FilterExpiredPrivate(pending, 1)
+	if len(pending) != 1 {
+		t.Fatalf("private transaction filtered before expiry")
+	}
+	txs.FilterExpiredPrivate(pending, 2)
+	if len(pending) != 0 {
+		t.Fatalf("private transaction not filtered after expiry")
+	}
+	// Move the chain to the maximum block and ensure the transaction gets dropped
+	head := &types.Header{Number: big.NewInt(1), GasLimit: 1000000, BaseFee: big.NewInt(params.InitialBaseFee)}
+	blockchain.chainHeadFeed.Send(core.ChainHeadEvent{Block: types.NewBlockWithHeader(head)})
+
+	// The transaction must remain private for as long as it's in the pool
+	for i := 0; i < 100 && txs.IsPrivate(tx.Hash()); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if txs.Has(tx.Hash()) {
+		t.Fatalf("expired private transaction not dropped")
+	}
+	if status := txs.PrivateStatus(tx.Hash()); status == nil || !status.Expired || status.MaxBlock != 1 {
+		t.Fatalf("private status mismatch: %+v", status)
+	}
+	if txs.IsPrivate(tx.Hash()) {
+		t.Fatalf("expired transaction still tracked as private")
+	}
+}
+
 // Tests that more expensive transactions push out cheap ones from the pool, but
 // without producing instability by creating gaps that start jumping transactions
 // back and forth between queued/pending.
@@ -0,0 +1,123 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// PriorityList is an allowlist of senders and target contracts whose transactions
+// are routed into the priority lane. Priority transactions are tracked within
+// their own pool limits, so they cannot be evicted by underpriced spam, and get
+// a reserved portion of the gas in each locally built block.
+//
+// A nil list is valid and contains nothing.
+type PriorityList struct {
+	senders   map[common.Address]struct{}
+	contracts map[common.Address]struct{}
+}
+
+// NewPriorityList creates an allowlist from the given senders and contracts.
+func NewPriorityList(senders []common.Address, contracts []common.Address) *PriorityList {
+	list := &PriorityList{
+		senders:   make(map[common.Address]struct{}),
+		contracts: make(map[common.Address]struct{}),
+	}
+	for _, addr := range senders {
+		list.senders[addr] = struct{}{}
+	}
+	for _, addr := range contracts {
+		list.contracts[addr] = struct{}{}
+	}
+	return list
+}
+
+// Empty returns whether the list has neither senders nor contracts.
+func (l *PriorityList) Empty() bool {
+	return l == nil || (len(l.senders) == 0 && len(l.contracts) == 0)
+}
+
+// Contains returns whether a transaction sent by the given account belongs to
+// the priority lane, either by its sender or by its recipient.
+func (l *PriorityList) Contains(from common.Address, tx *types.Transaction) bool {
+	if l.Empty() {
+		return false
+	}
+	if _, ok := l.senders[from]; ok {
+		return true
+	}
+	if to := tx.To(); to != nil {
+		if _, ok := l.contracts[*to]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsSender returns whether all transactions of the given account belong
+// to the priority lane.
+func (l *PriorityList) ContainsSender(addr common.Address) bool {
+	if l.Empty() {
+		return false
+	}
+	_, ok := l.senders[addr]
+	return ok
+}
+
+// Merge returns a new list containing the entries of both lists.
+func (l *PriorityList) Merge(other *PriorityList) *PriorityList {
+	merged := NewPriorityList(nil, nil)
+	for _, list := range []*PriorityList{l, other} {
+		if list == nil {
+			continue
+		}
+		for addr := range list.senders {
+			merged.senders[addr] = struct{}{}
+		}
+		for addr := range list.contracts {
+			merged.contracts[addr] = struct{}{}
+		}
+	}
+	return merged
+}
+
+// Split separates a set of pending transactions, grouped by account and sorted
+// by nonce, into the priority lane and the rest. Since an account's transactions
+// need to execute in nonce order, only the leading run of priority transactions
+// of each account is moved into the priority lane; everything after the first
+// non-priority transaction stays with the rest.
+func (l *PriorityList) Split(pending map[common.Address][]*types.Transaction) (priority, rest map[common.Address][]*types.Transaction) {
+	if l.Empty() {
+		return nil, pending
+	}
+	priority = make(map[common.Address][]*types.Transaction)
+	rest = make(map[common.Address][]*types.Transaction)
+	for from, txs := range pending {
+		n := 0
+		for n < len(txs) && l.Contains(from, txs[n]) {
+			n++
+		}
+		if n > 0 {
+			priority[from] = txs[:n]
+		}
+		if n < len(txs) {
+			rest[from] = txs[n:]
+		}
+	}
+	return priority, rest
+}
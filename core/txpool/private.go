@@ -0,0 +1,157 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// privateRetention is the number of blocks the status of an expired private
+// transaction is retained for, before it is forgotten.
+const privateRetention = 128
+
+// PrivateTxStatus is the tracking status of a private transaction.
+type PrivateTxStatus struct {
+	MaxBlock uint64 // Last block number the transaction may be included in
+	Expired  bool   // Whether the transaction was dropped after reaching MaxBlock
+}
+
+// privateSet tracks the transactions submitted privately into the pool. They are
+// never gossiped to the network and get dropped once the chain progresses past
+// their maximum block number.
+type privateSet struct {
+	txs  map[common.Hash]*PrivateTxStatus
+	lock sync.RWMutex
+}
+
+// newPrivateSet creates an empty private transaction tracker.
+func newPrivateSet() *privateSet {
+	return &privateSet{
+		txs: make(map[common.Hash]*PrivateTxStatus),
+	}
+}
+
+// AddPrivate injects a transaction into the pool which is not to be propagated
+// to the network, only included in locally built blocks up to maxBlock.
+//
+// Private transactions are added as remote ones, so they don't end up in the
+// local journal and leak out as regular transactions after a restart.
+func (p *TxPool) AddPrivate(tx *types.Transaction, maxBlock uint64) error {
+	hash := tx.Hash()
+
+	p.private.lock.Lock()
+	p.private.txs[hash] = &PrivateTxStatus{MaxBlock: maxBlock}
+	p.private.lock.Unlock()
+
+	if err := p.Add([]*Transaction{{Tx: tx}}, false, true)[0]; err != nil {
+		p.private.lock.Lock()
+		delete(p.private.txs, hash)
+		p.private.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+// IsPrivate returns whether a transaction was submitted privately and is still
+// awaiting inclusion. Such transactions must not be propagated to the network.
+func (p *TxPool) IsPrivate(hash common.Hash) bool {
+	p.private.lock.RLock()
+	defer p.private.lock.RUnlock()
+
+	status, ok := p.private.txs[hash]
+	return ok && !status.Expired
+}
+
+// PrivateStatus returns the tracking status of a private transaction, or nil
+// if the transaction was not submitted privately or was already forgotten.
+func (p *TxPool) PrivateStatus(hash common.Hash) *PrivateTxStatus {
+	p.private.lock.RLock()
+	defer p.private.lock.RUnlock()
+
+	status, ok := p.private.txs[hash]
+	if !ok {
+		return nil
+	}
+	cpy := *status
+	return &cpy
+}
+
+// FilterExpiredPrivate trims the pending transactions of each account, grouped
+// by account and sorted by nonce, before the first private transaction that
+// would be included after its maximum block number. It closes the gap between
+// a new block being built and the pool dropping the expired transactions.
+func (p *TxPool) FilterExpiredPrivate(pending map[common.Address][]*types.Transaction, number uint64) {
+	p.private.lock.RLock()
+	defer p.private.lock.RUnlock()
+
+	if len(p.private.txs) == 0 {
+		return
+	}
+	for from, txs := range pending {
+		for i, tx := range txs {
+			if status, ok := p.private.txs[tx.Hash()]; ok && (status.Expired || status.MaxBlock < number) {
+				if i == 0 {
+					delete(pending, from)
+				} else {
+					pending[from] = txs[:i]
+				}
+				break
+			}
+		}
+	}
+}
+
+// expirePrivate drops all private transactions from the pool which can no longer
+// be included after the given head, and forgets long expired ones.
+//
+// Transactions are only marked expired once removed from the subpools, so they
+// keep being reported private, and are never gossiped, while still pending.
+func (p *TxPool) expirePrivate(head *types.Header) {
+	number := head.Number.Uint64()
+
+	var expired []common.Hash
+	p.private.lock.Lock()
+	for hash, status := range p.private.txs {
+		switch {
+		case status.Expired && status.MaxBlock+privateRetention < number:
+			delete(p.private.txs, hash)
+		case !status.Expired && status.MaxBlock <= number:
+			expired = append(expired, hash)
+		}
+	}
+	p.private.lock.Unlock()
+
+	for _, hash := range expired {
+		for _, subpool := range p.subpools {
+			if subpool.Remove(hash) {
+				log.Debug("Dropped expired private transaction", "hash", hash)
+				break
+			}
+		}
+	}
+	p.private.lock.Lock()
+	for _, hash := range expired {
+		if status, ok := p.private.txs[hash]; ok {
+			status.Expired = true
+		}
+	}
+	p.private.lock.Unlock()
+}
@@ -79,6 +79,11 @@ type SubPool interface {
 	// to a later point to batch multiple ones together.
 	Add(txs []*Transaction, local bool, sync bool) []error
 
+	// Remove drops a transaction from the pool, demoting any subsequent ones of
+	// the same account that become unexecutable. It returns whether the
+	// transaction was found.
+	Remove(hash common.Hash) bool
+
 	// Pending retrieves all currently processable transactions, grouped by origin
 	// account and sorted by nonce.
 	Pending(enforceTips bool) map[common.Address][]*types.Transaction
@@ -105,6 +110,10 @@ type SubPool interface {
 	// Locals retrieves the accounts currently considered local by the pool.
 	Locals() []common.Address
 
+	// Priority retrieves the allowlist of senders and contracts whose transactions
+	// are tracked in the priority lane of the pool.
+	Priority() *PriorityList
+
 	// Status returns the known status (unknown/pending/queued) of a transaction
 	// identified by their hashes.
 	Status(hash common.Hash) TxStatus
@@ -53,6 +53,7 @@ type BlockChain interface {
 // resource constraints.
 type TxPool struct {
 	subpools []SubPool               // List of subpools for specialized transaction handling
+	private  *privateSet             // Transactions submitted privately, exempt from propagation
 	subs     event.SubscriptionScope // Subscription scope to unscubscribe all on shutdown
 	quit     chan chan error         // Quit channel to tear down the head updater
 }
@@ -67,6 +68,7 @@ func New(gasTip *big.Int, chain BlockChain, subpools []SubPool) (*TxPool, error)
 
 	pool := &TxPool{
 		subpools: subpools,
+		private:  newPrivateSet(),
 		quit:     make(chan chan error),
 	}
 	for i, subpool := range subpools {
@@ -139,6 +141,7 @@ func (p *TxPool) loop(head *types.Header, chain BlockChain) {
 					for _, subpool := range p.subpools {
 						subpool.Reset(oldHead, newHead)
 					}
+					p.expirePrivate(newHead)
 					resetDone <- newHead
 				}(oldHead, newHead)
 
@@ -339,6 +342,18 @@ func (p *TxPool) Locals() []common.Address {
 	return flat
 }
 
+// Priority retrieves the allowlist of senders and contracts whose transactions
+// are tracked in the priority lane of any subpool.
+func (p *TxPool) Priority() *PriorityList {
+	var list *PriorityList
+	for _, subpool := range p.subpools {
+		if prio := subpool.Priority(); !prio.Empty() {
+			list = list.Merge(prio)
+		}
+	}
+	return list
+}
+
 // Status returns the known status (unknown/pending/queued) of a transaction
 // identified by their hashes.
 func (p *TxPool) Status(hash common.Hash) TxStatus {
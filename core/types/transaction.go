@@ -410,6 +410,12 @@ func (tx *Transaction) Hash() common.Hash {
 	return h
 }
 
+// Time returns the time the transaction was first seen locally. It is used to
+// order transactions by arrival.
+func (tx *Transaction) Time() time.Time {
+	return tx.time
+}
+
 // Size returns the true encoded storage size of the transaction, either by encoding
 // and returning it, or returning a previously cached value.
 func (tx *Transaction) Size() uint64 {
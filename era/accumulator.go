@@ -0,0 +1,97 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// accumulatorDepth is the depth of the accumulator merkle tree, fitting
+// MaxEra1Size leaves.
+const accumulatorDepth = 13
+
+// ComputeAccumulator calculates the SSZ hash tree root of the Era1 accumulator
+// of header records, which is a list of (block hash, total difficulty) tuples
+// with a maximum length of MaxEra1Size.
+func ComputeAccumulator(hashes []common.Hash, tds []*big.Int) (common.Hash, error) {
+	if len(hashes) != len(tds) {
+		return common.Hash{}, errors.New("must have equal number hashes as td values")
+	}
+	if len(hashes) > MaxEra1Size {
+		return common.Hash{}, fmt.Errorf("too many records: have %d, max %d", len(hashes), MaxEra1Size)
+	}
+	leaves := make([][32]byte, len(hashes))
+	for i := range hashes {
+		leaves[i] = headerRecordRoot(hashes[i], tds[i])
+	}
+	root := merkleize(leaves, accumulatorDepth)
+
+	// Mix in the length of the list.
+	var length [32]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(hashes)))
+	return sha256.Sum256(append(root[:], length[:]...)), nil
+}
+
+// headerRecordRoot computes the hash tree root of a single header record,
+// which is the container of a block hash and a uint256 total difficulty.
+func headerRecordRoot(hash common.Hash, td *big.Int) [32]byte {
+	var buf [64]byte
+	copy(buf[:32], hash[:])
+	copy(buf[32:], bigToLE32(td))
+	return sha256.Sum256(buf[:])
+}
+
+// merkleize computes the root of a binary merkle tree of the given depth, with
+// the missing leaves filled with zero chunks.
+func merkleize(leaves [][32]byte, depth int) [32]byte {
+	var (
+		layer = leaves
+		zero  [32]byte // Root of an all-zero subtree of the current height
+	)
+	for i := 0; i < depth; i++ {
+		next := make([][32]byte, (len(layer)+1)/2)
+		for j := range next {
+			left, right := layer[2*j], zero
+			if 2*j+1 < len(layer) {
+				right = layer[2*j+1]
+			}
+			next[j] = sha256.Sum256(append(left[:], right[:]...))
+		}
+		zero = sha256.Sum256(append(zero[:], zero[:]...))
+		layer = next
+	}
+	if len(layer) == 0 {
+		return zero
+	}
+	return layer[0]
+}
+
+// bigToLE32 encodes the given integer as a 32 byte little endian value.
+func bigToLE32(n *big.Int) []byte {
+	b := make([]byte, 32)
+	be := n.Bytes()
+	for i := 0; i < len(be) && i < 32; i++ {
+		b[i] = be[len(be)-1-i]
+	}
+	return b
+}
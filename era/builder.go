@@ -0,0 +1,164 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/era/e2store"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Builder is used to create Era1 archives of block data.
+//
+// Blocks must be added in order, starting with the first block of the epoch.
+// Once all blocks are added, Finalize writes the accumulator and the block
+// index, completing the file:
+//
+//	b := era.NewBuilder(w)
+//	for _, block := range blocks {
+//		b.Add(block, receipts, td)
+//	}
+//	root, err := b.Finalize()
+type Builder struct {
+	w        *e2store.Writer
+	startNum *uint64
+	indexes  []uint64
+	hashes   []common.Hash
+	tds      []*big.Int
+	written  int
+}
+
+// NewBuilder returns a new Builder instance.
+func NewBuilder(w io.Writer) *Builder {
+	return &Builder{w: e2store.NewWriter(w)}
+}
+
+// Add writes a block, its receipts and the total difficulty of the chain up to
+// and including the block to the archive.
+func (b *Builder) Add(block *types.Block, receipts types.Receipts, td *big.Int) error {
+	header, err := rlp.EncodeToBytes(block.Header())
+	if err != nil {
+		return err
+	}
+	body, err := rlp.EncodeToBytes(block.Body())
+	if err != nil {
+		return err
+	}
+	if receipts == nil {
+		receipts = types.Receipts{}
+	}
+	rs, err := rlp.EncodeToBytes(receipts)
+	if err != nil {
+		return err
+	}
+	return b.AddRLP(header, body, rs, block.NumberU64(), block.Hash(), td)
+}
+
+// AddRLP writes an already RLP encoded block to the archive.
+func (b *Builder) AddRLP(header, body, receipts []byte, number uint64, hash common.Hash, td *big.Int) error {
+	// Write Era1 version entry before first block.
+	if b.startNum == nil {
+		n, err := b.w.Write(TypeVersion, nil)
+		if err != nil {
+			return err
+		}
+		b.startNum = &number
+		b.written += n
+	}
+	if len(b.indexes) >= MaxEra1Size {
+		return fmt.Errorf("exceeds maximum batch size of %d", MaxEra1Size)
+	}
+	if want := *b.startNum + uint64(len(b.indexes)); number != want {
+		return fmt.Errorf("non contiguous block: have %d, want %d", number, want)
+	}
+	b.indexes = append(b.indexes, uint64(b.written))
+	b.hashes = append(b.hashes, hash)
+	b.tds = append(b.tds, new(big.Int).Set(td))
+
+	// Write the compressed block data, followed by the total difficulty.
+	for _, entry := range []struct {
+		typ  uint16
+		data []byte
+	}{
+		{TypeCompressedHeader, header},
+		{TypeCompressedBody, body},
+		{TypeCompressedReceipts, receipts},
+	} {
+		blob, err := compress(entry.data)
+		if err != nil {
+			return err
+		}
+		n, err := b.w.Write(entry.typ, blob)
+		if err != nil {
+			return err
+		}
+		b.written += n
+	}
+	n, err := b.w.Write(TypeTotalDifficulty, bigToLE32(td))
+	if err != nil {
+		return err
+	}
+	b.written += n
+	return nil
+}
+
+// Finalize computes the accumulator and block index values, then writes the
+// corresponding e2store entries. The accumulator root is returned.
+func (b *Builder) Finalize() (common.Hash, error) {
+	if b.startNum == nil {
+		return common.Hash{}, errors.New("finalize called on empty builder")
+	}
+	// Compute accumulator root and write entry.
+	root, err := ComputeAccumulator(b.hashes, b.tds)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("error calculating accumulator root: %w", err)
+	}
+	n, err := b.w.Write(TypeAccumulator, root[:])
+	b.written += n
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("error writing accumulator: %w", err)
+	}
+	// Get beginning of index entry to calculate block relative offset.
+	base := int64(b.written)
+
+	// Construct block index. Detailed format described in package docs.
+	var (
+		count = len(b.indexes)
+		index = make([]byte, 16+count*8)
+	)
+	binary.LittleEndian.PutUint64(index, *b.startNum)
+	// Each offset is relative to the start of the index entry, so a block
+	// can be located without knowing the size of the file.
+	for i, offset := range b.indexes {
+		relative := int64(offset) - base
+		binary.LittleEndian.PutUint64(index[8+i*8:], uint64(relative))
+	}
+	binary.LittleEndian.PutUint64(index[8+count*8:], uint64(count))
+
+	// Finally, write the block index entry.
+	if _, err := b.w.Write(TypeBlockIndex, index); err != nil {
+		return common.Hash{}, fmt.Errorf("unable to write block index: %w", err)
+	}
+	return root, nil
+}
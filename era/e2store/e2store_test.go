@@ -0,0 +1,161 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package e2store
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestEncode(t *testing.T) {
+	for _, test := range []struct {
+		entries []Entry
+		want    string
+		name    string
+	}{
+		{
+			name:    "emptyEntry",
+			entries: []Entry{{0xffff, nil}},
+			want:    "ffff000000000000",
+		},
+		{
+			name:    "beef",
+			entries: []Entry{{42, common.Hex2Bytes("beef")}},
+			want:    "2a00020000000000beef",
+		},
+		{
+			name: "twoEntries",
+			entries: []Entry{
+				{42, common.Hex2Bytes("beef")},
+				{9, common.Hex2Bytes("abcdabcd")},
+			},
+			want: "2a00020000000000beef0900040000000000abcdabcd",
+		},
+	} {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var (
+				b = bytes.NewBuffer(nil)
+				w = NewWriter(b)
+			)
+			for _, e := range tt.entries {
+				if _, err := w.Write(e.Type, e.Value); err != nil {
+					t.Fatalf("encoding error: %v", err)
+				}
+			}
+			if want, have := common.FromHex(tt.want), b.Bytes(); !bytes.Equal(want, have) {
+				t.Fatalf("encoding mismatch (want %x, have %x", want, have)
+			}
+			r := NewReader(bytes.NewReader(b.Bytes()))
+			for _, want := range tt.entries {
+				have, err := r.Read()
+				if err != nil {
+					t.Fatalf("decoding error: %v", err)
+				}
+				if have.Type != want.Type {
+					t.Fatalf("decoded entry does type mismatch (want %v, got %v)", want.Type, have.Type)
+				}
+				if !bytes.Equal(have.Value, want.Value) {
+					t.Fatalf("decoded entry does not match (want %#x, got %#x)", want.Value, have.Value)
+				}
+			}
+			if _, err := r.Read(); err != io.EOF {
+				t.Fatalf("expected EOF after last entry, have %v", err)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for i, tt := range []struct {
+		have string
+		err  error
+	}{
+		{ // basic valid decoding
+			have: "ffff000000000000",
+		},
+		{ // basic invalid decoding
+			have: "ffff000000000001",
+			err:  errors.New("reserved bytes are non-zero"),
+		},
+		{ // no more entries to read, returns EOF
+			have: "",
+			err:  io.EOF,
+		},
+		{ // malformed type
+			have: "bad",
+			err:  io.ErrUnexpectedEOF,
+		},
+		{ // malformed length
+			have: "badbeef",
+			err:  io.ErrUnexpectedEOF,
+		},
+		{ // specified length longer than actual value
+			have: "beef010000000000",
+			err:  io.ErrUnexpectedEOF,
+		},
+	} {
+		r := NewReader(bytes.NewReader(common.FromHex(tt.have)))
+		if tt.err != nil {
+			_, err := r.Read()
+			if err == nil {
+				t.Fatalf("test %d: expected error, got none", i)
+			}
+			if err.Error() != tt.err.Error() {
+				t.Fatalf("test %d: error mismatch (want %v, have %v)", i, tt.err, err)
+			}
+			continue
+		}
+		if _, err := r.Read(); err != nil {
+			t.Fatalf("test %d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	var (
+		b = bytes.NewBuffer(nil)
+		w = NewWriter(b)
+	)
+	w.Write(1, []byte{0x01})
+	w.Write(2, []byte{0x02})
+	w.Write(1, []byte{0x03})
+
+	r := NewReader(bytes.NewReader(b.Bytes()))
+	e, err := r.Find(2)
+	if err != nil {
+		t.Fatalf("failed to find entry: %v", err)
+	}
+	if !bytes.Equal(e.Value, []byte{0x02}) {
+		t.Fatalf("found entry mismatch: have %x, want 02", e.Value)
+	}
+	if _, err := r.Find(3); err != io.EOF {
+		t.Fatalf("missing entry error mismatch: have %v, want %v", err, io.EOF)
+	}
+	all, err := r.FindAll(1)
+	if err != nil {
+		t.Fatalf("failed to find entries: %v", err)
+	}
+	if len(all) != 2 || all[0].Value[0] != 0x01 || all[1].Value[0] != 0x03 {
+		t.Fatalf("found entries mismatch: %v", all)
+	}
+}
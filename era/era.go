@@ -0,0 +1,374 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package era implements reading and writing Era1 history archives.
+//
+// An Era1 file is an e2store encoded archive of a fixed range of MaxEra1Size
+// consecutive blocks, holding their headers, bodies, receipts and total
+// difficulties, followed by an accumulator root committing to all the blocks
+// of the file and an index to look up individual blocks:
+//
+//	era1 := Version | block-tuple* | Accumulator | BlockIndex
+//	block-tuple := CompressedHeader | CompressedBody | CompressedReceipts | TotalDifficulty
+//	BlockIndex := starting-number | index | index | index ... | count
+//
+// Headers, bodies and receipts are stored RLP encoded and snappy compressed
+// using the framed format. The total difficulty is a 32 byte little endian
+// integer. The block index offsets are relative to the start of the index
+// entry.
+package era
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/era/e2store"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/snappy"
+)
+
+const (
+	TypeVersion            uint16 = 0x3265
+	TypeCompressedHeader   uint16 = 0x03
+	TypeCompressedBody     uint16 = 0x04
+	TypeCompressedReceipts uint16 = 0x05
+	TypeTotalDifficulty    uint16 = 0x06
+	TypeAccumulator        uint16 = 0x07
+	TypeBlockIndex         uint16 = 0x3266
+
+	// MaxEra1Size is the number of blocks stored in a single Era1 file.
+	MaxEra1Size = 8192
+
+	// headerSize is the size of the header of an e2store entry.
+	headerSize = 8
+)
+
+// Filename returns a recognizable Era1-formatted file name for the specified
+// epoch and network. The short accumulator root prefix makes sure files of
+// different chains or incomplete epochs are not confused.
+func Filename(network string, epoch int, root common.Hash) string {
+	return fmt.Sprintf("%s-%05d-%s.era1", network, epoch, root.Hex()[2:10])
+}
+
+// ReadDir reads the directory listing of the given network's Era1 files,
+// sorted by epoch. An error is returned if an epoch is missing from the list.
+func ReadDir(dir, network string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %w", dir, err)
+	}
+	var (
+		next    = uint64(0)
+		eras    []string
+		epochs  = make(map[string]uint64)
+		pattern = network + "-*-*.era1"
+	)
+	for _, entry := range entries {
+		if ok, _ := path.Match(pattern, entry.Name()); !ok || entry.IsDir() {
+			continue
+		}
+		parts := strings.Split(strings.TrimSuffix(entry.Name(), ".era1"), "-")
+		if len(parts) < 3 {
+			continue
+		}
+		epoch, err := strconv.ParseUint(parts[len(parts)-2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed era1 filename: %s", entry.Name())
+		}
+		epochs[entry.Name()] = epoch
+		eras = append(eras, entry.Name())
+	}
+	sort.Slice(eras, func(i, j int) bool {
+		return epochs[eras[i]] < epochs[eras[j]]
+	})
+	for i, name := range eras {
+		if i == 0 {
+			next = epochs[name]
+		}
+		if epochs[name] != next {
+			return nil, fmt.Errorf("missing epoch %d", next)
+		}
+		next++
+	}
+	return eras, nil
+}
+
+// ReadAtSeekCloser is the source an Era1 file is read from.
+type ReadAtSeekCloser interface {
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+// Era reads an Era1 file.
+type Era struct {
+	f ReadAtSeekCloser // backing era1 file
+	s *e2store.Reader  // e2store reader over f
+
+	start  uint64 // number of the first block in the file
+	count  uint64 // number of blocks in the file
+	index  int64  // offset of the block index entry
+	length int64  // total size of the file
+}
+
+// Open returns an Era backed by the given filename.
+func Open(filename string) (*Era, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	e, err := From(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", filepath.Base(filename), err)
+	}
+	return e, nil
+}
+
+// From returns an Era backed by f.
+func From(f ReadAtSeekCloser) (*Era, error) {
+	e := &Era{f: f, s: e2store.NewReader(f)}
+	if err := e.loadIndex(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Close closes the backing file.
+func (e *Era) Close() error {
+	return e.f.Close()
+}
+
+// Start returns the number of the first block in the file.
+func (e *Era) Start() uint64 {
+	return e.start
+}
+
+// Count returns the number of blocks in the file.
+func (e *Era) Count() uint64 {
+	return e.count
+}
+
+// loadIndex reads the block index at the end of the file and validates its
+// boundaries.
+func (e *Era) loadIndex() error {
+	length, err := e.f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	e.length = length
+
+	// The block count is stored in the last 8 bytes of the file.
+	b := make([]byte, 16)
+	if length < headerSize+16 {
+		return fmt.Errorf("file too short: %d bytes", length)
+	}
+	if _, err := e.f.ReadAt(b[:8], length-8); err != nil {
+		return err
+	}
+	e.count = binary.LittleEndian.Uint64(b[:8])
+	if e.count == 0 || e.count > MaxEra1Size {
+		return fmt.Errorf("invalid block count %d", e.count)
+	}
+	e.index = length - headerSize - int64(8*(e.count+2))
+	if e.index < 0 {
+		return fmt.Errorf("block index out of bounds")
+	}
+	typ, size, err := e.s.ReadMetadataAt(e.index)
+	if err != nil {
+		return err
+	}
+	if typ != TypeBlockIndex || int64(size) != int64(8*(e.count+2)) {
+		return fmt.Errorf("invalid block index entry")
+	}
+	if _, err := e.f.ReadAt(b[:8], e.index+headerSize); err != nil {
+		return err
+	}
+	e.start = binary.LittleEndian.Uint64(b[:8])
+	return nil
+}
+
+// headerOffset returns the offset of the header entry of the given block.
+func (e *Era) headerOffset(num uint64) (int64, error) {
+	if num < e.start || num >= e.start+e.count {
+		return 0, fmt.Errorf("out-of-bounds: %d not in [%d, %d)", num, e.start, e.start+e.count)
+	}
+	b := make([]byte, 8)
+	if _, err := e.f.ReadAt(b, e.index+headerSize+8+int64(num-e.start)*8); err != nil {
+		return 0, err
+	}
+	off := e.index + int64(binary.LittleEndian.Uint64(b))
+	if off < 0 || off >= e.index {
+		return 0, fmt.Errorf("invalid offset for block %d", num)
+	}
+	return off, nil
+}
+
+// readTuple reads the raw entries of the given block: the decompressed header,
+// body and receipts and the total difficulty.
+func (e *Era) readTuple(num uint64) (header, body, receipts []byte, td *big.Int, err error) {
+	off, err := e.headerOffset(num)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	blobs := make([][]byte, 3)
+	for i, typ := range []uint16{TypeCompressedHeader, TypeCompressedBody, TypeCompressedReceipts} {
+		r, n, err := e.s.ReaderAt(typ, off)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if blobs[i], err = io.ReadAll(snappy.NewReader(r)); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		off += int64(n)
+	}
+	r, _, err := e.s.ReaderAt(TypeTotalDifficulty, off)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	blob, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return blobs[0], blobs[1], blobs[2], leToBig(blob), nil
+}
+
+// GetRawBodyByNumber returns the RLP encoded body of the given block.
+func (e *Era) GetRawBodyByNumber(num uint64) ([]byte, error) {
+	_, body, _, _, err := e.readTuple(num)
+	return body, err
+}
+
+// GetRawReceiptsByNumber returns the RLP encoded receipts of the given block.
+func (e *Era) GetRawReceiptsByNumber(num uint64) ([]byte, error) {
+	_, _, receipts, _, err := e.readTuple(num)
+	return receipts, err
+}
+
+// GetBlockByNumber returns the block with the given number.
+func (e *Era) GetBlockByNumber(num uint64) (*types.Block, error) {
+	header, body, _, _, err := e.readTuple(num)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBlock(header, body)
+}
+
+// GetReceiptsByNumber returns the receipts of the given block. Only the
+// consensus fields of the receipts are stored in the archive.
+func (e *Era) GetReceiptsByNumber(num uint64) (types.Receipts, error) {
+	_, _, receipts, _, err := e.readTuple(num)
+	if err != nil {
+		return nil, err
+	}
+	return decodeReceipts(receipts)
+}
+
+// GetTotalDifficultyByNumber returns the total difficulty of the chain up to
+// and including the given block.
+func (e *Era) GetTotalDifficultyByNumber(num uint64) (*big.Int, error) {
+	_, _, _, td, err := e.readTuple(num)
+	return td, err
+}
+
+// InitialTD returns the total difficulty of the chain before the first block
+// of the file.
+func (e *Era) InitialTD() (*big.Int, error) {
+	header, _, _, td, err := e.readTuple(e.start)
+	if err != nil {
+		return nil, err
+	}
+	var h types.Header
+	if err := rlp.DecodeBytes(header, &h); err != nil {
+		return nil, err
+	}
+	return new(big.Int).Sub(td, h.Difficulty), nil
+}
+
+// Accumulator returns the accumulator root stored in the file.
+func (e *Era) Accumulator() (common.Hash, error) {
+	r, _, err := e.s.ReaderAt(TypeAccumulator, e.index-headerSize-common.HashLength)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	blob, err := io.ReadAll(r)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(blob) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("invalid accumulator length %d", len(blob))
+	}
+	return common.BytesToHash(blob), nil
+}
+
+// decodeBlock assembles a block from its RLP encoded header and body.
+func decodeBlock(header, body []byte) (*types.Block, error) {
+	var (
+		h types.Header
+		b types.Body
+	)
+	if err := rlp.DecodeBytes(header, &h); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if err := rlp.DecodeBytes(body, &b); err != nil {
+		return nil, fmt.Errorf("invalid body: %w", err)
+	}
+	return types.NewBlockWithHeader(&h).WithBody(b.Transactions, b.Uncles).WithWithdrawals(b.Withdrawals), nil
+}
+
+// decodeReceipts decodes a list of consensus encoded receipts.
+func decodeReceipts(blob []byte) (types.Receipts, error) {
+	var receipts types.Receipts
+	if err := rlp.DecodeBytes(blob, &receipts); err != nil {
+		return nil, fmt.Errorf("invalid receipts: %w", err)
+	}
+	return receipts, nil
+}
+
+// leToBig decodes a little endian integer.
+func leToBig(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+// compress snappy compresses the given data using the framed format.
+func compress(data []byte) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		w   = snappy.NewBufferedWriter(&buf)
+	)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// makeTestChain creates a chain of blocks carrying a transaction and a receipt
+// each, along with their total difficulties.
+func makeTestChain(start uint64, n int) ([]*types.Block, []types.Receipts, []*big.Int) {
+	var (
+		blocks   = make([]*types.Block, n)
+		receipts = make([]types.Receipts, n)
+		tds      = make([]*big.Int, n)
+		parent   common.Hash
+		td       = big.NewInt(int64(start))
+	)
+	for i := 0; i < n; i++ {
+		number := start + uint64(i)
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     new(big.Int).SetUint64(number),
+			Difficulty: big.NewInt(1),
+			GasLimit:   8_000_000,
+		}
+		tx := types.NewTransaction(number, common.Address{byte(i)}, big.NewInt(int64(i)), 21000, big.NewInt(1), nil)
+		receipt := &types.Receipt{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: 21000,
+			Logs:              []*types.Log{{Address: common.Address{byte(i)}, Data: []byte{byte(i)}}},
+		}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+		blocks[i] = types.NewBlock(header, []*types.Transaction{tx}, nil, []*types.Receipt{receipt}, trie.NewStackTrie(nil))
+		receipts[i] = types.Receipts{receipt}
+		tds[i] = new(big.Int).Add(td, big.NewInt(1))
+		td, parent = tds[i], blocks[i].Hash()
+	}
+	return blocks, receipts, tds
+}
+
+func TestEra1Builder(t *testing.T) {
+	var (
+		f, _                  = os.CreateTemp(t.TempDir(), "era1-test")
+		builder               = NewBuilder(f)
+		blocks, receipts, tds = makeTestChain(MaxEra1Size, 128)
+	)
+	for i := range blocks {
+		if err := builder.Add(blocks[i], receipts[i], tds[i]); err != nil {
+			t.Fatalf("error adding entry: %v", err)
+		}
+	}
+	// Finalize Era1 file.
+	root, err := builder.Finalize()
+	if err != nil {
+		t.Fatalf("error finalizing era1: %v", err)
+	}
+	f.Close()
+
+	e, err := Open(f.Name())
+	if err != nil {
+		t.Fatalf("failed to open era: %v", err)
+	}
+	defer e.Close()
+
+	if e.Start() != MaxEra1Size || e.Count() != uint64(len(blocks)) {
+		t.Fatalf("range mismatch: have [%d, +%d], want [%d, +%d]", e.Start(), e.Count(), MaxEra1Size, len(blocks))
+	}
+	if have, err := e.Accumulator(); err != nil || have != root {
+		t.Fatalf("accumulator mismatch: have %x (%v), want %x", have, err, root)
+	}
+	hashes := make([]common.Hash, len(blocks))
+	for i, block := range blocks {
+		hashes[i] = block.Hash()
+	}
+	if want, _ := ComputeAccumulator(hashes, tds); want != root {
+		t.Fatalf("recomputed accumulator mismatch: have %x, want %x", root, want)
+	}
+	if have, err := e.InitialTD(); err != nil || have.Cmp(big.NewInt(MaxEra1Size)) != 0 {
+		t.Fatalf("initial td mismatch: have %v (%v), want %d", have, err, MaxEra1Size)
+	}
+	// Check the blocks can be looked up directly.
+	for i, want := range blocks {
+		number := want.NumberU64()
+		block, err := e.GetBlockByNumber(number)
+		if err != nil {
+			t.Fatalf("error reading block %d: %v", number, err)
+		}
+		if block.Hash() != want.Hash() || block.Transactions()[0].Hash() != want.Transactions()[0].Hash() {
+			t.Fatalf("block %d mismatch", number)
+		}
+		rs, err := e.GetReceiptsByNumber(number)
+		if err != nil {
+			t.Fatalf("error reading receipts %d: %v", number, err)
+		}
+		if types.DeriveSha(rs, trie.NewStackTrie(nil)) != want.ReceiptHash() {
+			t.Fatalf("receipts %d mismatch", number)
+		}
+		td, err := e.GetTotalDifficultyByNumber(number)
+		if err != nil || td.Cmp(tds[i]) != 0 {
+			t.Fatalf("td %d mismatch: have %v (%v), want %v", number, td, err, tds[i])
+		}
+	}
+	if _, err := e.GetBlockByNumber(MaxEra1Size - 1); err == nil {
+		t.Fatalf("expected error reading block before range")
+	}
+	if _, err := e.GetBlockByNumber(MaxEra1Size + uint64(len(blocks))); err == nil {
+		t.Fatalf("expected error reading block after range")
+	}
+	// Check the iterator walks all blocks in order.
+	it := NewIterator(e)
+	for i := 0; it.Next(); i++ {
+		if it.Number() != blocks[i].NumberU64() || it.Block().Hash() != blocks[i].Hash() {
+			t.Fatalf("iterator block %d mismatch", i)
+		}
+		if len(it.Receipts()) != 1 || it.TotalDifficulty().Cmp(tds[i]) != 0 {
+			t.Fatalf("iterator block %d receipts or td mismatch", i)
+		}
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("iterator error: %v", err)
+	}
+	if it.Number() != blocks[len(blocks)-1].NumberU64() {
+		t.Fatalf("iterator stopped early at %d", it.Number())
+	}
+}
+
+func TestEra1BuilderLimits(t *testing.T) {
+	blocks, receipts, tds := makeTestChain(0, 2)
+
+	builder := NewBuilder(new(bytes.Buffer))
+	if _, err := builder.Finalize(); err == nil {
+		t.Fatalf("expected error finalizing empty builder")
+	}
+	if err := builder.Add(blocks[1], receipts[1], tds[1]); err != nil {
+		t.Fatalf("error adding entry: %v", err)
+	}
+	if err := builder.Add(blocks[0], receipts[0], tds[0]); err == nil {
+		t.Fatalf("expected error adding non contiguous block")
+	}
+}
+
+func TestAccumulator(t *testing.T) {
+	// Check the sparse merkleization against a naive full tree.
+	leaves := make([][32]byte, 5)
+	for i := range leaves {
+		leaves[i] = sha256.Sum256([]byte{byte(i)})
+	}
+	full := make([][32]byte, 16)
+	copy(full, leaves)
+	for len(full) > 1 {
+		next := make([][32]byte, len(full)/2)
+		for i := range next {
+			next[i] = sha256.Sum256(append(full[2*i][:], full[2*i+1][:]...))
+		}
+		full = next
+	}
+	if have := merkleize(leaves, 4); have != full[0] {
+		t.Fatalf("merkle root mismatch: have %x, want %x", have, full[0])
+	}
+	// Check the accumulator validates its input.
+	if _, err := ComputeAccumulator(make([]common.Hash, 2), make([]*big.Int, 1)); err == nil {
+		t.Fatalf("expected error on length mismatch")
+	}
+	if _, err := ComputeAccumulator(make([]common.Hash, MaxEra1Size+1), make([]*big.Int, MaxEra1Size+1)); err == nil {
+		t.Fatalf("expected error on too many records")
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		Filename("mainnet", 1, common.Hash{0x01}),
+		Filename("mainnet", 0, common.Hash{0x02}),
+		Filename("testnet", 0, common.Hash{0x03}),
+		"checksums.txt",
+	} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	have, err := ReadDir(dir, "mainnet")
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	want := []string{"mainnet-00000-02000000.era1", "mainnet-00001-01000000.era1"}
+	if len(have) != len(want) || have[0] != want[0] || have[1] != want[1] {
+		t.Fatalf("listing mismatch: have %v, want %v", have, want)
+	}
+	os.WriteFile(filepath.Join(dir, Filename("mainnet", 3, common.Hash{})), nil, 0644)
+	if _, err := ReadDir(dir, "mainnet"); err == nil {
+		t.Fatalf("expected error on missing epoch")
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Iterator walks over the blocks of an Era1 file in order.
+type Iterator struct {
+	e    *Era
+	next uint64
+
+	block    *types.Block
+	receipts types.Receipts
+	td       *big.Int
+	err      error
+}
+
+// NewIterator returns a new Iterator instance positioned before the first
+// block of the file.
+func NewIterator(e *Era) *Iterator {
+	return &Iterator{e: e, next: e.start}
+}
+
+// Next moves the iterator to the next block. It returns false when the end of
+// the file is reached or an error occurs.
+func (it *Iterator) Next() bool {
+	if it.err != nil || it.next >= it.e.start+it.e.count {
+		return false
+	}
+	header, body, receipts, td, err := it.e.readTuple(it.next)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	if it.block, it.err = decodeBlock(header, body); it.err != nil {
+		return false
+	}
+	if it.receipts, it.err = decodeReceipts(receipts); it.err != nil {
+		return false
+	}
+	it.td = td
+	it.next++
+	return true
+}
+
+// Number returns the number of the current block.
+func (it *Iterator) Number() uint64 {
+	return it.next - 1
+}
+
+// Block returns the current block.
+func (it *Iterator) Block() *types.Block {
+	return it.block
+}
+
+// Receipts returns the receipts of the current block.
+func (it *Iterator) Receipts() types.Receipts {
+	return it.receipts
+}
+
+// TotalDifficulty returns the total difficulty of the chain up to and
+// including the current block.
+func (it *Iterator) TotalDifficulty() *big.Int {
+	return it.td
+}
+
+// Error returns the error which stopped the iteration, if any.
+func (it *Iterator) Error() error {
+	return it.err
+}
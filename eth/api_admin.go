@@ -25,6 +25,7 @@ import (
 	"strings"
 
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -137,3 +138,50 @@ func (api *AdminAPI) ImportChain(file string) (bool, error) {
 	}
 	return true, nil
 }
+
+// Backup writes a consistent copy of the chain database into the given directory,
+// which must not exist yet, while the node keeps running. State which is still
+// held in memory is not included; a node restored from the backup recovers it
+// the same way as after a crash.
+func (api *AdminAPI) Backup(dir string) (*rawdb.BackupMeta, error) {
+	return rawdb.Backup(api.eth.ChainDb(), dir)
+}
+
+// DatabaseStatsResult is the storage used by every category of data, along with
+// its growth over the recorded incremental inspection passes.
+type DatabaseStatsResult struct {
+	Current *rawdb.DatabaseStats   `json:"current"`
+	History []*rawdb.DatabaseStats `json:"history"`
+	Growth  []rawdb.DatabaseGrowth `json:"growth"` // Growth since the oldest recorded pass
+}
+
+// DbStats returns the storage used by every category of data as gathered by the
+// incremental database inspection, along with the statistics of the recorded
+// inspection passes and the growth since the oldest one. If a non-zero limit is
+// given, the inspection is first advanced by at most that many entries.
+func (api *AdminAPI) DbStats(limit *int) (*DatabaseStatsResult, error) {
+	var (
+		db      = api.eth.ChainDb()
+		current *rawdb.DatabaseStats
+		err     error
+	)
+	switch {
+	case limit != nil && *limit < 0:
+		return nil, errors.New("negative inspection limit")
+	case limit != nil && *limit > 0:
+		current, err = rawdb.InspectDatabaseIncremental(db, *limit)
+	default:
+		current, err = rawdb.ReadDatabaseStats(db)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := &DatabaseStatsResult{
+		Current: current,
+		History: rawdb.ReadDatabaseStatsHistory(db),
+	}
+	if len(result.History) > 0 {
+		result.Growth = current.Growth(result.History[0])
+	}
+	return result, nil
+}
@@ -156,11 +156,23 @@ func (b *EthAPIBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumbe
 		}
 		return b.eth.blockchain.GetBlock(header.Hash(), header.Number.Uint64()), nil
 	}
-	return b.eth.blockchain.GetBlockByNumber(uint64(number)), nil
+	block := b.eth.blockchain.GetBlockByNumber(uint64(number))
+	if block == nil && b.eth.blockchain.HistoryPruned(uint64(number)) {
+		return nil, rawdb.ErrHistoryPruned
+	}
+	return block, nil
 }
 
 func (b *EthAPIBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
-	return b.eth.blockchain.GetBlockByHash(hash), nil
+	number := rawdb.ReadHeaderNumber(b.eth.chainDb, hash)
+	if number == nil {
+		return nil, nil
+	}
+	block := b.eth.blockchain.GetBlock(hash, *number)
+	if block == nil && b.eth.blockchain.HistoryPruned(*number) {
+		return nil, rawdb.ErrHistoryPruned
+	}
+	return block, nil
 }
 
 // GetBody returns body of a block. It does not resolve special block numbers.
@@ -171,6 +183,9 @@ func (b *EthAPIBackend) GetBody(ctx context.Context, hash common.Hash, number rp
 	if body := b.eth.blockchain.GetBody(hash); body != nil {
 		return body, nil
 	}
+	if b.eth.blockchain.HistoryPruned(uint64(number)) {
+		return nil, rawdb.ErrHistoryPruned
+	}
 	return nil, errors.New("block body not found")
 }
 
@@ -188,6 +203,9 @@ func (b *EthAPIBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash r
 		}
 		block := b.eth.blockchain.GetBlock(hash, header.Number.Uint64())
 		if block == nil {
+			if b.eth.blockchain.HistoryPruned(header.Number.Uint64()) {
+				return nil, rawdb.ErrHistoryPruned
+			}
 			return nil, errors.New("header found, but block body is missing")
 		}
 		return block, nil
@@ -242,11 +260,21 @@ func (b *EthAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockN
 }
 
 func (b *EthAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
-	return b.eth.blockchain.GetReceiptsByHash(hash), nil
+	receipts := b.eth.blockchain.GetReceiptsByHash(hash)
+	if receipts == nil {
+		if number := rawdb.ReadHeaderNumber(b.eth.chainDb, hash); number != nil && b.eth.blockchain.HistoryPruned(*number) {
+			return nil, rawdb.ErrHistoryPruned
+		}
+	}
+	return receipts, nil
 }
 
 func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash, number uint64) ([][]*types.Log, error) {
-	return rawdb.ReadLogs(b.eth.chainDb, hash, number, b.ChainConfig()), nil
+	logs := rawdb.ReadLogs(b.eth.chainDb, hash, number, b.ChainConfig())
+	if logs == nil && b.eth.blockchain.HistoryPruned(number) {
+		return nil, rawdb.ErrHistoryPruned
+	}
+	return logs, nil
 }
 
 func (b *EthAPIBackend) GetTd(ctx context.Context, hash common.Hash) *big.Int {
@@ -298,6 +326,27 @@ func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.eth.txPool.Add([]*txpool.Transaction{{Tx: signedTx}}, true, false)[0]
 }
 
+func (b *EthAPIBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction, maxBlock uint64) error {
+	return b.eth.txPool.AddPrivate(signedTx, maxBlock)
+}
+
+func (b *EthAPIBackend) PrivateTxStatus(hash common.Hash) *txpool.PrivateTxStatus {
+	return b.eth.txPool.PrivateStatus(hash)
+}
+
+func (b *EthAPIBackend) SendBundle(ctx context.Context, txs []*types.Transaction, minBlock, maxBlock uint64, revertingTxHashes []common.Hash) (common.Hash, error) {
+	bundle := &miner.Bundle{
+		Txs:               txs,
+		MinBlock:          minBlock,
+		MaxBlock:          maxBlock,
+		RevertingTxHashes: revertingTxHashes,
+	}
+	if err := b.eth.Miner().AddBundle(bundle); err != nil {
+		return common.Hash{}, err
+	}
+	return bundle.Hash(), nil
+}
+
 func (b *EthAPIBackend) GetPoolTransactions() (types.Transactions, error) {
 	pending := b.eth.txPool.Pending(false)
 	var txs types.Transactions
@@ -316,6 +365,11 @@ func (b *EthAPIBackend) GetPoolTransaction(hash common.Hash) *types.Transaction
 
 func (b *EthAPIBackend) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
 	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(b.eth.ChainDb(), txHash)
+	if tx == nil {
+		if number := rawdb.ReadTxLookupEntry(b.eth.ChainDb(), txHash); number != nil && b.eth.blockchain.HistoryPruned(*number) {
+			return nil, common.Hash{}, 0, 0, rawdb.ErrHistoryPruned
+		}
+	}
 	return tx, blockHash, blockNumber, index, nil
 }
 
@@ -392,6 +446,14 @@ func (b *EthAPIBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, sections
 }
 
+func (b *EthAPIBackend) AddressIndexStatus() (uint64, uint64) {
+	if b.eth.addressIndexer == nil {
+		return 0, 0
+	}
+	sections, _, _ := b.eth.addressIndexer.Sections()
+	return params.AddressIndexBlocks, sections
+}
+
 func (b *EthAPIBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	for i := 0; i < bloomFilterThreads; i++ {
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.eth.bloomRequests)
@@ -0,0 +1,88 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the transactions of pruned blocks are reported as pruned instead
+// of unknown.
+func TestGetTransactionPruned(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.HomesteadSigner{}
+	)
+	_, blocks, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 8, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(addr), common.Address{0x01}, big.NewInt(1), params.TxGas, block.BaseFee(), nil), signer, key)
+		block.AddTx(tx)
+	})
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create freezer db: %v", err)
+	}
+	defer db.Close()
+	rawdb.WriteAncientBlocks(db, append([]*types.Block{gspec.ToBlock()}, blocks...), append([]types.Receipts{{}}, receipts...), big.NewInt(0))
+	rawdb.IndexTransactions(db, 0, uint64(len(blocks))+1, nil)
+
+	chain, err := core.NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := rawdb.PruneHistory(db, 5); err != nil {
+		t.Fatalf("failed to prune history: %v", err)
+	}
+	backend := &EthAPIBackend{eth: &Ethereum{blockchain: chain, chainDb: db}}
+	for _, block := range blocks {
+		hash := block.Transactions()[0].Hash()
+		tx, _, _, _, err := backend.GetTransaction(context.Background(), hash)
+		if block.NumberU64() < 5 {
+			if tx != nil || !errors.Is(err, rawdb.ErrHistoryPruned) {
+				t.Errorf("block %d: pruned transaction mismatch: have %v, %v, want %v", block.NumberU64(), tx, err, rawdb.ErrHistoryPruned)
+			}
+			continue
+		}
+		if err != nil || tx == nil || tx.Hash() != hash {
+			t.Errorf("block %d: transaction mismatch: have %v, %v", block.NumberU64(), tx, err)
+		}
+	}
+	// Unknown transactions are still reported as missing
+	if tx, _, _, _, err := backend.GetTransaction(context.Background(), common.Hash{0x01}); tx != nil || err != nil {
+		t.Errorf("unknown transaction mismatch: have %v, %v", tx, err)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
@@ -430,3 +431,25 @@ func (api *DebugAPI) SetTrieFlushInterval(interval string) error {
 func (api *DebugAPI) GetTrieFlushInterval() string {
 	return api.eth.blockchain.GetTrieFlushInterval().String()
 }
+
+// ExecutionWitness re-executes the given block on top of its parent state and
+// returns the witness needed to execute it statelessly: the block itself, the
+// ancestor headers it depends on, and all the trie nodes and contract codes
+// accessed.
+//
+// Witnesses are produced on demand only, nothing is recorded while importing
+// blocks. The parent state of the block must still be available, which on a
+// node that isn't running in archive mode limits this to the recent blocks.
+func (api *DebugAPI) ExecutionWitness(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*stateless.Witness, error) {
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber {
+		return nil, errors.New("witness of the pending block is not available")
+	}
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %v not found", blockNrOrHash)
+	}
+	return api.eth.blockchain.ExecutionWitness(block)
+}
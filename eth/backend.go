@@ -43,6 +43,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/eth/protocols/precommit"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -74,6 +75,7 @@ type Ethereum struct {
 	handler            *handler
 	ethDialCandidates  enode.Iterator
 	snapDialCandidates enode.Iterator
+	tpcDialCandidates  enode.Iterator
 	merger             *consensus.Merger
 
 	// DB interfaces
@@ -85,6 +87,7 @@ type Ethereum struct {
 
 	bloomRequests     chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
+	addressIndexer    *core.ChainIndexer             // Address indexer operating during block imports (optional)
 	closeBloomHandler chan struct{}
 
 	APIBackend *EthAPIBackend
@@ -133,6 +136,21 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	scheme, err := rawdb.ParseStateScheme(config.StateScheme, chainDb)
+	if err != nil {
+		return nil, err
+	}
+	if scheme == rawdb.PathScheme && config.NoPruning {
+		return nil, errors.New("archive mode is not supported by the path-based state scheme")
+	}
+	verkle := config.StateTrie == "verkle"
+	if verkle {
+		if scheme != rawdb.HashScheme {
+			return nil, errors.New("verkle state is only supported by the hash-based state scheme")
+		}
+		log.Warn("Using experimental verkle state tree, snapshots are disabled")
+		config.SnapshotCache = 0
+	}
 	if err := pruner.RecoverPruning(stack.ResolvePath(""), chainDb); err != nil {
 		log.Error("Failed to recover state", "error", err)
 	}
@@ -191,6 +209,10 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
+			StateScheme:         scheme,
+			StateHistory:        config.StateHistory,
+			HistoryRetain:       config.HistoryRetain,
+			Verkle:              verkle,
 		}
 	)
 	// Override the chain config with provided settings.
@@ -207,6 +229,11 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
 
+	if config.AddressIndex {
+		eth.addressIndexer = core.NewAddressIndexer(chainDb, eth.blockchain.Config(), params.AddressIndexBlocks, params.AddressIndexConfirms)
+		eth.addressIndexer.Start(eth.blockchain)
+	}
+
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
@@ -228,6 +255,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		BloomCache:     uint64(cacheLimit),
 		EventMux:       eth.eventMux,
 		RequiredBlocks: config.RequiredBlocks,
+		PreCommits:     config.ThoraPreCommits,
 	}); err != nil {
 		return nil, err
 	}
@@ -255,6 +283,10 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	eth.tpcDialCandidates, err = dnsclient.NewIterator(eth.config.TpcDiscoveryURLs...)
+	if err != nil {
+		return nil, err
+	}
 
 	// Start the RPC service
 	eth.netRPCService = ethapi.NewNetAPI(eth.p2pServer, config.NetworkId)
@@ -535,6 +567,9 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 	if s.config.SnapshotCache > 0 {
 		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates)...)
 	}
+	if s.handler.thora != nil {
+		protos = append(protos, precommit.MakeProtocols((*precommitHandler)(s.handler), s.tpcDialCandidates)...)
+	}
 	return protos
 }
 
@@ -568,10 +603,14 @@ func (s *Ethereum) Stop() error {
 	// Stop all the peer-related stuff first.
 	s.ethDialCandidates.Close()
 	s.snapDialCandidates.Close()
+	s.tpcDialCandidates.Close()
 	s.handler.Stop()
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
+	if s.addressIndexer != nil {
+		s.addressIndexer.Close()
+	}
 	close(s.closeBloomHandler)
 	s.txPool.Close()
 	s.miner.Close()
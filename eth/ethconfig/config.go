@@ -69,6 +69,7 @@ var Defaults = Config{
 	TrieDirtyCache:     256,
 	TrieTimeout:        60 * time.Minute,
 	SnapshotCache:      102,
+	StateHistory:       params.FullImmutabilityThreshold,
 	FilterLogCacheSize: 32,
 	Miner:              miner.DefaultConfig,
 	TxPool:             legacypool.DefaultConfig,
@@ -76,6 +77,8 @@ var Defaults = Config{
 	RPCEVMTimeout:      5 * time.Second,
 	GPO:                FullNodeGPO,
 	RPCTxFeeCap:        1, // 1 ether
+	TraceCacheDBBlocks: 90000,
+	TraceFilterRange:   10000,
 }
 
 //go:generate go run github.com/fjl/gencodec -type Config -formats toml -out gen_config.go
@@ -94,17 +97,24 @@ type Config struct {
 	// for nodes to connect to.
 	EthDiscoveryURLs  []string
 	SnapDiscoveryURLs []string
+	TpcDiscoveryURLs  []string
 
 	NoPruning  bool // Whether to disable pruning and flush everything to disk
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	HistoryRetain uint64 `toml:",omitempty"` // The number of recent blocks whose bodies and receipts are retained (0 = all)
+	AddressIndex  bool   `toml:",omitempty"` // Whether to index the canonical transactions of each address
 
 	// RequiredBlocks is a set of block number -> hash mappings which must be in the
 	// canonical chain of all remote peers. Setting the option makes geth verify the
 	// presence of these blocks for every new peer connection.
 	RequiredBlocks map[uint64]common.Hash `toml:"-"`
 
+	// ThoraPreCommits enables the `tpc` protocol on Thora chains, over which the
+	// signers gossip pre-commits for freshly sealed blocks.
+	ThoraPreCommits bool `toml:",omitempty"`
+
 	// Light client options
 	LightServ        int  `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightIngress     int  `toml:",omitempty"` // Incoming bandwidth limit for light servers
@@ -130,9 +140,24 @@ type Config struct {
 	SnapshotCache  int
 	Preimages      bool
 
+	// State options
+	StateScheme  string `toml:",omitempty"` // State scheme used to store ethereum state and merkle trie nodes on top
+	StateHistory uint64 `toml:",omitempty"` // Number of recent blocks to retain state history for, path scheme only (0 = entire chain)
+	StateTrie    string `toml:",omitempty"` // Tree to store the state in, "mpt" or the experimental "verkle" (hash scheme only)
+
 	// This is the number of blocks for which logs will be cached in the filter system.
 	FilterLogCacheSize int
 
+	// Tracing result cache options
+	TraceCache         int      `toml:",omitempty"` // Number of block traces cached in memory, 0 disables the cache
+	TraceCacheDB       bool     `toml:",omitempty"` // Whether to persist the cached block traces into a separate database
+	TraceCacheDBBlocks uint64   `toml:",omitempty"` // Number of recent blocks whose traces are persisted, 0 retains all
+	TracePretrace      []string `toml:",omitempty"` // Tracers to run on new canonical blocks in the background
+
+	// TraceFilterRange is the maximum number of blocks a trace_filter request
+	// may span (0 = no limit).
+	TraceFilterRange uint64 `toml:",omitempty"`
+
 	// Mining options
 	Miner miner.Config
 
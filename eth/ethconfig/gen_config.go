@@ -21,10 +21,14 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SyncMode                downloader.SyncMode
 		EthDiscoveryURLs        []string
 		SnapDiscoveryURLs       []string
+		TpcDiscoveryURLs        []string
 		NoPruning               bool
 		NoPrefetch              bool
 		TxLookupLimit           uint64                 `toml:",omitempty"`
+		HistoryRetain           uint64                 `toml:",omitempty"`
+		AddressIndex            bool                   `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
+		ThoraPreCommits         bool                   `toml:",omitempty"`
 		LightServ               int                    `toml:",omitempty"`
 		LightIngress            int                    `toml:",omitempty"`
 		LightEgress             int                    `toml:",omitempty"`
@@ -43,7 +47,15 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TrieTimeout             time.Duration
 		SnapshotCache           int
 		Preimages               bool
+		StateScheme             string `toml:",omitempty"`
+		StateTrie               string `toml:",omitempty"`
+		StateHistory            uint64 `toml:",omitempty"`
 		FilterLogCacheSize      int
+		TraceCache              int      `toml:",omitempty"`
+		TraceCacheDB            bool     `toml:",omitempty"`
+		TraceCacheDBBlocks      uint64   `toml:",omitempty"`
+		TracePretrace           []string `toml:",omitempty"`
+		TraceFilterRange        uint64   `toml:",omitempty"`
 		Miner                   miner.Config
 		TxPool                  legacypool.Config
 		GPO                     gasprice.Config
@@ -61,10 +73,14 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SyncMode = c.SyncMode
 	enc.EthDiscoveryURLs = c.EthDiscoveryURLs
 	enc.SnapDiscoveryURLs = c.SnapDiscoveryURLs
+	enc.TpcDiscoveryURLs = c.TpcDiscoveryURLs
 	enc.NoPruning = c.NoPruning
 	enc.NoPrefetch = c.NoPrefetch
 	enc.TxLookupLimit = c.TxLookupLimit
+	enc.HistoryRetain = c.HistoryRetain
+	enc.AddressIndex = c.AddressIndex
 	enc.RequiredBlocks = c.RequiredBlocks
+	enc.ThoraPreCommits = c.ThoraPreCommits
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
 	enc.LightEgress = c.LightEgress
@@ -83,7 +99,15 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TrieTimeout = c.TrieTimeout
 	enc.SnapshotCache = c.SnapshotCache
 	enc.Preimages = c.Preimages
+	enc.StateScheme = c.StateScheme
+	enc.StateTrie = c.StateTrie
+	enc.StateHistory = c.StateHistory
 	enc.FilterLogCacheSize = c.FilterLogCacheSize
+	enc.TraceCache = c.TraceCache
+	enc.TraceCacheDB = c.TraceCacheDB
+	enc.TraceCacheDBBlocks = c.TraceCacheDBBlocks
+	enc.TracePretrace = c.TracePretrace
+	enc.TraceFilterRange = c.TraceFilterRange
 	enc.Miner = c.Miner
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
@@ -105,10 +129,14 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SyncMode                *downloader.SyncMode
 		EthDiscoveryURLs        []string
 		SnapDiscoveryURLs       []string
+		TpcDiscoveryURLs        []string
 		NoPruning               *bool
 		NoPrefetch              *bool
 		TxLookupLimit           *uint64                `toml:",omitempty"`
+		HistoryRetain           *uint64                `toml:",omitempty"`
+		AddressIndex            *bool                  `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
+		ThoraPreCommits         *bool                  `toml:",omitempty"`
 		LightServ               *int                   `toml:",omitempty"`
 		LightIngress            *int                   `toml:",omitempty"`
 		LightEgress             *int                   `toml:",omitempty"`
@@ -127,7 +155,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TrieTimeout             *time.Duration
 		SnapshotCache           *int
 		Preimages               *bool
+		StateScheme             *string `toml:",omitempty"`
+		StateTrie               *string `toml:",omitempty"`
+		StateHistory            *uint64 `toml:",omitempty"`
 		FilterLogCacheSize      *int
+		TraceCache              *int     `toml:",omitempty"`
+		TraceCacheDB            *bool    `toml:",omitempty"`
+		TraceCacheDBBlocks      *uint64  `toml:",omitempty"`
+		TracePretrace           []string `toml:",omitempty"`
+		TraceFilterRange        *uint64  `toml:",omitempty"`
 		Miner                   *miner.Config
 		TxPool                  *legacypool.Config
 		GPO                     *gasprice.Config
@@ -158,6 +194,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.SnapDiscoveryURLs != nil {
 		c.SnapDiscoveryURLs = dec.SnapDiscoveryURLs
 	}
+	if dec.TpcDiscoveryURLs != nil {
+		c.TpcDiscoveryURLs = dec.TpcDiscoveryURLs
+	}
 	if dec.NoPruning != nil {
 		c.NoPruning = *dec.NoPruning
 	}
@@ -167,9 +206,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TxLookupLimit != nil {
 		c.TxLookupLimit = *dec.TxLookupLimit
 	}
+	if dec.HistoryRetain != nil {
+		c.HistoryRetain = *dec.HistoryRetain
+	}
+	if dec.AddressIndex != nil {
+		c.AddressIndex = *dec.AddressIndex
+	}
 	if dec.RequiredBlocks != nil {
 		c.RequiredBlocks = dec.RequiredBlocks
 	}
+	if dec.ThoraPreCommits != nil {
+		c.ThoraPreCommits = *dec.ThoraPreCommits
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
@@ -224,9 +272,33 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.Preimages != nil {
 		c.Preimages = *dec.Preimages
 	}
+	if dec.StateScheme != nil {
+		c.StateScheme = *dec.StateScheme
+	}
+	if dec.StateTrie != nil {
+		c.StateTrie = *dec.StateTrie
+	}
+	if dec.StateHistory != nil {
+		c.StateHistory = *dec.StateHistory
+	}
 	if dec.FilterLogCacheSize != nil {
 		c.FilterLogCacheSize = *dec.FilterLogCacheSize
 	}
+	if dec.TraceCache != nil {
+		c.TraceCache = *dec.TraceCache
+	}
+	if dec.TraceCacheDB != nil {
+		c.TraceCacheDB = *dec.TraceCacheDB
+	}
+	if dec.TraceCacheDBBlocks != nil {
+		c.TraceCacheDBBlocks = *dec.TraceCacheDBBlocks
+	}
+	if dec.TracePretrace != nil {
+		c.TracePretrace = dec.TracePretrace
+	}
+	if dec.TraceFilterRange != nil {
+		c.TraceFilterRange = *dec.TraceFilterRange
+	}
 	if dec.Miner != nil {
 		c.Miner = *dec.Miner
 	}
@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -247,7 +248,15 @@ func (api *FilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
 }
 
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
-func (api *FilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
+//
+// If a non-negative fromBlock or a cursor is given, the matching historical logs
+// are replayed first, after which the subscription switches over to the live ones
+// without gaps or duplicates. Logs dropped by a chain reorg are delivered again
+// with the removed property set to true. The position of the last received log
+// can be passed as cursor to resume the subscription after a reconnect. If the
+// replay fails or falls too far behind the live logs, the subscription is ended
+// with an error and needs to be resumed the same way.
+func (api *FilterAPI) Logs(ctx context.Context, crit LogsCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -258,19 +267,66 @@ func (api *FilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subsc
 		matchedLogs = make(chan []*types.Log)
 	)
 
-	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery(crit), matchedLogs)
+	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery(crit.FilterCriteria), matchedLogs)
+	if err != nil {
+		return nil, err
+	}
+	// Replay the historical logs only after the live subscription is installed,
+	// so no logs are missed in between.
+	replay, err := newLogsReplay(api.sys, crit)
 	if err != nil {
+		logsSub.Unsubscribe()
 		return nil, err
 	}
+	var (
+		replayLogs chan []*types.Log
+		replayDone chan error
+		cancel     = func() {}
+	)
+	if replay != nil {
+		var replayCtx context.Context
+		replayCtx, cancel = context.WithCancel(context.Background())
 
+		replayLogs, replayDone = make(chan []*types.Log), make(chan error, 1)
+		go func() { replayDone <- replay.run(replayCtx, replayLogs) }()
+	}
+	notify := func(logs []*types.Log) {
+		for _, log := range logs {
+			log := log
+			notifier.Notify(rpcSub.ID, &log)
+		}
+	}
 	go func() {
+		defer cancel()
 		for {
 			select {
 			case logs := <-matchedLogs:
-				for _, log := range logs {
-					log := log
-					notifier.Notify(rpcSub.ID, &log)
+				if replayDone != nil {
+					// Give up on the subscription if the replay can't keep up,
+					// as dropping live logs would leave a gap
+					if err := replay.buffer(logs); err != nil {
+						log.Warn("Terminating logs subscription", "id", rpcSub.ID, "err", err)
+						notifier.Terminate(rpcSub.ID, err)
+						logsSub.Unsubscribe()
+						return
+					}
+					continue
+				}
+				notify(logs)
+			case logs := <-replayLogs:
+				replay.track(logs)
+				notify(logs)
+			case err := <-replayDone:
+				// Delivering the live logs after a failed replay would leave a
+				// gap, so end the subscription for the client to resume it
+				if err != nil {
+					log.Warn("Failed to replay historical logs", "id", rpcSub.ID, "err", err)
+					notifier.Terminate(rpcSub.ID, fmt.Errorf("log replay failed: %w", err))
+					logsSub.Unsubscribe()
+					return
 				}
+				notify(replay.flush())
+				replayLogs, replayDone = nil, nil
 			case <-rpcSub.Err(): // client send an unsubscribe request
 				logsSub.Unsubscribe()
 				return
@@ -44,6 +44,8 @@ import (
 type Config struct {
 	LogCacheSize int           // maximum number of cached blocks (default: 32)
 	Timeout      time.Duration // how long filters stay active (default: 5min)
+	ReplayRange  uint64        // maximum number of blocks replayed into a logs subscription (default: 100000)
+	ReplayBuffer int           // maximum number of live logs held back during a replay (default: 10000)
 }
 
 func (cfg Config) withDefaults() Config {
@@ -53,6 +55,12 @@ func (cfg Config) withDefaults() Config {
 	if cfg.LogCacheSize == 0 {
 		cfg.LogCacheSize = 32
 	}
+	if cfg.ReplayRange == 0 {
+		cfg.ReplayRange = 100000
+	}
+	if cfg.ReplayBuffer == 0 {
+		cfg.ReplayBuffer = 10000
+	}
 	return cfg
 }
 
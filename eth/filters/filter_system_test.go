@@ -24,11 +24,13 @@ import (
 	"math/rand"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
@@ -52,6 +54,7 @@ type testBackend struct {
 	chainFeed       event.Feed
 	pendingBlock    *types.Block
 	pendingReceipts types.Receipts
+	logsErr         error // Error returned by GetLogs if set
 }
 
 func (b *testBackend) ChainConfig() *params.ChainConfig {
@@ -121,6 +124,9 @@ func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.
 }
 
 func (b *testBackend) GetLogs(ctx context.Context, hash common.Hash, number uint64) ([][]*types.Log, error) {
+	if b.logsErr != nil {
+		return nil, b.logsErr
+	}
 	logs := rawdb.ReadLogs(b.db, hash, number, params.TestChainConfig)
 	return logs, nil
 }
@@ -565,6 +571,296 @@ func TestLogFilter(t *testing.T) {
 	}
 }
 
+// TestLogsSubscriptionReplay tests that a logs subscription with a starting
+// block replays the historical logs before switching over to the live ones,
+// and that it can be resumed from a cursor.
+func TestLogsSubscriptionReplay(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db           = rawdb.NewMemoryDatabase()
+		backend, sys = newTestFilterSystem(t, db, Config{})
+		addr         = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		gspec        = &core.Genesis{
+			Config:  params.TestChainConfig,
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+	)
+	_, chain, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 10, func(i int, gen *core.BlockGen) {
+		if i%3 == 1 {
+			gen.AddUncheckedReceipt(makeReceipt(addr))
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x999"), big.NewInt(999), 999, gen.BaseFee(), nil))
+		}
+	})
+	gspec.MustCommit(db)
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", NewFilterAPI(sys, false)); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	// Blocks 2, 5 and 8 contain a log each
+	history := []*types.Block{chain[1], chain[4], chain[7]}
+	expect := func(t *testing.T, ch chan types.Log, block *types.Block, removed bool) types.Log {
+		t.Helper()
+		select {
+		case log := <-ch:
+			if log.BlockHash != block.Hash() || log.Removed != removed {
+				t.Fatalf("unexpected log: have block %d (%x) removed %v, want block %d (%x) removed %v",
+					log.BlockNumber, log.BlockHash, log.Removed, block.NumberU64(), block.Hash(), removed)
+			}
+			return log
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for log of block %d", block.NumberU64())
+		}
+		return types.Log{}
+	}
+	// Replay the history, then deliver live logs and reorged logs
+	ch := make(chan types.Log, 16)
+	sub, err := client.EthSubscribe(context.Background(), ch, "logs", map[string]interface{}{
+		"fromBlock": "0x0",
+		"address":   addr,
+	})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	live := &types.Log{Address: addr, Topics: []common.Hash{}, BlockNumber: 11, BlockHash: common.Hash{0x11}}
+	backend.logsFeed.Send([]*types.Log{live})
+	backend.rmLogsFeed.Send(core.RemovedLogsEvent{Logs: []*types.Log{{
+		Address:     addr,
+		Topics:      []common.Hash{},
+		BlockNumber: chain[7].NumberU64(),
+		BlockHash:   chain[7].Hash(),
+		Removed:     true,
+	}}})
+	var cursor types.Log
+	for _, block := range history {
+		cursor = expect(t, ch, block, false)
+	}
+	select {
+	case log := <-ch:
+		if log.BlockNumber != live.BlockNumber || log.BlockHash != live.BlockHash {
+			t.Fatalf("unexpected live log: have block %d, want %d", log.BlockNumber, live.BlockNumber)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for live log")
+	}
+	expect(t, ch, chain[7], true)
+	sub.Unsubscribe()
+
+	// Resume from the middle of the history
+	cursor = types.Log{BlockNumber: history[1].NumberU64(), BlockHash: history[1].Hash()}
+	ch = make(chan types.Log, 16)
+	sub, err = client.EthSubscribe(context.Background(), ch, "logs", map[string]interface{}{
+		"address": addr,
+		"cursor": map[string]interface{}{
+			"blockNumber": hexutil.Uint64(cursor.BlockNumber),
+			"blockHash":   cursor.BlockHash,
+			"logIndex":    hexutil.Uint(cursor.Index),
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	expect(t, ch, history[2], false)
+	select {
+	case log := <-ch:
+		t.Fatalf("unexpected log of block %d", log.BlockNumber)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestLogsSubscriptionReplayFailure tests that a logs subscription whose replay
+// fails is ended with an error instead of delivering the live logs with a gap.
+func TestLogsSubscriptionReplayFailure(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db           = rawdb.NewMemoryDatabase()
+		backend, sys = newTestFilterSystem(t, db, Config{})
+		addr         = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		gspec        = &core.Genesis{
+			Config:  params.TestChainConfig,
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+	)
+	_, chain, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 4, func(i int, gen *core.BlockGen) {
+		gen.AddUncheckedReceipt(makeReceipt(addr))
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x999"), big.NewInt(999), 999, gen.BaseFee(), nil))
+	})
+	gspec.MustCommit(db)
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	backend.logsErr = errors.New("logs unavailable")
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", NewFilterAPI(sys, false)); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	ch := make(chan types.Log, 16)
+	sub, err := client.EthSubscribe(context.Background(), ch, "logs", map[string]interface{}{
+		"fromBlock": "0x0",
+		"address":   addr,
+	})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	backend.logsFeed.Send([]*types.Log{{Address: addr, Topics: []common.Hash{}, BlockNumber: 5, BlockHash: common.Hash{0x05}}})
+	select {
+	case log := <-ch:
+		t.Fatalf("unexpected log of block %d", log.BlockNumber)
+	case err := <-sub.Err():
+		if err == nil || !strings.Contains(err.Error(), "log replay failed") {
+			t.Fatalf("subscription error mismatch: have %v, want log replay failure", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the subscription to fail")
+	}
+}
+
+// TestLogsSubscriptionReplayLimits tests that replays spanning too many blocks
+// are rejected, and that the live logs held back during a replay are capped.
+func TestLogsSubscriptionReplayLimits(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		_, sys = newTestFilterSystem(t, db, Config{ReplayRange: 5, ReplayBuffer: 2})
+		gspec  = &core.Genesis{Config: params.TestChainConfig, BaseFee: big.NewInt(params.InitialBaseFee)}
+	)
+	_, chain, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 10, nil)
+	gspec.MustCommit(db)
+	for _, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+	}
+	if _, err := newLogsReplay(sys, LogsCriteria{FilterCriteria: FilterCriteria{FromBlock: big.NewInt(5)}}); !errors.Is(err, errReplayRangeTooLarge) {
+		t.Fatalf("replay range error mismatch: have %v, want %v", err, errReplayRangeTooLarge)
+	}
+	replay, err := newLogsReplay(sys, LogsCriteria{FilterCriteria: FilterCriteria{FromBlock: big.NewInt(6)}})
+	if err != nil {
+		t.Fatalf("failed to create replay: %v", err)
+	}
+	if err := replay.buffer([]*types.Log{{BlockNumber: 11}, {BlockNumber: 11}}); err != nil {
+		t.Fatalf("failed to buffer logs: %v", err)
+	}
+	if err := replay.buffer([]*types.Log{{BlockNumber: 12}}); !errors.Is(err, errReplayBufferFull) {
+		t.Fatalf("replay buffer error mismatch: have %v, want %v", err, errReplayBufferFull)
+	}
+}
+
+// TestNewBlocksSubscription tests that the block subscription delivers the new
+// canonical blocks with their receipts, and reports reorged blocks as reverted.
+func TestNewBlocksSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db           = rawdb.NewMemoryDatabase()
+		backend, sys = newTestFilterSystem(t, db, Config{})
+		addr         = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		gspec        = &core.Genesis{
+			Config:  params.TestChainConfig,
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		generate = func(i int, gen *core.BlockGen) {
+			gen.AddUncheckedReceipt(makeReceipt(addr))
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x999"), big.NewInt(999), 999, gen.BaseFee(), nil))
+		}
+	)
+	genDb, chain, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 6, generate)
+	forkChain, forkReceipts := core.GenerateChain(gspec.Config, chain[3], ethash.NewFaker(), genDb, 3, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{0x01})
+		generate(i, gen)
+	})
+	gspec.MustCommit(db)
+
+	insert := func(blocks []*types.Block, receipts []types.Receipts) {
+		for i, block := range blocks {
+			rawdb.WriteBlock(db, block)
+			rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+			rawdb.WriteHeadBlockHash(db, block.Hash())
+			rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+		}
+		head := blocks[len(blocks)-1]
+		backend.chainFeed.Send(core.ChainEvent{Block: head, Hash: head.Hash()})
+	}
+	insert(chain[:5], receipts[:5])
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", NewFilterAPI(sys, false)); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	type update struct {
+		Type  string `json:"type"`
+		Block struct {
+			Hash         common.Hash   `json:"hash"`
+			Transactions []interface{} `json:"transactions"`
+		} `json:"block"`
+		Receipts []*types.Receipt `json:"receipts"`
+	}
+	ch := make(chan update)
+	sub, err := client.EthSubscribe(context.Background(), ch, "newBlocks")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	expect := func(typ string, block *types.Block) {
+		t.Helper()
+		select {
+		case have := <-ch:
+			if have.Type != typ || have.Block.Hash != block.Hash() {
+				t.Fatalf("have %s %x, want %s %x", have.Type, have.Block.Hash, typ, block.Hash())
+			}
+			if len(have.Block.Transactions) != 1 || len(have.Receipts) != 1 {
+				t.Fatalf("have %d transactions and %d receipts, want 1", len(have.Block.Transactions), len(have.Receipts))
+			}
+			if have.Receipts[0].BlockHash != block.Hash() {
+				t.Fatalf("receipt of block %x, want %x", have.Receipts[0].BlockHash, block.Hash())
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for %s of block %d", typ, block.NumberU64())
+		}
+	}
+	// Extend the chain, then reorg it onto a longer side chain
+	insert(chain[5:], receipts[5:])
+	expect(BlockApply, chain[5])
+
+	insert(forkChain, forkReceipts)
+	expect(BlockRevert, chain[5])
+	expect(BlockRevert, chain[4])
+	for _, block := range forkChain {
+		expect(BlockApply, block)
+	}
+}
+
 // TestPendingLogsSubscription tests if a subscription receives the correct pending logs that are posted to the event feed.
 func TestPendingLogsSubscription(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,217 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// replayBatchSize is the number of blocks whose logs are retrieved at once
+	// when replaying historical logs into a subscription.
+	replayBatchSize = 1000
+
+	// replayReorgWindow is the depth below the replay head within which live
+	// logs received during a replay are reconciled with the replayed ones.
+	replayReorgWindow = 128
+)
+
+var (
+	// errReplayRangeTooLarge is returned if a logs subscription asks for more
+	// historical blocks than allowed to be replayed.
+	errReplayRangeTooLarge = errors.New("replay range too large")
+
+	// errReplayBufferFull is returned if more live logs arrived during a replay
+	// than can be held back.
+	errReplayBufferFull = errors.New("replay buffer full")
+)
+
+// LogCursor is the position of the last log a subscriber received, used to
+// resume a logs subscription after a reconnect without gaps or duplicates.
+// Every delivered log carries its own cursor in its blockNumber, blockHash
+// and logIndex fields.
+type LogCursor struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	LogIndex    hexutil.Uint   `json:"logIndex"`
+}
+
+// LogsCriteria represents the arguments of a logs subscription. If a fromBlock
+// or a cursor is given, the historical logs are replayed before the live ones.
+type LogsCriteria struct {
+	FilterCriteria
+	Cursor *LogCursor `json:"cursor"`
+}
+
+// UnmarshalJSON sets *args fields with given data.
+func (args *LogsCriteria) UnmarshalJSON(data []byte) error {
+	if err := args.FilterCriteria.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	var raw struct {
+		Cursor *LogCursor `json:"cursor"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Cursor != nil && args.BlockHash != nil {
+		return errors.New("cannot specify both BlockHash and Cursor")
+	}
+	args.Cursor = raw.Cursor
+	return nil
+}
+
+// logsReplay retrieves the historical logs of a subscription, and reconciles
+// them with the live logs received in the meantime.
+type logsReplay struct {
+	sys    *FilterSystem
+	crit   FilterCriteria
+	cursor *LogCursor
+	begin  uint64
+	end    uint64
+
+	replayed map[common.Hash]struct{} // Blocks replayed within the reorg window
+	buffered []*types.Log             // Live logs received during the replay
+}
+
+// newLogsReplay creates the replay of the historical logs matching the given
+// criteria, up to the current head. It returns nil if there is nothing to
+// replay. It must be called after the live subscription was installed, so no
+// logs fall between the two.
+func newLogsReplay(sys *FilterSystem, crit LogsCriteria) (*logsReplay, error) {
+	header := sys.backend.CurrentHeader()
+	if header == nil {
+		return nil, nil
+	}
+	var (
+		head  = header.Number.Uint64()
+		begin uint64
+	)
+	switch {
+	case crit.Cursor != nil:
+		begin = uint64(crit.Cursor.BlockNumber)
+	case crit.FromBlock != nil && crit.FromBlock.Sign() >= 0:
+		begin = crit.FromBlock.Uint64()
+	default:
+		return nil, nil
+	}
+	end := head
+	if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 && crit.ToBlock.Uint64() < end {
+		end = crit.ToBlock.Uint64()
+	}
+	if begin > end {
+		return nil, nil
+	}
+	if end-begin >= sys.cfg.ReplayRange {
+		return nil, fmt.Errorf("%w: %d blocks, limit %d", errReplayRangeTooLarge, end-begin+1, sys.cfg.ReplayRange)
+	}
+	return &logsReplay{
+		sys:      sys,
+		crit:     crit.FilterCriteria,
+		cursor:   crit.Cursor,
+		begin:    begin,
+		end:      end,
+		replayed: make(map[common.Hash]struct{}),
+	}, nil
+}
+
+// run retrieves the historical logs in batches and delivers them on the given
+// channel until the replay is done or the context is cancelled.
+func (r *logsReplay) run(ctx context.Context, out chan<- []*types.Log) error {
+	for from := r.begin; from <= r.end; from += replayBatchSize {
+		to := from + replayBatchSize - 1
+		if to > r.end {
+			to = r.end
+		}
+		logs, err := r.sys.NewRangeFilter(int64(from), int64(to), r.crit.Addresses, r.crit.Topics).Logs(ctx)
+		if err != nil {
+			return err
+		}
+		if from == r.begin && r.cursor != nil {
+			logs = r.skipDelivered(logs)
+		}
+		if len(logs) == 0 {
+			continue
+		}
+		select {
+		case out <- logs:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// skipDelivered drops the logs up to and including the cursor. If the cursor
+// block was reorged out, all logs of the new canonical block are kept.
+func (r *logsReplay) skipDelivered(logs []*types.Log) []*types.Log {
+	kept := logs[:0]
+	for _, log := range logs {
+		if log.BlockHash == r.cursor.BlockHash && log.Index <= uint(r.cursor.LogIndex) {
+			continue
+		}
+		kept = append(kept, log)
+	}
+	return kept
+}
+
+// track records the blocks of replayed logs, which live logs may duplicate.
+func (r *logsReplay) track(logs []*types.Log) {
+	for _, log := range logs {
+		if log.BlockNumber+replayReorgWindow > r.end {
+			r.replayed[log.BlockHash] = struct{}{}
+		}
+	}
+}
+
+// buffer holds back live logs until the replay is done. It fails if the replay
+// falls too far behind the live logs.
+func (r *logsReplay) buffer(logs []*types.Log) error {
+	if len(r.buffered)+len(logs) > r.sys.cfg.ReplayBuffer {
+		return fmt.Errorf("%w: %d logs", errReplayBufferFull, r.sys.cfg.ReplayBuffer)
+	}
+	r.buffered = append(r.buffered, logs...)
+	return nil
+}
+
+// flush returns the live logs received during the replay which it did not
+// already cover: logs of new blocks, logs of blocks which replaced replayed
+// ones and the removal of replayed logs.
+func (r *logsReplay) flush() []*types.Log {
+	var logs []*types.Log
+	for _, log := range r.buffered {
+		if log.BlockNumber > r.end {
+			logs = append(logs, log)
+			continue
+		}
+		if log.BlockNumber+replayReorgWindow <= r.end {
+			continue
+		}
+		if _, ok := r.replayed[log.BlockHash]; ok == log.Removed {
+			logs = append(logs, log)
+		}
+	}
+	r.buffered = nil
+	return logs
+}
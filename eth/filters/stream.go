@@ -0,0 +1,254 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// BlockApply marks a block which became part of the canonical chain.
+	BlockApply = "apply"
+
+	// BlockRevert marks a previously applied block which was reorged out of
+	// the canonical chain.
+	BlockRevert = "revert"
+)
+
+// BlockUpdate is a notification of the newBlocks subscription.
+type BlockUpdate struct {
+	Type     string                 `json:"type"`
+	Block    map[string]interface{} `json:"block"`
+	Receipts types.Receipts         `json:"receipts"`
+}
+
+// blockStream tracks the canonical chain as seen by a single subscriber. New
+// heads are coalesced, so a slow subscriber lags behind and catches up with
+// the chain instead of being dropped or stalling the event system.
+type blockStream struct {
+	sys  *FilterSystem
+	last *types.Header // Last head delivered to the subscriber
+
+	head   *types.Header // Latest head announced by the chain
+	lock   sync.Mutex
+	update chan struct{}
+}
+
+// newBlockStream creates a block stream starting after the given head.
+func newBlockStream(sys *FilterSystem, head *types.Header) *blockStream {
+	return &blockStream{
+		sys:    sys,
+		last:   head,
+		update: make(chan struct{}, 1),
+	}
+}
+
+// announce records a new chain head, superseding any not yet processed one.
+func (s *blockStream) announce(head *types.Header) {
+	s.lock.Lock()
+	s.head = head
+	s.lock.Unlock()
+
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+}
+
+// latest returns the most recently announced chain head.
+func (s *blockStream) latest() *types.Header {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.head
+}
+
+// advance delivers the blocks reverted and applied between the last delivered
+// head and the given one. Reverted blocks are delivered first, newest first,
+// followed by the applied ones in chain order.
+func (s *blockStream) advance(ctx context.Context, head *types.Header, deliver func(*BlockUpdate) error) error {
+	var (
+		backend = s.sys.backend
+		reverts []*types.Header
+		applies []*types.Header
+		oldHead = s.last
+		newHead = head
+		err     error
+	)
+	parent := func(header *types.Header) (*types.Header, error) {
+		parent, err := backend.HeaderByHash(ctx, header.ParentHash)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			return nil, fmt.Errorf("missing parent of block #%d [%x]", header.Number, header.Hash())
+		}
+		return parent, nil
+	}
+	// Find the common ancestor of the old and new heads
+	for newHead.Number.Cmp(oldHead.Number) > 0 {
+		applies = append(applies, newHead)
+		if newHead, err = parent(newHead); err != nil {
+			return err
+		}
+	}
+	for oldHead.Number.Cmp(newHead.Number) > 0 {
+		reverts = append(reverts, oldHead)
+		if oldHead, err = parent(oldHead); err != nil {
+			return err
+		}
+	}
+	for oldHead.Hash() != newHead.Hash() {
+		reverts = append(reverts, oldHead)
+		applies = append(applies, newHead)
+		if oldHead, err = parent(oldHead); err != nil {
+			return err
+		}
+		if newHead, err = parent(newHead); err != nil {
+			return err
+		}
+	}
+	// Deliver the chain transition, tracking progress so a failure midway
+	// resumes from the last delivered block
+	for _, header := range reverts {
+		update, err := s.makeUpdate(ctx, BlockRevert, header)
+		if err != nil {
+			return err
+		}
+		if err := deliver(update); err != nil {
+			return err
+		}
+		if s.last, err = parent(header); err != nil {
+			return err
+		}
+	}
+	for i := len(applies) - 1; i >= 0; i-- {
+		update, err := s.makeUpdate(ctx, BlockApply, applies[i])
+		if err != nil {
+			return err
+		}
+		if err := deliver(update); err != nil {
+			return err
+		}
+		s.last = applies[i]
+	}
+	return nil
+}
+
+// makeUpdate assembles the notification for the block with the given header.
+func (s *blockStream) makeUpdate(ctx context.Context, typ string, header *types.Header) (*BlockUpdate, error) {
+	var (
+		backend = s.sys.backend
+		hash    = header.Hash()
+	)
+	body, err := backend.GetBody(ctx, hash, rpc.BlockNumber(header.Number.Int64()))
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := backend.GetReceipts(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) != len(body.Transactions) {
+		return nil, fmt.Errorf("receipts length mismatch: %d receipts, %d transactions", len(receipts), len(body.Transactions))
+	}
+	if receipts == nil {
+		receipts = types.Receipts{}
+	}
+	block := types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles)
+	return &BlockUpdate{
+		Type:     typ,
+		Block:    ethapi.RPCMarshalBlock(block, true, true, backend.ChainConfig()),
+		Receipts: receipts,
+	}, nil
+}
+
+// NewBlocks sends a notification with the full block and its receipts each time
+// a block is added to the canonical chain. On a chain reorg, the blocks removed
+// from the canonical chain are first reported as reverted, followed by the new
+// canonical blocks as applied. Subscribers which cannot keep up are not dropped,
+// they receive the pending chain transitions as fast as they consume them.
+func (api *FilterAPI) NewBlocks(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	head := api.sys.backend.CurrentHeader()
+	if head == nil {
+		return nil, errors.New("current header not available")
+	}
+	var (
+		rpcSub     = notifier.CreateSubscription()
+		stream     = newBlockStream(api.sys, head)
+		headers    = make(chan *types.Header)
+		headersSub = api.events.SubscribeNewHeads(headers)
+		quit       = make(chan struct{})
+	)
+	// Keep consuming chain events so the event system is never blocked
+	go func() {
+		defer close(quit)
+		for {
+			select {
+			case h := <-headers:
+				stream.announce(h)
+			case <-rpcSub.Err():
+				headersSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				headersSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+	// Deliver the chain transitions at the pace of the subscriber
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		deliver := func(update *BlockUpdate) error {
+			select {
+			case <-quit:
+				return context.Canceled
+			default:
+			}
+			return notifier.Notify(rpcSub.ID, update)
+		}
+		for {
+			select {
+			case <-stream.update:
+				head := stream.latest()
+				if err := stream.advance(ctx, head, deliver); err != nil {
+					if errors.Is(err, context.Canceled) {
+						return
+					}
+					log.Warn("Failed to stream blocks", "id", rpcSub.ID, "head", head.Number, "hash", head.Hash(), "err", err)
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/thora"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/forkid"
 	"github.com/ethereum/go-ethereum/core/txpool"
@@ -51,6 +52,10 @@ const (
 	// All transactions with a higher size will be announced and need to be fetched
 	// by the peer.
 	txMaxBroadcastSize = 4096
+
+	// precommitChanSize is the size of channel listening to ChainHeadEvent
+	// for signing Thora pre-commits.
+	precommitChanSize = 16
 )
 
 var (
@@ -78,6 +83,10 @@ type txPool interface {
 	// SubscribeNewTxsEvent should return an event subscription of
 	// NewTxsEvent and send events to the given channel.
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
+
+	// IsPrivate returns whether a transaction was submitted privately and
+	// must not be propagated to the network.
+	IsPrivate(hash common.Hash) bool
 }
 
 // handlerConfig is the collection of initialization parameters to create a full
@@ -92,6 +101,7 @@ type handlerConfig struct {
 	BloomCache     uint64                 // Megabytes to alloc for snap sync bloom
 	EventMux       *event.TypeMux         // Legacy event mux, deprecate for `feed`
 	RequiredBlocks map[uint64]common.Hash // Hard coded map of required block hashes for sync challenges
+	PreCommits     bool                   // Whether to gossip Thora signer pre-commits
 }
 
 type handler struct {
@@ -119,6 +129,12 @@ type handler struct {
 
 	requiredBlocks map[uint64]common.Hash
 
+	thora          *thora.Thora      // Thora engine to sign and collect pre-commits with (nil if disabled)
+	precommitPeers *precommitPeerSet // Peers connected over the `tpc` protocol
+	precommitQueue *precommitQueue   // Pre-commits of blocks not yet imported
+	precommitCh    chan core.ChainHeadEvent
+	precommitSub   event.Subscription
+
 	// channels for fetcher, syncer, txsyncLoop
 	quitSync chan struct{}
 
@@ -149,6 +165,15 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		handlerDoneCh:  make(chan struct{}),
 		handlerStartCh: make(chan struct{}),
 	}
+	if config.PreCommits {
+		if engine, ok := thora.FromEngine(h.chain.Engine()); ok {
+			h.thora = engine
+			h.precommitPeers = newPrecommitPeerSet()
+			h.precommitQueue = newPrecommitQueue()
+		} else {
+			log.Warn("Ignoring pre-commit gossip on non-Thora network")
+		}
+	}
 	if config.Sync == downloader.FullSync {
 		// The database seems empty as the current block is the genesis. Yet the snap
 		// block is ahead, so snap sync was enabled for this node at a certain point.
@@ -354,7 +379,7 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 		td      = h.chain.GetTd(hash, number)
 	)
 	forkID := forkid.NewID(h.chain.Config(), genesis.Hash(), number, head.Time)
-	if err := peer.Handshake(h.networkID, td, hash, genesis.Hash(), forkID, h.forkFilter); err != nil {
+	if err := peer.Handshake(h.networkID, td, hash, genesis.Hash(), h.chain.HistoryTail(), forkID, h.forkFilter); err != nil {
 		peer.Log().Debug("Ethereum handshake failed", "err", err)
 		return err
 	}
@@ -532,6 +557,14 @@ func (h *handler) Start(maxPeers int) {
 	h.minedBlockSub = h.eventMux.Subscribe(core.NewMinedBlockEvent{})
 	go h.minedBroadcastLoop()
 
+	// sign and broadcast pre-commits for new chain heads
+	if h.thora != nil {
+		h.wg.Add(1)
+		h.precommitCh = make(chan core.ChainHeadEvent, precommitChanSize)
+		h.precommitSub = h.chain.SubscribeChainHeadEvent(h.precommitCh)
+		go h.precommitLoop()
+	}
+
 	// start sync handlers
 	h.wg.Add(1)
 	go h.chainSync.loop()
@@ -544,6 +577,9 @@ func (h *handler) Start(maxPeers int) {
 func (h *handler) Stop() {
 	h.txsSub.Unsubscribe()        // quits txBroadcastLoop
 	h.minedBlockSub.Unsubscribe() // quits blockBroadcastLoop
+	if h.precommitSub != nil {
+		h.precommitSub.Unsubscribe() // quits precommitLoop
+	}
 
 	// Quit chainSync and txsync64.
 	// After this is done, no new peers will be accepted.
@@ -554,6 +590,9 @@ func (h *handler) Stop() {
 	// sessions which are already established but not added to h.peers yet
 	// will exit when they try to register.
 	h.peers.close()
+	if h.precommitPeers != nil {
+		h.precommitPeers.close()
+	}
 	h.wg.Wait()
 
 	log.Info("Ethereum protocol stopped")
@@ -620,6 +659,10 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 	)
 	// Broadcast transactions to a batch of peers not knowing about it
 	for _, tx := range txs {
+		// Private transactions are only ever included locally
+		if h.txpool.IsPrivate(tx.Hash()) {
+			continue
+		}
 		peers := h.peers.peersWithoutTransaction(tx.Hash())
 
 		var numDirect int
@@ -0,0 +1,291 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/thora"
+	"github.com/ethereum/go-ethereum/eth/protocols/precommit"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// maxPendingPreCommits is the maximum number of pre-commits of blocks not yet
+// imported to hold on to.
+const maxPendingPreCommits = 1024
+
+// precommitHandler implements the precommit.Backend interface to handle the
+// signer pre-commits gossiped over the `tpc` protocol.
+type precommitHandler handler
+
+// RunPeer is invoked when a peer joins on the `tpc` protocol.
+func (h *precommitHandler) RunPeer(peer *precommit.Peer, hand precommit.Handler) error {
+	if err := h.precommitPeers.register(peer); err != nil {
+		return err
+	}
+	defer h.precommitPeers.unregister(peer.ID())
+
+	return hand(peer)
+}
+
+// PeerInfo retrieves all known `tpc` information about a peer.
+func (h *precommitHandler) PeerInfo(id enode.ID) interface{} {
+	if p := h.precommitPeers.peer(id.String()); p != nil {
+		return &struct {
+			Version uint `json:"version"`
+		}{p.Version()}
+	}
+	return nil
+}
+
+// Handle is invoked from a peer's message handler when it receives a new remote
+// message that the handler couldn't consume and serve itself.
+func (h *precommitHandler) Handle(peer *precommit.Peer, packet precommit.Packet) error {
+	switch packet := packet.(type) {
+	case *precommit.PreCommitsPacket:
+		var fresh []*thora.PreCommit
+		for _, pc := range *packet {
+			added, err := h.thora.AddPreCommit(h.chain, pc)
+			if err != nil {
+				// Pre-commits are validated before being relayed, so a peer
+				// sending invalid ones is faulty or forcing signature checks.
+				if thora.IsInvalidPreCommit(err) {
+					return fmt.Errorf("invalid pre-commit %d [%x]: %w", pc.Number, pc.Hash, err)
+				}
+				// Pre-commits may race ahead of their block, hold on to them
+				// until it gets imported
+				if thora.IsPendingPreCommit(err) {
+					h.precommitQueue.enqueue(peer.ID(), pc)
+				}
+				peer.Log().Trace("Rejected pre-commit", "number", pc.Number, "hash", pc.Hash, "err", err)
+				continue
+			}
+			peer.MarkPreCommit(pc.ID())
+			if added {
+				fresh = append(fresh, pc)
+			}
+		}
+		if len(fresh) > 0 {
+			(*handler)(h).broadcastPreCommits(fresh)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unexpected tpc packet type: %T", packet)
+	}
+}
+
+// broadcastPreCommits propagates a batch of pre-commits to all `tpc` peers
+// which are not known to already have them.
+func (h *handler) broadcastPreCommits(precommits []*thora.PreCommit) {
+	for _, peer := range h.precommitPeers.all() {
+		var send []*thora.PreCommit
+		for _, pc := range precommits {
+			if !peer.KnownPreCommit(pc.ID()) {
+				send = append(send, pc)
+			}
+		}
+		if len(send) > 0 {
+			peer.AsyncSendPreCommits(send)
+		}
+	}
+}
+
+// retryPreCommits re-validates the queued pre-commits of blocks which were not
+// yet imported when received, relaying the ones which became valid. Those of
+// blocks still unknown are queued again, stale ones are dropped.
+func (h *handler) retryPreCommits() {
+	var fresh []*thora.PreCommit
+	for _, pending := range h.precommitQueue.flush() {
+		peer := h.precommitPeers.peer(pending.peer)
+
+		added, err := h.thora.AddPreCommit(h.chain, pending.pc)
+		switch {
+		case err == nil:
+			if peer != nil {
+				peer.MarkPreCommit(pending.pc.ID())
+			}
+			if added {
+				fresh = append(fresh, pending.pc)
+			}
+		case thora.IsPendingPreCommit(err):
+			h.precommitQueue.enqueue(pending.peer, pending.pc)
+
+		case thora.IsInvalidPreCommit(err) && peer != nil:
+			peer.Log().Debug("Dropping peer relaying invalid pre-commit", "number", pending.pc.Number, "hash", pending.pc.Hash, "err", err)
+			peer.Disconnect(p2p.DiscUselessPeer)
+		}
+	}
+	if len(fresh) > 0 {
+		h.broadcastPreCommits(fresh)
+	}
+}
+
+// precommitLoop signs a pre-commit for every new chain head and gossips it to
+// the connected `tpc` peers. Nodes without signing credentials or which are
+// not authorized signers simply skip the head.
+func (h *handler) precommitLoop() {
+	defer h.wg.Done()
+
+	for {
+		select {
+		case ev := <-h.precommitCh:
+			// Head events are only sent for the last block of an imported batch,
+			// so retry all the queued pre-commits, not only the head's ones
+			h.retryPreCommits()
+
+			header := ev.Block.Header()
+			pc, err := h.thora.SignPreCommit(header.Number.Uint64(), header.Hash())
+			if err != nil {
+				continue
+			}
+			added, err := h.thora.AddPreCommit(h.chain, pc)
+			if err != nil {
+				log.Trace("Local pre-commit rejected", "number", pc.Number, "hash", pc.Hash, "err", err)
+				continue
+			}
+			if added {
+				h.broadcastPreCommits([]*thora.PreCommit{pc})
+			}
+		case <-h.precommitSub.Err():
+			return
+		}
+	}
+}
+
+// pendingPreCommit is a pre-commit of a block not yet imported, along with the
+// peer it was received from.
+type pendingPreCommit struct {
+	pc   *thora.PreCommit
+	peer string
+}
+
+// precommitQueue tracks the pre-commits received ahead of their blocks, until
+// the blocks get imported and the pre-commits can be validated.
+type precommitQueue struct {
+	pending map[common.Hash]*pendingPreCommit // Queued pre-commits, keyed by ID
+	lock    sync.Mutex
+}
+
+// newPrecommitQueue creates an empty queue of pending pre-commits.
+func newPrecommitQueue() *precommitQueue {
+	return &precommitQueue{
+		pending: make(map[common.Hash]*pendingPreCommit),
+	}
+}
+
+// enqueue schedules a pre-commit to be retried once its block is imported. The
+// pre-commit is dropped if the queue is already full.
+func (q *precommitQueue) enqueue(peer string, pc *thora.PreCommit) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if _, ok := q.pending[pc.ID()]; ok {
+		return
+	}
+	if len(q.pending) >= maxPendingPreCommits {
+		log.Trace("Dropping pending pre-commit", "number", pc.Number, "hash", pc.Hash)
+		return
+	}
+	q.pending[pc.ID()] = &pendingPreCommit{pc: pc, peer: peer}
+}
+
+// flush removes and returns all the queued pre-commits.
+func (q *precommitQueue) flush() []*pendingPreCommit {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	list := make([]*pendingPreCommit, 0, len(q.pending))
+	for id, pending := range q.pending {
+		list = append(list, pending)
+		delete(q.pending, id)
+	}
+	return list
+}
+
+// precommitPeerSet is the set of peers connected over the `tpc` protocol.
+type precommitPeerSet struct {
+	peers  map[string]*precommit.Peer
+	lock   sync.RWMutex
+	closed bool
+}
+
+// newPrecommitPeerSet creates a new peer set to track the active `tpc` peers.
+func newPrecommitPeerSet() *precommitPeerSet {
+	return &precommitPeerSet{
+		peers: make(map[string]*precommit.Peer),
+	}
+}
+
+// register injects a new `tpc` peer into the working set, or returns an error
+// if the peer is already known.
+func (ps *precommitPeerSet) register(peer *precommit.Peer) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if ps.closed {
+		return errPeerSetClosed
+	}
+	if _, ok := ps.peers[peer.ID()]; ok {
+		return errPeerAlreadyRegistered
+	}
+	ps.peers[peer.ID()] = peer
+	return nil
+}
+
+// unregister removes a remote peer from the active set.
+func (ps *precommitPeerSet) unregister(id string) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	delete(ps.peers, id)
+}
+
+// peer retrieves the registered peer with the given id.
+func (ps *precommitPeerSet) peer(id string) *precommit.Peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	return ps.peers[id]
+}
+
+// all returns a snapshot of all the registered peers.
+func (ps *precommitPeerSet) all() []*precommit.Peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*precommit.Peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		list = append(list, p)
+	}
+	return list
+}
+
+// close disconnects all peers and prevents new ones from being registered.
+func (ps *precommitPeerSet) close() {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	for _, p := range ps.peers {
+		p.Disconnect(p2p.DiscQuitting)
+	}
+	ps.closed = true
+}
@@ -0,0 +1,212 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/consensus/thora"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/protocols/precommit"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that pre-commits received over `tpc` are relayed to the peers not yet
+// knowing them, never echoed back or relayed twice, and that pre-commits racing
+// ahead of their block are relayed once the block is imported.
+func TestPreCommitRelay(t *testing.T) {
+	t.Parallel()
+
+	// Create a Thora chain with two signers, sorted by address
+	keys := make([]*ecdsa.PrivateKey, 2)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	if bytes.Compare(crypto.PubkeyToAddress(keys[0].PublicKey).Bytes(), crypto.PubkeyToAddress(keys[1].PublicKey).Bytes()) > 0 {
+		keys[0], keys[1] = keys[1], keys[0]
+	}
+	gspec := &core.Genesis{
+		Config:    params.AllThoraProtocolChanges,
+		ExtraData: make([]byte, 32+2*common.AddressLength+crypto.SignatureLength),
+		BaseFee:   big.NewInt(params.InitialBaseFee),
+	}
+	for i, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		copy(gspec.ExtraData[32+i*common.AddressLength:], addr[:])
+	}
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = thora.New(gspec.Config.Thora, rawdb.NewMemoryDatabase())
+	)
+	chain, err := core.NewBlockChain(db, nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	handler, _ := newHandler(&handlerConfig{
+		Database:   db,
+		Chain:      chain,
+		TxPool:     newTestTxPool(),
+		Merger:     consensus.NewMerger(rawdb.NewMemoryDatabase()),
+		Network:    1,
+		Sync:       downloader.FullSync,
+		BloomCache: 1,
+		PreCommits: true,
+	})
+	handler.Start(1000)
+	defer handler.Stop()
+
+	// Connect two remote peers over `tpc`, collecting everything sent to them
+	var (
+		backend = (*precommitHandler)(handler)
+		remotes = make([]*p2p.MsgPipeRW, 2)
+		sinks   = make([]chan precommit.PreCommitsPacket, 2)
+	)
+	for i := range remotes {
+		local, remote := p2p.MsgPipe()
+		defer local.Close()
+		defer remote.Close()
+
+		peer := precommit.NewPeer(precommit.TPC1, p2p.NewPeerPipe(enode.ID{byte(i + 1)}, "", nil, local), local)
+		defer peer.Close()
+
+		go backend.RunPeer(peer, func(peer *precommit.Peer) error {
+			return precommit.Handle(backend, peer)
+		})
+		remotes[i], sinks[i] = remote, make(chan precommit.PreCommitsPacket, 16)
+
+		go func(remote *p2p.MsgPipeRW, sink chan precommit.PreCommitsPacket) {
+			for {
+				msg, err := remote.ReadMsg()
+				if err != nil {
+					return
+				}
+				var packet precommit.PreCommitsPacket
+				if err := msg.Decode(&packet); err != nil {
+					t.Errorf("failed to decode pre-commits: %v", err)
+				}
+				sink <- packet
+			}
+		}(remote, sinks[i])
+	}
+	for len(handler.precommitPeers.all()) < len(remotes) {
+		time.Sleep(time.Millisecond)
+	}
+	// Create the helpers to sign pre-commits and check their propagation
+	sign := func(key *ecdsa.PrivateKey, number uint64, hash common.Hash) *thora.PreCommit {
+		signer := thora.New(gspec.Config.Thora, rawdb.NewMemoryDatabase())
+		defer signer.Close()
+
+		signer.Authorize(crypto.PubkeyToAddress(key.PublicKey), func(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+			return crypto.Sign(crypto.Keccak256(data), key)
+		}, nil)
+		pc, err := signer.SignPreCommit(number, hash)
+		if err != nil {
+			t.Fatalf("failed to sign pre-commit: %v", err)
+		}
+		return pc
+	}
+	send := func(peer int, pc *thora.PreCommit) {
+		if err := p2p.Send(remotes[peer], precommit.PreCommitsMsg, []*thora.PreCommit{pc}); err != nil {
+			t.Fatalf("failed to send pre-commit: %v", err)
+		}
+	}
+	expect := func(peer int, pc *thora.PreCommit) {
+		select {
+		case packet := <-sinks[peer]:
+			if len(packet) != 1 || packet[0].ID() != pc.ID() {
+				t.Fatalf("peer %d: relayed pre-commits mismatch: have %v, want %x", peer, packet, pc.ID())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("peer %d: pre-commit not relayed", peer)
+		}
+	}
+	silent := func(peer int) {
+		select {
+		case packet := <-sinks[peer]:
+			t.Fatalf("peer %d: unexpected pre-commits relayed: %v", peer, packet)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	// Relay a pre-commit of a known block, ensuring it's only sent once
+	genesis := chain.Genesis()
+
+	pc := sign(keys[0], 0, genesis.Hash())
+	send(0, pc)
+	expect(1, pc)
+
+	send(1, pc)
+	silent(0)
+	silent(1)
+
+	// Send a pre-commit ahead of its block and ensure it's relayed after import
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit(),
+		Time:       genesis.Time() + 1,
+		Difficulty: big.NewInt(2),
+		Extra:      make([]byte, 32+crypto.SignatureLength),
+		BaseFee:    misc.CalcBaseFee(gspec.Config, genesis.Header()),
+	}
+	statedb, err := chain.StateAt(genesis.Root())
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	block, err := engine.FinalizeAndAssemble(chain, header, statedb, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to assemble block: %v", err)
+	}
+	header = block.Header()
+	sig, _ := crypto.Sign(thora.SealHash(header).Bytes(), keys[1])
+	copy(header.Extra[32:], sig)
+	block = block.WithSeal(header)
+
+	pc = sign(keys[1], 1, block.Hash())
+	send(0, pc)
+	silent(1)
+
+	if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+	expect(1, pc)
+	silent(0)
+
+	conf, err := engine.Confirmations(chain, block.Hash())
+	if err != nil {
+		t.Fatalf("failed to retrieve confirmations: %v", err)
+	}
+	if len(conf.Signers) != 1 || conf.Signers[0] != crypto.PubkeyToAddress(keys[1].PublicKey) {
+		t.Errorf("confirmation signers mismatch: have %v", conf.Signers)
+	}
+}
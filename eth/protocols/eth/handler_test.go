@@ -624,3 +624,54 @@ func testGetBlockReceipts(t *testing.T, protocol uint) {
 		t.Errorf("receipts mismatch: %v", err)
 	}
 }
+
+// Tests that the bodies and receipts of pruned blocks are not served, even if
+// they are still cached.
+func TestServePrunedHistory(t *testing.T) {
+	t.Parallel()
+
+	var (
+		gspec = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{testAddr: {Balance: big.NewInt(100_000_000_000_000_000)}},
+		}
+		signer = types.HomesteadSigner{}
+	)
+	_, blocks, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 8, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testAddr), common.Address{0x01}, big.NewInt(1), params.TxGas, block.BaseFee(), nil), signer, testKey)
+		block.AddTx(tx)
+	})
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create freezer db: %v", err)
+	}
+	defer db.Close()
+	rawdb.WriteAncientBlocks(db, append([]*types.Block{gspec.ToBlock()}, blocks...), append([]types.Receipts{{}}, receipts...), big.NewInt(0))
+
+	chain, err := core.NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	var hashes []common.Hash
+	for _, block := range blocks {
+		hashes = append(hashes, block.Hash())
+	}
+	// Fill the caches before pruning the history below block 5
+	if have := len(ServiceGetBlockBodiesQuery(chain, hashes)); have != len(blocks) {
+		t.Fatalf("served bodies mismatch: have %d, want %d", have, len(blocks))
+	}
+	if have := len(ServiceGetReceiptsQuery(chain, hashes)); have != len(blocks) {
+		t.Fatalf("served receipts mismatch: have %d, want %d", have, len(blocks))
+	}
+	if _, err := rawdb.PruneHistory(db, 5); err != nil {
+		t.Fatalf("failed to prune history: %v", err)
+	}
+	if have := len(ServiceGetBlockBodiesQuery(chain, hashes)); have != 4 {
+		t.Errorf("served bodies mismatch: have %d, want %d", have, 4)
+	}
+	if have := len(ServiceGetReceiptsQuery(chain, hashes)); have != 4 {
+		t.Errorf("served receipts mismatch: have %d, want %d", have, 4)
+	}
+}
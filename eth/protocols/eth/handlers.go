@@ -225,6 +225,10 @@ func ServiceGetBlockBodiesQuery(chain *core.BlockChain, query GetBlockBodiesPack
 			lookups >= 2*maxBodiesServe {
 			break
 		}
+		// Don't serve pruned history, even if still cached
+		if header := chain.GetHeaderByHash(hash); header == nil || chain.HistoryPruned(header.Number.Uint64()) {
+			continue
+		}
 		if data := chain.GetBodyRLP(hash); len(data) != 0 {
 			bodies = append(bodies, data)
 			bytes += len(data)
@@ -293,12 +297,14 @@ func ServiceGetReceiptsQuery(chain *core.BlockChain, query GetReceiptsPacket) []
 			lookups >= 2*maxReceiptsServe {
 			break
 		}
-		// Retrieve the requested block's receipts
+		// Retrieve the requested block's receipts, unless they were pruned
+		header := chain.GetHeaderByHash(hash)
+		if header == nil || chain.HistoryPruned(header.Number.Uint64()) {
+			continue
+		}
 		results := chain.GetReceiptsByHash(hash)
-		if results == nil {
-			if header := chain.GetHeaderByHash(hash); header == nil || header.ReceiptHash != types.EmptyRootHash {
-				continue
-			}
+		if results == nil && header.ReceiptHash != types.EmptyRootHash {
+			continue
 		}
 		// If known, encode and queue for response packet
 		if encoded, err := rlp.EncodeToBytes(results); err != nil {
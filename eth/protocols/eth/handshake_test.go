@@ -52,19 +52,19 @@ func testHandshake(t *testing.T, protocol uint) {
 			want: errNoStatusMsg,
 		},
 		{
-			code: StatusMsg, data: StatusPacket{10, 1, td, head.Hash(), genesis.Hash(), forkID},
+			code: StatusMsg, data: StatusPacket{10, 1, td, head.Hash(), genesis.Hash(), forkID, 0},
 			want: errProtocolVersionMismatch,
 		},
 		{
-			code: StatusMsg, data: StatusPacket{uint32(protocol), 999, td, head.Hash(), genesis.Hash(), forkID},
+			code: StatusMsg, data: StatusPacket{uint32(protocol), 999, td, head.Hash(), genesis.Hash(), forkID, 0},
 			want: errNetworkIDMismatch,
 		},
 		{
-			code: StatusMsg, data: StatusPacket{uint32(protocol), 1, td, head.Hash(), common.Hash{3}, forkID},
+			code: StatusMsg, data: StatusPacket{uint32(protocol), 1, td, head.Hash(), common.Hash{3}, forkID, 0},
 			want: errGenesisMismatch,
 		},
 		{
-			code: StatusMsg, data: StatusPacket{uint32(protocol), 1, td, head.Hash(), genesis.Hash(), forkid.ID{Hash: [4]byte{0x00, 0x01, 0x02, 0x03}}},
+			code: StatusMsg, data: StatusPacket{uint32(protocol), 1, td, head.Hash(), genesis.Hash(), forkid.ID{Hash: [4]byte{0x00, 0x01, 0x02, 0x03}}, 0},
 			want: errForkIDRejected,
 		},
 	}
@@ -80,7 +80,7 @@ func testHandshake(t *testing.T, protocol uint) {
 		// Send the junk test with one peer, check the handshake failure
 		go p2p.Send(app, test.code, test.data)
 
-		err := peer.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, forkid.NewFilter(backend.chain))
+		err := peer.Handshake(1, td, head.Hash(), genesis.Hash(), 0, forkID, forkid.NewFilter(backend.chain))
 		if err == nil {
 			t.Errorf("test %d: protocol returned nil error, want %q", i, test.want)
 		} else if !errors.Is(err, test.want) {
@@ -88,3 +88,42 @@ func testHandshake(t *testing.T, protocol uint) {
 		}
 	}
 }
+
+// Tests that the history tail advertised in the handshake is recorded.
+func TestHandshakeHistoryTail(t *testing.T) {
+	t.Parallel()
+
+	backend := newTestBackend(3)
+	defer backend.close()
+
+	var (
+		genesis = backend.chain.Genesis()
+		head    = backend.chain.CurrentBlock()
+		td      = backend.chain.GetTd(head.Hash(), head.Number.Uint64())
+		forkID  = forkid.NewID(backend.chain.Config(), genesis.Hash(), head.Number.Uint64(), head.Time)
+		filter  = forkid.NewFilter(backend.chain)
+	)
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	defer net.Close()
+
+	local := NewPeer(ETH68, p2p.NewPeer(enode.ID{1}, "local", nil), app, nil)
+	defer local.Close()
+	remote := NewPeer(ETH68, p2p.NewPeer(enode.ID{2}, "remote", nil), net, nil)
+	defer remote.Close()
+
+	errc := make(chan error, 1)
+	go func() { errc <- remote.Handshake(1, td, head.Hash(), genesis.Hash(), 0, forkID, filter) }()
+	if err := local.Handshake(1, td, head.Hash(), genesis.Hash(), 2, forkID, filter); err != nil {
+		t.Fatalf("local handshake failed: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("remote handshake failed: %v", err)
+	}
+	if tail := local.HistoryTail(); tail != 0 {
+		t.Errorf("local view of history tail mismatch: have %d, want 0", tail)
+	}
+	if tail := remote.HistoryTail(); tail != 2 {
+		t.Errorf("remote view of history tail mismatch: have %d, want 2", tail)
+	}
+}
@@ -72,8 +72,9 @@ type Peer struct {
 	rw        p2p.MsgReadWriter // Input/output streams for snap
 	version   uint              // Protocol version negotiated
 
-	head common.Hash // Latest advertised head block hash
-	td   *big.Int    // Latest advertised head block total difficulty
+	head        common.Hash // Latest advertised head block hash
+	td          *big.Int    // Latest advertised head block total difficulty
+	historyTail uint64      // First block whose body and receipts the peer serves
 
 	knownBlocks     *knownCache            // Set of block hashes known to be known by this peer
 	queuedBlocks    chan *blockPropagation // Queue of blocks to broadcast to the peer
@@ -156,6 +157,15 @@ func (p *Peer) SetHead(hash common.Hash, td *big.Int) {
 	p.td.Set(td)
 }
 
+// HistoryTail retrieves the number of the first block whose body and receipts
+// are served by the peer, the history below it has been pruned.
+func (p *Peer) HistoryTail() uint64 {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.historyTail
+}
+
 // KnownBlock returns whether peer is known to already have a block.
 func (p *Peer) KnownBlock(hash common.Hash) bool {
 	return p.knownBlocks.Contains(hash)
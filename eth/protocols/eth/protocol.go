@@ -93,6 +93,7 @@ type StatusPacket struct {
 	Head            common.Hash
 	Genesis         common.Hash
 	ForkID          forkid.ID
+	HistoryTail     uint64 `rlp:"optional"` // First block with retained body and receipts, omitted if unpruned
 }
 
 // NewBlockHashesPacket is the network packet for the block announcements.
@@ -0,0 +1,158 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package precommit
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/consensus/thora"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+const (
+	// maxKnownPreCommits is the maximum number of pre-commit identifiers to keep
+	// in the known list (prevent DOS).
+	maxKnownPreCommits = 8192
+
+	// maxQueuedPreCommits is the maximum number of pre-commit batches to queue up
+	// before dropping broadcasts. Pre-commits are only useful while their block
+	// is fresh, so there's no point in queueing many behind a slow peer.
+	maxQueuedPreCommits = 64
+)
+
+// Peer is a collection of relevant information we have about a `tpc` peer.
+type Peer struct {
+	id string // Unique ID for the peer, cached
+
+	*p2p.Peer                   // The embedded P2P package peer
+	rw        p2p.MsgReadWriter // Input/output streams for tpc
+	version   uint              // Protocol version negotiated
+
+	known  *lru.Cache[common.Hash, struct{}] // Set of pre-commit IDs known to be known by this peer
+	queued chan []*thora.PreCommit           // Queue of pre-commits to broadcast to the peer
+
+	logger log.Logger    // Contextual logger with the peer id injected
+	term   chan struct{} // Termination channel to stop the broadcaster
+}
+
+// NewPeer create a wrapper for a network connection and negotiated  protocol
+// version.
+func NewPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
+	id := p.ID().String()
+	peer := &Peer{
+		id:      id,
+		Peer:    p,
+		rw:      rw,
+		version: version,
+		known:   lru.NewCache[common.Hash, struct{}](maxKnownPreCommits),
+		queued:  make(chan []*thora.PreCommit, maxQueuedPreCommits),
+		logger:  log.New("peer", id[:8]),
+		term:    make(chan struct{}),
+	}
+	go peer.broadcastPreCommits()
+	return peer
+}
+
+// NewFakePeer create a fake tpc peer without a backing p2p peer, for testing purposes.
+func NewFakePeer(version uint, id string, rw p2p.MsgReadWriter) *Peer {
+	peer := &Peer{
+		id:      id,
+		rw:      rw,
+		version: version,
+		known:   lru.NewCache[common.Hash, struct{}](maxKnownPreCommits),
+		queued:  make(chan []*thora.PreCommit, maxQueuedPreCommits),
+		logger:  log.New("peer", id[:8]),
+		term:    make(chan struct{}),
+	}
+	go peer.broadcastPreCommits()
+	return peer
+}
+
+// Close signals the broadcast goroutine to terminate. Only ever call this if
+// you created the peer yourself via NewPeer. Otherwise let whoever created it
+// clean it up!
+func (p *Peer) Close() {
+	close(p.term)
+}
+
+// ID retrieves the peer's unique identifier.
+func (p *Peer) ID() string {
+	return p.id
+}
+
+// Version retrieves the peer's negotiated `tpc` protocol version.
+func (p *Peer) Version() uint {
+	return p.version
+}
+
+// Log overrides the P2P logger with the higher level one containing only the id.
+func (p *Peer) Log() log.Logger {
+	return p.logger
+}
+
+// KnownPreCommit returns whether peer is known to already have a pre-commit.
+func (p *Peer) KnownPreCommit(id common.Hash) bool {
+	return p.known.Contains(id)
+}
+
+// MarkPreCommit marks a pre-commit as known for the peer, ensuring that it
+// will never be propagated to this particular peer.
+func (p *Peer) MarkPreCommit(id common.Hash) {
+	p.known.Add(id, struct{}{})
+}
+
+// SendPreCommits sends a batch of pre-commits to the peer and includes them
+// in its known set.
+func (p *Peer) SendPreCommits(precommits []*thora.PreCommit) error {
+	for _, pc := range precommits {
+		p.MarkPreCommit(pc.ID())
+	}
+	return p2p.Send(p.rw, PreCommitsMsg, precommits)
+}
+
+// AsyncSendPreCommits queues a batch of pre-commits for propagation to a remote
+// peer. If the peer's broadcast queue is full, the event is silently dropped.
+func (p *Peer) AsyncSendPreCommits(precommits []*thora.PreCommit) {
+	select {
+	case p.queued <- precommits:
+		// Mark all the pre-commits as known, but ensure we don't overflow our limits
+		for _, pc := range precommits {
+			p.MarkPreCommit(pc.ID())
+		}
+	default:
+		p.Log().Debug("Dropping pre-commit propagation", "count", len(precommits))
+	}
+}
+
+// broadcastPreCommits is a write loop that sends the queued pre-commits to the
+// remote peer. The goal is to have an async writer that does not lock up node
+// internals and at the same time rate limits queued data.
+func (p *Peer) broadcastPreCommits() {
+	for {
+		select {
+		case precommits := <-p.queued:
+			if err := p.SendPreCommits(precommits); err != nil {
+				return
+			}
+			p.Log().Trace("Propagated pre-commits", "count", len(precommits))
+
+		case <-p.term:
+			return
+		}
+	}
+}
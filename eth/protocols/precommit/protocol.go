@@ -0,0 +1,70 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package precommit implements the `tpc` protocol, over which Thora signers
+// gossip their pre-commits for freshly sealed blocks.
+package precommit
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/consensus/thora"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	TPC1 = 1
+)
+
+// ProtocolName is the official short name of the `tpc` protocol used during
+// devp2p capability negotiation.
+const ProtocolName = "tpc"
+
+// ProtocolVersions are the supported versions of the `tpc` protocol (first
+// is primary).
+var ProtocolVersions = []uint{TPC1}
+
+// protocolLengths are the number of implemented message corresponding to
+// different protocol versions.
+var protocolLengths = map[uint]uint64{TPC1: 1}
+
+// maxMessageSize is the maximum cap on the size of a protocol message.
+const maxMessageSize = 256 * 1024
+
+// maxPreCommits is the maximum number of pre-commits accepted in a single message.
+const maxPreCommits = 1024
+
+const (
+	PreCommitsMsg = 0x00
+)
+
+var (
+	errMsgTooLarge    = errors.New("message too long")
+	errDecode         = errors.New("invalid message")
+	errInvalidMsgCode = errors.New("invalid message code")
+)
+
+// Packet represents a p2p message in the `tpc` protocol.
+type Packet interface {
+	Name() string // Name returns a string corresponding to the message type.
+	Kind() byte   // Kind returns the message type.
+}
+
+// PreCommitsPacket is the network packet for propagating signer pre-commits.
+type PreCommitsPacket []*thora.PreCommit
+
+func (*PreCommitsPacket) Name() string { return "PreCommits" }
+func (*PreCommitsPacket) Kind() byte   { return PreCommitsMsg }
@@ -24,6 +24,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -64,6 +65,11 @@ func (eth *Ethereum) StateAtBlock(ctx context.Context, block *types.Block, reexe
 		report   = true
 		origin   = block.NumberU64()
 	)
+	// The path-based database can't regenerate historic states over an ephemeral
+	// database, only the states retained by the live database are served.
+	if eth.blockchain.TrieDB().Scheme() == rawdb.PathScheme {
+		return eth.pathState(block)
+	}
 	// The state is only for reading purposes, check the state presence in
 	// live database.
 	if readOnly {
@@ -188,6 +194,16 @@ func (eth *Ethereum) StateAtBlock(ctx context.Context, block *types.Block, reexe
 	return statedb, func() { database.TrieDB().Dereference(block.Root()) }, nil
 }
 
+// pathState returns the state of the given block from the live path-based
+// database, which holds the states of the recent blocks only.
+func (eth *Ethereum) pathState(block *types.Block) (*state.StateDB, tracers.StateReleaseFunc, error) {
+	statedb, err := eth.blockchain.StateAt(block.Root())
+	if err != nil {
+		return nil, nil, fmt.Errorf("historical state %x is not available in path scheme: %w", block.Root(), err)
+	}
+	return statedb, noopReleaser, nil
+}
+
 // stateAtTransaction returns the execution environment of a certain transaction.
 func (eth *Ethereum) stateAtTransaction(ctx context.Context, block *types.Block, txIndex int, reexec uint64) (*core.Message, vm.BlockContext, *state.StateDB, tracers.StateReleaseFunc, error) {
 	// Short circuit if it's genesis block.
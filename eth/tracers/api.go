@@ -93,6 +93,7 @@ type Backend interface {
 // API is the collection of tracing APIs exposed over the private debugging endpoint.
 type API struct {
 	backend Backend
+	cache   *Cache // Optional cache of block traces, nil if disabled
 }
 
 // NewAPI creates a new API definition for the tracing methods of the Ethereum service.
@@ -566,8 +567,30 @@ func (api *API) StandardTraceBadBlockToFile(ctx context.Context, hash common.Has
 
 // traceBlock configures a new tracer according to the provided configuration, and
 // executes all the transactions contained within. The return value will be one item
-// per transaction, dependent on the requested tracer.
+// per transaction, dependent on the requested tracer. If the trace cache is enabled,
+// the results are served from and stored into it.
 func (api *API) traceBlock(ctx context.Context, block *types.Block, config *TraceConfig) ([]*txTraceResult, error) {
+	if api.cache == nil {
+		return api.executeBlock(ctx, block, config)
+	}
+	key, err := api.cache.key(block.NumberU64(), block.Hash(), config)
+	if err != nil {
+		return nil, err
+	}
+	if results := api.cache.get(key); results != nil {
+		return results, nil
+	}
+	results, err := api.executeBlock(ctx, block, config)
+	if err != nil {
+		return nil, err
+	}
+	api.cache.put(key, results)
+	return results, nil
+}
+
+// executeBlock executes all the transactions contained within the block with the
+// tracer configured by the provided configuration.
+func (api *API) executeBlock(ctx context.Context, block *types.Block, config *TraceConfig) ([]*txTraceResult, error) {
 	if block.NumberU64() == 0 {
 		return nil, errors.New("genesis is not traceable")
 	}
@@ -968,13 +991,22 @@ func (api *API) traceTx(ctx context.Context, message *core.Message, txctx *Conte
 	return tracer.GetResult()
 }
 
-// APIs return the collection of RPC services the tracer package offers.
-func APIs(backend Backend) []rpc.API {
+// APIs return the collection of RPC services the tracer package offers. The
+// block traces are served from the given cache, unless it's nil. Trace filters
+// may span at most filterRange blocks, unless it's zero.
+func APIs(backend Backend, cache *Cache, filterRange uint64) []rpc.API {
+	api := NewAPI(backend)
+	api.cache = cache
+
 	// Append all the local APIs and return
 	return []rpc.API{
 		{
 			Namespace: "debug",
-			Service:   NewAPI(backend),
+			Service:   api,
+		},
+		{
+			Namespace: "trace",
+			Service:   &TraceAPI{api: api, filterRange: filterRange},
 		},
 	}
 }
@@ -32,6 +32,8 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/consensus/thora"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -40,6 +42,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -134,6 +137,10 @@ func (b *testBackend) ChainDb() ethdb.Database {
 	return b.chaindb
 }
 
+func (b *testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.chain.SubscribeChainHeadEvent(ch)
+}
+
 // teardown releases the associated resources.
 func (b *testBackend) teardown() {
 	b.chain.Stop()
@@ -451,6 +458,169 @@ func TestTraceBlock(t *testing.T) {
 	}
 }
 
+func TestGetBalanceChanges(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts and a token minting 100 units to any caller
+	accounts := newAccounts(2)
+	token := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	topic := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	code := []byte{
+		byte(vm.CALLDATASIZE), byte(vm.PUSH1), 0x24, byte(vm.EQ), byte(vm.PUSH1), 0x3a, byte(vm.JUMPI),
+		// mint: balance[caller] = 100, emit Transfer(0, caller, 100)
+		byte(vm.PUSH1), 0x64, byte(vm.CALLER), byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x64, byte(vm.PUSH1), 0x00, byte(vm.MSTORE),
+		byte(vm.CALLER), byte(vm.PUSH1), 0x00, byte(vm.PUSH32),
+	}
+	code = append(code, topic.Bytes()...)
+	code = append(code, []byte{
+		byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.LOG3), byte(vm.STOP),
+		// balanceOf(address): return balance[address]
+		byte(vm.JUMPDEST), byte(vm.PUSH1), 0x04, byte(vm.CALLDATALOAD), byte(vm.SLOAD),
+		byte(vm.PUSH1), 0x00, byte(vm.MSTORE), byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.RETURN),
+	}...)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			accounts[1].addr: {Balance: big.NewInt(params.Ether)},
+			token:            {Balance: common.Big0, Code: code},
+		},
+	}
+	signer := types.HomesteadSigner{}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(0, accounts[1].addr, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(1, token, common.Big0, 100000, b.BaseFee(), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+	})
+	defer backend.teardown()
+	api := NewAPI(backend)
+
+	if _, err := api.GetBalanceChanges(context.Background(), 0); err == nil {
+		t.Fatal("expected error for genesis block")
+	}
+	changes, err := api.GetBalanceChanges(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("failed to get balance changes: %v", err)
+	}
+	if len(changes.Native) != 3 {
+		t.Errorf("native balance changes mismatch: have %d, want 3 (sender, recipient, coinbase)", len(changes.Native))
+	}
+	if diff := changes.Native[accounts[1].addr]; diff == nil || diff.Before.ToInt().Int64() != params.Ether || diff.After.ToInt().Int64() != params.Ether+1000 {
+		t.Errorf("recipient balance change mismatch: %+v", diff)
+	}
+	if diff := changes.Native[accounts[0].addr]; diff == nil || diff.After.ToInt().Cmp(new(big.Int).Sub(diff.Before.ToInt(), big.NewInt(1000))) >= 0 {
+		t.Errorf("sender balance change mismatch: %+v", diff)
+	}
+	if _, ok := changes.Native[token]; ok {
+		t.Error("unchanged token contract balance reported")
+	}
+	if len(changes.Tokens) != 1 || len(changes.Tokens[token]) != 1 {
+		t.Fatalf("token balance changes mismatch: %v", changes.Tokens)
+	}
+	if diff := changes.Tokens[token][accounts[0].addr]; diff == nil || diff.Before.ToInt().Sign() != 0 || diff.After.ToInt().Int64() != 100 {
+		t.Errorf("token balance change mismatch: %+v", diff)
+	}
+}
+
+// Tests that on Thora, where the coinbase carries the signer vote, the fees are
+// reported for the block sealer and the block reward for its recipient.
+func TestGetBalanceChangesThora(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	sealer, sender := accounts[0], accounts[1]
+	for _, recipient := range []*common.Address{nil, {0xee}} {
+		var (
+			config      = *params.AllThoraProtocolChanges
+			thoraConfig = *config.Thora
+		)
+		thoraConfig.RewardRecipient = recipient
+		config.Thora = &thoraConfig
+
+		genesis := &core.Genesis{
+			Config:    &config,
+			ExtraData: make([]byte, 32+common.AddressLength+crypto.SignatureLength),
+			Alloc:     core.GenesisAlloc{sender.addr: {Balance: big.NewInt(params.Ether)}},
+			BaseFee:   big.NewInt(params.InitialBaseFee),
+		}
+		copy(genesis.ExtraData[32:], sealer.addr[:])
+
+		backend := &testBackend{
+			chainConfig: &config,
+			engine:      thora.New(config.Thora, rawdb.NewMemoryDatabase()),
+			chaindb:     rawdb.NewMemoryDatabase(),
+		}
+		chain, err := core.NewBlockChain(backend.chaindb, &core.CacheConfig{TrieDirtyDisabled: true}, genesis, nil, backend.engine, vm.Config{}, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create tester chain: %v", err)
+		}
+		backend.chain = chain
+
+		// Seal two blocks transferring funds, so the second one rewards the
+		// author of the first one if no recipient is configured
+		var (
+			signer = types.LatestSigner(&config)
+			fees   = new(big.Int)
+		)
+		for i := uint64(0); i < 2; i++ {
+			parent := chain.CurrentBlock()
+			header := &types.Header{
+				ParentHash: parent.Hash(),
+				Number:     new(big.Int).Add(parent.Number, common.Big1),
+				GasLimit:   parent.GasLimit,
+				Time:       parent.Time + 1,
+				Difficulty: big.NewInt(2),
+				Extra:      make([]byte, 32+crypto.SignatureLength),
+				BaseFee:    misc.CalcBaseFee(&config, parent),
+			}
+			statedb, err := chain.StateAt(parent.Root)
+			if err != nil {
+				t.Fatalf("failed to retrieve state: %v", err)
+			}
+			gasPrice := new(big.Int).Mul(header.BaseFee, common.Big2)
+			tx := types.MustSignNewTx(sender.key, signer, &types.LegacyTx{Nonce: i, To: &common.Address{0x01}, Value: big.NewInt(1000), Gas: params.TxGas, GasPrice: gasPrice})
+			receipt, err := core.ApplyTransaction(&config, chain, &sealer.addr, new(core.GasPool).AddGas(header.GasLimit), statedb, header, tx, &header.GasUsed, vm.Config{})
+			if err != nil {
+				t.Fatalf("failed to apply transaction: %v", err)
+			}
+			block, err := backend.engine.FinalizeAndAssemble(chain, header, statedb, []*types.Transaction{tx}, nil, []*types.Receipt{receipt}, nil)
+			if err != nil {
+				t.Fatalf("failed to assemble block: %v", err)
+			}
+			header = block.Header()
+			sig, _ := crypto.Sign(thora.SealHash(header).Bytes(), sealer.key)
+			copy(header.Extra[32:], sig)
+			if _, err := chain.InsertChain(types.Blocks{block.WithSeal(header)}); err != nil {
+				t.Fatalf("failed to insert block: %v", err)
+			}
+			fees.Mul(new(big.Int).SetUint64(receipt.GasUsed), new(big.Int).Sub(gasPrice, header.BaseFee))
+		}
+		changes, err := NewAPI(backend).GetBalanceChanges(context.Background(), 2)
+		backend.teardown()
+		if err != nil {
+			t.Fatalf("failed to get balance changes: %v", err)
+		}
+		gain := func(addr common.Address) *big.Int {
+			diff := changes.Native[addr]
+			if diff == nil {
+				return nil
+			}
+			return new(big.Int).Sub(diff.After.ToInt(), diff.Before.ToInt())
+		}
+		want := new(big.Int).Set(fees)
+		if recipient == nil {
+			want.Add(want, config.Thora.BlockReward)
+		} else if have := gain(*recipient); have == nil || have.Cmp(config.Thora.BlockReward) != 0 {
+			t.Errorf("reward recipient balance change mismatch: have %v, want %v", have, config.Thora.BlockReward)
+		}
+		if have := gain(sealer.addr); have == nil || have.Cmp(want) != 0 {
+			t.Errorf("sealer balance change mismatch (recipient %v): have %v, want %v", recipient, have, want)
+		}
+	}
+}
+
 func TestTracingWithOverrides(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
@@ -0,0 +1,208 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// balanceOfGas is the gas allowance of a token balance query.
+const balanceOfGas = 100000
+
+// balanceOfSelector is the method identifier of the ERC-20 balanceOf method.
+var balanceOfSelector = crypto.Keccak256([]byte("balanceOf(address)"))[:4]
+
+// BalanceDiff is the balance of an account before and after a block.
+type BalanceDiff struct {
+	Before *hexutil.Big `json:"before"`
+	After  *hexutil.Big `json:"after"`
+}
+
+// BalanceChanges is the set of balances modified by a block, for the native
+// currency and for each ERC-20 token transferred in the block.
+type BalanceChanges struct {
+	Native map[common.Address]*BalanceDiff                    `json:"native"`
+	Tokens map[common.Address]map[common.Address]*BalanceDiff `json:"tokens"`
+}
+
+// touchTracer collects the accounts participating in any call frame.
+type touchTracer struct {
+	accounts map[common.Address]struct{}
+}
+
+func (t *touchTracer) touch(addrs ...common.Address) {
+	for _, addr := range addrs {
+		t.accounts[addr] = struct{}{}
+	}
+}
+
+func (t *touchTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *touchTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *touchTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.touch(from, to)
+}
+
+func (t *touchTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {}
+
+func (t *touchTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.touch(from, to)
+}
+
+func (t *touchTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (t *touchTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+func (t *touchTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// GetBalanceChanges returns the balances of all accounts modified by the given
+// block, before and after it, including the fees and block rewards. Besides the
+// native currency, the balances of the holders of all ERC-20 tokens transferred
+// in the block are reported, as long as the token contract correctly answers to
+// balanceOf queries.
+func (api *API) GetBalanceChanges(ctx context.Context, number rpc.BlockNumber) (*BalanceChanges, error) {
+	block, err := api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
+	if err != nil {
+		return nil, err
+	}
+	statedb, release, err := api.backend.StateAtBlock(ctx, parent, defaultTraceReexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		prestate = statedb.Copy()
+		config   = api.backend.ChainConfig()
+		is158    = config.IsEIP158(block.Number())
+		blockCtx = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+		signer   = types.MakeSigner(config, block.Number(), block.Time())
+		touched  = &touchTracer{accounts: make(map[common.Address]struct{})}
+		holders  = make(map[common.Address]map[common.Address]struct{})
+	)
+	// Fees are paid to the block author, which isn't necessarily the coinbase,
+	// and rewards may be paid to the block and uncle beneficiaries
+	touched.touch(blockCtx.Coinbase, block.Coinbase())
+	for _, uncle := range block.Uncles() {
+		touched.touch(uncle.Coinbase)
+	}
+	for _, withdrawal := range block.Withdrawals() {
+		touched.touch(withdrawal.Address)
+	}
+	// Thora pays the block reward to a configured recipient, or to the author
+	// of the parent block
+	if thora := config.Thora; thora != nil && thora.BlockReward != nil {
+		if thora.RewardRecipient != nil && *thora.RewardRecipient != (common.Address{}) {
+			touched.touch(*thora.RewardRecipient)
+		} else if author, err := api.backend.Engine().Author(parent.Header()); err == nil {
+			touched.touch(author)
+		}
+	}
+	for i, tx := range block.Transactions() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		msg, _ := core.TransactionToMessage(tx, signer, block.BaseFee())
+		statedb.SetTxContext(tx.Hash(), i)
+
+		vmenv := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, config, vm.Config{Tracer: touched, NoBaseFee: true})
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.GasLimit)); err != nil {
+			return nil, fmt.Errorf("tracing failed: %w", err)
+		}
+		for _, log := range statedb.GetLogs(tx.Hash(), block.NumberU64(), block.Hash()) {
+			for _, transfer := range DecodeTokenTransfers(log.Address, log.Topics, log.Data) {
+				if transfer.Type != TransferERC20 {
+					continue
+				}
+				if holders[log.Address] == nil {
+					holders[log.Address] = make(map[common.Address]struct{})
+				}
+				holders[log.Address][transfer.From] = struct{}{}
+				holders[log.Address][transfer.To] = struct{}{}
+			}
+		}
+		statedb.Finalise(is158)
+	}
+	// Compare against the state after the block, rewards included
+	poststate, postRelease, err := api.backend.StateAtBlock(ctx, block, defaultTraceReexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer postRelease()
+
+	changes := &BalanceChanges{
+		Native: make(map[common.Address]*BalanceDiff),
+		Tokens: make(map[common.Address]map[common.Address]*BalanceDiff),
+	}
+	for addr := range touched.accounts {
+		before, after := prestate.GetBalance(addr), poststate.GetBalance(addr)
+		if before.Cmp(after) != 0 {
+			changes.Native[addr] = &BalanceDiff{Before: (*hexutil.Big)(before), After: (*hexutil.Big)(after)}
+		}
+	}
+	for token, accounts := range holders {
+		for addr := range accounts {
+			before := api.balanceOf(blockCtx, prestate, token, addr)
+			after := api.balanceOf(blockCtx, poststate, token, addr)
+			if before == nil || after == nil || before.Cmp(after) == 0 {
+				continue
+			}
+			if changes.Tokens[token] == nil {
+				changes.Tokens[token] = make(map[common.Address]*BalanceDiff)
+			}
+			changes.Tokens[token][addr] = &BalanceDiff{Before: (*hexutil.Big)(before), After: (*hexutil.Big)(after)}
+		}
+	}
+	return changes, nil
+}
+
+// balanceOf queries the ERC-20 balance of an account in the given state. It
+// returns nil if the token contract does not answer the query properly.
+func (api *API) balanceOf(blockCtx vm.BlockContext, statedb *state.StateDB, token, addr common.Address) *big.Int {
+	var (
+		input = append(common.CopyBytes(balanceOfSelector), common.LeftPadBytes(addr.Bytes(), 32)...)
+		txCtx = vm.TxContext{GasPrice: new(big.Int)}
+		vmenv = vm.NewEVM(blockCtx, txCtx, statedb, api.backend.ChainConfig(), vm.Config{NoBaseFee: true})
+	)
+	ret, _, err := vmenv.StaticCall(vm.AccountRef(common.Address{}), token, input, balanceOfGas)
+	if err != nil || len(ret) != 32 {
+		return nil
+	}
+	return new(big.Int).SetBytes(ret)
+}
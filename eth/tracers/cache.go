@@ -0,0 +1,243 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	cacheHitMeter        = metrics.NewRegisteredMeter("trace/cache/hit", nil)
+	cacheMissMeter       = metrics.NewRegisteredMeter("trace/cache/miss", nil)
+	cacheInvalidateMeter = metrics.NewRegisteredMeter("trace/cache/invalidate", nil)
+)
+
+// cacheKey identifies the traces of a block produced by a tracer configuration.
+type cacheKey struct {
+	number uint64
+	block  common.Hash
+	config common.Hash
+}
+
+// dbKey returns the key of the traces in the persistent store: the block number
+// and hash followed by the hash of the tracer configuration, so all traces of a
+// block can be iterated over.
+func (k cacheKey) dbKey() []byte {
+	key := make([]byte, 8+2*common.HashLength)
+	binary.BigEndian.PutUint64(key, k.number)
+	copy(key[8:], k.block[:])
+	copy(key[8+common.HashLength:], k.config[:])
+	return key
+}
+
+// blockPrefix returns the persistent store prefix of all traces of a block.
+func blockPrefix(number uint64, hash common.Hash) []byte {
+	prefix := make([]byte, 8+common.HashLength)
+	binary.BigEndian.PutUint64(prefix, number)
+	copy(prefix[8:], hash[:])
+	return prefix
+}
+
+// Cache stores the block traces produced by the tracing API, so that recent
+// blocks requested repeatedly are only executed once. Traces are kept in an
+// in-memory LRU and optionally in a separate database.
+type Cache struct {
+	mem   *lru.Cache[cacheKey, []byte] // JSON encoded traces of recently used blocks
+	db    ethdb.KeyValueStore          // Optional persistent store, nil if disabled
+	limit uint64                       // Number of recent blocks retained in the persistent store, 0 if unlimited
+
+	lock sync.Mutex // Protects the persisted block range
+	head uint64     // Highest block persisted
+	tail uint64     // Lowest block which may still be persisted
+}
+
+// NewCache creates a trace cache holding the traces of up to the given number of
+// block and tracer combinations in memory. If db is non-nil, the traces are also
+// persisted into it, retaining only the given number of blocks below the highest
+// traced one, unless zero.
+func NewCache(size int, db ethdb.KeyValueStore, limit uint64) *Cache {
+	return &Cache{
+		mem:   lru.NewCache[cacheKey, []byte](size),
+		db:    db,
+		limit: limit,
+	}
+}
+
+// key derives the cache key of tracing the given block with a configuration.
+// Only the options affecting the trace results are taken into account.
+func (c *Cache) key(number uint64, hash common.Hash, config *TraceConfig) (cacheKey, error) {
+	var spec struct {
+		Tracer       string          `json:"tracer,omitempty"`
+		Logger       *logger.Config  `json:"logger,omitempty"`
+		TracerConfig json.RawMessage `json:"tracerConfig,omitempty"`
+	}
+	if config != nil {
+		if config.Tracer != nil && *config.Tracer != "" {
+			spec.Tracer = *config.Tracer
+			if len(config.TracerConfig) > 0 {
+				compact := new(bytes.Buffer)
+				if err := json.Compact(compact, config.TracerConfig); err != nil {
+					return cacheKey{}, err
+				}
+				spec.TracerConfig = compact.Bytes()
+			}
+		} else {
+			spec.Logger = config.Config
+		}
+	}
+	blob, err := json.Marshal(spec)
+	if err != nil {
+		return cacheKey{}, err
+	}
+	return cacheKey{number: number, block: hash, config: crypto.Keccak256Hash(blob)}, nil
+}
+
+// get retrieves the cached traces of a block, or nil if they are not cached.
+func (c *Cache) get(key cacheKey) []*txTraceResult {
+	blob, ok := c.mem.Get(key)
+	if !ok && c.db != nil {
+		if data, err := c.db.Get(key.dbKey()); err == nil {
+			blob, ok = data, true
+			c.mem.Add(key, blob)
+		}
+	}
+	if !ok {
+		cacheMissMeter.Mark(1)
+		return nil
+	}
+	var cached []struct {
+		TxHash common.Hash     `json:"txHash"`
+		Result json.RawMessage `json:"result,omitempty"`
+	}
+	if err := json.Unmarshal(blob, &cached); err != nil {
+		log.Error("Failed to decode cached traces", "number", key.number, "hash", key.block, "err", err)
+		cacheMissMeter.Mark(1)
+		return nil
+	}
+	cacheHitMeter.Mark(1)
+
+	results := make([]*txTraceResult, len(cached))
+	for i, res := range cached {
+		results[i] = &txTraceResult{TxHash: res.TxHash, Result: res.Result}
+	}
+	return results
+}
+
+// put caches the traces of a block. Failed traces are not cached, as they may
+// have been caused by transient conditions like timeouts.
+func (c *Cache) put(key cacheKey, results []*txTraceResult) {
+	for _, res := range results {
+		if res.Error != "" {
+			return
+		}
+	}
+	blob, err := json.Marshal(results)
+	if err != nil {
+		log.Error("Failed to encode traces", "number", key.number, "hash", key.block, "err", err)
+		return
+	}
+	c.mem.Add(key, blob)
+	if c.db != nil && c.retain(key.number) {
+		if err := c.db.Put(key.dbKey(), blob); err != nil {
+			log.Error("Failed to store traces", "number", key.number, "hash", key.block, "err", err)
+		}
+	}
+}
+
+// retain reports whether the traces of the given block are to be persisted. If
+// the block advances the head of the persistent store, the traces of the blocks
+// falling out of the retained range are deleted.
+func (c *Cache) retain(number uint64) bool {
+	if c.limit == 0 {
+		return true
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if number > c.head {
+		c.head = number
+		if number >= c.limit {
+			c.prune(number - c.limit + 1)
+		}
+	}
+	return number+c.limit > c.head
+}
+
+// prune deletes the persisted traces of all blocks below the given number. The
+// caller must hold the cache lock.
+func (c *Cache) prune(limit uint64) {
+	if limit <= c.tail {
+		return
+	}
+	start := make([]byte, 8)
+	binary.BigEndian.PutUint64(start, c.tail)
+
+	it := c.db.NewIterator(nil, start)
+	defer it.Release()
+
+	batch := c.db.NewBatch()
+	for it.Next() {
+		if key := it.Key(); len(key) < 8 || binary.BigEndian.Uint64(key) >= limit {
+			break
+		}
+		batch.Delete(it.Key())
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Error("Failed to delete traces", "err", err)
+				return
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to delete traces", "err", err)
+		return
+	}
+	c.tail = limit
+}
+
+// Invalidate drops all cached traces of the given block.
+func (c *Cache) Invalidate(number uint64, hash common.Hash) {
+	for _, key := range c.mem.Keys() {
+		if key.number == number && key.block == hash {
+			c.mem.Remove(key)
+		}
+	}
+	if c.db != nil {
+		it := c.db.NewIterator(blockPrefix(number, hash), nil)
+		batch := c.db.NewBatch()
+		for it.Next() {
+			batch.Delete(it.Key())
+		}
+		it.Release()
+		if err := batch.Write(); err != nil {
+			log.Error("Failed to delete traces", "number", number, "hash", hash, "err", err)
+		}
+	}
+	cacheInvalidateMeter.Mark(1)
+}
@@ -0,0 +1,242 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// pretraceTestTracer is the tracer run by the pretracer in the tests.
+const pretraceTestTracer = "pretraceTestTracer"
+
+func init() {
+	DefaultDirectory.Register(pretraceTestTracer, func(ctx *Context, cfg json.RawMessage) (Tracer, error) {
+		return logger.NewStructLogger(nil), nil
+	}, false)
+}
+
+// newCacheTestBackend creates a test backend with a transfer between the given
+// accounts in every block, sent to the given recipient from the given block on.
+func newCacheTestBackend(t *testing.T, accounts []Account, n int, fork int, recipient common.Address) (*testBackend, *atomic.Int32) {
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{accounts[0].addr: {Balance: big.NewInt(params.Ether)}},
+	}
+	backend := newTestBackend(t, n, genesis, func(i int, b *core.BlockGen) {
+		to := accounts[1].addr
+		if i >= fork {
+			to = recipient
+		}
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), to, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), types.HomesteadSigner{}, accounts[0].key)
+		b.AddTx(tx)
+	})
+	refs := new(atomic.Int32)
+	backend.refHook = func() { refs.Add(1) }
+	return backend, refs
+}
+
+func TestTraceCache(t *testing.T) {
+	t.Parallel()
+
+	backend, refs := newCacheTestBackend(t, newAccounts(2), 3, 3, common.Address{})
+	defer backend.teardown()
+
+	db := rawdb.NewMemoryDatabase()
+	api := NewAPI(backend)
+	api.cache = NewCache(16, db, 0)
+
+	trace := func(config *TraceConfig) string {
+		t.Helper()
+		results, err := api.TraceBlockByNumber(context.Background(), 1, config)
+		if err != nil {
+			t.Fatalf("failed to trace block: %v", err)
+		}
+		blob, _ := json.Marshal(results)
+		return string(blob)
+	}
+	// Repeated traces are served from the cache
+	want := trace(nil)
+	if have := trace(nil); have != want {
+		t.Fatalf("cached trace mismatch:\nhave %s\nwant %s", have, want)
+	}
+	if have := refs.Load(); have != 1 {
+		t.Fatalf("block executions mismatch: have %d, want 1", have)
+	}
+	// Options affecting the results are cached separately
+	trace(&TraceConfig{Config: &logger.Config{EnableMemory: true}})
+	if have := refs.Load(); have != 2 {
+		t.Fatalf("block executions mismatch: have %d, want 2", have)
+	}
+	timeout := "10s"
+	trace(&TraceConfig{Timeout: &timeout})
+	if have := refs.Load(); have != 2 {
+		t.Fatalf("block executions mismatch: have %d, want 2", have)
+	}
+	// A fresh cache on top of the same database still has the traces
+	api.cache = NewCache(16, db, 0)
+	if have := trace(nil); have != want {
+		t.Fatalf("persisted trace mismatch:\nhave %s\nwant %s", have, want)
+	}
+	if have := refs.Load(); have != 2 {
+		t.Fatalf("block executions mismatch: have %d, want 2", have)
+	}
+	// Invalidated blocks are executed again
+	block := backend.chain.GetBlockByNumber(1)
+	api.cache.Invalidate(block.NumberU64(), block.Hash())
+	trace(nil)
+	if have := refs.Load(); have != 3 {
+		t.Fatalf("block executions mismatch: have %d, want 3", have)
+	}
+}
+
+// Tests that the persistent trace store only retains the most recent blocks.
+func TestTraceCacheRetention(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		cache   = NewCache(16, db, 2)
+		results = []*txTraceResult{{TxHash: common.Hash{0x01}, Result: json.RawMessage(`{}`)}}
+	)
+	key := func(number uint64) cacheKey {
+		key, err := cache.key(number, common.Hash{byte(number)}, nil)
+		if err != nil {
+			t.Fatalf("failed to derive cache key: %v", err)
+		}
+		return key
+	}
+	persisted := func(number uint64) bool {
+		ok, _ := db.Has(key(number).dbKey())
+		return ok
+	}
+	for number := uint64(1); number <= 3; number++ {
+		cache.put(key(number), results)
+	}
+	if persisted(1) || !persisted(2) || !persisted(3) {
+		t.Fatalf("persisted blocks mismatch: have [%v %v %v], want [false true true]", persisted(1), persisted(2), persisted(3))
+	}
+	// Blocks below the retained range are only cached in memory
+	cache.put(key(1), results)
+	if persisted(1) {
+		t.Fatal("block below the retained range persisted")
+	}
+	if cache.get(key(1)) == nil {
+		t.Fatal("block below the retained range not cached in memory")
+	}
+}
+
+func TestPretracer(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	backend, refs := newCacheTestBackend(t, accounts, 4, 4, common.Address{})
+	defer backend.teardown()
+
+	var (
+		cache     = NewCache(64, nil, 0)
+		pretracer = NewPretracer(backend, cache, []string{pretraceTestTracer})
+		tracer    = pretraceTestTracer
+		config    = &TraceConfig{Tracer: &tracer}
+	)
+	cached := func(block *types.Block) bool {
+		key, err := cache.key(block.NumberU64(), block.Hash(), config)
+		if err != nil {
+			t.Fatalf("failed to derive cache key: %v", err)
+		}
+		_, ok := cache.mem.Peek(key)
+		return ok
+	}
+	// Pretrace the initial chain
+	pretracer.update(backend.chain.CurrentHeader())
+	if have := refs.Load(); have != 4 {
+		t.Fatalf("block executions mismatch: have %d, want 4", have)
+	}
+	old := make([]*types.Block, 5)
+	for i := 1; i <= 4; i++ {
+		if old[i] = backend.chain.GetBlockByNumber(uint64(i)); !cached(old[i]) {
+			t.Errorf("block %d not pretraced", i)
+		}
+	}
+	// Reorg to a longer chain forking after block 2
+	fork, _ := newCacheTestBackend(t, accounts, 5, 2, common.Address{0xff})
+	defer fork.teardown()
+
+	blocks := make([]*types.Block, 0, 5)
+	for i := 1; i <= 5; i++ {
+		blocks = append(blocks, fork.chain.GetBlockByNumber(uint64(i)))
+	}
+	if blocks[1].Hash() != old[2].Hash() || blocks[2].Hash() == old[3].Hash() {
+		t.Fatalf("unexpected fork point")
+	}
+	if _, err := backend.chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	pretracer.update(backend.chain.CurrentHeader())
+
+	for i := 1; i <= 2; i++ {
+		if !cached(old[i]) {
+			t.Errorf("block %d traces dropped", i)
+		}
+	}
+	for i := 3; i <= 4; i++ {
+		if cached(old[i]) {
+			t.Errorf("reorged block %d traces not invalidated", i)
+		}
+	}
+	for i := 3; i <= 5; i++ {
+		if !cached(blocks[i-1]) {
+			t.Errorf("new block %d not pretraced", i)
+		}
+	}
+	if have := refs.Load(); have != 7 {
+		t.Fatalf("block executions mismatch: have %d, want 7", have)
+	}
+	// Rewind the chain, dropping the traces beyond the new head
+	if err := backend.chain.SetHead(3); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	pretracer.update(backend.chain.CurrentHeader())
+	if !cached(blocks[2]) {
+		t.Errorf("block 3 traces dropped")
+	}
+	for i := 4; i <= 5; i++ {
+		if cached(blocks[i-1]) {
+			t.Errorf("rewound block %d traces not invalidated", i)
+		}
+	}
+	// The pretraced traces are served by the API
+	api := NewAPI(backend)
+	api.cache = cache
+	if _, err := api.TraceBlockByNumber(context.Background(), rpc.BlockNumber(3), config); err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	if have := refs.Load(); have != 7 {
+		t.Fatalf("block executions mismatch: have %d, want 7", have)
+	}
+}
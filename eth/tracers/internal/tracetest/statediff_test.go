@@ -0,0 +1,163 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// TestStateDiffTracer tests that the state diff tracer reports the balance,
+// nonce, code and storage changes of the touched accounts, and marks created
+// and destroyed accounts.
+func TestStateDiffTracer(t *testing.T) {
+	var (
+		to          = common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		destructed  = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+		beneficiary = common.HexToAddress("0x00000000000000000000000000000000000000cc")
+		origin      = common.HexToAddress("0x00000000000000000000000000000000feed")
+		created     = crypto.CreateAddress(to, 1)
+		context     = vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			Coinbase:    common.Address{},
+			BlockNumber: new(big.Int).SetUint64(8000000),
+			Time:        5,
+			Difficulty:  big.NewInt(0x30000),
+			GasLimit:    uint64(6000000),
+		}
+		txContext = vm.TxContext{
+			Origin:   origin,
+			GasPrice: big.NewInt(1),
+		}
+	)
+	code := []byte{
+		// Overwrite slot 1 with 0x2a and write 0x01 to the empty slot 2
+		byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0x1, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x1, byte(vm.PUSH1), 0x2, byte(vm.SSTORE),
+		// Create a contract with empty code
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.CREATE), byte(vm.POP),
+		// Call the self-destructing contract
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1),
+		byte(vm.PUSH1), 0xbb, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+	}
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(),
+		core.GenesisAlloc{
+			to: core.GenesisAccount{
+				Code:    code,
+				Nonce:   1,
+				Balance: common.Big0,
+				Storage: map[common.Hash]common.Hash{common.BigToHash(common.Big1): common.HexToHash("0x07")},
+			},
+			destructed: core.GenesisAccount{Code: []byte{byte(vm.PUSH1), 0xcc, byte(vm.SELFDESTRUCT)}, Balance: big.NewInt(9)},
+			origin:     core.GenesisAccount{Balance: big.NewInt(500000000000000)},
+		}, false)
+
+	tracer, err := tracers.DefaultDirectory.New("stateDiffTracer", new(tracers.Context), nil)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	evm := vm.NewEVM(context, txContext, statedb, params.MainnetChainConfig, vm.Config{Tracer: tracer})
+	msg := &core.Message{
+		To:        &to,
+		From:      origin,
+		Value:     big.NewInt(0),
+		GasLimit:  300000,
+		GasPrice:  big.NewInt(1),
+		GasFeeCap: big.NewInt(1),
+		GasTipCap: big.NewInt(1),
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(msg.GasLimit))
+	res, err := st.TransitionDb()
+	if err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	if res.Err != nil {
+		t.Fatalf("transaction failed: %v", res.Err)
+	}
+	raw, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	var diff tracers.StateDiff
+	if err := json.Unmarshal(raw, &diff); err != nil {
+		t.Fatalf("failed to decode trace result: %v", err)
+	}
+	check := func(name string, have *tracers.Delta, kind, from, to string) {
+		t.Helper()
+		if have == nil {
+			t.Errorf("%s: missing delta", name)
+			return
+		}
+		want := tracers.Delta{Kind: kind, From: from, To: to}
+		if *have != want {
+			t.Errorf("%s: delta mismatch: have %+v, want %+v", name, *have, want)
+		}
+	}
+	// The sender paid the fees and bumped its nonce
+	if acc := diff[origin]; acc == nil {
+		t.Fatalf("sender missing from diff: %s", raw)
+	} else {
+		check("sender nonce", acc.Nonce, tracers.DeltaChanged, "0x0", "0x1")
+		check("sender code", acc.Code, tracers.DeltaUnchanged, "", "")
+		if acc.Balance == nil || acc.Balance.Kind != tracers.DeltaChanged {
+			t.Errorf("sender balance not changed: %+v", acc.Balance)
+		}
+	}
+	// The contract wrote its storage and bumped its nonce with the creation
+	if acc := diff[to]; acc == nil {
+		t.Fatalf("contract missing from diff: %s", raw)
+	} else {
+		check("contract nonce", acc.Nonce, tracers.DeltaChanged, "0x1", "0x2")
+		check("contract balance", acc.Balance, tracers.DeltaUnchanged, "", "")
+		if len(acc.Storage) != 2 {
+			t.Errorf("contract storage changes mismatch: have %d, want 2", len(acc.Storage))
+		}
+		check("contract slot 1", acc.Storage[common.BigToHash(common.Big1)], tracers.DeltaChanged,
+			common.HexToHash("0x07").Hex(), common.HexToHash("0x2a").Hex())
+		check("contract slot 2", acc.Storage[common.BigToHash(common.Big2)], tracers.DeltaChanged,
+			common.Hash{}.Hex(), common.HexToHash("0x01").Hex())
+	}
+	// The created contract was born, the self-destructed one died
+	if acc := diff[created]; acc == nil {
+		t.Errorf("created contract missing from diff: %s", raw)
+	} else {
+		check("created nonce", acc.Nonce, tracers.DeltaBorn, "", "0x1")
+		check("created code", acc.Code, tracers.DeltaBorn, "", "0x")
+	}
+	if acc := diff[destructed]; acc == nil {
+		t.Errorf("destructed contract missing from diff: %s", raw)
+	} else {
+		check("destructed balance", acc.Balance, tracers.DeltaDied, "0x9", "")
+		check("destructed code", acc.Code, tracers.DeltaDied, "0x60ccff", "")
+	}
+	if acc := diff[beneficiary]; acc == nil {
+		t.Errorf("beneficiary missing from diff: %s", raw)
+	} else {
+		check("beneficiary balance", acc.Balance, tracers.DeltaBorn, "", "0x9")
+	}
+}
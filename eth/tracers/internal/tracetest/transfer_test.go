@@ -0,0 +1,112 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// TestTokenTransferTracer tests that the token transfer tracer reports native
+// transfers of all call frames along with decoded token transfer events, and
+// drops the transfers of reverted frames.
+func TestTokenTransferTracer(t *testing.T) {
+	var (
+		to       = common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		reverter = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+		origin   = common.HexToAddress("0x00000000000000000000000000000000feed")
+		topic    = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+		context  = vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			Coinbase:    common.Address{},
+			BlockNumber: new(big.Int).SetUint64(8000000),
+			Time:        5,
+			Difficulty:  big.NewInt(0x30000),
+			GasLimit:    uint64(6000000),
+		}
+		txContext = vm.TxContext{
+			Origin:   origin,
+			GasPrice: big.NewInt(1),
+		}
+	)
+	code := []byte{
+		// Send 3 wei to 0xff
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1),
+		byte(vm.PUSH1), 0x3, byte(vm.PUSH1), 0xff, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		// Send 2 wei to a reverting contract
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1),
+		byte(vm.PUSH1), 0x2, byte(vm.PUSH1), 0xbb, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		// Emit Transfer(self, 0xff, 7)
+		byte(vm.PUSH1), 0x7, byte(vm.PUSH1), 0x0, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0xff, byte(vm.ADDRESS), byte(vm.PUSH32),
+	}
+	code = append(code, topic.Bytes()...)
+	code = append(code, byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x0, byte(vm.LOG3))
+
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(),
+		core.GenesisAlloc{
+			to:       core.GenesisAccount{Code: code, Balance: big.NewInt(10)},
+			reverter: core.GenesisAccount{Code: []byte{byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.REVERT)}, Balance: common.Big0},
+			origin:   core.GenesisAccount{Balance: big.NewInt(500000000000000)},
+		}, false)
+
+	tracer, err := tracers.DefaultDirectory.New("tokenTransferTracer", new(tracers.Context), nil)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	evm := vm.NewEVM(context, txContext, statedb, params.MainnetChainConfig, vm.Config{Tracer: tracer})
+	msg := &core.Message{
+		To:        &to,
+		From:      origin,
+		Value:     big.NewInt(5),
+		GasLimit:  200000,
+		GasPrice:  big.NewInt(1),
+		GasFeeCap: big.NewInt(1),
+		GasTipCap: big.NewInt(1),
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(msg.GasLimit))
+	if _, err := st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	want := fmt.Sprintf(`[`+
+		`{"type":"native","from":"%[1]s","to":"%[2]s","value":"0x5"},`+
+		`{"type":"native","from":"%[2]s","to":"0x00000000000000000000000000000000000000ff","value":"0x3"},`+
+		`{"type":"erc20","token":"%[2]s","from":"%[2]s","to":"0x00000000000000000000000000000000000000ff","value":"0x7"}`+
+		`]`, hexAddress(origin), hexAddress(to))
+	if string(res) != want {
+		t.Errorf("trace mismatch\n have: %v\n want: %v\n", string(res), want)
+	}
+}
+
+func hexAddress(addr common.Address) string {
+	return "0x" + common.Bytes2Hex(addr.Bytes())
+}
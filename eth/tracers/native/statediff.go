@@ -0,0 +1,245 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("stateDiffTracer", newStateDiffTracer, false)
+}
+
+// diffAccount is the state of an account before a transaction, along with the
+// storage slots written by it.
+type diffAccount struct {
+	exists  bool
+	balance *big.Int
+	nonce   uint64
+	code    []byte
+	storage map[common.Hash]common.Hash
+}
+
+// stateDiffTracer reports the balance, nonce, code and storage changes done by
+// a transaction, in the style of the OpenEthereum stateDiff output. Only the
+// accounts and storage slots actually modified are reported, the values of the
+// other fields are omitted.
+//
+// Example:
+//
+//	> debug.traceTransaction("0x...", {tracer: "stateDiffTracer"})
+//	{
+//	  "0x...": {
+//	    balance: {"*": {from: "0x56bc75e2d63100000", to: "0x56bc75e2d630e7d80"}},
+//	    nonce: {"*": {from: "0x0", to: "0x1"}},
+//	    code: "=",
+//	    storage: {}
+//	  },
+//	  "0x...": {
+//	    balance: {"+": "0x0"},
+//	    nonce: {"+": "0x1"},
+//	    code: {"+": "0x6080..."},
+//	    storage: {"0x00...00": {"+": "0x00...2a"}}
+//	  }
+//	}
+type stateDiffTracer struct {
+	noopTracer
+	env       *vm.EVM
+	pre       map[common.Address]*diffAccount
+	diff      tracers.StateDiff
+	gasLimit  uint64      // Amount of gas bought for the whole tx
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+func newStateDiffTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &stateDiffTracer{
+		pre:  make(map[common.Address]*diffAccount),
+		diff: make(tracers.StateDiff),
+	}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *stateDiffTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+
+	t.lookupAccount(from)
+	t.lookupAccount(env.Context.Coinbase)
+
+	// A created contract is already initialized with the transferred value
+	if create {
+		t.pre[to] = &diffAccount{
+			balance: new(big.Int).Sub(env.StateDB.GetBalance(to), value),
+			storage: make(map[common.Hash]common.Hash),
+		}
+		t.pre[to].exists = t.pre[to].balance.Sign() != 0
+	} else {
+		t.lookupAccount(to)
+		t.pre[to].balance.Sub(t.pre[to].balance, value)
+	}
+	// The sender balance is after reducing: value and gasLimit.
+	// We need to re-add them to get the pre-tx balance.
+	fromBal := t.pre[from].balance
+	consumedGas := new(big.Int).Mul(env.TxContext.GasPrice, new(big.Int).SetUint64(t.gasLimit))
+	fromBal.Add(fromBal, new(big.Int).Add(value, consumedGas))
+	t.pre[from].nonce--
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *stateDiffTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil {
+		return
+	}
+	// Skip if tracing was interrupted
+	if t.interrupt.Load() {
+		return
+	}
+	stackData := scope.Stack.Data()
+	stackLen := len(stackData)
+	caller := scope.Contract.Address()
+	switch {
+	case stackLen >= 1 && op == vm.SSTORE:
+		t.lookupAccount(caller)
+		slot := common.Hash(stackData[stackLen-1].Bytes32())
+		if _, ok := t.pre[caller].storage[slot]; !ok {
+			t.pre[caller].storage[slot] = t.env.StateDB.GetState(caller, slot)
+		}
+	case stackLen >= 1 && op == vm.SELFDESTRUCT:
+		t.lookupAccount(caller)
+		t.lookupAccount(common.Address(stackData[stackLen-1].Bytes20()))
+	case stackLen >= 3 && (op == vm.CALL || op == vm.CALLCODE):
+		t.lookupAccount(common.Address(stackData[stackLen-2].Bytes20()))
+	case op == vm.CREATE:
+		t.lookupAccount(crypto.CreateAddress(caller, t.env.StateDB.GetNonce(caller)))
+		t.lookupAccount(caller)
+	case stackLen >= 4 && op == vm.CREATE2:
+		offset := stackData[stackLen-2]
+		size := stackData[stackLen-3]
+		init, err := tracers.GetMemoryCopyPadded(scope.Memory, int64(offset.Uint64()), int64(size.Uint64()))
+		if err != nil {
+			log.Warn("failed to copy CREATE2 input", "err", err, "tracer", "stateDiffTracer", "offset", offset, "size", size)
+			return
+		}
+		salt := stackData[stackLen-4]
+		t.lookupAccount(crypto.CreateAddress2(caller, salt.Bytes32(), crypto.Keccak256(init)))
+		t.lookupAccount(caller)
+	}
+}
+
+func (t *stateDiffTracer) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+// CaptureTxEnd compares the touched accounts against their final state.
+func (t *stateDiffTracer) CaptureTxEnd(restGas uint64) {
+	if t.env == nil {
+		return
+	}
+	statedb := t.env.StateDB
+	for addr, pre := range t.pre {
+		post := &diffAccount{
+			exists:  !statedb.Empty(addr) && !statedb.HasSelfDestructed(addr),
+			balance: statedb.GetBalance(addr),
+			nonce:   statedb.GetNonce(addr),
+			code:    statedb.GetCode(addr),
+		}
+		if pre.exists == post.exists && pre.balance.Cmp(post.balance) == 0 && pre.nonce == post.nonce && bytes.Equal(pre.code, post.code) && !storageChanged(statedb, addr, pre.storage) {
+			continue
+		}
+		diff := &tracers.AccountDiff{
+			Balance: tracers.NewDelta(diffField(pre, (*hexutil.Big)(pre.balance)), diffField(post, (*hexutil.Big)(post.balance))),
+			Nonce:   tracers.NewDelta(diffField(pre, hexutil.Uint64(pre.nonce)), diffField(post, hexutil.Uint64(post.nonce))),
+			Code:    tracers.NewDelta(diffField(pre, hexutil.Bytes(pre.code)), diffField(post, hexutil.Bytes(post.code))),
+			Storage: make(map[common.Hash]*tracers.Delta),
+		}
+		for slot, val := range pre.storage {
+			var from, to string
+			if pre.exists {
+				from = val.Hex()
+			}
+			if post.exists {
+				to = statedb.GetState(addr, slot).Hex()
+			}
+			if delta := tracers.NewDelta(from, to); delta != nil && delta.Kind != tracers.DeltaUnchanged {
+				diff.Storage[slot] = delta
+			}
+		}
+		t.diff[addr] = diff
+	}
+}
+
+// storageChanged reports whether any of the written slots of an account differs
+// from its value before the transaction.
+func storageChanged(statedb vm.StateDB, addr common.Address, pre map[common.Hash]common.Hash) bool {
+	for slot, val := range pre {
+		if statedb.GetState(addr, slot) != val {
+			return true
+		}
+	}
+	return false
+}
+
+// diffField encodes the value of an account field, or returns the empty string
+// if the account does not exist.
+func diffField(acc *diffAccount, val interface{ String() string }) string {
+	if !acc.exists {
+		return ""
+	}
+	return val.String()
+}
+
+// GetResult returns the json-encoded state diff, and any error arising from the
+// encoding or forceful termination (via `Stop`).
+func (t *stateDiffTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.diff)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *stateDiffTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// lookupAccount fetches the state of an account before the transaction touches
+// it, if it's not tracked yet.
+func (t *stateDiffTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.pre[addr]; ok {
+		return
+	}
+	statedb := t.env.StateDB
+	t.pre[addr] = &diffAccount{
+		exists:  !statedb.Empty(addr),
+		balance: new(big.Int).Set(statedb.GetBalance(addr)),
+		nonce:   statedb.GetNonce(addr),
+		code:    statedb.GetCode(addr),
+		storage: make(map[common.Hash]common.Hash),
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("tokenTransferTracer", newTokenTransferTracer, false)
+}
+
+// tokenTransferTracer collects all movements of the native currency and of
+// ERC-20, ERC-721 and ERC-1155 tokens done by a transaction, including the ones
+// in internal calls. Transfers of reverted call frames are discarded.
+//
+// Example:
+//
+//	> debug.traceTransaction("0x...", {tracer: "tokenTransferTracer"})
+//	[
+//	  {type: "native", from: "0x...", to: "0x...", value: "0xde0b6b3a7640000"},
+//	  {type: "erc20", token: "0x...", from: "0x...", to: "0x...", value: "0x64"}
+//	]
+type tokenTransferTracer struct {
+	noopTracer
+	frames    [][]*tracers.Transfer // Transfers of each active call frame
+	interrupt atomic.Bool           // Atomic flag to signal execution interruption
+	reason    error                 // Textual reason for the interruption
+}
+
+// newTokenTransferTracer returns a native go tracer which collects the value
+// transfers of a transaction, and implements vm.EVMLogger.
+func newTokenTransferTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &tokenTransferTracer{}, nil
+}
+
+// record appends transfers to the innermost active call frame.
+func (t *tokenTransferTracer) record(transfers ...*tracers.Transfer) {
+	t.frames[len(t.frames)-1] = append(t.frames[len(t.frames)-1], transfers...)
+}
+
+// recordNative appends a transfer of the native currency, if any value moves.
+func (t *tokenTransferTracer) recordNative(from, to common.Address, value *big.Int) {
+	if value == nil || value.Sign() == 0 {
+		return
+	}
+	t.record(&tracers.Transfer{
+		Type:  tracers.TransferNative,
+		From:  from,
+		To:    to,
+		Value: (*hexutil.Big)(new(big.Int).Set(value)),
+	})
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *tokenTransferTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.frames = [][]*tracers.Transfer{nil}
+	t.recordNative(from, to, value)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *tokenTransferTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if err != nil {
+		t.frames[0] = nil
+	}
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *tokenTransferTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	// skip if the previous op caused an error
+	if err != nil {
+		return
+	}
+	// Skip if tracing was interrupted
+	if t.interrupt.Load() {
+		return
+	}
+	// Only logs with topics may announce token transfers
+	if op < vm.LOG3 || op > vm.LOG4 {
+		return
+	}
+	var (
+		size      = int(op - vm.LOG0)
+		stackData = scope.Stack.Data()
+	)
+	if len(stackData) < size+2 {
+		return
+	}
+	mStart := stackData[len(stackData)-1]
+	mSize := stackData[len(stackData)-2]
+	topics := make([]common.Hash, size)
+	for i := 0; i < size; i++ {
+		topics[i] = common.Hash(stackData[len(stackData)-2-(i+1)].Bytes32())
+	}
+	data, err := tracers.GetMemoryCopyPadded(scope.Memory, int64(mStart.Uint64()), int64(mSize.Uint64()))
+	if err != nil {
+		// mSize was unrealistically large
+		log.Warn("failed to copy log data", "err", err, "tracer", "tokenTransferTracer", "offset", mStart, "size", mSize)
+		return
+	}
+	t.record(tracers.DecodeTokenTransfers(scope.Contract.Address(), topics, data)...)
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *tokenTransferTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.frames = append(t.frames, nil)
+
+	// Only these operations move funds to another account
+	switch typ {
+	case vm.CALL, vm.CREATE, vm.CREATE2, vm.SELFDESTRUCT:
+		t.recordNative(from, to, value)
+	}
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *tokenTransferTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	size := len(t.frames)
+	if size <= 1 {
+		return
+	}
+	frame := t.frames[size-1]
+	t.frames = t.frames[:size-1]
+
+	// Transfers of reverted frames never happened
+	if err == nil {
+		t.frames[size-2] = append(t.frames[size-2], frame...)
+	}
+}
+
+// GetResult returns the json-encoded list of transfers, and any error arising
+// from the encoding or forceful termination (via `Stop`).
+func (t *tokenTransferTracer) GetResult() (json.RawMessage, error) {
+	transfers := []*tracers.Transfer{}
+	if len(t.frames) > 0 {
+		transfers = append(transfers, t.frames[0]...)
+	}
+	res, err := json.Marshal(transfers)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *tokenTransferTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
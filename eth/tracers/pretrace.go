@@ -0,0 +1,202 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// pretraceBacklog is the maximum number of blocks behind the head which are
+	// pretraced on a head update, limiting the work done while syncing.
+	pretraceBacklog = 16
+
+	// pretraceReorgWindow is the number of recently pretraced blocks tracked to
+	// invalidate their traces if they are reorged out.
+	pretraceReorgWindow = 128
+)
+
+var (
+	pretraceTimer     = metrics.NewRegisteredTimer("trace/pretrace/time", nil)
+	pretraceFailMeter = metrics.NewRegisteredMeter("trace/pretrace/fail", nil)
+)
+
+// PretraceBackend is the backend of the pretracer, which on top of the tracing
+// backend delivers chain head events.
+type PretraceBackend interface {
+	Backend
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Pretracer traces new canonical blocks with a set of tracers in the background
+// and stores the results in the trace cache, so that they are ready when first
+// requested. The traces of pretraced blocks replaced by a reorg are invalidated.
+type Pretracer struct {
+	api     *API
+	backend PretraceBackend
+	tracers []string
+
+	canon map[uint64]common.Hash // Recently pretraced canonical blocks
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewPretracer creates a pretracer running the given tracers on all new
+// canonical blocks, and storing the results in the given cache.
+func NewPretracer(backend PretraceBackend, cache *Cache, tracers []string) *Pretracer {
+	api := NewAPI(backend)
+	api.cache = cache
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Pretracer{
+		api:     api,
+		backend: backend,
+		tracers: tracers,
+		canon:   make(map[uint64]common.Hash),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start implements node.Lifecycle, starting the pretracing loop.
+func (p *Pretracer) Start() error {
+	p.wg.Add(1)
+	go p.loop()
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the pretracing loop.
+func (p *Pretracer) Stop() error {
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+// loop pretraces the blocks of each chain head update. Heads arriving while a
+// previous update is processed are coalesced into the most recent one.
+func (p *Pretracer) loop() {
+	defer p.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := p.backend.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-heads:
+			head := ev.Block
+			for drained := false; !drained; {
+				select {
+				case ev := <-heads:
+					head = ev.Block
+				default:
+					drained = true
+				}
+			}
+			p.update(head.Header())
+
+		case <-sub.Err():
+			return
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// update invalidates the traces of the pretraced blocks replaced by the chain
+// ending in the given head, and pretraces its recent blocks.
+func (p *Pretracer) update(head *types.Header) {
+	number := head.Number.Uint64()
+
+	// Drop the traces of blocks beyond the new head, the chain was rewound or
+	// replaced by a shorter one
+	for n, hash := range p.canon {
+		if n > number {
+			p.api.cache.Invalidate(n, hash)
+			delete(p.canon, n)
+		}
+	}
+	// Walk back the new chain until it joins the pretraced one, dropping the
+	// traces of the blocks it replaces
+	var fresh []*types.Header
+	for header := head; header != nil && header.Number.Sign() > 0; {
+		n := header.Number.Uint64()
+		known, ok := p.canon[n]
+		if ok && known == header.Hash() {
+			break
+		}
+		if ok {
+			p.api.cache.Invalidate(n, known)
+			delete(p.canon, n)
+		} else if n+pretraceBacklog <= number {
+			break
+		}
+		if n+pretraceBacklog > number {
+			fresh = append(fresh, header)
+		}
+		parent, err := p.backend.HeaderByHash(p.ctx, header.ParentHash)
+		if err != nil {
+			log.Debug("Failed to retrieve parent for pretracing", "number", n-1, "hash", header.ParentHash, "err", err)
+			break
+		}
+		header = parent
+	}
+	// Pretrace the new blocks in chain order
+	for i := len(fresh) - 1; i >= 0; i-- {
+		if p.ctx.Err() != nil {
+			return
+		}
+		p.pretrace(fresh[i])
+	}
+	// Forget the blocks too old to be reorged out
+	for n := range p.canon {
+		if n+pretraceReorgWindow <= number {
+			delete(p.canon, n)
+		}
+	}
+}
+
+// pretrace traces the given block with all configured tracers.
+func (p *Pretracer) pretrace(header *types.Header) {
+	hash := header.Hash()
+	block, err := p.api.blockByHash(p.ctx, hash)
+	if err != nil {
+		log.Debug("Failed to retrieve block for pretracing", "number", header.Number, "hash", hash, "err", err)
+		return
+	}
+	start := time.Now()
+	for _, name := range p.tracers {
+		tracer := name
+		if _, err := p.api.traceBlock(p.ctx, block, &TraceConfig{Tracer: &tracer}); err != nil {
+			log.Debug("Failed to pretrace block", "number", block.Number(), "hash", hash, "tracer", name, "err", err)
+			pretraceFailMeter.Mark(1)
+		}
+	}
+	pretraceTimer.UpdateSince(start)
+	p.canon[block.NumberU64()] = hash
+}
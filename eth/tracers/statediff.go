@@ -0,0 +1,256 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// stateDiffTracer is the name of the native tracer producing state diffs.
+const stateDiffTracer = "stateDiffTracer"
+
+// Kinds of state field changes.
+const (
+	DeltaUnchanged = "="
+	DeltaBorn      = "+"
+	DeltaDied      = "-"
+	DeltaChanged   = "*"
+)
+
+// Delta is the change of a single state field, with the values encoded as hex
+// strings. An empty value means the field did not exist, because the account
+// was created or destroyed. It is marshalled in the OpenEthereum style:
+//
+//	"="                               unchanged
+//	{"+": to}                         created along with the account
+//	{"-": from}                       destroyed along with the account
+//	{"*": {"from": from, "to": to}}   changed
+//
+// When known, the value at the start of the block is added as "original".
+type Delta struct {
+	Kind     string
+	From     string
+	To       string
+	Original string
+}
+
+// NewDelta creates the change of a field between two values, or returns nil if
+// the field existed neither before nor after.
+func NewDelta(from, to string) *Delta {
+	switch {
+	case from == "" && to == "":
+		return nil
+	case from == "":
+		return &Delta{Kind: DeltaBorn, To: to}
+	case to == "":
+		return &Delta{Kind: DeltaDied, From: from}
+	case from == to:
+		return &Delta{Kind: DeltaUnchanged, From: from, To: to}
+	default:
+		return &Delta{Kind: DeltaChanged, From: from, To: to}
+	}
+}
+
+type deltaChange struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Original string `json:"original,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d *Delta) MarshalJSON() ([]byte, error) {
+	switch d.Kind {
+	case DeltaUnchanged:
+		return json.Marshal(DeltaUnchanged)
+	case DeltaBorn:
+		return json.Marshal(map[string]string{DeltaBorn: d.To})
+	case DeltaDied:
+		return json.Marshal(map[string]string{DeltaDied: d.From})
+	case DeltaChanged:
+		return json.Marshal(map[string]deltaChange{DeltaChanged: {d.From, d.To, d.Original}})
+	}
+	return nil, fmt.Errorf("invalid delta kind %q", d.Kind)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The values of unchanged fields are
+// not part of the encoding, so they are lost.
+func (d *Delta) UnmarshalJSON(input []byte) error {
+	var kind string
+	if err := json.Unmarshal(input, &kind); err == nil {
+		if kind != DeltaUnchanged {
+			return fmt.Errorf("invalid delta %q", kind)
+		}
+		*d = Delta{Kind: DeltaUnchanged}
+		return nil
+	}
+	var dec map[string]json.RawMessage
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if len(dec) != 1 {
+		return errors.New("invalid delta")
+	}
+	for kind, raw := range dec {
+		switch kind {
+		case DeltaBorn:
+			*d = Delta{Kind: DeltaBorn}
+			return json.Unmarshal(raw, &d.To)
+		case DeltaDied:
+			*d = Delta{Kind: DeltaDied}
+			return json.Unmarshal(raw, &d.From)
+		case DeltaChanged:
+			var change deltaChange
+			if err := json.Unmarshal(raw, &change); err != nil {
+				return err
+			}
+			*d = Delta{Kind: DeltaChanged, From: change.From, To: change.To, Original: change.Original}
+			return nil
+		}
+		return fmt.Errorf("invalid delta kind %q", kind)
+	}
+	return nil
+}
+
+// AccountDiff is the change of a single account.
+type AccountDiff struct {
+	Balance *Delta                 `json:"balance"`
+	Nonce   *Delta                 `json:"nonce"`
+	Code    *Delta                 `json:"code"`
+	Storage map[common.Hash]*Delta `json:"storage"`
+}
+
+// StateDiff is the set of accounts changed by a transaction or block.
+type StateDiff map[common.Address]*AccountDiff
+
+// Merge folds the diff of a subsequent transaction into the diff of a block.
+// The transaction diff is annotated with the values the changed fields had at
+// the start of the block. Fields of changed accounts which were not touched are
+// reported as unchanged.
+func (d StateDiff) Merge(tx StateDiff) {
+	for addr, next := range tx {
+		prev := d[addr]
+		if prev == nil {
+			prev = &AccountDiff{Storage: make(map[common.Hash]*Delta)}
+			d[addr] = prev
+		}
+		prev.Balance = mergeDelta(prev.Balance, next.Balance)
+		prev.Nonce = mergeDelta(prev.Nonce, next.Nonce)
+		prev.Code = mergeDelta(prev.Code, next.Code)
+		for slot, delta := range next.Storage {
+			if merged := mergeDelta(prev.Storage[slot], delta); merged != nil {
+				prev.Storage[slot] = merged
+			} else {
+				delete(prev.Storage, slot)
+			}
+		}
+		// Drop accounts which were created and destroyed within the block
+		if prev.Balance == nil && prev.Nonce == nil && prev.Code == nil && len(prev.Storage) == 0 {
+			delete(d, addr)
+			continue
+		}
+		for _, field := range []**Delta{&prev.Balance, &prev.Nonce, &prev.Code} {
+			if *field == nil {
+				*field = &Delta{Kind: DeltaUnchanged}
+			}
+		}
+	}
+}
+
+// mergeDelta combines the accumulated change of a field with a subsequent one,
+// and annotates the latter with the value at the start of the block.
+func mergeDelta(prev, next *Delta) *Delta {
+	if next == nil || next.Kind == DeltaUnchanged {
+		if next != nil && prev != nil {
+			next.Original = prev.From
+		}
+		if prev == nil && next != nil {
+			return &Delta{Kind: DeltaUnchanged, From: next.From, To: next.To}
+		}
+		return prev
+	}
+	if prev == nil || prev.Kind == DeltaUnchanged {
+		next.Original = next.From
+		merged := *next
+		return &merged
+	}
+	next.Original = prev.From
+	merged := NewDelta(prev.From, next.To)
+	if merged != nil {
+		merged.Original = prev.From
+	}
+	return merged
+}
+
+// TxStateDiff is the state diff of a single transaction of a block.
+type TxStateDiff struct {
+	TxHash    common.Hash `json:"txHash"`
+	StateDiff StateDiff   `json:"stateDiff"`
+}
+
+// BlockStateDiff is the state diff of a block, along with the diffs of all its
+// transactions.
+type BlockStateDiff struct {
+	Transactions []*TxStateDiff `json:"transactions"`
+	StateDiff    StateDiff      `json:"stateDiff"`
+}
+
+// TraceBlockStateDiff returns the state changes of each transaction of the given
+// block, along with the changes of the whole block. Every changed transaction
+// field also carries the value it had at the start of the block.
+func (api *API) TraceBlockStateDiff(ctx context.Context, number rpc.BlockNumber, config *TraceConfig) (*BlockStateDiff, error) {
+	block, err := api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	traceConfig := &TraceConfig{}
+	if config != nil {
+		*traceConfig = *config
+	}
+	tracer := stateDiffTracer
+	traceConfig.Tracer = &tracer
+
+	results, err := api.traceBlock(ctx, block, traceConfig)
+	if err != nil {
+		return nil, err
+	}
+	diff := &BlockStateDiff{
+		Transactions: make([]*TxStateDiff, len(results)),
+		StateDiff:    make(StateDiff),
+	}
+	for i, result := range results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("tx %#x: %s", result.TxHash, result.Error)
+		}
+		raw, ok := result.Result.(json.RawMessage)
+		if !ok {
+			return nil, fmt.Errorf("tx %#x: unexpected trace result %T", result.TxHash, result.Result)
+		}
+		txDiff := make(StateDiff)
+		if err := json.Unmarshal(raw, &txDiff); err != nil {
+			return nil, fmt.Errorf("tx %#x: %w", result.TxHash, err)
+		}
+		diff.StateDiff.Merge(txDiff)
+		diff.Transactions[i] = &TxStateDiff{TxHash: result.TxHash, StateDiff: txDiff}
+	}
+	return diff, nil
+}
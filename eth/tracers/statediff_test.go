@@ -0,0 +1,148 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestDeltaJSON(t *testing.T) {
+	var tests = []struct {
+		delta *Delta
+		want  string
+	}{
+		{NewDelta("0x1", "0x1"), `"="`},
+		{NewDelta("", "0x1"), `{"+":"0x1"}`},
+		{NewDelta("0x1", ""), `{"-":"0x1"}`},
+		{NewDelta("0x1", "0x2"), `{"*":{"from":"0x1","to":"0x2"}}`},
+		{&Delta{Kind: DeltaChanged, From: "0x1", To: "0x2", Original: "0x0"}, `{"*":{"from":"0x1","to":"0x2","original":"0x0"}}`},
+	}
+	for i, tt := range tests {
+		have, err := json.Marshal(tt.delta)
+		if err != nil {
+			t.Fatalf("test %d: failed to marshal: %v", i, err)
+		}
+		if string(have) != tt.want {
+			t.Errorf("test %d: encoding mismatch: have %s, want %s", i, have, tt.want)
+		}
+		var dec Delta
+		if err := json.Unmarshal(have, &dec); err != nil {
+			t.Fatalf("test %d: failed to unmarshal: %v", i, err)
+		}
+		want := *tt.delta
+		if want.Kind == DeltaUnchanged {
+			want = Delta{Kind: DeltaUnchanged}
+		}
+		if dec != want {
+			t.Errorf("test %d: decoding mismatch: have %+v, want %+v", i, dec, want)
+		}
+	}
+}
+
+func TestStateDiffMerge(t *testing.T) {
+	var (
+		acc     = common.Address{0x01}
+		created = common.Address{0x02}
+		slot    = common.Hash{0x01}
+	)
+	txs := []StateDiff{
+		{
+			acc: {
+				Balance: NewDelta("0x10", "0x8"),
+				Nonce:   NewDelta("0x0", "0x1"),
+				Code:    &Delta{Kind: DeltaUnchanged},
+				Storage: map[common.Hash]*Delta{slot: NewDelta("0x0", "0x1")},
+			},
+			created: {
+				Balance: NewDelta("", "0x0"),
+				Nonce:   NewDelta("", "0x1"),
+				Code:    NewDelta("", "0x60"),
+				Storage: map[common.Hash]*Delta{},
+			},
+		},
+		{
+			acc: {
+				Balance: NewDelta("0x8", "0x4"),
+				Nonce:   NewDelta("0x1", "0x2"),
+				Code:    &Delta{Kind: DeltaUnchanged},
+				Storage: map[common.Hash]*Delta{slot: NewDelta("0x1", "0x2")},
+			},
+			created: {
+				Balance: NewDelta("0x0", ""),
+				Nonce:   NewDelta("0x1", ""),
+				Code:    NewDelta("0x60", ""),
+				Storage: map[common.Hash]*Delta{},
+			},
+		},
+	}
+	block := make(StateDiff)
+	for _, tx := range txs {
+		block.Merge(tx)
+	}
+	want := StateDiff{
+		acc: {
+			Balance: &Delta{Kind: DeltaChanged, From: "0x10", To: "0x4", Original: "0x10"},
+			Nonce:   &Delta{Kind: DeltaChanged, From: "0x0", To: "0x2", Original: "0x0"},
+			Code:    &Delta{Kind: DeltaUnchanged},
+			Storage: map[common.Hash]*Delta{slot: {Kind: DeltaChanged, From: "0x0", To: "0x2", Original: "0x0"}},
+		},
+	}
+	if !reflect.DeepEqual(block, want) {
+		have, _ := json.Marshal(block)
+		wantJSON, _ := json.Marshal(want)
+		t.Fatalf("block diff mismatch:\nhave %s\nwant %s", have, wantJSON)
+	}
+	// The transaction diffs are annotated with the values at the block start
+	if have := txs[1][acc].Storage[slot].Original; have != "0x0" {
+		t.Errorf("second transaction storage original mismatch: have %q, want %q", have, "0x0")
+	}
+	if have := txs[1][acc].Balance.Original; have != "0x10" {
+		t.Errorf("second transaction balance original mismatch: have %q, want %q", have, "0x10")
+	}
+}
+
+// Tests that the block diff reports the untouched fields of partially changed
+// accounts as unchanged, when merging the encoded transaction diffs.
+func TestStateDiffMergePartial(t *testing.T) {
+	txs := []string{
+		`{"0x0100000000000000000000000000000000000000": {"balance": {"*": {"from": "0x10", "to": "0x8"}}, "nonce": "=", "code": "=", "storage": {}}}`,
+		`{"0x0100000000000000000000000000000000000000": {"balance": "=", "nonce": {"*": {"from": "0x0", "to": "0x1"}}, "code": "=", "storage": {}},
+		  "0x0200000000000000000000000000000000000000": {"balance": {"*": {"from": "0x0", "to": "0x1"}}, "nonce": "=", "code": "=", "storage": {}}}`,
+	}
+	block := make(StateDiff)
+	for _, tx := range txs {
+		diff := make(StateDiff)
+		if err := json.Unmarshal([]byte(tx), &diff); err != nil {
+			t.Fatalf("failed to decode transaction diff: %v", err)
+		}
+		block.Merge(diff)
+	}
+	have, err := json.Marshal(block)
+	if err != nil {
+		t.Fatalf("failed to encode block diff: %v", err)
+	}
+	want := `{` +
+		`"0x0100000000000000000000000000000000000000":{"balance":{"*":{"from":"0x10","to":"0x8","original":"0x10"}},"nonce":{"*":{"from":"0x0","to":"0x1","original":"0x0"}},"code":"=","storage":{}},` +
+		`"0x0200000000000000000000000000000000000000":{"balance":{"*":{"from":"0x0","to":"0x1","original":"0x0"}},"nonce":"=","code":"=","storage":{}}}`
+	if string(have) != want {
+		t.Fatalf("block diff mismatch:\nhave %s\nwant %s", have, want)
+	}
+}
@@ -0,0 +1,405 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// flatCallTracer is the name of the native tracer producing parity style
+	// flat call traces.
+	flatCallTracer = "flatCallTracer"
+
+	// muxTracer is the name of the native tracer running multiple tracers at once.
+	muxTracer = "muxTracer"
+
+	// Trace types accepted by the replay methods.
+	traceTypeTrace     = "trace"
+	traceTypeStateDiff = "stateDiff"
+	traceTypeVMTrace   = "vmTrace"
+)
+
+// flatCallTracerConfig makes the flat call tracer report parity error messages.
+var flatCallTracerConfig = json.RawMessage(`{"convertParityErrors":true}`)
+
+var errVMTraceUnsupported = errors.New("vmTrace is not supported")
+
+// TraceAPI is the collection of parity compatible tracing APIs exposed over the
+// trace namespace. All traces are produced by the flat call tracer.
+type TraceAPI struct {
+	api         *API
+	filterRange uint64 // Maximum number of blocks a filter may span, 0 if unlimited
+}
+
+// NewTraceAPI creates a new API definition for the parity style tracing methods.
+func NewTraceAPI(backend Backend, filterRange uint64) *TraceAPI {
+	return &TraceAPI{api: NewAPI(backend), filterRange: filterRange}
+}
+
+// flatTraceConfig returns the trace config running the flat call tracer.
+func flatTraceConfig() *TraceConfig {
+	tracer := flatCallTracer
+	return &TraceConfig{Tracer: &tracer, TracerConfig: flatCallTracerConfig}
+}
+
+// Block returns the flat call traces of all transactions in the given block.
+func (api *TraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]json.RawMessage, error) {
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	results, err := api.api.traceBlock(ctx, block, flatTraceConfig())
+	if err != nil {
+		return nil, err
+	}
+	traces := []json.RawMessage{}
+	for _, result := range results {
+		frames, err := decodeFlatTrace(result.TxHash, result.Result)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, frames...)
+	}
+	return traces, nil
+}
+
+// Transaction returns the flat call traces of the given transaction.
+func (api *TraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]json.RawMessage, error) {
+	result, err := api.api.TraceTransaction(ctx, hash, flatTraceConfig())
+	if err != nil {
+		return nil, err
+	}
+	return decodeFlatTrace(hash, result)
+}
+
+// TraceFilterArgs are the criteria of a trace_filter request.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`   // First block to trace, latest if unset
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`     // Last block to trace, latest if unset
+	FromAddress []common.Address `json:"fromAddress"` // Senders of the traced calls, any if empty
+	ToAddress   []common.Address `json:"toAddress"`   // Recipients of the traced calls, any if empty
+	After       *uint64          `json:"after"`       // Number of matching traces to skip
+	Count       *uint64          `json:"count"`       // Maximum number of matching traces to return
+}
+
+// Filter returns the flat call traces of the given block range, which match the
+// given sender and recipient addresses.
+func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]json.RawMessage, error) {
+	head, err := api.api.blockByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	resolve := func(number *rpc.BlockNumber) (uint64, error) {
+		if number == nil || *number == rpc.LatestBlockNumber || *number == rpc.PendingBlockNumber {
+			return head.NumberU64(), nil
+		}
+		if *number < 0 {
+			return 0, fmt.Errorf("unsupported block number %d", *number)
+		}
+		return uint64(*number), nil
+	}
+	from, err := resolve(args.FromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := resolve(args.ToBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if to > head.NumberU64() {
+		to = head.NumberU64()
+	}
+	// The genesis block contains no transactions to trace
+	if from == 0 {
+		from = 1
+	}
+	if api.filterRange > 0 && from <= to && to-from >= api.filterRange {
+		return nil, fmt.Errorf("block range %d-%d exceeds the limit of %d blocks", from, to, api.filterRange)
+	}
+	var (
+		filter = newTraceFilter(args.FromAddress, args.ToAddress)
+		skip   uint64
+		traces = []json.RawMessage{}
+	)
+	if args.After != nil {
+		skip = *args.After
+	}
+	full := func() bool {
+		return args.Count != nil && uint64(len(traces)) >= *args.Count
+	}
+	for number := from; number <= to && !full(); number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		frames, err := api.Block(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		for _, frame := range frames {
+			ok, err := filter.match(frame)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if full() {
+				break
+			}
+			traces = append(traces, frame)
+		}
+	}
+	return traces, nil
+}
+
+// TraceResults is the result of replaying a transaction with a set of trace
+// types. Fields of trace types which were not requested are null.
+type TraceResults struct {
+	Output          hexutil.Bytes     `json:"output"`
+	StateDiff       StateDiff         `json:"stateDiff"`
+	Trace           []json.RawMessage `json:"trace"`
+	VMTrace         interface{}       `json:"vmTrace"`
+	TransactionHash *common.Hash      `json:"transactionHash,omitempty"`
+}
+
+// ReplayBlockTransactions replays all transactions of the given block and
+// returns the requested trace types of each.
+func (api *TraceAPI) ReplayBlockTransactions(ctx context.Context, number rpc.BlockNumber, traceTypes []string) ([]*TraceResults, error) {
+	config, err := replayTraceConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	results, err := api.api.traceBlock(ctx, block, config)
+	if err != nil {
+		return nil, err
+	}
+	replays := make([]*TraceResults, len(results))
+	for i, result := range results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("tx %#x: %s", result.TxHash, result.Error)
+		}
+		replay, err := decodeReplay(result.TxHash, result.Result, traceTypes)
+		if err != nil {
+			return nil, err
+		}
+		hash := result.TxHash
+		replay.TransactionHash = &hash
+		replays[i] = replay
+	}
+	return replays, nil
+}
+
+// Call executes the given call on top of the given block and returns the
+// requested trace types.
+func (api *TraceAPI) Call(ctx context.Context, args ethapi.TransactionArgs, traceTypes []string, blockNrOrHash *rpc.BlockNumberOrHash) (*TraceResults, error) {
+	config, err := replayTraceConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	result, err := api.api.TraceCall(ctx, args, *blockNrOrHash, &TraceCallConfig{TraceConfig: *config})
+	if err != nil {
+		return nil, err
+	}
+	return decodeReplay(common.Hash{}, result, traceTypes)
+}
+
+// replayTraceConfig returns the trace config producing the given trace types.
+// The flat call tracer always runs, as it provides the output of the call.
+func replayTraceConfig(traceTypes []string) (*TraceConfig, error) {
+	mux := map[string]json.RawMessage{flatCallTracer: flatCallTracerConfig}
+	for _, typ := range traceTypes {
+		switch typ {
+		case traceTypeTrace:
+		case traceTypeStateDiff:
+			mux[stateDiffTracer] = json.RawMessage(`{}`)
+		case traceTypeVMTrace:
+			return nil, errVMTraceUnsupported
+		default:
+			return nil, fmt.Errorf("unknown trace type %q", typ)
+		}
+	}
+	cfg, err := json.Marshal(mux)
+	if err != nil {
+		return nil, err
+	}
+	tracer := muxTracer
+	return &TraceConfig{Tracer: &tracer, TracerConfig: cfg}, nil
+}
+
+// decodeFlatTrace splits the result of the flat call tracer into its frames.
+func decodeFlatTrace(hash common.Hash, result interface{}) ([]json.RawMessage, error) {
+	raw, ok := result.(json.RawMessage)
+	if !ok {
+		return nil, fmt.Errorf("tx %#x: unexpected trace result %T", hash, result)
+	}
+	var frames []json.RawMessage
+	if err := json.Unmarshal(raw, &frames); err != nil {
+		return nil, fmt.Errorf("tx %#x: %w", hash, err)
+	}
+	return frames, nil
+}
+
+// decodeReplay assembles the requested trace types from the result of the mux
+// tracer configured by replayTraceConfig.
+func decodeReplay(hash common.Hash, result interface{}, traceTypes []string) (*TraceResults, error) {
+	raw, ok := result.(json.RawMessage)
+	if !ok {
+		return nil, fmt.Errorf("tx %#x: unexpected trace result %T", hash, result)
+	}
+	var mux map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &mux); err != nil {
+		return nil, fmt.Errorf("tx %#x: %w", hash, err)
+	}
+	frames, err := decodeFlatTrace(hash, mux[flatCallTracer])
+	if err != nil {
+		return nil, err
+	}
+	replay := new(TraceResults)
+	if len(frames) > 0 {
+		var top flatFrame
+		if err := json.Unmarshal(frames[0], &top); err != nil {
+			return nil, fmt.Errorf("tx %#x: %w", hash, err)
+		}
+		if top.Result != nil {
+			if top.Result.Output != nil {
+				replay.Output = *top.Result.Output
+			} else if top.Result.Code != nil {
+				replay.Output = *top.Result.Code
+			}
+		}
+	}
+	for _, typ := range traceTypes {
+		switch typ {
+		case traceTypeTrace:
+			replay.Trace = frames
+		case traceTypeStateDiff:
+			replay.StateDiff = make(StateDiff)
+			if err := json.Unmarshal(mux[stateDiffTracer], &replay.StateDiff); err != nil {
+				return nil, fmt.Errorf("tx %#x: %w", hash, err)
+			}
+		}
+	}
+	return replay, nil
+}
+
+// flatFrame is the subset of a flat call trace frame needed to filter traces
+// and to extract the call output.
+type flatFrame struct {
+	Type   string `json:"type"`
+	Action struct {
+		From          *common.Address `json:"from"`
+		To            *common.Address `json:"to"`
+		Address       *common.Address `json:"address"`
+		RefundAddress *common.Address `json:"refundAddress"`
+	} `json:"action"`
+	Result *struct {
+		Address *common.Address `json:"address"`
+		Code    *hexutil.Bytes  `json:"code"`
+		Output  *hexutil.Bytes  `json:"output"`
+	} `json:"result"`
+}
+
+// sender returns the account originating the traced action.
+func (f *flatFrame) sender() *common.Address {
+	if f.Type == "suicide" {
+		return f.Action.Address
+	}
+	return f.Action.From
+}
+
+// recipient returns the account receiving the traced action: the callee of a
+// call, the created contract or the beneficiary of a self-destruct.
+func (f *flatFrame) recipient() *common.Address {
+	switch f.Type {
+	case "create":
+		if f.Result != nil {
+			return f.Result.Address
+		}
+		return nil
+	case "suicide":
+		return f.Action.RefundAddress
+	}
+	return f.Action.To
+}
+
+// traceFilter matches flat call trace frames against sender and recipient
+// address sets. An empty set matches any address.
+type traceFilter struct {
+	from map[common.Address]struct{}
+	to   map[common.Address]struct{}
+}
+
+func newTraceFilter(from, to []common.Address) *traceFilter {
+	f := &traceFilter{
+		from: make(map[common.Address]struct{}),
+		to:   make(map[common.Address]struct{}),
+	}
+	for _, addr := range from {
+		f.from[addr] = struct{}{}
+	}
+	for _, addr := range to {
+		f.to[addr] = struct{}{}
+	}
+	return f
+}
+
+// match reports whether the given frame matches both address sets.
+func (f *traceFilter) match(raw json.RawMessage) (bool, error) {
+	if len(f.from) == 0 && len(f.to) == 0 {
+		return true, nil
+	}
+	var frame flatFrame
+	if err := json.Unmarshal(raw, &frame); err != nil {
+		return false, err
+	}
+	return matchAddress(f.from, frame.sender()) && matchAddress(f.to, frame.recipient()), nil
+}
+
+func matchAddress(set map[common.Address]struct{}, addr *common.Address) bool {
+	if len(set) == 0 {
+		return true
+	}
+	if addr == nil {
+		return false
+	}
+	_, ok := set[*addr]
+	return ok
+}
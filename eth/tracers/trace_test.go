@@ -0,0 +1,218 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// flatTestTracer stands in for the native flat call tracer, which can't be
+// imported here, reporting a single frame holding the traced transaction hash.
+type flatTestTracer struct {
+	*logger.StructLogger
+	hash common.Hash
+}
+
+func (t *flatTestTracer) GetResult() (json.RawMessage, error) {
+	return json.Marshal([]map[string]common.Hash{{"transactionHash": t.hash}})
+}
+
+func init() {
+	DefaultDirectory.Register(flatCallTracer, func(ctx *Context, cfg json.RawMessage) (Tracer, error) {
+		return &flatTestTracer{StructLogger: logger.NewStructLogger(nil), hash: ctx.TxHash}, nil
+	}, false)
+}
+
+func TestTraceFilterMatch(t *testing.T) {
+	var (
+		a = common.HexToAddress("0xaa")
+		b = common.HexToAddress("0xbb")
+		c = common.HexToAddress("0xcc")
+
+		call     = json.RawMessage(`{"type":"call","action":{"from":"` + a.Hex() + `","to":"` + b.Hex() + `"},"result":{"output":"0x"}}`)
+		create   = json.RawMessage(`{"type":"create","action":{"from":"` + a.Hex() + `"},"result":{"address":"` + c.Hex() + `","code":"0x"}}`)
+		destruct = json.RawMessage(`{"type":"suicide","action":{"address":"` + b.Hex() + `","refundAddress":"` + c.Hex() + `"}}`)
+	)
+	var tests = []struct {
+		from, to []common.Address
+		frame    json.RawMessage
+		want     bool
+	}{
+		{nil, nil, call, true},
+		{[]common.Address{a}, nil, call, true},
+		{[]common.Address{b}, nil, call, false},
+		{nil, []common.Address{b}, call, true},
+		{[]common.Address{a}, []common.Address{c}, call, false},
+		{[]common.Address{a, b}, []common.Address{b, c}, call, true},
+		{nil, []common.Address{c}, create, true},
+		{[]common.Address{b}, nil, create, false},
+		{[]common.Address{b}, []common.Address{c}, destruct, true},
+		{[]common.Address{a}, nil, destruct, false},
+	}
+	for i, tt := range tests {
+		have, err := newTraceFilter(tt.from, tt.to).match(tt.frame)
+		if err != nil {
+			t.Fatalf("test %d: failed to match: %v", i, err)
+		}
+		if have != tt.want {
+			t.Errorf("test %d: match mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+func TestReplayTraceTypes(t *testing.T) {
+	if _, err := replayTraceConfig([]string{"vmTrace"}); !errors.Is(err, errVMTraceUnsupported) {
+		t.Errorf("vmTrace error mismatch: have %v, want %v", err, errVMTraceUnsupported)
+	}
+	if _, err := replayTraceConfig([]string{"foo"}); err == nil {
+		t.Error("expected error for unknown trace type")
+	}
+	config, err := replayTraceConfig([]string{"trace", "stateDiff"})
+	if err != nil {
+		t.Fatalf("failed to create config: %v", err)
+	}
+	var mux map[string]json.RawMessage
+	if err := json.Unmarshal(config.TracerConfig, &mux); err != nil {
+		t.Fatalf("failed to decode mux config: %v", err)
+	}
+	if _, ok := mux[flatCallTracer]; !ok {
+		t.Error("flat call tracer missing from mux config")
+	}
+	if _, ok := mux[stateDiffTracer]; !ok {
+		t.Error("state diff tracer missing from mux config")
+	}
+
+	// Decode a mux result, only including the requested trace types
+	acc := common.HexToAddress("0xaa")
+	result := json.RawMessage(`{
+		"flatCallTracer": [{"type":"call","action":{"from":"` + acc.Hex() + `"},"result":{"output":"0x2a"}}],
+		"stateDiffTracer": {"` + acc.Hex() + `": {"balance":"=","nonce":{"*":{"from":"0x0","to":"0x1"}},"code":"=","storage":{}}}
+	}`)
+	replay, err := decodeReplay(common.Hash{}, result, []string{"stateDiff"})
+	if err != nil {
+		t.Fatalf("failed to decode replay: %v", err)
+	}
+	if replay.Output.String() != "0x2a" {
+		t.Errorf("output mismatch: have %s, want 0x2a", replay.Output)
+	}
+	if replay.Trace != nil {
+		t.Errorf("unrequested trace returned: %s", replay.Trace)
+	}
+	if diff := replay.StateDiff[acc]; diff == nil || diff.Nonce == nil || diff.Nonce.To != "0x1" {
+		t.Errorf("state diff mismatch: %+v", replay.StateDiff)
+	}
+	if replay, err = decodeReplay(common.Hash{}, result, []string{"trace"}); err != nil {
+		t.Fatalf("failed to decode replay: %v", err)
+	}
+	if len(replay.Trace) != 1 || replay.StateDiff != nil {
+		t.Errorf("trace mismatch: trace %d frames, state diff %v", len(replay.Trace), replay.StateDiff)
+	}
+}
+
+func TestTraceFilterRange(t *testing.T) {
+	t.Parallel()
+
+	genesis := &core.Genesis{Config: params.TestChainConfig}
+	backend := newTestBackend(t, 4, genesis, func(i int, b *core.BlockGen) {})
+	defer backend.teardown()
+	api := NewTraceAPI(backend, 2)
+
+	block := func(n int64) *rpc.BlockNumber {
+		number := rpc.BlockNumber(n)
+		return &number
+	}
+	if _, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: block(1), ToBlock: block(3)}); err == nil {
+		t.Fatal("expected error for block range over the limit")
+	}
+	// The genesis block is not traced, so it does not count against the limit
+	if _, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: block(0), ToBlock: block(2)}); err != nil {
+		t.Fatalf("failed to filter block range within the limit: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := api.Filter(ctx, TraceFilterArgs{FromBlock: block(3), ToBlock: block(4)}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled filter error mismatch: have %v, want %v", err, context.Canceled)
+	}
+}
+
+// Tests that the filtered traces are paged by the after and count arguments.
+func TestTraceFilterPaging(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	signer := types.HomesteadSigner{}
+	backend := newTestBackend(t, 3, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), accounts[1].addr, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+	})
+	defer backend.teardown()
+	api := NewTraceAPI(backend, 0)
+
+	var (
+		from  = rpc.BlockNumber(1)
+		to    = rpc.BlockNumber(3)
+		count = func(n uint64) *uint64 { return &n }
+	)
+	all, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &from, ToBlock: &to})
+	if err != nil {
+		t.Fatalf("failed to filter traces: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("trace count mismatch: have %d, want %d", len(all), 3)
+	}
+	for i, tt := range []struct {
+		after *uint64
+		count *uint64
+		want  []json.RawMessage
+	}{
+		{count: count(0), want: []json.RawMessage{}},
+		{count: count(1), want: all[:1]},
+		{after: count(1), count: count(1), want: all[1:2]},
+		{after: count(2), count: count(5), want: all[2:]},
+	} {
+		have, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &from, ToBlock: &to, After: tt.after, Count: tt.count})
+		if err != nil {
+			t.Fatalf("test %d: failed to filter traces: %v", i, err)
+		}
+		if len(have) != len(tt.want) {
+			t.Fatalf("test %d: trace count mismatch: have %d, want %d", i, len(have), len(tt.want))
+		}
+		for j := range have {
+			if !bytes.Equal(have[j], tt.want[j]) {
+				t.Errorf("test %d: trace %d mismatch: have %s, want %s", i, j, have[j], tt.want[j])
+			}
+		}
+	}
+}
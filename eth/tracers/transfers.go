@@ -0,0 +1,144 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Kinds of value transfers.
+const (
+	TransferNative  = "native"
+	TransferERC20   = "erc20"
+	TransferERC721  = "erc721"
+	TransferERC1155 = "erc1155"
+)
+
+var (
+	// transferTopic is the event signature of ERC-20 and ERC-721 transfers.
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+	// transferSingleTopic is the event signature of single ERC-1155 transfers.
+	transferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+
+	// transferBatchTopic is the event signature of batched ERC-1155 transfers.
+	transferBatchTopic = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+// Transfer is a movement of the native currency or of a token.
+type Transfer struct {
+	Type     string          `json:"type"`
+	Token    *common.Address `json:"token,omitempty"`
+	Operator *common.Address `json:"operator,omitempty"`
+	From     common.Address  `json:"from"`
+	To       common.Address  `json:"to"`
+	TokenID  *hexutil.Big    `json:"tokenId,omitempty"`
+	Value    *hexutil.Big    `json:"value,omitempty"`
+}
+
+// DecodeTokenTransfers decodes the token transfers announced by a log emitted
+// by the given contract. It returns nil if the log is not a well-formed
+// ERC-20, ERC-721 or ERC-1155 transfer event.
+func DecodeTokenTransfers(token common.Address, topics []common.Hash, data []byte) []*Transfer {
+	if len(topics) == 0 {
+		return nil
+	}
+	switch {
+	case topics[0] == transferTopic && len(topics) == 3 && len(data) == 32:
+		return []*Transfer{{
+			Type:  TransferERC20,
+			Token: &token,
+			From:  common.BytesToAddress(topics[1].Bytes()),
+			To:    common.BytesToAddress(topics[2].Bytes()),
+			Value: (*hexutil.Big)(new(big.Int).SetBytes(data)),
+		}}
+
+	case topics[0] == transferTopic && len(topics) == 4 && len(data) == 0:
+		return []*Transfer{{
+			Type:    TransferERC721,
+			Token:   &token,
+			From:    common.BytesToAddress(topics[1].Bytes()),
+			To:      common.BytesToAddress(topics[2].Bytes()),
+			TokenID: (*hexutil.Big)(topics[3].Big()),
+		}}
+
+	case topics[0] == transferSingleTopic && len(topics) == 4 && len(data) == 64:
+		operator := common.BytesToAddress(topics[1].Bytes())
+		return []*Transfer{{
+			Type:     TransferERC1155,
+			Token:    &token,
+			Operator: &operator,
+			From:     common.BytesToAddress(topics[2].Bytes()),
+			To:       common.BytesToAddress(topics[3].Bytes()),
+			TokenID:  (*hexutil.Big)(new(big.Int).SetBytes(data[:32])),
+			Value:    (*hexutil.Big)(new(big.Int).SetBytes(data[32:])),
+		}}
+
+	case topics[0] == transferBatchTopic && len(topics) == 4:
+		ids, values := decodeUintArray(data, 0), decodeUintArray(data, 32)
+		if ids == nil || len(ids) != len(values) {
+			return nil
+		}
+		var (
+			operator  = common.BytesToAddress(topics[1].Bytes())
+			from      = common.BytesToAddress(topics[2].Bytes())
+			to        = common.BytesToAddress(topics[3].Bytes())
+			transfers = make([]*Transfer, len(ids))
+		)
+		for i := range ids {
+			transfers[i] = &Transfer{
+				Type:     TransferERC1155,
+				Token:    &token,
+				Operator: &operator,
+				From:     from,
+				To:       to,
+				TokenID:  (*hexutil.Big)(ids[i]),
+				Value:    (*hexutil.Big)(values[i]),
+			}
+		}
+		return transfers
+	}
+	return nil
+}
+
+// decodeUintArray decodes an ABI encoded dynamic uint256 array, whose offset
+// is stored at the given position of the data. It returns nil if the data is
+// malformed.
+func decodeUintArray(data []byte, pos int) []*big.Int {
+	if len(data) < pos+32 {
+		return nil
+	}
+	offset := new(big.Int).SetBytes(data[pos : pos+32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+		return nil
+	}
+	start := int(offset.Uint64())
+	size := new(big.Int).SetBytes(data[start : start+32])
+	if !size.IsUint64() || size.Uint64() > uint64(len(data)-start-32)/32 {
+		return nil
+	}
+	items := make([]*big.Int, size.Uint64())
+	for i := range items {
+		begin := start + 32 + 32*i
+		items[i] = new(big.Int).SetBytes(data[begin : begin+32])
+	}
+	return items
+}
@@ -287,6 +287,9 @@ func TestEthClient(t *testing.T) {
 		"TransactionSender": {
 			func(t *testing.T) { testTransactionSender(t, client) },
 		},
+		"BlockReceipts": {
+			func(t *testing.T) { testBlockReceipts(t, chain, client) },
+		},
 	}
 
 	t.Parallel()
@@ -708,3 +711,34 @@ func sendTransaction(ec *Client) error {
 	}
 	return ec.SendTransaction(context.Background(), tx)
 }
+
+func testBlockReceipts(t *testing.T, chain []*types.Block, client *rpc.Client) {
+	ec := NewClient(client)
+
+	// Receipts should be retrievable by both number and hash
+	for _, query := range []rpc.BlockNumberOrHash{
+		rpc.BlockNumberOrHashWithNumber(2),
+		rpc.BlockNumberOrHashWithHash(chain[2].Hash(), false),
+	} {
+		receipts, err := ec.BlockReceipts(context.Background(), query)
+		if err != nil {
+			t.Fatalf("%v: failed to retrieve block receipts: %v", query, err)
+		}
+		txs := chain[2].Transactions()
+		if len(receipts) != len(txs) {
+			t.Fatalf("%v: receipt count mismatch: have %d, want %d", query, len(receipts), len(txs))
+		}
+		for i, receipt := range receipts {
+			if receipt.TxHash != txs[i].Hash() || receipt.BlockHash != chain[2].Hash() || receipt.TransactionIndex != uint(i) {
+				t.Errorf("%v: receipt %d mismatch: %+v", query, i, receipt)
+			}
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				t.Errorf("%v: receipt %d status mismatch: have %d", query, i, receipt.Status)
+			}
+		}
+	}
+	// Missing blocks should be reported as not found
+	if _, err := ec.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(3)); err != ethereum.NotFound {
+		t.Fatalf("missing block error mismatch: have %v, want %v", err, ethereum.NotFound)
+	}
+}
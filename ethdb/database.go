@@ -55,6 +55,14 @@ type Compacter interface {
 	Compact(start []byte, limit []byte) error
 }
 
+// Checkpointer wraps the Checkpoint method of a data store. It's optional, not
+// all backends are able to create consistent copies of themselves.
+type Checkpointer interface {
+	// Checkpoint writes a consistent point-in-time copy of the data store into
+	// the given directory, which must not exist yet.
+	Checkpoint(dir string) error
+}
+
 // KeyValueStore contains all the methods required to allow handling different
 // key-value data stores backing the high level database.
 type KeyValueStore interface {
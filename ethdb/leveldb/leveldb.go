@@ -462,6 +462,49 @@ type snapshot struct {
 	db *leveldb.Snapshot
 }
 
+// Checkpoint implements ethdb.Checkpointer, copying all the data visible in a
+// snapshot of the database into a new database in the given directory.
+func (db *Database) Checkpoint(dir string) error {
+	if common.FileExist(dir) {
+		return fmt.Errorf("checkpoint directory %s already exists", dir)
+	}
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+
+	dst, err := leveldb.OpenFile(dir, &opt.Options{ErrorIfExist: true})
+	if err != nil {
+		return err
+	}
+	var (
+		it    = snap.NewIterator(nil, nil)
+		batch = new(leveldb.Batch)
+	)
+	defer it.Release()
+
+	for it.Next() {
+		batch.Put(it.Key(), it.Value())
+		if len(batch.Dump()) >= ethdb.IdealBatchSize {
+			if err := dst.Write(batch, nil); err != nil {
+				dst.Close()
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Write(batch, nil); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
 // Has retrieves if a key is present in the snapshot backing by a key-value
 // data store.
 func (snap *snapshot) Has(key []byte) (bool, error) {
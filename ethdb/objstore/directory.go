@@ -0,0 +1,117 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package objstore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Directory is an object store backed by a local directory, mainly used as a
+// stand-in for a remote service in tests and on network file systems.
+type Directory struct {
+	root string
+}
+
+// NewDirectory creates an object store in the given directory, creating it if
+// it doesn't exist yet.
+func NewDirectory(root string) (*Directory, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &Directory{root: root}, nil
+}
+
+// path resolves the file path of an object.
+func (d *Directory) path(key string) string {
+	return filepath.Join(d.root, filepath.FromSlash(key))
+}
+
+// Put implements Store, writing the object into a temporary file first so that
+// it's replaced atomically.
+func (d *Directory) Put(key string, r io.Reader, size int64) error {
+	path := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	n, err := io.Copy(f, r)
+	if err == nil && n != size {
+		err = fmt.Errorf("object size mismatch: have %d, want %d", n, size)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Get implements Store.
+func (d *Directory) Get(key string) (io.ReadCloser, error) {
+	f, err := os.Open(d.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// GetRange implements Store.
+func (d *Directory) GetRange(key string, offset, length int64) (io.ReadCloser, error) {
+	f, err := d.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(f.(*os.File), offset, length), f}, nil
+}
+
+// Size implements Store.
+func (d *Directory) Size(key string) (int64, error) {
+	stat, err := os.Stat(d.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return stat.Size(), nil
+}
+
+// Delete implements Store.
+func (d *Directory) Delete(key string) error {
+	err := os.Remove(d.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package objstore implements flat stores of immutable blobs, which large and
+// rarely accessed database files can be offloaded into. Besides S3-compatible
+// object storage services, a local directory is supported as a stand-in.
+package objstore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ErrNotFound is returned if the requested object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Store is a flat namespace of immutable objects identified by slash separated
+// keys. Objects are always written in full, a concurrent reader either sees the
+// complete old content or the complete new one.
+type Store interface {
+	// Put uploads the object of the given size, read from the reader, replacing
+	// any existing object with the same key.
+	Put(key string, r io.Reader, size int64) error
+
+	// Get opens the object for reading. The caller must close the returned
+	// reader.
+	Get(key string) (io.ReadCloser, error)
+
+	// GetRange opens up to length bytes of the object starting at the given
+	// offset for reading, less if the object ends earlier. The caller must
+	// close the returned reader.
+	GetRange(key string, offset, length int64) (io.ReadCloser, error)
+
+	// Size returns the size of the object in bytes.
+	Size(key string) (int64, error)
+
+	// Delete removes the object. Deleting a non-existent object is not an error.
+	Delete(key string) error
+}
+
+// Open creates an object store from the given URL. The supported formats are:
+//
+//   - file:///path/to/dir
+//   - s3://bucket/prefix?endpoint=http://127.0.0.1:9000&region=us-east-1
+//
+// S3 credentials are read from the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+// environment variables.
+func Open(rawurl string) (Store, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "file":
+		path := u.Path
+		if u.Host != "" {
+			path = u.Host + path
+		}
+		if path == "" {
+			return nil, errors.New("missing directory in object store url")
+		}
+		return NewDirectory(path)
+
+	case "s3":
+		if u.Host == "" {
+			return nil, errors.New("missing bucket in object store url")
+		}
+		return NewS3(S3Config{
+			Endpoint:  u.Query().Get("endpoint"),
+			Region:    u.Query().Get("region"),
+			Bucket:    u.Host,
+			Prefix:    strings.TrimPrefix(u.Path, "/"),
+			AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		})
+
+	default:
+		return nil, fmt.Errorf("unsupported object store scheme %q", u.Scheme)
+	}
+}
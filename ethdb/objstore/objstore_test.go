@@ -0,0 +1,203 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package objstore
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testStore runs the generic tests of the Store interface.
+func testStore(t *testing.T, store Store) {
+	if _, err := store.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing object get: have %v, want %v", err, ErrNotFound)
+	}
+	if _, err := store.Size("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing object size: have %v, want %v", err, ErrNotFound)
+	}
+	if err := store.Delete("missing"); err != nil {
+		t.Fatalf("missing object delete failed: %v", err)
+	}
+	blobs := map[string][]byte{
+		"a":          []byte("hello"),
+		"nested/b":   bytes.Repeat([]byte{0xff}, 100000),
+		"nested/c/d": {},
+	}
+	for key, blob := range blobs {
+		if err := store.Put(key, bytes.NewReader(blob), int64(len(blob))); err != nil {
+			t.Fatalf("failed to put %q: %v", key, err)
+		}
+	}
+	if err := store.Put("a", bytes.NewReader([]byte("world")), 5); err != nil {
+		t.Fatalf("failed to overwrite object: %v", err)
+	}
+	blobs["a"] = []byte("world")
+
+	for key, blob := range blobs {
+		size, err := store.Size(key)
+		if err != nil {
+			t.Fatalf("failed to stat %q: %v", key, err)
+		}
+		if size != int64(len(blob)) {
+			t.Fatalf("size mismatch for %q: have %d, want %d", key, size, len(blob))
+		}
+		r, err := store.Get(key)
+		if err != nil {
+			t.Fatalf("failed to get %q: %v", key, err)
+		}
+		have, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("failed to read %q: %v", key, err)
+		}
+		if !bytes.Equal(have, blob) {
+			t.Fatalf("content mismatch for %q", key)
+		}
+	}
+	// Ranges are cut off at the end of the object
+	for _, tt := range []struct{ offset, length, want int64 }{{0, 10, 10}, {99990, 100, 10}, {500, 1, 1}} {
+		r, err := store.GetRange("nested/b", tt.offset, tt.length)
+		if err != nil {
+			t.Fatalf("failed to get range %d+%d: %v", tt.offset, tt.length, err)
+		}
+		have, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("failed to read range %d+%d: %v", tt.offset, tt.length, err)
+		}
+		if int64(len(have)) != tt.want || !bytes.Equal(have, blobs["nested/b"][tt.offset:tt.offset+tt.want]) {
+			t.Fatalf("range %d+%d mismatch: have %d bytes, want %d", tt.offset, tt.length, len(have), tt.want)
+		}
+	}
+	if _, err := store.GetRange("missing", 0, 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing object range get: have %v, want %v", err, ErrNotFound)
+	}
+	if err := store.Delete("nested/b"); err != nil {
+		t.Fatalf("failed to delete object: %v", err)
+	}
+	if _, err := store.Get("nested/b"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted object get: have %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestDirectory(t *testing.T) {
+	store, err := NewDirectory(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, store)
+
+	// Writing a truncated object must fail without replacing the old one
+	if err := store.Put("a", strings.NewReader("hi"), 5); err == nil {
+		t.Fatal("truncated put succeeded")
+	}
+	if size, _ := store.Size("a"); size != 5 {
+		t.Fatalf("object replaced by failed put, size %d", size)
+	}
+}
+
+// fakeS3 is a minimal in-memory implementation of the S3 object API.
+type fakeS3 struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+		http.Error(w, "missing signature", http.StatusForbidden)
+		return
+	}
+	if r.Header.Get("X-Amz-Content-Sha256") != unsignedPayload {
+		http.Error(w, "missing payload hash", http.StatusBadRequest)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		blob, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.objects[r.URL.Path] = blob
+	case http.MethodGet, http.MethodHead:
+		blob, ok := s.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(blob))
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3(t *testing.T) {
+	backend := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	store, err := NewS3(S3Config{
+		Endpoint:  server.URL,
+		Bucket:    "bucket",
+		Prefix:    "/ancient/",
+		AccessKey: "key",
+		SecretKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, store)
+
+	if _, ok := backend.objects["/bucket/ancient/a"]; !ok {
+		t.Fatal("object not stored under the bucket and prefix")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open("file://" + dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := store.(*Directory).root; have != dir {
+		t.Fatalf("directory mismatch: have %s, want %s", have, dir)
+	}
+	store, err = Open("s3://bucket/some/prefix?endpoint=http://127.0.0.1:9000&region=eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3 := store.(*S3)
+	if s3.bucket != "bucket" || s3.prefix != "some/prefix/" || s3.region != "eu-west-1" || s3.endpoint.Host != "127.0.0.1:9000" {
+		t.Fatalf("s3 config mismatch: %+v", s3)
+	}
+	for _, url := range []string{"ftp://host/path", "s3:///prefix", "file://"} {
+		if _, err := Open(url); err == nil {
+			t.Fatalf("invalid url %q accepted", url)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package objstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	// unsignedPayload is the payload hash placeholder which S3 accepts in place
+	// of the SHA256 of the request body, allowing objects to be streamed.
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+
+	// defaultS3Timeout is the default time limit of establishing a connection
+	// and receiving the response headers, as well as of whole ranged reads.
+	defaultS3Timeout = time.Minute
+)
+
+// S3Config contains the settings of an S3-compatible object store.
+type S3Config struct {
+	Endpoint  string        // Service endpoint, defaults to AWS for the region
+	Region    string        // Signing region, defaults to us-east-1
+	Bucket    string        // Bucket to store the objects in
+	Prefix    string        // Key prefix within the bucket
+	AccessKey string        // Access key ID of the credentials
+	SecretKey string        // Secret access key of the credentials
+	Timeout   time.Duration // Time limit of requests, excluding the transfer of whole objects
+}
+
+// S3 is an object store backed by an S3-compatible service, such as AWS S3 or
+// MinIO. Objects are addressed path-style, which all known implementations
+// support.
+type S3 struct {
+	endpoint *url.URL
+	region   string
+	bucket   string
+	prefix   string
+	creds    aws.Credentials
+	signer   *v4.Signer
+	client   *http.Client
+	timeout  time.Duration
+}
+
+// NewS3 creates an object store for the given S3 bucket.
+func NewS3(config S3Config) (*S3, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("missing s3 bucket")
+	}
+	region := config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid s3 endpoint scheme %q", u.Scheme)
+	}
+	prefix := strings.Trim(config.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = defaultS3Timeout
+	}
+	// Whole objects may be gigabytes large, so their transfer can't be limited
+	// as a whole, only the connection setup and the server response.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = timeout
+	transport.ResponseHeaderTimeout = timeout
+
+	return &S3{
+		endpoint: u,
+		region:   region,
+		bucket:   config.Bucket,
+		prefix:   prefix,
+		creds: aws.Credentials{
+			AccessKeyID:     config.AccessKey,
+			SecretAccessKey: config.SecretKey,
+		},
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			o.DisableURIPathEscaping = true
+		}),
+		client:  &http.Client{Transport: transport},
+		timeout: timeout,
+	}, nil
+}
+
+// request creates a request for the given object.
+func (s *S3) request(ctx context.Context, method string, key string, body io.Reader, size int64) (*http.Request, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + s.prefix + key
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	return req, nil
+}
+
+// do signs and executes a request, converting unsuccessful responses into
+// errors.
+func (s *S3) do(req *http.Request) (*http.Response, error) {
+	if err := s.signer.SignHTTP(req.Context(), s.creds, req, unsignedPayload, "s3", s.region, time.Now()); err != nil {
+		return nil, err
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	return nil, fmt.Errorf("s3 %s %s failed: %s: %s", req.Method, req.URL.Path, res.Status, strings.TrimSpace(string(msg)))
+}
+
+// Put implements Store.
+func (s *S3) Put(key string, r io.Reader, size int64) error {
+	// Hide any other interfaces of the reader, otherwise the http client may
+	// decide on its own how much of it to send.
+	req, err := s.request(context.Background(), http.MethodPut, key, io.NopCloser(r), size)
+	if err != nil {
+		return err
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// Get implements Store.
+func (s *S3) Get(key string) (io.ReadCloser, error) {
+	req, err := s.request(context.Background(), http.MethodGet, key, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.ContentLength < 0 {
+		return res.Body, nil
+	}
+	return &sizedReader{r: res.Body, left: res.ContentLength}, nil
+}
+
+// GetRange implements Store. As the range is expected to be small, the whole
+// request including the transfer is subject to the timeout.
+func (s *S3) GetRange(key string, offset, length int64) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	req, err := s.request(ctx, http.MethodGet, key, nil, 0)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	res, err := s.do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	body := &cancelReader{r: res.Body, cancel: cancel}
+	if res.StatusCode != http.StatusPartialContent {
+		body.Close()
+		return nil, fmt.Errorf("s3 GET %s: range not satisfied: %s", req.URL.Path, res.Status)
+	}
+	if res.ContentLength < 0 {
+		return body, nil
+	}
+	return &sizedReader{r: body, left: res.ContentLength}, nil
+}
+
+// Size implements Store.
+func (s *S3) Size(key string) (int64, error) {
+	req, err := s.request(context.Background(), http.MethodHead, key, nil, 0)
+	if err != nil {
+		return 0, err
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+
+	if res.ContentLength < 0 {
+		return 0, errors.New("missing object size")
+	}
+	return res.ContentLength, nil
+}
+
+// Delete implements Store.
+func (s *S3) Delete(key string) error {
+	req, err := s.request(context.Background(), http.MethodDelete, key, nil, 0)
+	if err != nil {
+		return err
+	}
+	res, err := s.do(req)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// sizedReader wraps a response body, turning a premature end of the stream into
+// an error instead of silently returning a truncated object.
+type sizedReader struct {
+	r    io.ReadCloser
+	left int64
+}
+
+func (r *sizedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.left -= int64(n)
+	if err == io.EOF && r.left > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (r *sizedReader) Close() error {
+	return r.r.Close()
+}
+
+// cancelReader wraps a response body, releasing the context of the request once
+// the body is closed.
+type cancelReader struct {
+	r      io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+func (r *cancelReader) Close() error {
+	defer r.cancel()
+	return r.r.Close()
+}
@@ -327,6 +327,12 @@ func (d *Database) NewSnapshot() (ethdb.Snapshot, error) {
 	return &snapshot{db: snap}, nil
 }
 
+// Checkpoint implements ethdb.Checkpointer, writing a copy of the database into
+// the given directory. The immutable table files are hard linked if possible.
+func (d *Database) Checkpoint(dir string) error {
+	return d.db.Checkpoint(dir, pebble.WithFlushedWAL())
+}
+
 // Has retrieves if a key is present in the snapshot backing by a key-value
 // data store.
 func (snap *snapshot) Has(key []byte) (bool, error) {
@@ -34,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/thora"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	ethproto "github.com/ethereum/go-ethereum/eth/protocols/eth"
@@ -78,10 +79,16 @@ type fullNodeBackend interface {
 	backend
 	Miner() *miner.Miner
 	BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error)
-	CurrentBlock() *types.Block
+	CurrentBlock() *types.Header
 	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
 }
 
+// signerBackend is implemented by full node backends able to tell whether the
+// local etherbase is currently an authorized signer of a proof-of-authority chain.
+type signerBackend interface {
+	ValidateBeforeMining() (bool, error)
+}
+
 // Service implements an Ethereum netstats reporting daemon that pushes local
 // chain statistics up to a monitoring server.
 type Service struct {
@@ -583,6 +590,7 @@ type blockStats struct {
 	TxHash     common.Hash    `json:"transactionsRoot"`
 	Root       common.Hash    `json:"stateRoot"`
 	Uncles     uncleStats     `json:"uncles"`
+	InTurn     *bool          `json:"inTurn,omitempty"` // Only reported on Thora chains
 }
 
 // txStats is the information to report about individual transactions.
@@ -605,6 +613,9 @@ func (s uncleStats) MarshalJSON() ([]byte, error) {
 func (s *Service) reportBlock(conn *connWrapper, block *types.Block) error {
 	// Gather the block details from the header or block chain
 	details := s.assembleBlockStats(block)
+	if details == nil {
+		return nil
+	}
 
 	// Assemble the block report and send it to the server
 	log.Trace("Sending new block to ethstats", "number", details.Number, "hash", details.Hash)
@@ -634,7 +645,13 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 	fullBackend, ok := s.backend.(fullNodeBackend)
 	if ok {
 		if block == nil {
-			block = fullBackend.CurrentBlock()
+			head := fullBackend.CurrentBlock()
+			block, _ = fullBackend.BlockByNumber(context.Background(), rpc.BlockNumber(head.Number.Uint64()))
+		}
+		// Short circuit if no block is available. It might happen when
+		// the blockchain is reorging.
+		if block == nil {
+			return nil
 		}
 		header = block.Header()
 		td = fullBackend.GetTd(context.Background(), header.Hash())
@@ -655,9 +672,15 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 		txs = []txStats{}
 	}
 
-	// Assemble and return the block stats
+	// Assemble and return the block stats, on Thora chains the author is the
+	// signer recovered from the seal rather than the (vote) coinbase.
 	author, _ := s.engine.Author(header)
 
+	var inturn *bool
+	if _, ok := thora.FromEngine(s.engine); ok && header.Number.Sign() > 0 {
+		turn := thora.IsInTurn(header)
+		inturn = &turn
+	}
 	return &blockStats{
 		Number:     header.Number,
 		Hash:       header.Hash(),
@@ -672,6 +695,7 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 		TxHash:     header.TxHash,
 		Root:       header.Root,
 		Uncles:     uncles,
+		InTurn:     inturn,
 	}
 }
 
@@ -759,13 +783,14 @@ func (s *Service) reportPending(conn *connWrapper) error {
 
 // nodeStats is the information to report about the local node.
 type nodeStats struct {
-	Active   bool `json:"active"`
-	Syncing  bool `json:"syncing"`
-	Mining   bool `json:"mining"`
-	Hashrate int  `json:"hashrate"`
-	Peers    int  `json:"peers"`
-	GasPrice int  `json:"gasPrice"`
-	Uptime   int  `json:"uptime"`
+	Active   bool  `json:"active"`
+	Syncing  bool  `json:"syncing"`
+	Mining   bool  `json:"mining"`
+	Hashrate int   `json:"hashrate"`
+	Peers    int   `json:"peers"`
+	GasPrice int   `json:"gasPrice"`
+	Uptime   int   `json:"uptime"`
+	Signer   *bool `json:"signer,omitempty"` // Only reported on Thora chains
 }
 
 // reportStats retrieves various stats about the node at the networking and
@@ -777,6 +802,7 @@ func (s *Service) reportStats(conn *connWrapper) error {
 		hashrate int
 		syncing  bool
 		gasprice int
+		signer   *bool
 	)
 	// check if backend is a full node
 	fullBackend, ok := s.backend.(fullNodeBackend)
@@ -792,6 +818,15 @@ func (s *Service) reportStats(conn *connWrapper) error {
 		if basefee := fullBackend.CurrentHeader().BaseFee; basefee != nil {
 			gasprice += int(basefee.Uint64())
 		}
+		if _, ok := thora.FromEngine(s.engine); ok {
+			if backend, ok := s.backend.(signerBackend); ok {
+				authorized, err := backend.ValidateBeforeMining()
+				if err != nil {
+					log.Debug("Failed to retrieve signer status", "err", err)
+				}
+				signer = &authorized
+			}
+		}
 	} else {
 		sync := s.backend.SyncProgress()
 		syncing = s.backend.CurrentHeader().Number.Uint64() >= sync.HighestBlock
@@ -809,6 +844,7 @@ func (s *Service) reportStats(conn *connWrapper) error {
 			GasPrice: gasprice,
 			Syncing:  syncing,
 			Uptime:   100,
+			Signer:   signer,
 		},
 	}
 	report := map[string][]interface{}{
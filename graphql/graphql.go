@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/consensus/thora"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/filters"
@@ -208,6 +209,20 @@ func (w *Withdrawal) Amount(ctx context.Context) hexutil.Uint64 {
 	return hexutil.Uint64(w.amount)
 }
 
+// ThoraVote represents a signer authorization vote cast in a Thora block.
+type ThoraVote struct {
+	address   common.Address
+	authorize bool
+}
+
+func (v *ThoraVote) Address(ctx context.Context) common.Address {
+	return v.address
+}
+
+func (v *ThoraVote) Authorize(ctx context.Context) bool {
+	return v.authorize
+}
+
 // Transaction represents an Ethereum transaction.
 // backend and hash are mandatory; all others will be fetched when required.
 type Transaction struct {
@@ -1009,6 +1024,68 @@ func (b *Block) Withdrawals(ctx context.Context) (*[]*Withdrawal, error) {
 	return &ret, nil
 }
 
+func (b *Block) Sealer(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	if b.r.backend.ChainConfig().Thora == nil {
+		return nil, nil
+	}
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The genesis block and malformed seals have no recoverable sealer
+	sealer, err := b.r.backend.Engine().Author(header)
+	if err != nil {
+		return nil, nil
+	}
+	return &Account{
+		r:             b.r,
+		address:       sealer,
+		blockNrOrHash: args.NumberOrLatest(),
+	}, nil
+}
+
+func (b *Block) InTurn(ctx context.Context) (*bool, error) {
+	if b.r.backend.ChainConfig().Thora == nil {
+		return nil, nil
+	}
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inturn := thora.IsInTurn(header)
+	return &inturn, nil
+}
+
+func (b *Block) Signers(ctx context.Context) (*[]common.Address, error) {
+	if b.r.backend.ChainConfig().Thora == nil {
+		return nil, nil
+	}
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	signers := thora.CheckpointSigners(header)
+	if signers == nil {
+		return nil, nil
+	}
+	return &signers, nil
+}
+
+func (b *Block) Vote(ctx context.Context) (*ThoraVote, error) {
+	if b.r.backend.ChainConfig().Thora == nil {
+		return nil, nil
+	}
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	address, authorize, ok := thora.HeaderVote(header)
+	if !ok {
+		return nil, nil
+	}
+	return &ThoraVote{address: address, authorize: authorize}, nil
+}
+
 // BlockFilterCriteria encapsulates criteria passed to a `logs` accessor inside
 // a block.
 type BlockFilterCriteria struct {
@@ -422,6 +422,49 @@ func TestWithdrawals(t *testing.T) {
 	}
 }
 
+func TestThoraFields(t *testing.T) {
+	var (
+		signer = common.HexToAddress("0x0000000000000000000000000000000000000abc")
+		config = *params.AllThoraProtocolChanges
+		stack  = createNode(t)
+	)
+	defer stack.Close()
+
+	genesis := &core.Genesis{
+		Config:     &config,
+		GasLimit:   11500000,
+		Difficulty: common.Big1,
+		ExtraData:  make([]byte, 32+common.AddressLength+65),
+	}
+	copy(genesis.ExtraData[32:], signer[:])
+
+	handler, _ := newGQLService(t, stack, false, genesis, 0, nil)
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	for i, tt := range []struct {
+		body string
+		want string
+	}{
+		{
+			body: "{block(number: 0) { sealer { address } inTurn signers vote { address authorize } } }",
+			want: `{"block":{"sealer":null,"inTurn":false,"signers":["0x0000000000000000000000000000000000000abc"],"vote":null}}`,
+		},
+	} {
+		res := handler.Schema.Exec(context.Background(), tt.body, "", map[string]interface{}{})
+		if res.Errors != nil {
+			t.Fatalf("failed to execute query for testcase #%d: %v", i, res.Errors)
+		}
+		have, err := json.Marshal(res.Data)
+		if err != nil {
+			t.Fatalf("failed to encode graphql response for testcase #%d: %s", i, err)
+		}
+		if string(have) != tt.want {
+			t.Errorf("response unmatch for testcase #%d.\nhave:\n%s\nwant:\n%s", i, have, tt.want)
+		}
+	}
+}
+
 func createNode(t *testing.T) *node.Node {
 	stack, err := node.New(&node.Config{
 		HTTPHost:     "127.0.0.1",
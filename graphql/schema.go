@@ -266,6 +266,28 @@ const schema string = `
         # Withdrawals is a list of withdrawals associated with this block. If
         # withdrawals are unavailable for this block, this field will be null.
         withdrawals: [Withdrawal!]
+        # Sealer is the account recovered from the Thora seal of this block. If
+        # the chain doesn't run Thora or the seal is invalid, this field will be null.
+        sealer(block: Long): Account
+        # InTurn reports whether this block was sealed by the in-turn Thora signer.
+        # If the chain doesn't run Thora, this field will be null.
+        inTurn: Boolean
+        # Signers is the list of authorized Thora signers embedded into this
+        # checkpoint block. For any other block this field will be null.
+        signers: [Address!]
+        # Vote is the Thora signer authorization vote cast in this block. If the
+        # block carries no vote, this field will be null.
+        vote: ThoraVote
+    }
+
+    # ThoraVote is a proposal cast by a Thora signer to modify the list of
+    # authorized signers.
+    type ThoraVote {
+        # Address is the account being voted on.
+        address: Address!
+        # Authorize is true if the vote is to authorize the account, false if
+        # it is to deauthorize it.
+        authorize: Boolean!
     }
 
     # CallData represents the data associated with a local contract call.
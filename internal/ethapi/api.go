@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -871,6 +872,33 @@ func (s *BlockChainAPI) GetUncleCountByBlockHash(ctx context.Context, blockHash
 	return nil
 }
 
+// GetBlockReceipts returns the receipts of all transactions in the block with
+// the given number or hash, in the same format as eth_getTransactionReceipt.
+func (s *BlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil || err != nil {
+		// When the block doesn't exist, the RPC method should return JSON null
+		// as per specification.
+		return nil, nil
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(txs) != len(receipts) {
+		return nil, fmt.Errorf("receipts length mismatch: %d vs %d", len(txs), len(receipts))
+	}
+	// Derive the sender.
+	signer := types.MakeSigner(s.b.ChainConfig(), block.Number(), block.Time())
+
+	result := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		result[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), signer, txs[i], i)
+	}
+	return result, nil
+}
+
 // GetCode returns the code stored at the given address in the state for the given block number.
 func (s *BlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
 	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
@@ -1701,13 +1729,18 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 
 	// Derive the sender.
 	signer := types.MakeSigner(s.b.ChainConfig(), header.Number, header.Time)
+	return marshalReceipt(receipt, blockHash, blockNumber, signer, tx, int(index)), nil
+}
+
+// marshalReceipt marshals a transaction receipt into a JSON object.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, signer types.Signer, tx *types.Transaction, txIndex int) map[string]interface{} {
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
-		"transactionIndex":  hexutil.Uint64(index),
+		"transactionHash":   tx.Hash(),
+		"transactionIndex":  hexutil.Uint64(txIndex),
 		"from":              from,
 		"to":                tx.To(),
 		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
@@ -1733,7 +1766,110 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
+}
+
+const (
+	// addressTxPageSize is the number of transactions returned in a single page
+	// by eth_getTransactionsByAddress.
+	addressTxPageSize = 100
+
+	// addressTxMaxPage is the highest page number accepted by
+	// eth_getTransactionsByAddress, bounding the entries skipped to reach it.
+	addressTxMaxPage = 100
+
+	// addressScanLimit is the maximum number of blocks not yet covered by the
+	// address index that eth_getTransactionsByAddress scans directly.
+	addressScanLimit = 8192
+)
+
+// GetTransactionsByAddress returns a page of the canonical transactions sent by,
+// sent to or creating the given address within the given block range, in
+// ascending order. Pages are numbered from zero and an empty page marks the end
+// of the results. Deeper pages must be reached by narrowing the block range. It
+// requires the address index to be enabled.
+func (s *TransactionAPI) GetTransactionsByAddress(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, page hexutil.Uint) ([]*RPCTransaction, error) {
+	if page > addressTxMaxPage {
+		return nil, fmt.Errorf("page %d exceeds the limit of %d, narrow the block range instead", page, addressTxMaxPage)
+	}
+	size, sections := s.b.AddressIndexStatus()
+	if size == 0 {
+		return nil, errors.New("address index not enabled")
+	}
+	from, err := s.b.HeaderByNumber(ctx, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.b.HeaderByNumber(ctx, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from == nil || to == nil {
+		return nil, errors.New("block not found")
+	}
+	var (
+		begin   = from.Number.Uint64()
+		end     = to.Number.Uint64()
+		indexed = sections * size
+		skip    = int(page) * addressTxPageSize
+		txs     []*RPCTransaction
+	)
+	if begin > end {
+		return nil, errors.New("invalid block range")
+	}
+	// Serve the indexed part of the range from the address index
+	if begin < indexed {
+		last := end
+		if last >= indexed {
+			last = indexed - 1
+		}
+		entries, skipped := rawdb.ReadAddressIndexPage(s.b.ChainDb(), address, begin, last, skip, addressTxPageSize)
+		for _, entry := range entries {
+			block, err := s.b.BlockByNumber(ctx, rpc.BlockNumber(entry.Number))
+			if err != nil {
+				return nil, err
+			}
+			if block == nil || int(entry.Index) >= len(block.Transactions()) {
+				return nil, fmt.Errorf("address index inconsistent at block #%d", entry.Number)
+			}
+			txs = append(txs, newRPCTransactionFromBlockIndex(block, uint64(entry.Index), s.b.ChainConfig()))
+		}
+		begin, skip = indexed, skip-skipped
+	}
+	// Scan the recent blocks not yet covered by the address index
+	if len(txs) < addressTxPageSize && begin <= end {
+		if end-begin >= addressScanLimit {
+			return nil, fmt.Errorf("address index not yet available beyond block #%d", indexed)
+		}
+		for number := begin; number <= end && len(txs) < addressTxPageSize; number++ {
+			block, err := s.b.BlockByNumber(ctx, rpc.BlockNumber(number))
+			if err != nil {
+				return nil, err
+			}
+			if block == nil {
+				break
+			}
+			signer := types.MakeSigner(s.b.ChainConfig(), block.Number(), block.Time())
+			for i, tx := range block.Transactions() {
+				addrs, err := core.TransactionAddresses(signer, tx)
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := addrs[address]; !ok {
+					continue
+				}
+				if skip > 0 {
+					skip--
+					continue
+				}
+				txs = append(txs, newRPCTransactionFromBlockIndex(block, uint64(i), s.b.ChainConfig()))
+				if len(txs) == addressTxPageSize {
+					break
+				}
+			}
+		}
+	}
+	return txs, nil
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -1839,6 +1975,83 @@ func (s *TransactionAPI) SendRawTransaction(ctx context.Context, input hexutil.B
 	return SubmitTransaction(ctx, s.b, tx)
 }
 
+// defaultPrivateTxBlocks is the number of blocks a private transaction remains
+// includable for if the submitter does not specify a maximum block number.
+const defaultPrivateTxBlocks = 25
+
+// PrivateTransactionArgs represents the arguments to submit a private transaction.
+type PrivateTransactionArgs struct {
+	Tx             hexutil.Bytes   `json:"tx"`
+	MaxBlockNumber *hexutil.Uint64 `json:"maxBlockNumber"`
+}
+
+// SendPrivateTransaction adds a signed transaction to the local transaction pool
+// without propagating it to the network. It is only included in blocks sealed
+// locally, up to and including its maximum block number, after which it is
+// dropped from the pool.
+func (s *TransactionAPI) SendPrivateTransaction(ctx context.Context, args PrivateTransactionArgs) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(args.Tx); err != nil {
+		return common.Hash{}, err
+	}
+	if err := checkTxFee(tx.GasPrice(), tx.Gas(), s.b.RPCTxFeeCap()); err != nil {
+		return common.Hash{}, err
+	}
+	if !s.b.UnprotectedAllowed() && !tx.Protected() {
+		return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
+	}
+	head := s.b.CurrentBlock().Number.Uint64()
+
+	maxBlock := head + defaultPrivateTxBlocks
+	if args.MaxBlockNumber != nil {
+		maxBlock = uint64(*args.MaxBlockNumber)
+	}
+	if maxBlock <= head {
+		return common.Hash{}, fmt.Errorf("max block number %d already reached (head %d)", maxBlock, head)
+	}
+	if err := s.b.SendPrivateTx(ctx, tx, maxBlock); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted private transaction", "hash", tx.Hash().Hex(), "nonce", tx.Nonce(), "recipient", tx.To(), "maxblock", maxBlock)
+	return tx.Hash(), nil
+}
+
+// PrivateTransactionStatus is the inclusion status of a private transaction.
+type PrivateTransactionStatus struct {
+	Status         string          `json:"status"` // One of pending, included, expired or dropped
+	MaxBlockNumber hexutil.Uint64  `json:"maxBlockNumber"`
+	BlockHash      *common.Hash    `json:"blockHash,omitempty"`
+	BlockNumber    *hexutil.Uint64 `json:"blockNumber,omitempty"`
+}
+
+// GetPrivateTransactionStatus returns the inclusion status of a transaction
+// submitted via SendPrivateTransaction, or nil if it is not known.
+func (s *TransactionAPI) GetPrivateTransactionStatus(ctx context.Context, hash common.Hash) (*PrivateTransactionStatus, error) {
+	tracked := s.b.PrivateTxStatus(hash)
+	if tracked == nil {
+		return nil, nil
+	}
+	res := &PrivateTransactionStatus{MaxBlockNumber: hexutil.Uint64(tracked.MaxBlock)}
+
+	tx, blockHash, blockNumber, _, err := s.b.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case tx != nil:
+		res.Status = "included"
+		res.BlockHash = &blockHash
+		res.BlockNumber = (*hexutil.Uint64)(&blockNumber)
+	case tracked.Expired:
+		res.Status = "expired"
+	case s.b.GetPoolTransaction(hash) == nil:
+		res.Status = "dropped"
+	default:
+		res.Status = "pending"
+	}
+	return res, nil
+}
+
 // Sign calculates an ECDSA signature for:
 // keccak256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
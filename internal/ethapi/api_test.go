@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"math/big"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -33,10 +34,13 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/consensus/thora"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -206,6 +210,8 @@ type testBackend struct {
 	db      ethdb.Database
 	chain   *core.BlockChain
 	pending *types.Block
+
+	addressSections uint64 // Number of sections covered by the address index
 }
 
 func newTestBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i int, b *core.BlockGen)) *testBackend {
@@ -360,6 +366,13 @@ func (b testBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) even
 func (b testBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
 	panic("implement me")
 }
+func (b testBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction, maxBlock uint64) error {
+	panic("implement me")
+}
+func (b testBackend) PrivateTxStatus(txHash common.Hash) *txpool.PrivateTxStatus { return nil }
+func (b testBackend) SendBundle(ctx context.Context, txs []*types.Transaction, minBlock, maxBlock uint64, revertingTxHashes []common.Hash) (common.Hash, error) {
+	panic("implement me")
+}
 func (b testBackend) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
 	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(b.db, txHash)
 	return tx, blockHash, blockNumber, index, nil
@@ -394,10 +407,16 @@ func (b testBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Sub
 	panic("implement me")
 }
 func (b testBackend) BloomStatus() (uint64, uint64) { panic("implement me") }
+func (b testBackend) AddressIndexStatus() (uint64, uint64) {
+	return testAddressIndexSize, b.addressSections
+}
 func (b testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	panic("implement me")
 }
 
+// testAddressIndexSize is the address index section size of the test backend.
+const testAddressIndexSize = 4
+
 func TestEstimateGas(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
@@ -648,6 +667,263 @@ func TestCall(t *testing.T) {
 	}
 }
 
+func TestSimulateV1(t *testing.T) {
+	t.Parallel()
+	// Initialize test accounts
+	var (
+		accounts = newAccounts(3)
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		genBlocks = 2
+		signer    = types.HomesteadSigner{}
+
+		number   = common.HexToAddress("0x1000") // NUMBER PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+		coinbase = common.HexToAddress("0x1001") // COINBASE PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+		logger   = common.HexToAddress("0x1002") // PUSH1 42 PUSH1 0 MSTORE PUSH1 32 PUSH1 0 LOG0 STOP
+		reverter = common.HexToAddress("0x1003") // PUSH1 0 PUSH1 0 REVERT
+	)
+	backend := newTestBackend(t, genBlocks, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: uint64(i), To: &accounts[1].addr, Value: big.NewInt(1000), Gas: params.TxGas, GasPrice: b.BaseFee(), Data: nil}), signer, accounts[0].key)
+		b.AddTx(tx)
+	})
+	api := NewBlockChainAPI(backend)
+
+	code := func(hex string) *hexutil.Bytes {
+		b := hexutil.Bytes(common.FromHex(hex))
+		return &b
+	}
+	var (
+		value     = (*hexutil.Big)(big.NewInt(1000))
+		time      = hexutil.Uint64(backend.chain.CurrentBlock().Time + 100)
+		jump      = (*hexutil.Big)(big.NewInt(10))
+		latest    = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		overrides = StateOverride{
+			number:           {Code: code("0x4360005260206000f3")},
+			coinbase:         {Code: code("0x4160005260206000f3")},
+			logger:           {Code: code("0x602a60005260206000a000")},
+			reverter:         {Code: code("0x60006000fd")},
+			accounts[2].addr: {Balance: newRPCBalance(big.NewInt(params.Ether))},
+		}
+	)
+	opts := SimOpts{
+		BlockStateCalls: []SimBlock{
+			{
+				StateOverrides: &overrides,
+				Calls: []TransactionArgs{
+					{From: &accounts[2].addr, To: &accounts[1].addr, Value: value},
+					{From: &accounts[1].addr, To: &accounts[0].addr, Value: value}, // funded by the previous call
+					{To: &logger},
+					{To: &reverter},
+					{To: &number},
+				},
+			},
+			{
+				BlockOverrides: &BlockOverrides{Number: jump, Time: &time, Coinbase: &accounts[2].addr},
+				Calls: []TransactionArgs{
+					{To: &number},
+					{To: &coinbase},
+					{To: &logger},
+				},
+			},
+		},
+	}
+	results, err := api.SimulateV1(context.Background(), opts, &latest)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("block count mismatch: have %d, want 2", len(results))
+	}
+	first, second := results[0]["calls"].([]SimCallResult), results[1]["calls"].([]SimCallResult)
+	if len(first) != 5 || len(second) != 3 {
+		t.Fatalf("call count mismatch: have %d and %d, want 5 and 3", len(first), len(second))
+	}
+	// The calls of the first block should chain their state and execute on
+	// top of the next block number
+	for i, res := range first[:3] {
+		if res.Status != hexutil.Uint64(types.ReceiptStatusSuccessful) || res.Error != nil {
+			t.Errorf("call %d failed: %+v", i, res.Error)
+		}
+	}
+	if len(first[2].Logs) != 1 || first[2].Logs[0].Address != logger || first[2].Logs[0].BlockHash != results[0]["hash"].(common.Hash) {
+		t.Errorf("log mismatch: %+v", first[2].Logs)
+	}
+	if first[3].Status != hexutil.Uint64(types.ReceiptStatusFailed) || first[3].Error == nil || first[3].Error.Code != 3 {
+		t.Errorf("revert mismatch: %+v", first[3])
+	}
+	if have := new(big.Int).SetBytes(first[4].ReturnValue); have.Uint64() != uint64(genBlocks+1) {
+		t.Errorf("block number mismatch: have %d, want %d", have, genBlocks+1)
+	}
+	// The second block should execute on its overridden fields, inheriting the
+	// state overrides of the first one
+	if have := new(big.Int).SetBytes(second[0].ReturnValue); have.Cmp(jump.ToInt()) != 0 {
+		t.Errorf("overridden block number mismatch: have %d, want %d", have, jump.ToInt())
+	}
+	if have := common.BytesToAddress(second[1].ReturnValue); have != accounts[2].addr {
+		t.Errorf("overridden coinbase mismatch: have %x, want %x", have, accounts[2].addr)
+	}
+	if have := results[1]["timestamp"].(hexutil.Uint64); have != time {
+		t.Errorf("overridden timestamp mismatch: have %d, want %d", have, time)
+	}
+	if have := results[1]["parentHash"].(common.Hash); have != results[0]["hash"].(common.Hash) {
+		t.Errorf("parent hash mismatch: have %x, want %x", have, results[0]["hash"])
+	}
+	// Invalid nonces and out of order blocks should be rejected if validation is requested
+	nonce := hexutil.Uint64(100)
+	opts = SimOpts{
+		BlockStateCalls: []SimBlock{{Calls: []TransactionArgs{{From: &accounts[0].addr, To: &accounts[1].addr, Nonce: &nonce}}}},
+	}
+	if _, err := api.SimulateV1(context.Background(), opts, &latest); err != nil {
+		t.Errorf("unvalidated simulation failed: %v", err)
+	}
+	opts.Validation = true
+	if _, err := api.SimulateV1(context.Background(), opts, &latest); !errors.Is(err, core.ErrNonceTooHigh) {
+		t.Errorf("validation error mismatch: have %v, want %v", err, core.ErrNonceTooHigh)
+	}
+	past := (*hexutil.Big)(big.NewInt(1))
+	opts = SimOpts{
+		BlockStateCalls: []SimBlock{{BlockOverrides: &BlockOverrides{Number: past}}},
+	}
+	if _, err := api.SimulateV1(context.Background(), opts, &latest); err == nil {
+		t.Errorf("out of order block accepted")
+	}
+}
+
+func TestCallBundle(t *testing.T) {
+	t.Parallel()
+	// Initialize test accounts
+	var (
+		accounts = newAccounts(2)
+		reverter = common.HexToAddress("0x1000") // PUSH1 0 PUSH1 0 REVERT
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+				reverter:         {Balance: common.Big0, Code: common.FromHex("0x60006000fd")},
+			},
+		}
+		signer = types.LatestSigner(params.TestChainConfig)
+	)
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {})
+	api := NewBundleAPI(backend)
+
+	var (
+		head    = backend.chain.CurrentBlock()
+		baseFee = misc.CalcBaseFee(params.TestChainConfig, head)
+		tip     = big.NewInt(params.GWei)
+		feeCap  = new(big.Int).Add(baseFee, tip)
+	)
+	transfer := types.MustSignNewTx(accounts[0].key, signer, &types.DynamicFeeTx{ChainID: params.TestChainConfig.ChainID, Nonce: 0, To: &accounts[1].addr, Value: big.NewInt(1000), Gas: params.TxGas, GasTipCap: tip, GasFeeCap: feeCap})
+	revert := types.MustSignNewTx(accounts[0].key, signer, &types.DynamicFeeTx{ChainID: params.TestChainConfig.ChainID, Nonce: 1, To: &reverter, Gas: 100000, GasTipCap: tip, GasFeeCap: feeCap})
+
+	encode := func(txs ...*types.Transaction) []hexutil.Bytes {
+		var encoded []hexutil.Bytes
+		for _, tx := range txs {
+			blob, _ := tx.MarshalBinary()
+			encoded = append(encoded, blob)
+		}
+		return encoded
+	}
+	coinbase := common.Address{0xc0}
+	result, err := api.CallBundle(context.Background(), CallBundleArgs{Txs: encode(transfer, revert), Coinbase: &coinbase})
+	if err != nil {
+		t.Fatalf("failed to call bundle: %v", err)
+	}
+	if len(result.Results) != 2 {
+		t.Fatalf("result count mismatch: have %d, want 2", len(result.Results))
+	}
+	if res := result.Results[0]; res.TxHash != transfer.Hash() || res.From != accounts[0].addr || res.Error != "" || uint64(res.GasUsed) != params.TxGas {
+		t.Errorf("transfer result mismatch: %+v", res)
+	}
+	if res := result.Results[1]; res.TxHash != revert.Hash() || res.Error == "" {
+		t.Errorf("revert result mismatch: %+v", res)
+	}
+	// The coinbase should only have earned the priority fees
+	used := uint64(result.Results[0].GasUsed + result.Results[1].GasUsed)
+	if want := new(big.Int).Mul(tip, new(big.Int).SetUint64(used)); result.CoinbaseDiff.ToInt().Cmp(want) != 0 {
+		t.Errorf("coinbase diff mismatch: have %v, want %v", result.CoinbaseDiff, want)
+	}
+	if uint64(result.TotalGasUsed) != used || result.BundleGasPrice.ToInt().Cmp(tip) != 0 {
+		t.Errorf("bundle totals mismatch: %+v", result)
+	}
+	if uint64(result.StateBlockNumber) != head.Number.Uint64() {
+		t.Errorf("state block mismatch: have %d, want %d", result.StateBlockNumber, head.Number)
+	}
+	// Invalid transactions should fail the whole call
+	if _, err := api.CallBundle(context.Background(), CallBundleArgs{Txs: encode(revert)}); !errors.Is(err, core.ErrNonceTooHigh) {
+		t.Errorf("invalid bundle error mismatch: have %v, want %v", err, core.ErrNonceTooHigh)
+	}
+}
+
+// Tests that on Thora, where the coinbase carries the signer vote, bundles are
+// simulated with the fees credited to the signer expected to seal the block.
+func TestCallBundleThora(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts = newAccounts(3)
+		sender   = accounts[0]
+		signers  = []common.Address{accounts[1].addr, accounts[2].addr}
+		config   = params.AllThoraProtocolChanges
+		coinbase = common.HexToAddress("0x1000") // COINBASE PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+		genesis  = &core.Genesis{
+			Config:    config,
+			ExtraData: make([]byte, 32+2*common.AddressLength+crypto.SignatureLength),
+			Alloc: core.GenesisAlloc{
+				sender.addr: {Balance: big.NewInt(params.Ether)},
+				coinbase:    {Balance: common.Big0, Code: common.FromHex("0x4160005260206000f3")},
+			},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+	)
+	sort.Slice(signers, func(i, j int) bool { return signers[i].Less(signers[j]) })
+	for i, signer := range signers {
+		copy(genesis.ExtraData[32+i*common.AddressLength:], signer[:])
+	}
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = thora.New(config.Thora, db)
+	)
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	var (
+		api    = NewBundleAPI(&testBackend{db: db, chain: chain})
+		tip    = big.NewInt(params.GWei)
+		feeCap = new(big.Int).Add(misc.CalcBaseFee(config, chain.CurrentBlock()), tip)
+		tx     = types.MustSignNewTx(sender.key, types.LatestSigner(config), &types.DynamicFeeTx{ChainID: config.ChainID, Nonce: 0, To: &coinbase, Gas: 100000, GasTipCap: tip, GasFeeCap: feeCap})
+	)
+	blob, _ := tx.MarshalBinary()
+
+	// Without local signing credentials, the fees go to the in-turn signer of
+	// the next block, otherwise to the local signer
+	for _, local := range []*common.Address{nil, &signers[0]} {
+		want := signers[1]
+		if local != nil {
+			engine.Authorize(*local, nil, nil)
+			want = *local
+		}
+		result, err := api.CallBundle(context.Background(), CallBundleArgs{Txs: []hexutil.Bytes{blob}})
+		if err != nil {
+			t.Fatalf("failed to call bundle: %v", err)
+		}
+		res := result.Results[0]
+		if res.Error != "" || common.BytesToAddress(res.Value) != want {
+			t.Errorf("coinbase mismatch (local %v): have %x, want %x, err %q", local, common.BytesToAddress(res.Value), want, res.Error)
+		}
+		if fees := new(big.Int).Mul(tip, new(big.Int).SetUint64(uint64(res.GasUsed))); result.CoinbaseDiff.ToInt().Cmp(fees) != 0 {
+			t.Errorf("coinbase diff mismatch (local %v): have %v, want %v", local, result.CoinbaseDiff, fees)
+		}
+	}
+}
+
 type Account struct {
 	key  *ecdsa.PrivateKey
 	addr common.Address
@@ -1140,3 +1416,192 @@ func TestRPCGetTransactionReceipt(t *testing.T) {
 		require.JSONEqf(t, want, have, "test %d: json not match, want: %s, have: %s", i, want, have)
 	}
 }
+
+func TestRPCGetBlockReceipts(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts
+	var (
+		acc1Key, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		acc2Key, _ = crypto.HexToECDSA("49a7b37aa6f6645917e7b807e9d1c00d4fa71f18343b0d4122a4d2df64dd6fee")
+		acc1Addr   = crypto.PubkeyToAddress(acc1Key.PublicKey)
+		acc2Addr   = crypto.PubkeyToAddress(acc2Key.PublicKey)
+		contract   = common.HexToAddress("0000000000000000000000000000000000031ec7")
+		genesis    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				acc1Addr: {Balance: big.NewInt(params.Ether)},
+				acc2Addr: {Balance: big.NewInt(params.Ether)},
+				// Same token contract as in TestRPCGetTransactionReceipt
+				contract: {Balance: big.NewInt(params.Ether), Code: common.FromHex("0x608060405234801561001057600080fd5b506004361061002b5760003560e01c8063a9059cbb14610030575b600080fd5b61004a6004803603810190610045919061016a565b610060565b60405161005791906101c5565b60405180910390f35b60008273ffffffffffffffffffffffffffffffffffffffff163373ffffffffffffffffffffffffffffffffffffffff167fddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef846040516100bf91906101ef565b60405180910390a36001905092915050565b600080fd5b600073ffffffffffffffffffffffffffffffffffffffff82169050919050565b6000610101826100d6565b9050919050565b610111816100f6565b811461011c57600080fd5b50565b60008135905061012e81610108565b92915050565b6000819050919050565b61014781610134565b811461015257600080fd5b50565b6000813590506101648161013e565b92915050565b60008060408385031215610181576101806100d1565b5b600061018f8582860161011f565b92505060206101a085828601610155565b9150509250929050565b60008115159050919050565b6101bf816101aa565b82525050565b60006020820190506101da60008301846101b6565b92915050565b6101e981610134565b82525050565b600060208201905061020460008301846101e0565b9291505056fea2646970667358221220b469033f4b77b9565ee84e0a2f04d496b18160d26034d54f9487e57788fd36d564736f6c63430008120033")},
+			},
+		}
+		signer = types.LatestSignerForChainID(params.TestChainConfig.ChainID)
+	)
+	// Pack transactions of all types into the first block, leaving the second empty
+	backend := newTestBackend(t, 2, genesis, func(i int, b *core.BlockGen) {
+		if i != 0 {
+			return
+		}
+		transfer := func(n int) []byte {
+			return common.FromHex(fmt.Sprintf("0xa9059cbb%s%s", common.HexToHash(common.BigToAddress(big.NewInt(int64(n))).Hex()).String()[2:], common.BytesToHash([]byte{byte(n)}).String()[2:]))
+		}
+		fee := new(big.Int).Add(b.BaseFee(), big.NewInt(500))
+		txs := []types.TxData{
+			&types.LegacyTx{Nonce: 0, To: &acc2Addr, Value: big.NewInt(1000), Gas: params.TxGas, GasPrice: b.BaseFee()},
+			&types.LegacyTx{Nonce: 1, To: nil, Gas: 53100, GasPrice: b.BaseFee(), Data: common.FromHex("0x60806040")},
+			&types.LegacyTx{Nonce: 2, To: &contract, Gas: 60000, GasPrice: b.BaseFee(), Data: transfer(1)},
+			&types.DynamicFeeTx{Nonce: 3, To: &contract, Gas: 60000, GasTipCap: big.NewInt(500), GasFeeCap: fee, Data: transfer(2)},
+			&types.AccessListTx{Nonce: 4, To: nil, Gas: 58100, GasPrice: b.BaseFee(), Data: common.FromHex("0x60806040"), AccessList: types.AccessList{{Address: contract, StorageKeys: []common.Hash{{0}}}}},
+		}
+		for _, data := range txs {
+			tx, err := types.SignNewTx(acc1Key, signer, data)
+			if err != nil {
+				t.Fatalf("failed to sign tx: %v", err)
+			}
+			b.AddTx(tx)
+		}
+	})
+	var (
+		ctx    = context.Background()
+		api    = NewBlockChainAPI(backend)
+		txapi  = NewTransactionAPI(backend, new(AddrLocker))
+		block  = backend.chain.GetBlockByNumber(1)
+		number = rpc.BlockNumber(1)
+	)
+	// Receipts must be retrievable both by number and by hash, and be identical
+	// to the ones served individually by eth_getTransactionReceipt.
+	for _, query := range []rpc.BlockNumberOrHash{
+		rpc.BlockNumberOrHashWithNumber(number),
+		rpc.BlockNumberOrHashWithHash(block.Hash(), true),
+	} {
+		receipts, err := api.GetBlockReceipts(ctx, query)
+		if err != nil {
+			t.Fatalf("%v: failed to retrieve block receipts: %v", query, err)
+		}
+		if len(receipts) != len(block.Transactions()) {
+			t.Fatalf("%v: receipt count mismatch: have %d, want %d", query, len(receipts), len(block.Transactions()))
+		}
+		var logs int
+		for i, tx := range block.Transactions() {
+			want, err := txapi.GetTransactionReceipt(ctx, tx.Hash())
+			if err != nil {
+				t.Fatalf("%v: failed to retrieve receipt %d: %v", query, i, err)
+			}
+			wantJSON, _ := json.Marshal(want)
+			haveJSON, _ := json.Marshal(receipts[i])
+			require.JSONEqf(t, string(wantJSON), string(haveJSON), "%v: receipt %d mismatch", query, i)
+
+			if have := receipts[i]["transactionIndex"].(hexutil.Uint64); have != hexutil.Uint64(i) {
+				t.Errorf("%v: receipt %d index mismatch: have %d", query, i, have)
+			}
+			for _, log := range receipts[i]["logs"].([]*types.Log) {
+				if log.Index != uint(logs) {
+					t.Errorf("%v: receipt %d log index mismatch: have %d, want %d", query, i, log.Index, logs)
+				}
+				logs++
+			}
+		}
+		if logs == 0 {
+			t.Errorf("%v: no logs derived", query)
+		}
+	}
+	// Empty blocks should return an empty list, missing ones null
+	receipts, err := api.GetBlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(2))
+	if err != nil || receipts == nil || len(receipts) != 0 {
+		t.Errorf("empty block receipts mismatch: have %v, err %v", receipts, err)
+	}
+	receipts, err = api.GetBlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(3))
+	if err != nil || receipts != nil {
+		t.Errorf("missing block receipts mismatch: have %v, err %v", receipts, err)
+	}
+	receipts, err = api.GetBlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(common.HexToHash("deadbeef"), false))
+	if err != nil || receipts != nil {
+		t.Errorf("missing block receipts mismatch: have %v, err %v", receipts, err)
+	}
+}
+
+func TestRPCGetTransactionsByAddress(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts
+	var (
+		acc1Key, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		acc1Addr   = crypto.PubkeyToAddress(acc1Key.PublicKey)
+		acc2Addr   = common.Address{0x02}
+		acc3Addr   = common.Address{0x03}
+		genesis    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{acc1Addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.LatestSignerForChainID(params.TestChainConfig.ChainID)
+	)
+	// Alternate transfers between two recipients, one per block
+	backend := newTestBackend(t, 10, genesis, func(i int, b *core.BlockGen) {
+		to := acc2Addr
+		if i%2 == 1 {
+			to = acc3Addr
+		}
+		tx, err := types.SignNewTx(acc1Key, signer, &types.LegacyTx{Nonce: uint64(i), To: &to, Value: big.NewInt(1000), Gas: params.TxGas, GasPrice: b.BaseFee()})
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		b.AddTx(tx)
+	})
+	// Index the first two sections, leaving the rest of the chain to be scanned
+	for section := uint64(0); section < 2; section++ {
+		entries := make(map[common.Address][]rawdb.AddressIndexEntry)
+		for number := section * testAddressIndexSize; number < (section+1)*testAddressIndexSize; number++ {
+			block := backend.chain.GetBlockByNumber(number)
+			for i, tx := range block.Transactions() {
+				addrs, err := core.TransactionAddresses(signer, tx)
+				if err != nil {
+					t.Fatalf("failed to derive addresses: %v", err)
+				}
+				for addr, roles := range addrs {
+					entries[addr] = append(entries[addr], rawdb.AddressIndexEntry{Number: number, Index: uint32(i), Roles: roles})
+				}
+			}
+		}
+		rawdb.WriteAddressIndexSection(backend.db, section, entries)
+	}
+	backend.addressSections = 2
+
+	api := NewTransactionAPI(backend, new(AddrLocker))
+	tests := []struct {
+		addr     common.Address
+		from, to rpc.BlockNumber
+		page     hexutil.Uint
+		want     []uint64
+	}{
+		{acc2Addr, 0, rpc.LatestBlockNumber, 0, []uint64{1, 3, 5, 7, 9}},
+		{acc3Addr, 0, rpc.LatestBlockNumber, 0, []uint64{2, 4, 6, 8, 10}},
+		{acc1Addr, 0, rpc.LatestBlockNumber, 0, []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{acc2Addr, 2, 5, 0, []uint64{3, 5}},
+		{acc3Addr, 9, 10, 0, []uint64{10}},
+		{acc2Addr, 0, rpc.LatestBlockNumber, 1, nil},
+		{acc1Addr, 0, rpc.LatestBlockNumber, addressTxMaxPage, nil},
+		{common.Address{0xff}, 0, rpc.LatestBlockNumber, 0, nil},
+	}
+	for i, tt := range tests {
+		txs, err := api.GetTransactionsByAddress(context.Background(), tt.addr, tt.from, tt.to, tt.page)
+		if err != nil {
+			t.Fatalf("test %d: failed to retrieve transactions: %v", i, err)
+		}
+		if len(txs) != len(tt.want) {
+			t.Fatalf("test %d: transaction count mismatch: have %d, want %d", i, len(txs), len(tt.want))
+		}
+		for j, tx := range txs {
+			block := backend.chain.GetBlockByNumber(tt.want[j])
+			if tx.Hash != block.Transactions()[0].Hash() {
+				t.Errorf("test %d: transaction %d mismatch: have %x, want block #%d", i, j, tx.Hash, tt.want[j])
+			}
+		}
+	}
+	if _, err := api.GetTransactionsByAddress(context.Background(), acc2Addr, 5, 2, 0); err == nil {
+		t.Errorf("inverted block range accepted")
+	}
+	if _, err := api.GetTransactionsByAddress(context.Background(), acc2Addr, 0, rpc.LatestBlockNumber, addressTxMaxPage+1); err == nil {
+		t.Errorf("page beyond the limit accepted")
+	}
+}
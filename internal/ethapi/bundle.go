@@ -0,0 +1,336 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/consensus/thora"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// defaultBundleBlocks is the number of blocks a bundle remains includable for
+// if the submitter does not specify a maximum block number.
+const defaultBundleBlocks = 25
+
+// BundleAPI provides an API to submit and simulate atomic transaction bundles.
+type BundleAPI struct {
+	b Backend
+}
+
+// NewBundleAPI creates a new transaction bundle API instance.
+func NewBundleAPI(b Backend) *BundleAPI {
+	return &BundleAPI{b}
+}
+
+// SendBundleArgs represents the arguments to submit a transaction bundle.
+type SendBundleArgs struct {
+	Txs               []hexutil.Bytes `json:"txs"`
+	MinBlockNumber    *hexutil.Uint64 `json:"minBlockNumber"`
+	MaxBlockNumber    *hexutil.Uint64 `json:"maxBlockNumber"`
+	RevertingTxHashes []common.Hash   `json:"revertingTxHashes"`
+}
+
+// SendBundle schedules a list of signed transactions for atomic inclusion at
+// the top of a locally sealed block within the given block range. None of the
+// transactions are propagated to the network. The bundle is only included if
+// none of its transactions revert, except those explicitly allowed to.
+func (s *BundleAPI) SendBundle(ctx context.Context, args SendBundleArgs) (common.Hash, error) {
+	txs, err := s.decodeTxs(args.Txs)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	for _, tx := range txs {
+		if err := checkTxFee(tx.GasPrice(), tx.Gas(), s.b.RPCTxFeeCap()); err != nil {
+			return common.Hash{}, err
+		}
+		if !s.b.UnprotectedAllowed() && !tx.Protected() {
+			return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
+		}
+	}
+	head := s.b.CurrentBlock().Number.Uint64()
+
+	var minBlock uint64
+	if args.MinBlockNumber != nil {
+		minBlock = uint64(*args.MinBlockNumber)
+	}
+	maxBlock := head + defaultBundleBlocks
+	if args.MaxBlockNumber != nil {
+		maxBlock = uint64(*args.MaxBlockNumber)
+	}
+	hash, err := s.b.SendBundle(ctx, txs, minBlock, maxBlock, args.RevertingTxHashes)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted transaction bundle", "hash", hash, "txs", len(txs), "min", minBlock, "max", maxBlock)
+	return hash, nil
+}
+
+// CallBundleArgs represents the arguments to simulate a transaction bundle.
+type CallBundleArgs struct {
+	Txs              []hexutil.Bytes        `json:"txs"`
+	BlockNumber      *hexutil.Uint64        `json:"blockNumber"`
+	StateBlockNumber *rpc.BlockNumberOrHash `json:"stateBlockNumber"`
+	Timestamp        *hexutil.Uint64        `json:"timestamp"`
+	Coinbase         *common.Address        `json:"coinbase"`
+	BaseFee          *hexutil.Big           `json:"baseFee"`
+}
+
+// CallBundleTxResult is the outcome of a single transaction of a simulated bundle.
+type CallBundleTxResult struct {
+	TxHash       common.Hash     `json:"txHash"`
+	From         common.Address  `json:"fromAddress"`
+	To           *common.Address `json:"toAddress"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	GasPrice     *hexutil.Big    `json:"gasPrice"`
+	GasFees      *hexutil.Big    `json:"gasFees"`
+	CoinbaseDiff *hexutil.Big    `json:"coinbaseDiff"`
+	Value        hexutil.Bytes   `json:"value,omitempty"`
+	Logs         []*types.Log    `json:"logs"`
+	Error        string          `json:"error,omitempty"`
+	Revert       hexutil.Bytes   `json:"revert,omitempty"`
+}
+
+// CallBundleResult is the outcome of a simulated bundle.
+type CallBundleResult struct {
+	Results          []CallBundleTxResult `json:"results"`
+	BundleGasPrice   *hexutil.Big         `json:"bundleGasPrice"`
+	CoinbaseDiff     *hexutil.Big         `json:"coinbaseDiff"`
+	GasFees          *hexutil.Big         `json:"gasFees"`
+	TotalGasUsed     hexutil.Uint64       `json:"totalGasUsed"`
+	StateBlockNumber hexutil.Uint64       `json:"stateBlockNumber"`
+}
+
+// CallBundle simulates a bundle of signed transactions on top of the state of
+// the given block, as if they were included in order at the top of the next
+// one, and reports the outcome of each transaction.
+//
+// Note, this function doesn't make any changes in the state/blockchain and is
+// useful to check whether a bundle would be included by eth_sendBundle.
+func (s *BundleAPI) CallBundle(ctx context.Context, args CallBundleArgs) (*CallBundleResult, error) {
+	txs, err := s.decodeTxs(args.Txs)
+	if err != nil {
+		return nil, err
+	}
+	stateBlock := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if args.StateBlockNumber != nil {
+		stateBlock = *args.StateBlockNumber
+	}
+	defer func(start time.Time) { log.Debug("Executing bundle call finished", "runtime", time.Since(start)) }(time.Now())
+
+	state, parent, err := s.b.StateAndHeaderByNumberOrHash(ctx, stateBlock)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	// Assemble the header of the block the bundle is simulated in
+	config := s.b.ChainConfig()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + simulateBlockTime,
+		Difficulty: parent.Difficulty,
+		Coinbase:   parent.Coinbase,
+	}
+	if args.BlockNumber != nil {
+		header.Number = new(big.Int).SetUint64(uint64(*args.BlockNumber))
+	}
+	if args.Timestamp != nil {
+		header.Time = uint64(*args.Timestamp)
+	}
+	if args.Coinbase != nil {
+		header.Coinbase = *args.Coinbase
+	}
+	if args.BaseFee != nil {
+		header.BaseFee = args.BaseFee.ToInt()
+	} else if config.IsLondon(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(config, parent)
+	}
+	// On Thora the coinbase carries the signer vote and the fees are credited to
+	// the sealer instead, so simulate the block as sealed by the next one
+	feeRecipient := header.Coinbase
+	if engine, ok := thora.FromEngine(s.b.Engine()); ok && args.Coinbase == nil {
+		feeRecipient, err = engine.NextSealer(&chainHeaderReader{ctx: ctx, b: s.b}, parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Setup context so it may be cancelled when the call has completed
+	// or, in case of unmetered gas, setup a context with a timeout.
+	var cancel context.CancelFunc
+	if timeout := s.b.RPCEVMTimeout(); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	var (
+		signer   = types.MakeSigner(config, header.Number, header.Time)
+		blockCtx = core.NewEVMBlockContext(header, NewChainContext(ctx, s.b), &feeRecipient)
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		result   = &CallBundleResult{
+			Results:          make([]CallBundleTxResult, 0, len(txs)),
+			StateBlockNumber: hexutil.Uint64(parent.Number.Uint64()),
+		}
+		coinbaseDiff = new(big.Int)
+		gasFees      = new(big.Int)
+		gasUsed      uint64
+	)
+	for i, tx := range txs {
+		msg, err := core.TransactionToMessage(tx, signer, header.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("tx %d [%v]: %w", i, tx.Hash(), err)
+		}
+		state.SetTxContext(tx.Hash(), i)
+		coinbaseBefore := state.GetBalance(feeRecipient)
+
+		evm, vmError := s.b.GetEVM(ctx, msg, state, header, &vm.Config{}, &blockCtx)
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		res, err := core.ApplyMessage(evm, msg, gp)
+		close(done)
+
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", s.b.RPCEVMTimeout())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tx %d [%v]: %w", i, tx.Hash(), err)
+		}
+		state.Finalise(config.IsEIP158(header.Number))
+
+		var (
+			txGasUsed  = new(big.Int).SetUint64(res.UsedGas)
+			txGasFees  = new(big.Int).Mul(txGasUsed, msg.GasPrice)
+			txCoinbase = new(big.Int).Sub(state.GetBalance(feeRecipient), coinbaseBefore)
+			txLogs     = state.GetLogs(tx.Hash(), header.Number.Uint64(), common.Hash{})
+			txResult   = CallBundleTxResult{
+				TxHash:       tx.Hash(),
+				From:         msg.From,
+				To:           tx.To(),
+				GasUsed:      hexutil.Uint64(res.UsedGas),
+				GasPrice:     (*hexutil.Big)(msg.GasPrice),
+				GasFees:      (*hexutil.Big)(txGasFees),
+				CoinbaseDiff: (*hexutil.Big)(txCoinbase),
+				Logs:         txLogs,
+			}
+		)
+		if txResult.Logs == nil {
+			txResult.Logs = []*types.Log{}
+		}
+		if res.Err != nil {
+			txResult.Error = res.Err.Error()
+			txResult.Revert = res.Revert()
+		} else {
+			txResult.Value = res.Return()
+		}
+		result.Results = append(result.Results, txResult)
+
+		coinbaseDiff.Add(coinbaseDiff, txCoinbase)
+		gasFees.Add(gasFees, txGasFees)
+		gasUsed += res.UsedGas
+	}
+	result.CoinbaseDiff = (*hexutil.Big)(coinbaseDiff)
+	result.GasFees = (*hexutil.Big)(gasFees)
+	result.TotalGasUsed = hexutil.Uint64(gasUsed)
+	if gasUsed > 0 {
+		result.BundleGasPrice = (*hexutil.Big)(new(big.Int).Div(coinbaseDiff, new(big.Int).SetUint64(gasUsed)))
+	} else {
+		result.BundleGasPrice = new(hexutil.Big)
+	}
+	return result, nil
+}
+
+// decodeTxs decodes the binary encoded transactions of a bundle.
+func (s *BundleAPI) decodeTxs(encoded []hexutil.Bytes) ([]*types.Transaction, error) {
+	if len(encoded) == 0 {
+		return nil, errors.New("bundle missing transactions")
+	}
+	txs := make([]*types.Transaction, len(encoded))
+	for i, input := range encoded {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(input); err != nil {
+			return nil, fmt.Errorf("tx %d: %w", i, err)
+		}
+		txs[i] = tx
+	}
+	return txs, nil
+}
+
+// chainHeaderReader implements consensus.ChainHeaderReader on top of the API
+// backend, for the consensus engine to resolve the signers of a block.
+type chainHeaderReader struct {
+	ctx context.Context
+	b   Backend
+}
+
+func (r *chainHeaderReader) Config() *params.ChainConfig {
+	return r.b.ChainConfig()
+}
+
+func (r *chainHeaderReader) CurrentHeader() *types.Header {
+	return r.b.CurrentHeader()
+}
+
+func (r *chainHeaderReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header, err := r.b.HeaderByHash(r.ctx, hash)
+	if err != nil || header == nil || header.Number.Uint64() != number {
+		return nil
+	}
+	return header
+}
+
+func (r *chainHeaderReader) GetHeaderByNumber(number uint64) *types.Header {
+	header, err := r.b.HeaderByNumber(r.ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return nil
+	}
+	return header
+}
+
+func (r *chainHeaderReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	header, err := r.b.HeaderByHash(r.ctx, hash)
+	if err != nil {
+		return nil
+	}
+	return header
+}
+
+func (r *chainHeaderReader) GetTd(hash common.Hash, number uint64) *big.Int {
+	return r.b.GetTd(r.ctx, hash)
+}
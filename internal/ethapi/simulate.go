@@ -0,0 +1,381 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// maxSimulateBlocks is the maximum number of blocks that can be simulated
+	// in a single request.
+	maxSimulateBlocks = 256
+
+	// simulateBlockTime is the time offset applied to simulated blocks which do
+	// not override their timestamp.
+	simulateBlockTime = 12
+
+	// errCodeVMError is the JSON error code of a simulated call failing with an
+	// EVM error other than a revert.
+	errCodeVMError = -32015
+)
+
+// SimBlock is a batch of calls to be executed on top of the previous simulated
+// block, with the block fields and the pre-state optionally overridden.
+type SimBlock struct {
+	BlockOverrides *BlockOverrides   `json:"blockOverrides"`
+	StateOverrides *StateOverride    `json:"stateOverrides"`
+	Calls          []TransactionArgs `json:"calls"`
+}
+
+// SimOpts are the inputs of eth_simulateV1.
+type SimOpts struct {
+	BlockStateCalls []SimBlock `json:"blockStateCalls"`
+	Validation      bool       `json:"validation"`
+}
+
+// SimCallResult is the result of a single simulated call.
+type SimCallResult struct {
+	ReturnValue hexutil.Bytes  `json:"returnData"`
+	Logs        []*types.Log   `json:"logs"`
+	GasUsed     hexutil.Uint64 `json:"gasUsed"`
+	Status      hexutil.Uint64 `json:"status"`
+	Error       *SimCallError  `json:"error,omitempty"`
+}
+
+// SimCallError is the error of a simulated call which executed, but failed.
+type SimCallError struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+	Data    string `json:"data,omitempty"`
+}
+
+// MakeHeader returns a copy of the given header with the overridden fields set.
+func (diff *BlockOverrides) MakeHeader(header *types.Header) *types.Header {
+	if diff == nil {
+		return header
+	}
+	h := types.CopyHeader(header)
+	if diff.Number != nil {
+		h.Number = diff.Number.ToInt()
+	}
+	if diff.Difficulty != nil {
+		h.Difficulty = diff.Difficulty.ToInt()
+	}
+	if diff.Time != nil {
+		h.Time = uint64(*diff.Time)
+	}
+	if diff.GasLimit != nil {
+		h.GasLimit = uint64(*diff.GasLimit)
+	}
+	if diff.Coinbase != nil {
+		h.Coinbase = *diff.Coinbase
+	}
+	if diff.Random != nil {
+		h.MixDigest = *diff.Random
+	}
+	if diff.BaseFee != nil {
+		h.BaseFee = diff.BaseFee.ToInt()
+	}
+	return h
+}
+
+// simChainContext is a ChainContext which is also aware of the blocks already
+// simulated, so that BLOCKHASH resolves them too.
+type simChainContext struct {
+	*ChainContext
+	headers map[common.Hash]*types.Header
+}
+
+// GetHeader returns a simulated header if known, falling back to the chain.
+func (context *simChainContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header, ok := context.headers[hash]; ok && header.Number.Uint64() == number {
+		return header
+	}
+	return context.ChainContext.GetHeader(hash, number)
+}
+
+// simulator executes a sequence of simulated blocks on top of a base state,
+// chaining the state changes across all calls and blocks.
+type simulator struct {
+	b           Backend
+	state       *state.StateDB
+	base        *types.Header
+	chain       *simChainContext
+	validate    bool
+	gasCap      uint64
+	gasConsumed uint64
+}
+
+// SimulateV1 executes a series of simulated blocks, each consisting of a list
+// of calls, on top of the given block. Every block can override the header
+// fields and the state it executes on. State changes are carried over from
+// one call to the next and from one block to the next.
+//
+// If validation is enabled, calls are subject to the same nonce, balance and
+// base fee checks as real transactions, and base fees are derived from the
+// parent blocks unless overridden. Otherwise the checks are skipped, similarly
+// to eth_call.
+//
+// Note, this function doesn't make any changes in the state/blockchain and is
+// useful to preview the outcome of multi transaction workflows.
+func (s *BlockChainAPI) SimulateV1(ctx context.Context, opts SimOpts, blockNrOrHash *rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	if len(opts.BlockStateCalls) == 0 {
+		return nil, errors.New("empty input")
+	} else if len(opts.BlockStateCalls) > maxSimulateBlocks {
+		return nil, fmt.Errorf("too many blocks: %d > %d", len(opts.BlockStateCalls), maxSimulateBlocks)
+	}
+	if blockNrOrHash == nil {
+		n := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &n
+	}
+	defer func(start time.Time) { log.Debug("Executing EVM simulation finished", "runtime", time.Since(start)) }(time.Now())
+
+	state, base, err := s.b.StateAndHeaderByNumberOrHash(ctx, *blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	// Setup context so it may be cancelled when the simulation has completed
+	// or, in case of unmetered gas, setup a context with a timeout.
+	var cancel context.CancelFunc
+	if timeout := s.b.RPCEVMTimeout(); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	sim := &simulator{
+		b:     s.b,
+		state: state,
+		base:  base,
+		chain: &simChainContext{
+			ChainContext: NewChainContext(ctx, s.b),
+			headers:      make(map[common.Hash]*types.Header),
+		},
+		validate: opts.Validation,
+		gasCap:   s.b.RPCGasCap(),
+	}
+	return sim.execute(ctx, opts.BlockStateCalls)
+}
+
+// execute runs all the simulated blocks and marshals their results.
+func (sim *simulator) execute(ctx context.Context, blocks []SimBlock) ([]map[string]interface{}, error) {
+	var (
+		parent  = sim.base
+		results = make([]map[string]interface{}, 0, len(blocks))
+	)
+	for bi, block := range blocks {
+		header, err := sim.makeHeader(parent, block.BlockOverrides)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", bi, err)
+		}
+		if err := block.StateOverrides.Apply(sim.state); err != nil {
+			return nil, fmt.Errorf("block %d: %w", bi, err)
+		}
+		calls, err := sim.processBlock(ctx, header, block.Calls)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", bi, err)
+		}
+		// The block hash is only known after all calls executed, fill it into
+		// the logs retroactively.
+		hash := header.Hash()
+		for _, call := range calls {
+			for _, l := range call.Logs {
+				l.BlockHash = hash
+			}
+		}
+		sim.chain.headers[hash] = header
+
+		fields := RPCMarshalHeader(header)
+		fields["calls"] = calls
+		results = append(results, fields)
+
+		parent = header
+	}
+	return results, nil
+}
+
+// makeHeader assembles the header of the next simulated block on top of parent.
+func (sim *simulator) makeHeader(parent *types.Header, overrides *BlockOverrides) (*types.Header, error) {
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Coinbase:   parent.Coinbase,
+		Difficulty: new(big.Int),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + simulateBlockTime,
+		MixDigest:  parent.MixDigest,
+	}
+	header = overrides.MakeHeader(header)
+
+	if header.Number.Cmp(parent.Number) <= 0 {
+		return nil, fmt.Errorf("block numbers must be in order: %d <= %d", header.Number, parent.Number)
+	}
+	if header.Time <= parent.Time {
+		return nil, fmt.Errorf("block timestamps must be in order: %d <= %d", header.Time, parent.Time)
+	}
+	config := sim.b.ChainConfig()
+	if header.BaseFee == nil && config.IsLondon(header.Number) {
+		// Without validation, fees are ignored the same way as in eth_call,
+		// so the base fee is only derived when it matters.
+		if sim.validate && parent.BaseFee != nil {
+			header.BaseFee = misc.CalcBaseFee(config, parent)
+		} else {
+			header.BaseFee = new(big.Int)
+		}
+	}
+	return header, nil
+}
+
+// processBlock executes the calls of a simulated block, updating the gas used
+// and the bloom filter of the header.
+func (sim *simulator) processBlock(ctx context.Context, header *types.Header, calls []TransactionArgs) ([]SimCallResult, error) {
+	var (
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		blockCtx = core.NewEVMBlockContext(header, sim.chain, &header.Coinbase)
+		vmConfig = &vm.Config{NoBaseFee: !sim.validate}
+		results  = make([]SimCallResult, len(calls))
+		receipts = make(types.Receipts, len(calls))
+		logIndex uint
+	)
+	for i, args := range calls {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := sim.sanitizeCall(&args, header, gp.Gas()); err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		msg, err := args.ToMessage(sim.gasCap, header.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		msg.Nonce = uint64(*args.Nonce)
+		msg.SkipAccountChecks = !sim.validate
+
+		// Execute the message on a synthetic transaction hash, so logs can be
+		// told apart.
+		txHash := args.toTransaction().Hash()
+		sim.state.SetTxContext(txHash, i)
+
+		evm, vmError := sim.b.GetEVM(ctx, msg, sim.state, header, vmConfig, &blockCtx)
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		result, err := core.ApplyMessage(evm, msg, gp)
+		close(done)
+
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", sim.b.RPCEVMTimeout())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		sim.state.Finalise(true)
+		sim.gasConsumed += result.UsedGas
+		header.GasUsed += result.UsedGas
+
+		logs := sim.state.GetLogs(txHash, header.Number.Uint64(), common.Hash{})
+		for _, l := range logs {
+			l.Index = logIndex
+			logIndex++
+		}
+		receipts[i] = &types.Receipt{Logs: logs}
+		receipts[i].Bloom = types.CreateBloom(types.Receipts{receipts[i]})
+
+		res := SimCallResult{
+			ReturnValue: result.Return(),
+			Logs:        logs,
+			GasUsed:     hexutil.Uint64(result.UsedGas),
+			Status:      hexutil.Uint64(types.ReceiptStatusSuccessful),
+		}
+		if res.Logs == nil {
+			res.Logs = []*types.Log{}
+		}
+		if result.Failed() {
+			res.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+			if errors.Is(result.Err, vm.ErrExecutionReverted) {
+				revertErr := newRevertError(result)
+				res.Error = &SimCallError{Message: revertErr.Error(), Code: revertErr.ErrorCode(), Data: revertErr.reason}
+			} else {
+				res.Error = &SimCallError{Message: result.Err.Error(), Code: errCodeVMError}
+			}
+		}
+		results[i] = res
+	}
+	header.Bloom = types.CreateBloom(receipts)
+	header.Root = sim.state.IntermediateRoot(sim.b.ChainConfig().IsEIP158(header.Number))
+	if len(calls) > 0 {
+		header.ReceiptHash = types.DeriveSha(receipts, trie.NewStackTrie(nil))
+	} else {
+		header.ReceiptHash = types.EmptyReceiptsHash
+	}
+	return results, nil
+}
+
+// sanitizeCall fills in the missing fields of a simulated call from the current
+// state and block, and enforces the gas limits.
+func (sim *simulator) sanitizeCall(args *TransactionArgs, header *types.Header, remaining uint64) error {
+	if args.From == nil {
+		args.From = new(common.Address)
+	}
+	if args.Nonce == nil {
+		nonce := hexutil.Uint64(sim.state.GetNonce(*args.From))
+		args.Nonce = &nonce
+	}
+	if args.Gas == nil {
+		gas := remaining
+		if sim.gasCap != 0 && sim.gasCap-sim.gasConsumed < gas {
+			gas = sim.gasCap - sim.gasConsumed
+		}
+		args.Gas = (*hexutil.Uint64)(&gas)
+	}
+	if uint64(*args.Gas) > remaining {
+		return fmt.Errorf("block gas limit reached: %d > %d", *args.Gas, remaining)
+	}
+	if sim.gasCap != 0 && sim.gasConsumed+uint64(*args.Gas) > sim.gasCap {
+		return fmt.Errorf("simulation gas cap reached: %d", sim.gasCap)
+	}
+	if args.ChainID == nil {
+		args.ChainID = (*hexutil.Big)(sim.b.ChainConfig().ChainID)
+	}
+	return nil
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
@@ -200,6 +201,18 @@ func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.eth.txPool.Add(ctx, signedTx)
 }
 
+func (b *LesApiBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction, maxBlock uint64) error {
+	return errors.New("private transactions are not supported by light clients")
+}
+
+func (b *LesApiBackend) PrivateTxStatus(txHash common.Hash) *txpool.PrivateTxStatus {
+	return nil
+}
+
+func (b *LesApiBackend) SendBundle(ctx context.Context, txs []*types.Transaction, minBlock, maxBlock uint64, revertingTxHashes []common.Hash) (common.Hash, error) {
+	return common.Hash{}, errors.New("bundles are not supported by light clients")
+}
+
 func (b *LesApiBackend) RemoveTx(txHash common.Hash) {
 	b.eth.txPool.RemoveTx(txHash)
 }
@@ -315,6 +328,10 @@ func (b *LesApiBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocksClient, sections
 }
 
+func (b *LesApiBackend) AddressIndexStatus() (uint64, uint64) {
+	return 0, 0
+}
+
 func (b *LesApiBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	for i := 0; i < bloomFilterThreads; i++ {
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.eth.bloomRequests)
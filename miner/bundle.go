@@ -0,0 +1,212 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// maxBundles is the maximum number of bundles tracked by the pool.
+	maxBundles = 1024
+
+	// maxBundleTxs is the maximum number of transactions in a single bundle.
+	maxBundleTxs = 64
+)
+
+var (
+	// ErrBundleEmpty is returned if a bundle without transactions is submitted.
+	ErrBundleEmpty = errors.New("bundle empty")
+
+	// ErrBundleTooLarge is returned if a bundle exceeds the transaction limit.
+	ErrBundleTooLarge = errors.New("bundle too large")
+
+	// ErrBundleExpired is returned if a bundle targets blocks already mined.
+	ErrBundleExpired = errors.New("bundle expired")
+
+	// ErrBundlePoolFull is returned if the bundle pool reached its capacity.
+	ErrBundlePoolFull = errors.New("bundle pool full")
+
+	// errBundleReverted is returned if a transaction of a bundle, which was not
+	// allowed to revert, failed during execution.
+	errBundleReverted = errors.New("bundle transaction reverted")
+
+	// errBundleNonceTooHigh is returned if a transaction of a bundle can't be
+	// included yet as it doesn't follow the account nonce.
+	errBundleNonceTooHigh = errors.New("bundle nonce too high")
+)
+
+// Bundle is a list of transactions which must be included atomically and in
+// order at the top of a block within the target block range.
+type Bundle struct {
+	Txs               []*types.Transaction
+	MinBlock          uint64        // First block the bundle may be included in, 0 for any
+	MaxBlock          uint64        // Last block the bundle may be included in
+	RevertingTxHashes []common.Hash // Transactions allowed to revert without dropping the bundle
+}
+
+// Hash returns the identifier of the bundle, the hash of its transaction hashes.
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([]byte, 0, len(b.Txs)*common.HashLength)
+	for _, tx := range b.Txs {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// checkNonces verifies that the nonces of the bundle transactions follow on the
+// account nonces of the given state. It returns true if the bundle can never be
+// included anymore, because it was already included or one of its transactions
+// was replaced or has an invalid signature.
+func (b *Bundle) checkNonces(signer types.Signer, state *state.StateDB) (bool, error) {
+	nonces := make(map[common.Address]uint64)
+	for _, tx := range b.Txs {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return true, err
+		}
+		next, ok := nonces[from]
+		if !ok {
+			next = state.GetNonce(from)
+		}
+		switch {
+		case tx.Nonce() < next:
+			return true, fmt.Errorf("%w: address %v, tx: %d state: %d", core.ErrNonceTooLow, from, tx.Nonce(), next)
+		case tx.Nonce() > next:
+			return false, fmt.Errorf("%w: address %v, tx: %d state: %d", errBundleNonceTooHigh, from, tx.Nonce(), next)
+		}
+		nonces[from] = next + 1
+	}
+	return false, nil
+}
+
+// canRevert returns whether the given transaction may revert without dropping
+// the bundle.
+func (b *Bundle) canRevert(hash common.Hash) bool {
+	for _, h := range b.RevertingTxHashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// bundlePool tracks the bundles submitted to the local miner, in arrival order,
+// until their target block range passes.
+type bundlePool struct {
+	bundles []*Bundle
+	known   map[common.Hash]struct{}
+	lock    sync.Mutex
+}
+
+// newBundlePool creates an empty bundle pool.
+func newBundlePool() *bundlePool {
+	return &bundlePool{
+		known: make(map[common.Hash]struct{}),
+	}
+}
+
+// add validates a bundle against the current chain head and schedules it for
+// inclusion. Duplicate bundles are silently ignored.
+func (p *bundlePool) add(bundle *Bundle, head uint64) error {
+	switch {
+	case len(bundle.Txs) == 0:
+		return ErrBundleEmpty
+	case len(bundle.Txs) > maxBundleTxs:
+		return fmt.Errorf("%w: %d > %d", ErrBundleTooLarge, len(bundle.Txs), maxBundleTxs)
+	case bundle.MaxBlock <= head:
+		return fmt.Errorf("%w: max block %d, head %d", ErrBundleExpired, bundle.MaxBlock, head)
+	case bundle.MinBlock > bundle.MaxBlock:
+		return fmt.Errorf("invalid bundle range: %d > %d", bundle.MinBlock, bundle.MaxBlock)
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	hash := bundle.Hash()
+	if _, ok := p.known[hash]; ok {
+		return nil
+	}
+	p.prune(head + 1)
+	if len(p.bundles) >= maxBundles {
+		return ErrBundlePoolFull
+	}
+	p.bundles = append(p.bundles, bundle)
+	p.known[hash] = struct{}{}
+
+	log.Debug("Added transaction bundle", "hash", hash, "txs", len(bundle.Txs), "min", bundle.MinBlock, "max", bundle.MaxBlock)
+	return nil
+}
+
+// pending returns the bundles which may be included in the block with the given
+// number, in arrival order, dropping the ones which can't be included anymore.
+func (p *bundlePool) pending(number uint64) []*Bundle {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.prune(number)
+
+	var bundles []*Bundle
+	for _, bundle := range p.bundles {
+		if bundle.MinBlock <= number {
+			bundles = append(bundles, bundle)
+		}
+	}
+	return bundles
+}
+
+// remove drops the bundle with the given hash from the pool.
+func (p *bundlePool) remove(hash common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if _, ok := p.known[hash]; !ok {
+		return
+	}
+	delete(p.known, hash)
+	for i, bundle := range p.bundles {
+		if bundle.Hash() == hash {
+			p.bundles = append(p.bundles[:i], p.bundles[i+1:]...)
+			break
+		}
+	}
+}
+
+// prune drops all bundles whose target range ends before the given block. The
+// caller must hold the pool lock.
+func (p *bundlePool) prune(number uint64) {
+	bundles := p.bundles[:0]
+	for _, bundle := range p.bundles {
+		if bundle.MaxBlock < number {
+			delete(p.known, bundle.Hash())
+			continue
+		}
+		bundles = append(bundles, bundle)
+	}
+	for i := len(bundles); i < len(p.bundles); i++ {
+		p.bundles[i] = nil
+	}
+	p.bundles = bundles
+}
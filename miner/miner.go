@@ -53,6 +53,9 @@ type Config struct {
 	Recommit  time.Duration  // The time interval for miner to re-create mining work.
 
 	NewPayloadTimeout time.Duration // The maximum time allowance for creating a new payload
+
+	PriorityGas uint64 `toml:",omitempty"` // Gas reserved at the top of each block for the tx pool's priority lane
+	Ordering    string `toml:",omitempty"` // Transaction ordering policy (price, fcfs, roundrobin or a registered one)
 }
 
 // DefaultConfig contains default settings for miner.
@@ -234,6 +237,12 @@ func (miner *Miner) SetGasCeil(ceil uint64) {
 	miner.worker.setGasCeil(ceil)
 }
 
+// AddBundle schedules a bundle of transactions for atomic inclusion at the top
+// of the locally built blocks within its target block range.
+func (miner *Miner) AddBundle(bundle *Bundle) error {
+	return miner.worker.bundles.add(bundle, miner.worker.chain.CurrentBlock().Number.Uint64())
+}
+
 // SubscribePendingLogs starts delivering logs from pending transactions
 // to the given channel.
 func (miner *Miner) SubscribePendingLogs(ch chan<- []*types.Log) event.Subscription {
@@ -0,0 +1,268 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"container/heap"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// OrderingPrice orders transactions by effective miner tip, breaking ties
+	// by arrival time. This is the default policy.
+	OrderingPrice = "price"
+
+	// OrderingFCFS orders transactions by the time they were first seen.
+	OrderingFCFS = "fcfs"
+
+	// OrderingRoundRobin takes one transaction from each sender in turn, with
+	// senders ordered by the arrival time of their first pending transaction.
+	OrderingRoundRobin = "roundrobin"
+)
+
+// TransactionOrdering yields a set of pending transactions in the order they
+// are to be committed into a block, honouring the nonce order of each sender.
+type TransactionOrdering interface {
+	// Peek returns the next transaction to commit, or nil if none is left.
+	Peek() *types.Transaction
+
+	// Shift replaces the current transaction with the next one from the same
+	// sender. It is used after the transaction was committed or skipped.
+	Shift()
+
+	// Pop removes the current transaction along with all remaining ones from
+	// the same sender. It is used when the transaction cannot be executed.
+	Pop()
+}
+
+// OrderingPolicy creates a transaction ordering from a set of pending transactions,
+// grouped by sender and sorted by nonce. Transactions with a sender mismatch or
+// a fee cap below the base fee must be dropped, along with all subsequent ones
+// from the same sender.
+//
+// Note, the input map is reowned so the policy may modify it.
+type OrderingPolicy func(signer types.Signer, txs map[common.Address][]*types.Transaction, baseFee *big.Int) TransactionOrdering
+
+var (
+	orderingLock     sync.RWMutex
+	orderingPolicies = map[string]OrderingPolicy{
+		OrderingPrice: func(signer types.Signer, txs map[common.Address][]*types.Transaction, baseFee *big.Int) TransactionOrdering {
+			return types.NewTransactionsByPriceAndNonce(signer, txs, baseFee)
+		},
+		OrderingFCFS:       newFCFSOrdering,
+		OrderingRoundRobin: newRoundRobinOrdering,
+	}
+)
+
+// RegisterOrdering makes a custom transaction ordering policy available under
+// the given name, to be selected through the miner configuration. It panics if
+// a policy with the same name is already registered.
+func RegisterOrdering(name string, policy OrderingPolicy) {
+	orderingLock.Lock()
+	defer orderingLock.Unlock()
+
+	if _, ok := orderingPolicies[name]; ok {
+		panic(fmt.Sprintf("transaction ordering %q already registered", name))
+	}
+	orderingPolicies[name] = policy
+}
+
+// OrderingPolicies returns the names of all available ordering policies.
+func OrderingPolicies() []string {
+	orderingLock.RLock()
+	defer orderingLock.RUnlock()
+
+	names := make([]string, 0, len(orderingPolicies))
+	for name := range orderingPolicies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupOrdering retrieves the ordering policy registered under the given name.
+func lookupOrdering(name string) (OrderingPolicy, bool) {
+	orderingLock.RLock()
+	defer orderingLock.RUnlock()
+
+	policy, ok := orderingPolicies[name]
+	return policy, ok
+}
+
+// sanitizeOrderingTxs drops the transactions of each sender starting at the
+// first one which is not signed by the sender or cannot pay the base fee.
+func sanitizeOrderingTxs(signer types.Signer, txs map[common.Address][]*types.Transaction, baseFee *big.Int) {
+	for from, accTxs := range txs {
+		for i, tx := range accTxs {
+			if _, err := tx.EffectiveGasTip(baseFee); err != nil {
+				accTxs = accTxs[:i]
+				break
+			}
+			if i == 0 {
+				if acc, _ := types.Sender(signer, tx); acc != from {
+					accTxs = nil
+					break
+				}
+			}
+		}
+		if len(accTxs) == 0 {
+			delete(txs, from)
+		} else {
+			txs[from] = accTxs
+		}
+	}
+}
+
+// txArrival returns the time a transaction was first seen. It's a variable so
+// that tests can inject arrival times instead of depending on the clock.
+var txArrival = (*types.Transaction).Time
+
+// txsByTime is a heap of the head transactions of each sender, ordered by the
+// time they were first seen, falling back to the hash for determinism.
+type txsByTime []*types.Transaction
+
+func (s txsByTime) Len() int { return len(s) }
+func (s txsByTime) Less(i, j int) bool {
+	if ti, tj := txArrival(s[i]), txArrival(s[j]); !ti.Equal(tj) {
+		return ti.Before(tj)
+	}
+	return s[i].Hash().Less(s[j].Hash())
+}
+func (s txsByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s *txsByTime) Push(x interface{}) {
+	*s = append(*s, x.(*types.Transaction))
+}
+
+func (s *txsByTime) Pop() interface{} {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	*s = old[0 : n-1]
+	return x
+}
+
+// fcfsOrdering yields transactions in the order they arrived, regardless of the
+// fees they pay.
+type fcfsOrdering struct {
+	txs    map[common.Address][]*types.Transaction
+	heads  txsByTime
+	signer types.Signer
+}
+
+// newFCFSOrdering creates a first-come-first-served transaction ordering.
+func newFCFSOrdering(signer types.Signer, txs map[common.Address][]*types.Transaction, baseFee *big.Int) TransactionOrdering {
+	sanitizeOrderingTxs(signer, txs, baseFee)
+
+	heads := make(txsByTime, 0, len(txs))
+	for from, accTxs := range txs {
+		heads = append(heads, accTxs[0])
+		txs[from] = accTxs[1:]
+	}
+	heap.Init(&heads)
+
+	return &fcfsOrdering{
+		txs:    txs,
+		heads:  heads,
+		signer: signer,
+	}
+}
+
+// Peek implements TransactionOrdering, returning the oldest transaction.
+func (o *fcfsOrdering) Peek() *types.Transaction {
+	if len(o.heads) == 0 {
+		return nil
+	}
+	return o.heads[0]
+}
+
+// Shift implements TransactionOrdering.
+func (o *fcfsOrdering) Shift() {
+	acc, _ := types.Sender(o.signer, o.heads[0])
+	if txs := o.txs[acc]; len(txs) > 0 {
+		o.heads[0], o.txs[acc] = txs[0], txs[1:]
+		heap.Fix(&o.heads, 0)
+		return
+	}
+	heap.Pop(&o.heads)
+}
+
+// Pop implements TransactionOrdering.
+func (o *fcfsOrdering) Pop() {
+	heap.Pop(&o.heads)
+}
+
+// roundRobinOrdering yields one transaction from each sender in turn, so no
+// single sender can monopolise a block.
+type roundRobinOrdering struct {
+	txs     map[common.Address][]*types.Transaction
+	senders []common.Address // Senders in turn order, the current one first
+}
+
+// newRoundRobinOrdering creates a fair per-sender transaction ordering.
+func newRoundRobinOrdering(signer types.Signer, txs map[common.Address][]*types.Transaction, baseFee *big.Int) TransactionOrdering {
+	sanitizeOrderingTxs(signer, txs, baseFee)
+
+	heads := make(txsByTime, 0, len(txs))
+	for _, accTxs := range txs {
+		heads = append(heads, accTxs[0])
+	}
+	sort.Sort(heads)
+
+	senders := make([]common.Address, len(heads))
+	for i, tx := range heads {
+		senders[i], _ = types.Sender(signer, tx)
+	}
+	return &roundRobinOrdering{
+		txs:     txs,
+		senders: senders,
+	}
+}
+
+// Peek implements TransactionOrdering, returning the next transaction of the
+// sender whose turn it is.
+func (o *roundRobinOrdering) Peek() *types.Transaction {
+	if len(o.senders) == 0 {
+		return nil
+	}
+	return o.txs[o.senders[0]][0]
+}
+
+// Shift implements TransactionOrdering, moving the current sender to the end
+// of the turn order.
+func (o *roundRobinOrdering) Shift() {
+	acc := o.senders[0]
+	if txs := o.txs[acc][1:]; len(txs) > 0 {
+		o.txs[acc] = txs
+		o.senders = append(o.senders[1:], acc)
+		return
+	}
+	o.Pop()
+}
+
+// Pop implements TransactionOrdering.
+func (o *roundRobinOrdering) Pop() {
+	delete(o.txs, o.senders[0])
+	o.senders = o.senders[1:]
+}
@@ -199,6 +199,9 @@ type worker struct {
 	pendingMu    sync.RWMutex
 	pendingTasks map[common.Hash]*task
 
+	bundles  *bundlePool    // Transaction bundles to be included at the top of blocks
+	ordering OrderingPolicy // Policy ordering the pool transactions within blocks
+
 	snapshotMu       sync.RWMutex // The lock used to protect the snapshots below
 	snapshotBlock    *types.Block
 	snapshotReceipts types.Receipts
@@ -242,6 +245,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		coinbase:           config.Etherbase,
 		extra:              config.ExtraData,
 		pendingTasks:       make(map[common.Hash]*task),
+		bundles:            newBundlePool(),
 		txsCh:              make(chan core.NewTxsEvent, txChanSize),
 		chainHeadCh:        make(chan core.ChainHeadEvent, chainHeadChanSize),
 		newWorkCh:          make(chan *newWorkReq),
@@ -266,6 +270,16 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 	}
 	worker.recommit = recommit
 
+	// Sanitize the transaction ordering policy, falling back to the default.
+	ordering, ok := lookupOrdering(worker.config.Ordering)
+	if !ok {
+		if worker.config.Ordering != "" {
+			log.Warn("Sanitizing unknown miner transaction ordering", "provided", worker.config.Ordering, "updated", OrderingPrice, "available", OrderingPolicies())
+		}
+		ordering, _ = lookupOrdering(OrderingPrice)
+	}
+	worker.ordering = ordering
+
 	// Sanitize the timeout config for creating payload.
 	newpayloadTimeout := worker.config.NewPayloadTimeout
 	if newpayloadTimeout == 0 {
@@ -538,7 +552,7 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs, w.current.header.BaseFee)
+				txset := w.ordering(w.current.signer, txs, w.current.header.BaseFee)
 				tcount := w.current.tcount
 				w.commitTransactions(w.current, txset, nil)
 
@@ -744,7 +758,7 @@ func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByPriceAndNonce, interrupt *atomic.Int32) error {
+func (w *worker) commitTransactions(env *environment, txs TransactionOrdering, interrupt *atomic.Int32) error {
 	gasLimit := env.header.GasLimit
 	if env.gasPool == nil {
 		env.gasPool = new(core.GasPool).AddGas(gasLimit)
@@ -898,13 +912,45 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 }
 
 // fillTransactions retrieves the pending transactions from the txpool and fills them
-// into the given sealing block. The transaction selection and ordering strategy can
-// be customized with the plugin in the future.
+// into the given sealing block. Within each group (priority, local and remote) the
+// transactions are ordered by the configured ordering policy.
 func (w *worker) fillTransactions(interrupt *atomic.Int32, env *environment) error {
 	// Split the pending transactions into locals and remotes
 	// Fill the block with all available pending transactions.
 	pending := w.eth.TxPool().Pending(true)
-
+	w.eth.TxPool().FilterExpiredPrivate(pending, env.header.Number.Uint64())
+
+	// Commit the bundles at the top of the block, before any pool transaction
+	w.commitBundles(env)
+
+	// Commit the priority lane first, within the gas reserved for it
+	if w.config.PriorityGas > 0 {
+		var priorityTxs map[common.Address][]*types.Transaction
+		priorityTxs, pending = w.eth.TxPool().Priority().Split(pending)
+		if len(priorityTxs) > 0 {
+			// The orderings consume the map they are given, keep the original
+			// around to find the transactions left out
+			lane := make(map[common.Address][]*types.Transaction, len(priorityTxs))
+			for from, txs := range priorityTxs {
+				lane[from] = txs
+			}
+			txs := w.ordering(env.signer, lane, env.header.BaseFee)
+			if err := w.commitReservedTransactions(env, txs, w.config.PriorityGas, interrupt); err != nil {
+				return err
+			}
+		}
+		// Priority transactions not fitting into the reserved gas fall back to
+		// the regular ordering, ahead of the rest of their account's ones
+		for from, txs := range priorityTxs {
+			nonce := env.state.GetNonce(from)
+			for len(txs) > 0 && txs[0].Nonce() < nonce {
+				txs = txs[1:]
+			}
+			if len(txs) > 0 {
+				pending[from] = append(txs[:len(txs):len(txs)], pending[from]...)
+			}
+		}
+	}
 	localTxs, remoteTxs := make(map[common.Address][]*types.Transaction), pending
 	for _, account := range w.eth.TxPool().Locals() {
 		if txs := remoteTxs[account]; len(txs) > 0 {
@@ -913,13 +959,13 @@ func (w *worker) fillTransactions(interrupt *atomic.Int32, env *environment) err
 		}
 	}
 	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, localTxs, env.header.BaseFee)
+		txs := w.ordering(env.signer, localTxs, env.header.BaseFee)
 		if err := w.commitTransactions(env, txs, interrupt); err != nil {
 			return err
 		}
 	}
 	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, remoteTxs, env.header.BaseFee)
+		txs := w.ordering(env.signer, remoteTxs, env.header.BaseFee)
 		if err := w.commitTransactions(env, txs, interrupt); err != nil {
 			return err
 		}
@@ -927,6 +973,95 @@ func (w *worker) fillTransactions(interrupt *atomic.Int32, env *environment) err
 	return nil
 }
 
+// commitBundles includes the pending bundles at the top of the block in arrival
+// order. Bundles that fail to execute, or that contain a reverted transaction
+// not explicitly allowed to revert, are skipped as a whole. Bundles which can't
+// be included anymore, typically because they already were, are dropped.
+func (w *worker) commitBundles(env *environment) {
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	for _, bundle := range w.bundles.pending(env.header.Number.Uint64()) {
+		if env.gasPool.Gas() < params.TxGas {
+			break
+		}
+		// Check the nonces up front, so that bundles which can't be included
+		// don't cost an execution attempt
+		stale, err := bundle.checkNonces(env.signer, env.state)
+		if stale {
+			log.Trace("Dropping stale transaction bundle", "hash", bundle.Hash(), "err", err)
+			w.bundles.remove(bundle.Hash())
+			continue
+		}
+		if err != nil {
+			log.Trace("Skipping transaction bundle", "hash", bundle.Hash(), "err", err)
+			continue
+		}
+		if err := w.commitBundle(env, bundle); err != nil {
+			log.Trace("Skipping transaction bundle", "hash", bundle.Hash(), "err", err)
+		}
+	}
+}
+
+// commitBundle atomically commits all transactions of a bundle, reverting the
+// environment to its previous state if any of them can't be included.
+//
+// The state journal is flushed after each transaction, so a state copy is used
+// to roll back instead of a snapshot. The copy is only taken once the bundle
+// passed all checks that don't require execution.
+func (w *worker) commitBundle(env *environment, bundle *Bundle) error {
+	for _, tx := range bundle.Txs {
+		if tx.Protected() && !w.chainConfig.IsEIP155(env.header.Number) {
+			return fmt.Errorf("replay protected transaction %x before EIP155", tx.Hash())
+		}
+	}
+	var (
+		state   = env.state.Copy()
+		gas     = env.gasPool.Gas()
+		gasUsed = env.header.GasUsed
+		tcount  = env.tcount
+		ntxs    = len(env.txs)
+	)
+	revert := func() {
+		env.state = state
+		env.gasPool.SetGas(gas)
+		env.header.GasUsed = gasUsed
+		env.tcount = tcount
+		env.txs = env.txs[:ntxs]
+		env.receipts = env.receipts[:ntxs]
+	}
+	for _, tx := range bundle.Txs {
+		env.state.SetTxContext(tx.Hash(), env.tcount)
+		if _, err := w.commitTransaction(env, tx); err != nil {
+			revert()
+			return err
+		}
+		if env.receipts[len(env.receipts)-1].Status == types.ReceiptStatusFailed && !bundle.canRevert(tx.Hash()) {
+			revert()
+			return fmt.Errorf("%w: %x", errBundleReverted, tx.Hash())
+		}
+		env.tcount++
+	}
+	return nil
+}
+
+// commitReservedTransactions commits transactions into the block, capping the
+// gas they may consume to the given reservation. The remainder of the block's
+// gas is made available again afterwards.
+func (w *worker) commitReservedTransactions(env *environment, txs TransactionOrdering, reserved uint64, interrupt *atomic.Int32) error {
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	available := env.gasPool.Gas()
+	if reserved > available {
+		reserved = available
+	}
+	env.gasPool = new(core.GasPool).AddGas(reserved)
+	defer env.gasPool.AddGas(available - reserved)
+
+	return w.commitTransactions(env, txs, interrupt)
+}
+
 // generateWork generates a sealing block based on the given parameters.
 func (w *worker) generateWork(params *generateParams) (*types.Block, *big.Int, error) {
 	work, err := w.prepareWork(params)
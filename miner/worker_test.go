@@ -17,8 +17,11 @@
 package miner
 
 import (
+	"crypto/ecdsa"
+	"errors"
 	"github.com/ethereum/go-ethereum/consensus/thora"
 	"math/big"
+	"sort"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -561,3 +564,284 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 		}
 	}
 }
+
+// Tests that bundles are atomically included at the top of the block, and are
+// skipped as a whole if any of their transactions reverts without permission.
+func TestBundleInclusion(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	var (
+		signer   = types.LatestSigner(ethashChainConfig)
+		gasPrice = big.NewInt(2 * params.InitialBaseFee)
+		other    = common.Address{0xff}
+		genesis  = b.chain.Genesis().Hash()
+	)
+	transfer := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{Nonce: 0, To: &other, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: gasPrice})
+	revert := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{Nonce: 1, Gas: 100000, GasPrice: gasPrice, Data: common.FromHex("0x60006000fd")})
+
+	build := func() *types.Block {
+		block, _, err := w.getSealingBlock(genesis, uint64(time.Now().Unix()), testUserAddress, common.Hash{}, nil, false)
+		if err != nil {
+			t.Fatalf("failed to build block: %v", err)
+		}
+		return block
+	}
+	// A bundle with an unexpected revert must be skipped entirely, leaving the
+	// pool transactions in the block
+	failing := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{Nonce: 1, Gas: 200000, GasPrice: gasPrice, Data: common.FromHex("0x60006000fd")})
+	if err := w.bundles.add(&Bundle{Txs: []*types.Transaction{transfer, failing}, MaxBlock: 1}, 0); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	block := build()
+	if len(block.Transactions()) != 1 || block.Transactions()[0].Hash() != pendingTxs[0].Tx.Hash() {
+		t.Fatalf("reverting bundle included: %v", block.Transactions())
+	}
+	// The same bundle with the revert allowed should go to the top of the block,
+	// superseding the pool transaction with the same nonce
+	if err := w.bundles.add(&Bundle{Txs: []*types.Transaction{transfer, revert}, MaxBlock: 1, RevertingTxHashes: []common.Hash{revert.Hash()}}, 0); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	block = build()
+	if txs := block.Transactions(); len(txs) != 2 || txs[0].Hash() != transfer.Hash() || txs[1].Hash() != revert.Hash() {
+		t.Fatalf("bundle not included at the top of the block: %v", txs)
+	}
+	// Once the bundle made it into the chain, it should be dropped from the pool
+	// instead of being retried, while bundles ahead of the nonce are kept
+	ahead := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{Nonce: 3, To: &other, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: gasPrice})
+	if err := w.bundles.add(&Bundle{Txs: []*types.Transaction{ahead}, MaxBlock: 1}, 0); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	statedb, err := b.chain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	statedb.SetNonce(testBankAddress, 2)
+	w.commitBundles(&environment{signer: signer, state: statedb, header: &types.Header{Number: big.NewInt(1), GasLimit: block.GasLimit()}})
+	if bundles := w.bundles.pending(1); len(bundles) != 1 || bundles[0].Txs[0].Hash() != ahead.Hash() {
+		t.Fatalf("stale bundles not dropped: %d left", len(bundles))
+	}
+	// Bundles past their target range should be rejected and pruned
+	if err := w.bundles.add(&Bundle{Txs: []*types.Transaction{transfer}, MaxBlock: 1}, 1); !errors.Is(err, ErrBundleExpired) {
+		t.Fatalf("expired bundle error mismatch: have %v, want %v", err, ErrBundleExpired)
+	}
+	if bundles := w.bundles.pending(2); len(bundles) != 0 {
+		t.Fatalf("expired bundles not pruned: %d left", len(bundles))
+	}
+}
+
+// senderOrdering is a custom transaction ordering for testing, which yields all
+// transactions of one sender before moving on, with senders sorted by address.
+type senderOrdering struct {
+	txs [][]*types.Transaction
+}
+
+func newSenderOrdering(signer types.Signer, txs map[common.Address][]*types.Transaction, baseFee *big.Int) TransactionOrdering {
+	senders := make([]common.Address, 0, len(txs))
+	for from := range txs {
+		senders = append(senders, from)
+	}
+	sort.Slice(senders, func(i, j int) bool { return senders[i].Less(senders[j]) })
+
+	o := new(senderOrdering)
+	for _, from := range senders {
+		o.txs = append(o.txs, txs[from])
+	}
+	return o
+}
+
+func (o *senderOrdering) Peek() *types.Transaction {
+	if len(o.txs) == 0 {
+		return nil
+	}
+	return o.txs[0][0]
+}
+
+func (o *senderOrdering) Shift() {
+	if o.txs[0] = o.txs[0][1:]; len(o.txs[0]) == 0 {
+		o.txs = o.txs[1:]
+	}
+}
+
+func (o *senderOrdering) Pop() { o.txs = o.txs[1:] }
+
+// Tests that the configured ordering policy determines the order of the pool
+// transactions within the sealed blocks.
+func TestTransactionOrdering(t *testing.T) {
+	if _, ok := lookupOrdering("sender"); !ok {
+		RegisterOrdering("sender", newSenderOrdering)
+	}
+	// Create three funded senders, sorted by address for the custom policy
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return crypto.PubkeyToAddress(keys[i].PublicKey).Less(crypto.PubkeyToAddress(keys[j].PublicKey))
+	})
+	alloc := make(core.GenesisAlloc)
+	for _, key := range keys {
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{Balance: testBankFunds}
+	}
+	// Assign each transaction a price and an arrival time:
+	//
+	//   a0: 1x @ t2, a1: 1x @ t3
+	//   b0: 3x @ t1, b1: 3x @ t4
+	//   c0: 2x @ t0
+	var (
+		signer   = types.LatestSigner(ethashChainConfig)
+		txs      = make(map[string]*types.Transaction)
+		arrivals = make(map[common.Hash]time.Time)
+	)
+	for i, spec := range []struct {
+		name  string
+		key   *ecdsa.PrivateKey
+		nonce uint64
+		price int64
+	}{
+		{"c0", keys[2], 0, 2}, {"b0", keys[1], 0, 3},
+		{"a0", keys[0], 0, 1}, {"a1", keys[0], 1, 1},
+		{"b1", keys[1], 1, 3},
+	} {
+		txs[spec.name] = types.MustSignNewTx(spec.key, signer, &types.LegacyTx{
+			Nonce:    spec.nonce,
+			To:       &testUserAddress,
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(spec.price * params.InitialBaseFee),
+		})
+		arrivals[txs[spec.name].Hash()] = time.Unix(int64(i), 0)
+	}
+	defer func(arrival func(*types.Transaction) time.Time) { txArrival = arrival }(txArrival)
+	txArrival = func(tx *types.Transaction) time.Time { return arrivals[tx.Hash()] }
+
+	tests := []struct {
+		ordering string
+		want     []string
+	}{
+		{"", []string{"b0", "b1", "c0", "a0", "a1"}},
+		{OrderingPrice, []string{"b0", "b1", "c0", "a0", "a1"}},
+		{OrderingFCFS, []string{"c0", "b0", "a0", "a1", "b1"}},
+		{OrderingRoundRobin, []string{"c0", "b0", "a0", "b1", "a1"}},
+		{"sender", []string{"a0", "a1", "b0", "b1", "c0"}},
+	}
+	for _, tt := range tests {
+		var (
+			engine = ethash.NewFaker()
+			gspec  = &core.Genesis{Config: ethashChainConfig, Alloc: alloc}
+		)
+		chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), &core.CacheConfig{TrieDirtyDisabled: true}, gspec, nil, engine, vm.Config{}, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create chain: %v", err)
+		}
+		legacy := legacypool.New(testTxPoolConfig, chain)
+		pool, _ := txpool.New(new(big.Int).SetUint64(testTxPoolConfig.PriceLimit), chain, []txpool.SubPool{legacy})
+
+		var batch []*txpool.Transaction
+		for _, tx := range txs {
+			batch = append(batch, &txpool.Transaction{Tx: tx})
+		}
+		for i, err := range pool.Add(batch, false, true) {
+			if err != nil {
+				t.Fatalf("%q: failed to add transaction %d: %v", tt.ordering, i, err)
+			}
+		}
+		config := *testConfig
+		config.Ordering = tt.ordering
+
+		backend := &testWorkerBackend{chain: chain, txPool: pool, genesis: gspec}
+		w := newWorker(&config, ethashChainConfig, engine, backend, new(event.TypeMux), nil, false)
+
+		block, _, err := w.getSealingBlock(chain.Genesis().Hash(), uint64(time.Now().Unix()), testBankAddress, common.Hash{}, nil, false)
+		if err != nil {
+			t.Fatalf("%q: failed to build block: %v", tt.ordering, err)
+		}
+		have := block.Transactions()
+		if len(have) != len(tt.want) {
+			t.Fatalf("%q: transaction count mismatch: have %d, want %d", tt.ordering, len(have), len(tt.want))
+		}
+		for i, name := range tt.want {
+			if have[i].Hash() != txs[name].Hash() {
+				t.Errorf("%q: transaction %d mismatch: want %s", tt.ordering, i, name)
+			}
+		}
+		w.close()
+		pool.Close()
+		chain.Stop()
+		engine.Close()
+	}
+}
+
+// Tests that priority transactions not fitting into the gas reserved for the
+// priority lane are not dropped, but fall back to the regular ordering.
+func TestPriorityLaneOverflow(t *testing.T) {
+	var (
+		prioKey, _ = crypto.GenerateKey()
+		restKey, _ = crypto.GenerateKey()
+		prioAddr   = crypto.PubkeyToAddress(prioKey.PublicKey)
+		restAddr   = crypto.PubkeyToAddress(restKey.PublicKey)
+		signer     = types.LatestSigner(ethashChainConfig)
+		engine     = ethash.NewFaker()
+		gspec      = &core.Genesis{
+			Config: ethashChainConfig,
+			Alloc: core.GenesisAlloc{
+				prioAddr: {Balance: testBankFunds},
+				restAddr: {Balance: testBankFunds},
+			},
+		}
+	)
+	defer engine.Close()
+
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), &core.CacheConfig{TrieDirtyDisabled: true}, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	poolConfig := testTxPoolConfig
+	poolConfig.PrioritySenders = []common.Address{prioAddr}
+	legacy := legacypool.New(poolConfig, chain)
+	pool, _ := txpool.New(new(big.Int).SetUint64(poolConfig.PriceLimit), chain, []txpool.SubPool{legacy})
+	defer pool.Close()
+
+	// Queue three cheap priority transactions and a more expensive regular one,
+	// reserving only enough gas for two of the priority ones
+	var txs []*types.Transaction
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		txs = append(txs, types.MustSignNewTx(prioKey, signer, &types.LegacyTx{Nonce: nonce, To: &testUserAddress, Gas: params.TxGas, GasPrice: big.NewInt(params.InitialBaseFee)}))
+	}
+	txs = append(txs, types.MustSignNewTx(restKey, signer, &types.LegacyTx{Nonce: 0, To: &testUserAddress, Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)}))
+
+	var batch []*txpool.Transaction
+	for _, tx := range txs {
+		batch = append(batch, &txpool.Transaction{Tx: tx})
+	}
+	for i, err := range pool.Add(batch, false, true) {
+		if err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	config := *testConfig
+	config.PriorityGas = 2 * params.TxGas
+
+	backend := &testWorkerBackend{chain: chain, txPool: pool, genesis: gspec}
+	w := newWorker(&config, ethashChainConfig, engine, backend, new(event.TypeMux), nil, false)
+	defer w.close()
+
+	block, _, err := w.getSealingBlock(chain.Genesis().Hash(), uint64(time.Now().Unix()), testBankAddress, common.Hash{}, nil, false)
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+	want := []*types.Transaction{txs[0], txs[1], txs[3], txs[2]}
+	have := block.Transactions()
+	if len(have) != len(want) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i, tx := range want {
+		if have[i].Hash() != tx.Hash() {
+			t.Errorf("transaction %d mismatch: have %x, want %x", i, have[i].Hash(), tx.Hash())
+		}
+	}
+}
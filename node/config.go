@@ -211,6 +211,14 @@ type Config struct {
 	EnablePersonal bool `toml:"-"`
 
 	DBEngine string `toml:",omitempty"`
+
+	// AncientRemote is the URL of an object store which the sealed segments of
+	// the ancient chain data are offloaded into. Empty keeps them on local disk.
+	AncientRemote string `toml:",omitempty"`
+
+	// AncientRemoteCache is the maximum size in megabytes of the offloaded
+	// ancient segments cached on the local disk.
+	AncientRemoteCache int `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/objstore"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -752,10 +753,22 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 	if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
 	} else {
+		var remote *rawdb.RemoteAncients
+		if n.config.AncientRemote != "" {
+			store, err := objstore.Open(n.config.AncientRemote)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open ancient object store: %v", err)
+			}
+			remote = &rawdb.RemoteAncients{
+				Store:     store,
+				CacheSize: uint64(n.config.AncientRemoteCache) * 1024 * 1024,
+			}
+		}
 		db, err = rawdb.Open(rawdb.OpenOptions{
 			Type:              n.config.DBEngine,
 			Directory:         n.ResolvePath(name),
 			AncientsDirectory: n.ResolveAncient(name, ancient),
+			AncientsRemote:    remote,
 			Namespace:         namespace,
 			Cache:             cache,
 			Handles:           handles,
@@ -800,6 +813,14 @@ func (db *closeTrackingDB) Close() error {
 	return db.Database.Close()
 }
 
+// Checkpoint implements ethdb.Checkpointer if the wrapped database supports it.
+func (db *closeTrackingDB) Checkpoint(dir string) error {
+	if cp, ok := db.Database.(ethdb.Checkpointer); ok {
+		return cp.Checkpoint(dir)
+	}
+	return errors.New("database does not support checkpoints")
+}
+
 // wrapDatabase ensures the database will be auto-closed when Node is closed.
 func (n *Node) wrapDatabase(db ethdb.Database) ethdb.Database {
 	wrapper := &closeTrackingDB{db, n}
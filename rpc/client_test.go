@@ -450,6 +450,37 @@ func TestClientSubscribe(t *testing.T) {
 	}
 }
 
+// This test checks that a subscription ended by the server delivers the notifications
+// sent before it and then reports the error.
+func TestClientSubscribeTerminate(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	nc := make(chan int)
+	count := 5
+	sub, err := client.Subscribe(context.Background(), "nftest", nc, "failingSubscription", count)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	for i := 0; i < count; i++ {
+		if val := <-nc; val != i {
+			t.Fatalf("value mismatch: got %d, want %d", val, i)
+		}
+	}
+	select {
+	case v := <-nc:
+		t.Fatal("received value after termination:", v)
+	case err := <-sub.Err():
+		if err == nil || err.Error() != "subscription failed" {
+			t.Fatalf("wrong error after termination: %v", err)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatalf("subscription not closed within 1s after termination")
+	}
+}
+
 // In this test, the connection drops while Subscribe is waiting for a response.
 func TestClientSubscribeClose(t *testing.T) {
 	server := newTestServer()
@@ -452,9 +452,17 @@ func (h *handler) handleSubscriptionResult(msg *jsonrpcMessage) {
 		h.log.Debug("Dropping invalid subscription message")
 		return
 	}
-	if h.clientSubs[result.ID] != nil {
-		h.clientSubs[result.ID].deliver(result.Result)
+	sub := h.clientSubs[result.ID]
+	if sub == nil {
+		return
+	}
+	// The server ended the subscription with an error
+	if result.Error != nil {
+		delete(h.clientSubs, result.ID)
+		sub.close(result.Error)
+		return
 	}
+	sub.deliver(result.Result)
 }
 
 // handleCallMsg executes a call message and returns the answer.
@@ -569,6 +577,18 @@ func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *cal
 	return msg.response(result)
 }
 
+// removeSubscription drops a server subscription ended by its notifier, closing
+// its error channel.
+func (h *handler) removeSubscription(id ID) {
+	h.subLock.Lock()
+	defer h.subLock.Unlock()
+
+	if s := h.serverSubs[id]; s != nil {
+		close(s.err)
+		delete(h.serverSubs, id)
+	}
+}
+
 // unsubscribe is the callback function for all *_unsubscribe calls.
 func (h *handler) unsubscribe(ctx context.Context, id ID) (bool, error) {
 	h.subLock.Lock()
@@ -44,6 +44,7 @@ var null = json.RawMessage("null")
 type subscriptionResult struct {
 	ID     string          `json:"subscription"`
 	Result json.RawMessage `json:"result,omitempty"`
+	Error  *jsonError      `json:"error,omitempty"`
 }
 
 // A value of this type can a JSON-RPC request, notification, successful response or
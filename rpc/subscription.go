@@ -106,6 +106,7 @@ type Notifier struct {
 	mu           sync.Mutex
 	sub          *Subscription
 	buffer       []json.RawMessage
+	failure      *jsonError // Error terminating the subscription, sent after the buffer
 	callReturned bool
 	activated    bool
 }
@@ -143,6 +144,9 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	} else if n.sub.ID != id {
 		panic("Notify with wrong ID")
 	}
+	if n.failure != nil {
+		return nil // subscription terminated
+	}
 	if n.activated {
 		return n.send(n.sub, enc)
 	}
@@ -150,6 +154,28 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	return nil
 }
 
+// Terminate ends the subscription with the given error, which is sent to the
+// client as the last notification of the subscription. Any later notification
+// is dropped.
+func (n *Notifier) Terminate(id ID, err error) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.sub == nil {
+		panic("can't Terminate before subscription is created")
+	} else if n.sub.ID != id {
+		panic("Terminate with wrong ID")
+	}
+	if n.failure != nil {
+		return nil // already terminated
+	}
+	n.failure = errorMessage(err).Error
+	if n.activated {
+		return n.terminate()
+	}
+	return nil
+}
+
 // Closed returns a channel that is closed when the RPC connection is closed.
 // Deprecated: use subscription error channel
 func (n *Notifier) Closed() <-chan interface{} {
@@ -178,11 +204,28 @@ func (n *Notifier) activate() error {
 		}
 	}
 	n.activated = true
+	if n.failure != nil {
+		return n.terminate()
+	}
 	return nil
 }
 
+// terminate removes the subscription from the server and sends the error that
+// ended it to the client. It's only called once the notifier is activated, after
+// which the handler no longer acquires n.mu while holding its subscription lock.
+func (n *Notifier) terminate() error {
+	n.h.removeSubscription(n.sub.ID)
+
+	params, _ := json.Marshal(&subscriptionResult{ID: string(n.sub.ID), Error: n.failure})
+	return n.write(params)
+}
+
 func (n *Notifier) send(sub *Subscription, data json.RawMessage) error {
 	params, _ := json.Marshal(&subscriptionResult{ID: string(sub.ID), Result: data})
+	return n.write(params)
+}
+
+func (n *Notifier) write(params json.RawMessage) error {
 	ctx := context.Background()
 
 	msg := &jsonrpcMessage{
@@ -202,6 +202,21 @@ func (s *notificationTestService) SomeSubscription(ctx context.Context, n, val i
 	return subscription, nil
 }
 
+// FailingSubscription sends n notifications and then ends the subscription with an error.
+func (s *notificationTestService) FailingSubscription(ctx context.Context, n int) (*Subscription, error) {
+	notifier, supported := NotifierFromContext(ctx)
+	if !supported {
+		return nil, ErrNotificationsUnsupported
+	}
+	subscription := notifier.CreateSubscription()
+	for i := 0; i < n; i++ {
+		notifier.Notify(subscription.ID, i)
+	}
+	notifier.Terminate(subscription.ID, errors.New("subscription failed"))
+	notifier.Notify(subscription.ID, n)
+	return subscription, nil
+}
+
 // HangSubscription blocks on s.unblockHangSubscription before sending anything.
 func (s *notificationTestService) HangSubscription(ctx context.Context, val int) (*Subscription, error) {
 	notifier, supported := NotifierFromContext(ctx)
@@ -184,6 +184,10 @@ var (
 		accounts.MimetypeClique,
 		0x02,
 	}
+	ApplicationThoraPreCommit = SigFormat{
+		accounts.MimetypeThoraPreCommit,
+		0x03,
+	}
 	TextPlain = SigFormat{
 		accounts.MimetypeTextPlain,
 		0x45,
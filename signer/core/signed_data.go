@@ -164,6 +164,33 @@ func (api *SignerAPI) determineSignatureFormat(ctx context.Context, contentType
 		// Clique uses V on the form 0 or 1
 		useEthereumV = false
 		req = &SignDataRequest{ContentType: mediaType, Rawdata: cliqueRlp, Messages: messages, Hash: sighash}
+	case apitypes.ApplicationThoraPreCommit.Mime:
+		// Thora pre-commits are the RLP list [domain, number, hash]
+		precommitRlp, err := fromHex(data)
+		if err != nil {
+			return nil, useEthereumV, err
+		}
+		var precommit struct {
+			Domain string
+			Number uint64
+			Hash   common.Hash
+		}
+		if err := rlp.DecodeBytes(precommitRlp, &precommit); err != nil {
+			return nil, useEthereumV, err
+		}
+		if precommit.Domain != "thora-precommit" {
+			return nil, useEthereumV, fmt.Errorf("invalid pre-commit domain %q", precommit.Domain)
+		}
+		messages := []*apitypes.NameValueType{
+			{
+				Name:  "Thora pre-commit",
+				Typ:   "thora",
+				Value: fmt.Sprintf("pre-commit of block %d [%#x]", precommit.Number, precommit.Hash),
+			},
+		}
+		// Pre-commits use V on the form 0 or 1, same as clique seals
+		useEthereumV = false
+		req = &SignDataRequest{ContentType: mediaType, Rawdata: precommitRlp, Messages: messages, Hash: crypto.Keccak256(precommitRlp)}
 	case apitypes.DataTyped.Mime:
 		// EIP-712 conformant typed data
 		var err error
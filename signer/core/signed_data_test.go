@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/signer/core"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
@@ -245,6 +246,28 @@ func TestSignData(t *testing.T) {
 	} else if have := signature; !bytes.Equal(have, want) {
 		t.Fatalf("want %x, have %x", want, have)
 	}
+	// Thora pre-commits are signed over the keccak256 of the raw data
+	precommit, _ := rlp.EncodeToBytes([]interface{}{"thora-precommit", uint64(1), common.Hash{0x01}})
+
+	control.approveCh <- "Y"
+	control.inputCh <- "a_long_password"
+	if signature, err = api.SignData(context.Background(), apitypes.ApplicationThoraPreCommit.Mime, a, hexutil.Encode(precommit)); err != nil {
+		t.Fatal(err)
+	}
+	if signature[64] > 1 {
+		t.Errorf("Expected V on the form 0 or 1, got %d", signature[64])
+	}
+	if pubkey, err := crypto.SigToPub(crypto.Keccak256(precommit), signature); err != nil {
+		t.Fatal(err)
+	} else if signer := crypto.PubkeyToAddress(*pubkey); signer != a.Address() {
+		t.Errorf("Expected pre-commit signer %x, got %x", a.Address(), signer)
+	}
+	control.approveCh <- "Y"
+	control.inputCh <- "a_long_password"
+	invalid, _ := rlp.EncodeToBytes([]interface{}{"thora-seal", uint64(1), common.Hash{0x01}})
+	if _, err = api.SignData(context.Background(), apitypes.ApplicationThoraPreCommit.Mime, a, hexutil.Encode(invalid)); err == nil {
+		t.Errorf("Expected error for pre-commit with invalid domain")
+	}
 }
 
 func TestDomainChainId(t *testing.T) {
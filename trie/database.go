@@ -23,6 +23,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/trie/triedb/hashdb"
+	"github.com/ethereum/go-ethereum/trie/triedb/pathdb"
 	"github.com/ethereum/go-ethereum/trie/trienode"
 	"github.com/ethereum/go-ethereum/trie/triestate"
 )
@@ -31,6 +32,9 @@ import (
 type Config struct {
 	Cache     int  // Memory allowance (MB) to use for caching trie nodes in memory
 	Preimages bool // Flag whether the preimage of trie key is recorded
+	Verkle    bool // Flag whether the state is kept in a verkle tree (experimental)
+
+	PathDB *pathdb.Config // Configs for the path-based scheme, hash-based scheme is used if nil
 
 	// Testing hooks
 	OnCommit func(states *triestate.Set) // Hook invoked when commit is performed
@@ -100,18 +104,34 @@ func NewDatabase(diskdb ethdb.Database) *Database {
 }
 
 // NewDatabaseWithConfig initializes the trie database with provided configs.
-// The path-based scheme is not activated yet, always initialized with legacy
-// hash-based scheme by default.
+// The path-based scheme is used if its config is specified, otherwise the
+// legacy hash-based scheme is initialized. Verkle nodes are always stored
+// with the hash-based scheme, keyed by their commitment.
 func NewDatabaseWithConfig(diskdb ethdb.Database, config *Config) *Database {
 	db := prepare(diskdb, config)
-	db.backend = hashdb.New(diskdb, db.cleans, mptResolver{})
+	if config != nil && config.PathDB != nil && !config.Verkle {
+		db.backend = pathdb.New(diskdb, db.cleans, config.PathDB)
+	} else {
+		db.backend = hashdb.New(diskdb, db.cleans, mptResolver{})
+	}
 	return db
 }
 
+// IsVerkle returns whether the state is kept in a verkle tree.
+func (db *Database) IsVerkle() bool {
+	return db.config != nil && db.config.Verkle
+}
+
 // Reader returns a reader for accessing all trie nodes with provided state root.
 // An error will be returned if the requested state is not available.
 func (db *Database) Reader(blockRoot common.Hash) (Reader, error) {
-	return db.backend.(*hashdb.Database).Reader(blockRoot)
+	switch b := db.backend.(type) {
+	case *hashdb.Database:
+		return b.Reader(blockRoot)
+	case *pathdb.Database:
+		return b.Reader(blockRoot)
+	}
+	return nil, errors.New("unknown backend")
 }
 
 // Update performs a state transition by committing dirty nodes contained in the
@@ -229,3 +249,50 @@ func (db *Database) Node(hash common.Hash) ([]byte, error) {
 	}
 	return hdb.Node(hash)
 }
+
+// Journal commits an entire diff hierarchy to disk into a single journal entry.
+// This is meant to be used during shutdown to persist the snapshot without
+// flattening everything down (bad for reorgs). It's only supported by path-based
+// database and will return an error for others.
+func (db *Database) Journal(root common.Hash) error {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok {
+		return errors.New("not supported")
+	}
+	return pdb.Journal(root)
+}
+
+// Recover rollbacks the database to a specified historical point. The state is
+// supported as the rollback destination only if it's canonical state and the
+// corresponding trie histories are existent. It's only supported by path-based
+// database and will return an error for others.
+func (db *Database) Recover(target common.Hash) error {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok {
+		return errors.New("not supported")
+	}
+	return pdb.Recover(target)
+}
+
+// Recoverable returns the indicator if the specified state is enabled to be
+// recovered. It's only supported by path-based database and will return false
+// for others.
+func (db *Database) Recoverable(root common.Hash) bool {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok {
+		return false
+	}
+	return pdb.Recoverable(root)
+}
+
+// Reset rebuilds the database with the specified state as the base, discarding
+// all the in-memory layers and trie histories. All the stored trie nodes are
+// wiped if the state is empty. It's only supported by path-based database and
+// will return an error for others.
+func (db *Database) Reset(root common.Hash) error {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok {
+		return errors.New("not supported")
+	}
+	return pdb.Reset(root)
+}
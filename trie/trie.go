@@ -652,6 +652,17 @@ func (t *Trie) hashRoot() (node, node) {
 	return hashed, cached
 }
 
+// Witness returns the blobs of all the trie nodes loaded from the database
+// since the trie was opened or last committed. Together they are sufficient
+// to replay all the accesses and modifications made to the trie.
+func (t *Trie) Witness() [][]byte {
+	blobs := make([][]byte, 0, len(t.tracer.accessList))
+	for _, blob := range t.tracer.accessList {
+		blobs = append(blobs, common.CopyBytes(blob))
+	}
+	return blobs
+}
+
 // Reset drops the referenced root node and cleans all internal state.
 func (t *Trie) Reset() {
 	t.root = nil
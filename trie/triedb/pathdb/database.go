@@ -0,0 +1,316 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/VictoriaMetrics/fastcache"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+)
+
+// maxDiffLayers is the maximum diff layers allowed in the layer tree.
+const maxDiffLayers = 128
+
+// layer is the interface implemented by all state layers which includes some
+// public methods and some additional methods for internal usage.
+type layer interface {
+	// Node retrieves the trie node with the node info. An error will be returned
+	// if the read operation exits abnormally. For example, if the layer is already
+	// stale, or the associated state is regarded as corrupted. Notably, no error
+	// will be returned if the requested node is not found in database.
+	Node(owner common.Hash, path []byte, hash common.Hash) ([]byte, error)
+
+	// rootHash returns the root hash for which this layer was made.
+	rootHash() common.Hash
+
+	// stateID returns the associated state id of layer.
+	stateID() uint64
+
+	// parentLayer returns the subsequent layer of it, or nil if the disk was reached.
+	parentLayer() layer
+
+	// update creates a new layer on top of the existing layer tree with
+	// the provided dirty trie nodes.
+	//
+	// Note, the maps are retained by the method to avoid copying everything.
+	update(root common.Hash, id uint64, nodes map[common.Hash]map[string]*trienode.Node) *diffLayer
+
+	// journal commits an entire diff hierarchy to disk into a single journal entry.
+	// This is meant to be used during shutdown to persist the layer without
+	// flattening everything down (bad for reorgs).
+	journal(w io.Writer) error
+}
+
+// Config contains the settings for database.
+type Config struct {
+	StateHistory uint64 // Number of recent state transitions to keep histories for, 0 keeps all
+}
+
+// Defaults contains default settings for Ethereum mainnet.
+var Defaults = &Config{
+	StateHistory: params.FullImmutabilityThreshold,
+}
+
+// Database is a multiple-layered structure for maintaining in-memory trie nodes.
+// It consists of one persistent base layer backed by a key-value store, on top
+// of which arbitrarily many in-memory diff layers are stacked. The memory diffs
+// can form a tree with branching, but the disk layer is singleton and common to
+// all. If a reorg goes deeper than the disk layer, a batch of reverse diffs can
+// be applied to rollback i.e. reverting the disk layer to its parent state, as
+// long as the state histories are still retained.
+type Database struct {
+	// readOnly is the flag whether the mutation is allowed to be applied.
+	// It will be set automatically when the database is journaled during
+	// the shutdown to reject all following unexpected mutations.
+	readOnly bool             // Indicator if database is opened in read only mode
+	config   *Config          // Configuration for database
+	diskdb   ethdb.Database   // Persistent storage for matured trie nodes
+	cleans   *fastcache.Cache // GC friendly memory cache of clean node RLPs
+	tree     *layerTree       // The group for all known layers
+	lock     sync.RWMutex     // Lock to prevent mutations from happening at the same time
+}
+
+// New attempts to load an already existing layer from a persistent key-value
+// store (with a number of memory layers from a journal). If the journal is not
+// matched with the base persistent layer, all the recorded diff layers are discarded.
+func New(diskdb ethdb.Database, cleans *fastcache.Cache, config *Config) *Database {
+	if config == nil {
+		config = Defaults
+	}
+	db := &Database{
+		config: config,
+		diskdb: diskdb,
+		cleans: cleans,
+	}
+	db.tree = newLayerTree(db.loadLayers(cleans))
+	return db
+}
+
+// Reader retrieves a layer belonging to the given state root.
+func (db *Database) Reader(root common.Hash) (layer, error) {
+	l := db.tree.get(root)
+	if l == nil {
+		return nil, fmt.Errorf("state %#x is not available", root)
+	}
+	return l, nil
+}
+
+// Update adds a new layer into the tree, if that can be linked to an existing
+// old parent. It is disallowed to insert a disk layer (the origin of all). Apart
+// from that this function will flatten the extra diff layers at bottom into disk
+// to only keep 128 diff layers in memory by default.
+func (db *Database) Update(root common.Hash, parentRoot common.Hash, nodes *trienode.MergedNodeSet) error {
+	// Hold the lock to prevent concurrent mutations.
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	// Short circuit if the database is in read only mode.
+	if db.readOnly {
+		return errDatabaseReadOnly
+	}
+	// The same state might be reached from different blocks, only track the
+	// first layer producing it.
+	if db.tree.get(root) != nil {
+		return nil
+	}
+	if err := db.tree.add(root, parentRoot, nodes); err != nil {
+		return err
+	}
+	// Keep 128 diff layers in the memory, persistent layer is 129th.
+	// - head layer is paired with HEAD state
+	// - head-1 layer is paired with HEAD-1 state
+	// - head-127 layer(bottom-most diff layer) is paired with HEAD-127 state
+	// - head-128 layer(disk layer) is paired with HEAD-128 state
+	return db.tree.cap(root, maxDiffLayers)
+}
+
+// Commit traverses downwards the layer tree from a specified layer with the
+// provided state root and all the layers below are flattened downwards. It
+// can be used alone and mostly for test purposes.
+func (db *Database) Commit(root common.Hash, report bool) error {
+	// Hold the lock to prevent concurrent mutations.
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	// Short circuit if the database is in read only mode.
+	if db.readOnly {
+		return errDatabaseReadOnly
+	}
+	return db.tree.cap(root, 0)
+}
+
+// Reset rebuilds the database with the specified state as the base. All the
+// in-memory layers, state histories and the journal are discarded.
+//
+//   - if the state is empty, all the stored trie nodes are wiped as well
+//   - otherwise the state must match the one persisted in the disk, e.g. after
+//     a snap sync completed
+func (db *Database) Reset(root common.Hash) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	// Short circuit if the database is in read only mode.
+	if db.readOnly {
+		return errDatabaseReadOnly
+	}
+	if root == types.EmptyRootHash {
+		if err := rawdb.DeletePathTrieNodes(db.diskdb); err != nil {
+			return err
+		}
+	} else {
+		// Ensure the provided state root matches the stored one.
+		_, stored := rawdb.ReadAccountTrieNode(db.diskdb, nil)
+		if stored != root {
+			return fmt.Errorf("state root mismatch: stored %x, target %x", stored, root)
+		}
+	}
+	// Drop the stale state histories and journal, they can't be applied on
+	// the new base.
+	if err := rawdb.DeleteTrieHistories(db.diskdb); err != nil {
+		return err
+	}
+	batch := db.diskdb.NewBatch()
+	rawdb.DeleteTrieJournal(batch)
+	rawdb.WritePersistentStateID(batch, 0)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	// Clean up all the in-memory layers and the cached nodes
+	if db.cleans != nil {
+		db.cleans.Reset()
+	}
+	dl := newDiskLayer(root, 0, db, db.cleans)
+	db.tree.forEach(func(l layer) {
+		if disk, ok := l.(*diskLayer); ok {
+			disk.markStale()
+		}
+	})
+	db.tree.reset(dl)
+	log.Info("Rebuilt trie database", "root", root)
+	return nil
+}
+
+// Recover rollbacks the database to a specified historical point. The state is
+// supported as the rollback destination only if it's canonical state and the
+// corresponding trie histories are existent.
+func (db *Database) Recover(root common.Hash) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	// Short circuit if rollback operation is not supported.
+	if db.readOnly {
+		return errDatabaseReadOnly
+	}
+	if !db.recoverable(root) {
+		return errStateUnrecoverable
+	}
+	// Apply the state histories upon the disk layer in order.
+	var (
+		dl    = db.tree.bottom()
+		start = dl.stateID()
+	)
+	for dl.rootHash() != root {
+		h, err := readHistory(db.diskdb, dl.stateID())
+		if err != nil {
+			return err
+		}
+		dl, err = dl.revert(h)
+		if err != nil {
+			return err
+		}
+		// reset layer with newly created disk layer. It must be
+		// done after each revert operation, otherwise the new
+		// disk layer won't be accessible from outside.
+		db.tree.reset(dl)
+	}
+	rawdb.DeleteTrieJournal(db.diskdb)
+	log.Info("Recovered state", "root", root, "from", start, "to", dl.stateID())
+	return nil
+}
+
+// Recoverable returns the indicator if the specified state is recoverable.
+func (db *Database) Recoverable(root common.Hash) bool {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.recoverable(root)
+}
+
+// recoverable is the lock free version of Recoverable.
+func (db *Database) recoverable(root common.Hash) bool {
+	// Ensure the requested state is a known state.
+	id := rawdb.ReadStateID(db.diskdb, root)
+	if id == nil {
+		return false
+	}
+	// Recoverable state must be below the disk layer. The recoverable
+	// state only refers to the state that is currently not available,
+	// but can be restored by applying state history.
+	dl := db.tree.bottom()
+	if *id >= dl.stateID() {
+		return false
+	}
+	// Histories are pruned from the tail, so the presence of the history
+	// right after the target ensures all the following ones are present.
+	return rawdb.HasTrieHistory(db.diskdb, *id+1)
+}
+
+// Close closes the trie database, rejecting all the following mutations.
+func (db *Database) Close() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.readOnly = true
+	return nil
+}
+
+// Size returns the current storage size of the memory cache in front of the
+// persistent database layer.
+func (db *Database) Size() (size common.StorageSize) {
+	db.tree.forEach(func(layer layer) {
+		if diff, ok := layer.(*diffLayer); ok {
+			size += common.StorageSize(diff.memory)
+		}
+	})
+	return size
+}
+
+// Initialized returns an indicator if the state data is already
+// initialized in path-based scheme.
+func (db *Database) Initialized(genesisRoot common.Hash) bool {
+	var inited bool
+	db.tree.forEach(func(layer layer) {
+		if layer.rootHash() != types.EmptyRootHash {
+			inited = true
+		}
+	})
+	return inited
+}
+
+// Scheme returns the node scheme used in the database.
+func (db *Database) Scheme() string {
+	return rawdb.PathScheme
+}
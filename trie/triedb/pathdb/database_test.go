@@ -0,0 +1,319 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+)
+
+// nodes is the full set of trie nodes of a state, indexed by owner and path.
+type nodes map[common.Hash]map[string][]byte
+
+func (n nodes) copy() nodes {
+	cpy := make(nodes)
+	for owner, subset := range n {
+		cpy[owner] = make(map[string][]byte)
+		for path, blob := range subset {
+			cpy[owner][path] = blob
+		}
+	}
+	return cpy
+}
+
+// tester is a helper to apply random state transitions to a path database
+// and to verify the contents of the resulting states.
+type tester struct {
+	disk   ethdb.Database
+	db     *Database
+	roots  []common.Hash
+	states map[common.Hash]nodes
+}
+
+func newTester(t *testing.T, history uint64, layers int) *tester {
+	disk := rawdb.NewMemoryDatabase()
+	tr := &tester{
+		disk:   disk,
+		db:     New(disk, nil, &Config{StateHistory: history}),
+		roots:  []common.Hash{types.EmptyRootHash},
+		states: map[common.Hash]nodes{types.EmptyRootHash: make(nodes)},
+	}
+	for i := 0; i < layers; i++ {
+		tr.extend(t)
+	}
+	return tr
+}
+
+// extend applies a random state transition on top of the last state. The
+// state root is the hash of a new random root node of the account trie.
+func (tr *tester) extend(t *testing.T) common.Hash {
+	var (
+		parent = tr.roots[len(tr.roots)-1]
+		state  = tr.states[parent].copy()
+		merged = trienode.NewMergedNodeSet()
+		owners = []common.Hash{{}, {0x1}, {0x2}}
+		root   common.Hash
+	)
+	for _, owner := range owners {
+		set := trienode.NewNodeSet(owner)
+		if state[owner] == nil {
+			state[owner] = make(map[string][]byte)
+		}
+		if owner == (common.Hash{}) {
+			blob := make([]byte, 32)
+			rand.Read(blob)
+			root = crypto.Keccak256Hash(blob)
+			set.AddNode(nil, trienode.NewWithPrev(root, blob, state[owner][""]))
+			state[owner][""] = blob
+		}
+		for i := 0; i < 5; i++ {
+			path := make([]byte, 1+rand.Intn(3)) // root node is updated separately
+			for j := range path {
+				path[j] = byte(rand.Intn(4))
+			}
+			if _, ok := set.Nodes[string(path)]; ok {
+				continue
+			}
+			prev := state[owner][string(path)]
+			if prev != nil && rand.Intn(3) == 0 {
+				set.AddNode(path, trienode.NewWithPrev(common.Hash{}, nil, prev))
+				delete(state[owner], string(path))
+				continue
+			}
+			blob := make([]byte, 8+rand.Intn(24))
+			rand.Read(blob)
+			set.AddNode(path, trienode.NewWithPrev(crypto.Keccak256Hash(blob), blob, prev))
+			state[owner][string(path)] = blob
+		}
+		if err := merged.Merge(set); err != nil {
+			t.Fatalf("failed to merge node set: %v", err)
+		}
+	}
+	if err := tr.db.Update(root, parent, merged); err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+	tr.roots = append(tr.roots, root)
+	tr.states[root] = state
+	return root
+}
+
+// verify checks that the given state is readable and matches the expectation.
+func (tr *tester) verify(t *testing.T, root common.Hash) {
+	t.Helper()
+
+	reader, err := tr.db.Reader(root)
+	if err != nil {
+		t.Fatalf("state %x is not available: %v", root, err)
+	}
+	for owner, subset := range tr.states[root] {
+		for path, blob := range subset {
+			have, err := reader.Node(owner, []byte(path), crypto.Keccak256Hash(blob))
+			if err != nil {
+				t.Fatalf("failed to read node %x %x: %v", owner, path, err)
+			}
+			if !bytes.Equal(have, blob) {
+				t.Fatalf("node %x %x mismatch: have %x, want %x", owner, path, have, blob)
+			}
+		}
+	}
+}
+
+// verifyDisk checks that the persistent trie nodes exactly match the given state.
+func (tr *tester) verifyDisk(t *testing.T, root common.Hash) {
+	t.Helper()
+
+	stored := make(nodes)
+	it := tr.disk.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if ok, path := rawdb.IsAccountTrieNode(it.Key()); ok {
+			if stored[common.Hash{}] == nil {
+				stored[common.Hash{}] = make(map[string][]byte)
+			}
+			stored[common.Hash{}][string(path)] = common.CopyBytes(it.Value())
+		} else if ok, owner, path := rawdb.IsStorageTrieNode(it.Key()); ok {
+			if stored[owner] == nil {
+				stored[owner] = make(map[string][]byte)
+			}
+			stored[owner][string(path)] = common.CopyBytes(it.Value())
+		}
+	}
+	want := tr.states[root]
+	for owner, subset := range want {
+		if len(subset) != len(stored[owner]) {
+			t.Fatalf("stored nodes of %x mismatch: have %d, want %d", owner, len(stored[owner]), len(subset))
+		}
+		for path, blob := range subset {
+			if !bytes.Equal(stored[owner][path], blob) {
+				t.Fatalf("stored node %x %x mismatch: have %x, want %x", owner, path, stored[owner][path], blob)
+			}
+		}
+	}
+}
+
+func TestDatabaseLayers(t *testing.T) {
+	tr := newTester(t, 0, 2*maxDiffLayers)
+
+	// Only the most recent states are kept in memory, the rest is flushed
+	if n := tr.db.tree.len(); n != maxDiffLayers+1 {
+		t.Fatalf("layer count mismatch: have %d, want %d", n, maxDiffLayers+1)
+	}
+	for _, root := range tr.roots[len(tr.roots)-maxDiffLayers-1:] {
+		tr.verify(t, root)
+	}
+	if _, err := tr.db.Reader(tr.roots[len(tr.roots)-maxDiffLayers-2]); err == nil {
+		t.Fatal("flushed state is still available")
+	}
+	bottom := tr.roots[len(tr.roots)-maxDiffLayers-1]
+	tr.verifyDisk(t, bottom)
+
+	// Committing the head flushes all layers
+	head := tr.roots[len(tr.roots)-1]
+	if err := tr.db.Commit(head, false); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	tr.verify(t, head)
+	tr.verifyDisk(t, head)
+}
+
+func TestDatabaseRecover(t *testing.T) {
+	tr := newTester(t, 0, 12)
+
+	head := tr.roots[len(tr.roots)-1]
+	if err := tr.db.Commit(head, false); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	// Roll back the state one transition at a time
+	for i := len(tr.roots) - 2; i >= 0; i-- {
+		root := tr.roots[i]
+		if !tr.db.Recoverable(root) {
+			t.Fatalf("state %d is not recoverable", i)
+		}
+		if err := tr.db.Recover(root); err != nil {
+			t.Fatalf("failed to recover state %d: %v", i, err)
+		}
+		tr.verify(t, root)
+		tr.verifyDisk(t, root)
+
+		if tr.db.Recoverable(tr.roots[i+1]) {
+			t.Fatalf("reverted state %d is still recoverable", i+1)
+		}
+	}
+	// The state can move forward again from the recovered point
+	tr.roots, tr.states = tr.roots[:1], map[common.Hash]nodes{types.EmptyRootHash: make(nodes)}
+	for i := 0; i < 4; i++ {
+		tr.extend(t)
+	}
+	if err := tr.db.Commit(tr.roots[len(tr.roots)-1], false); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	tr.verifyDisk(t, tr.roots[len(tr.roots)-1])
+}
+
+func TestDatabaseHistoryPruning(t *testing.T) {
+	tr := newTester(t, 4, 0)
+	for i := 0; i < 10; i++ {
+		if err := tr.db.Commit(tr.extend(t), false); err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+	}
+	for i, root := range tr.roots[:len(tr.roots)-1] {
+		want := i >= len(tr.roots)-1-4
+		if have := tr.db.Recoverable(root); have != want {
+			t.Errorf("state %d recoverability mismatch: have %v, want %v", i, have, want)
+		}
+	}
+	for id := uint64(1); id <= 10; id++ {
+		if have, want := rawdb.HasTrieHistory(tr.disk, id), id > 6; have != want {
+			t.Errorf("history %d presence mismatch: have %v, want %v", id, have, want)
+		}
+	}
+	if err := tr.db.Recover(tr.roots[0]); err == nil {
+		t.Fatal("recovered state beyond the history window")
+	}
+	target := tr.roots[len(tr.roots)-5]
+	if err := tr.db.Recover(target); err != nil {
+		t.Fatalf("failed to recover state: %v", err)
+	}
+	tr.verifyDisk(t, target)
+}
+
+func TestDatabaseJournal(t *testing.T) {
+	tr := newTester(t, 0, 4)
+	if err := tr.db.Commit(tr.roots[4], false); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		tr.extend(t)
+	}
+	head := tr.roots[len(tr.roots)-1]
+	if err := tr.db.Journal(head); err != nil {
+		t.Fatalf("failed to journal: %v", err)
+	}
+	if err := tr.db.Update(common.Hash{0xff}, head, trienode.NewMergedNodeSet()); err != errDatabaseReadOnly {
+		t.Fatalf("update error mismatch: have %v, want %v", err, errDatabaseReadOnly)
+	}
+	// Reopen the database, all the journaled layers are restored
+	tr.db = New(tr.disk, nil, nil)
+	for _, root := range tr.roots[4:] {
+		tr.verify(t, root)
+	}
+	tr.extend(t)
+	tr.verify(t, tr.roots[len(tr.roots)-1])
+
+	// A journal not matching the persistent state is discarded
+	if err := tr.db.Journal(tr.roots[len(tr.roots)-1]); err != nil {
+		t.Fatalf("failed to journal: %v", err)
+	}
+	rawdb.WritePersistentStateID(tr.disk, 100)
+	tr.db = New(tr.disk, nil, nil)
+	if _, err := tr.db.Reader(head); err == nil {
+		t.Fatal("layer restored from mismatched journal")
+	}
+	tr.verify(t, tr.roots[4])
+}
+
+func TestDatabaseReset(t *testing.T) {
+	tr := newTester(t, 0, 4)
+	if err := tr.db.Commit(tr.roots[len(tr.roots)-1], false); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if err := tr.db.Reset(common.Hash{0xff}); err == nil {
+		t.Fatal("reset to a state not matching the disk")
+	}
+	if err := tr.db.Reset(types.EmptyRootHash); err != nil {
+		t.Fatalf("failed to reset: %v", err)
+	}
+	tr.roots, tr.states = tr.roots[:1], map[common.Hash]nodes{types.EmptyRootHash: make(nodes)}
+	tr.verifyDisk(t, types.EmptyRootHash)
+	for id := uint64(1); id <= 4; id++ {
+		if rawdb.HasTrieHistory(tr.disk, id) {
+			t.Fatalf("history %d not wiped", id)
+		}
+	}
+	if tr.db.Initialized(types.EmptyRootHash) {
+		t.Fatal("wiped database reported as initialized")
+	}
+}
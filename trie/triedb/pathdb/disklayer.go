@@ -0,0 +1,271 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/fastcache"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+)
+
+// diskLayer is a low level persistent layer built on top of a key-value store.
+type diskLayer struct {
+	root   common.Hash      // Immutable, root hash to which this layer was made for
+	id     uint64           // Immutable, corresponding state id
+	db     *Database        // Path-based trie database
+	cleans *fastcache.Cache // GC friendly memory cache of clean node RLPs
+	stale  bool             // Signals that the layer became stale (state progressed)
+	lock   sync.RWMutex     // Lock used to protect stale flag
+}
+
+// newDiskLayer creates a new disk layer based on the passing arguments.
+func newDiskLayer(root common.Hash, id uint64, db *Database, cleans *fastcache.Cache) *diskLayer {
+	return &diskLayer{
+		root:   root,
+		id:     id,
+		db:     db,
+		cleans: cleans,
+	}
+}
+
+// rootHash implements the layer interface, returning root hash of corresponding state.
+func (dl *diskLayer) rootHash() common.Hash {
+	return dl.root
+}
+
+// stateID implements the layer interface, returning the state id of disk layer.
+func (dl *diskLayer) stateID() uint64 {
+	return dl.id
+}
+
+// parentLayer implements the layer interface, returning nil as there's no layer
+// below the disk.
+func (dl *diskLayer) parentLayer() layer {
+	return nil
+}
+
+// isStale return whether this layer has become stale (was flattened across) or if
+// it's still live.
+func (dl *diskLayer) isStale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+// markStale sets the stale flag as true.
+func (dl *diskLayer) markStale() {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if dl.stale {
+		panic("triedb disk layer is stale") // we've committed into the same base from two children, boom
+	}
+	dl.stale = true
+}
+
+// cacheKey constructs the unique key of clean cache.
+func cacheKey(owner common.Hash, path []byte) []byte {
+	if owner == (common.Hash{}) {
+		return path
+	}
+	return append(owner.Bytes(), path...)
+}
+
+// Node implements the layer interface, retrieving the trie node with the
+// provided node info. No error will be returned if the node is not found.
+func (dl *diskLayer) Node(owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, errSnapshotStale
+	}
+	// Try to retrieve the trie node from the clean memory cache
+	key := cacheKey(owner, path)
+	if dl.cleans != nil {
+		if blob := dl.cleans.Get(nil, key); len(blob) > 0 {
+			if crypto.Keccak256Hash(blob) == hash {
+				cleanHitMeter.Mark(1)
+				cleanReadMeter.Mark(int64(len(blob)))
+				return blob, nil
+			}
+			log.Error("Unexpected trie node in clean cache", "owner", owner, "path", path, "expect", hash)
+		}
+		cleanMissMeter.Mark(1)
+	}
+	// Try to retrieve the trie node from the disk.
+	var (
+		nBlob []byte
+		nHash common.Hash
+	)
+	if owner == (common.Hash{}) {
+		nBlob, nHash = rawdb.ReadAccountTrieNode(dl.db.diskdb, path)
+	} else {
+		nBlob, nHash = rawdb.ReadStorageTrieNode(dl.db.diskdb, owner, path)
+	}
+	if nHash != hash {
+		return nil, newUnexpectedNodeError("disk", hash, nHash, owner, path)
+	}
+	diskReadMeter.Mark(int64(len(nBlob)))
+	if dl.cleans != nil && len(nBlob) > 0 {
+		dl.cleans.Set(key, nBlob)
+		cleanWriteMeter.Mark(int64(len(nBlob)))
+	}
+	return nBlob, nil
+}
+
+// update implements the layer interface, returning a new diff layer on top
+// with the given state set.
+func (dl *diskLayer) update(root common.Hash, id uint64, nodes map[common.Hash]map[string]*trienode.Node) *diffLayer {
+	return newDiffLayer(dl, root, id, nodes)
+}
+
+// commit merges the given bottom-most diff layer into the persistent state,
+// recording the overwritten trie nodes as a history for rolling back the state
+// and returns a newly constructed disk layer. Note the current disk layer will
+// be invalidated.
+func (dl *diskLayer) commit(bottom *diffLayer) (*diskLayer, error) {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if dl.stale {
+		return nil, errSnapshotStale
+	}
+	if bottom.id != dl.id+1 {
+		return nil, fmt.Errorf("non-sequential state id, disk %d, diff %d", dl.id, bottom.id)
+	}
+	var (
+		start = time.Now()
+		batch = dl.db.diskdb.NewBatch()
+		hist  = newHistory(bottom.root, dl.root)
+		size  int
+		count int
+	)
+	for owner, subset := range bottom.nodes {
+		for path, n := range subset {
+			// Track the original value of the node to allow reverting the
+			// state transition later
+			var prev []byte
+			if owner == (common.Hash{}) {
+				prev, _ = rawdb.ReadAccountTrieNode(dl.db.diskdb, []byte(path))
+			} else {
+				prev, _ = rawdb.ReadStorageTrieNode(dl.db.diskdb, owner, []byte(path))
+			}
+			hist.add(owner, []byte(path), prev)
+
+			if n.IsDeleted() {
+				rawdb.DeleteTrieNode(batch, owner, []byte(path), common.Hash{}, rawdb.PathScheme)
+				if dl.cleans != nil {
+					dl.cleans.Del(cacheKey(owner, []byte(path)))
+				}
+			} else {
+				rawdb.WriteTrieNode(batch, owner, []byte(path), common.Hash{}, n.Blob, rawdb.PathScheme)
+				if dl.cleans != nil {
+					dl.cleans.Set(cacheKey(owner, []byte(path)), n.Blob)
+				}
+			}
+			size += len(path) + len(n.Blob)
+		}
+		count += len(subset)
+	}
+	// Store the history and the state lookups along with the new state, then
+	// drop the histories falling out of the retention window.
+	blob, err := hist.encode()
+	if err != nil {
+		return nil, err
+	}
+	rawdb.WriteTrieHistory(batch, bottom.id, blob)
+	rawdb.WriteStateID(batch, bottom.root, bottom.id)
+	if dl.id == 0 {
+		rawdb.WriteStateID(batch, dl.root, 0)
+	}
+	rawdb.WritePersistentStateID(batch, bottom.id)
+	historySizeMeter.Mark(int64(len(blob)))
+
+	if limit := dl.db.config.StateHistory; limit != 0 && bottom.id > limit {
+		if err := pruneHistory(dl.db.diskdb, batch, bottom.id-limit); err != nil {
+			return nil, err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	dl.stale = true
+
+	commitTimeTimer.UpdateSince(start)
+	commitNodesMeter.Mark(int64(count))
+	commitSizeMeter.Mark(int64(size))
+	log.Debug("Persisted trie layer", "id", bottom.id, "nodes", count, "size", common.StorageSize(size), "elapsed", common.PrettyDuration(time.Since(start)))
+
+	return newDiskLayer(bottom.root, bottom.id, dl.db, dl.cleans), nil
+}
+
+// revert applies the given history on top of the disk layer, rolling the state
+// back to its parent and returning the newly constructed disk layer. Note the
+// current disk layer will be invalidated.
+func (dl *diskLayer) revert(h *history) (*diskLayer, error) {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if dl.stale {
+		return nil, errSnapshotStale
+	}
+	if h.Root != dl.root {
+		return nil, errUnexpectedHistory
+	}
+	if dl.id == 0 {
+		return nil, errStateUnrecoverable
+	}
+	var (
+		start = time.Now()
+		batch = dl.db.diskdb.NewBatch()
+	)
+	for _, n := range h.Nodes {
+		if len(n.Blob) == 0 {
+			rawdb.DeleteTrieNode(batch, n.Owner, n.Path, common.Hash{}, rawdb.PathScheme)
+		} else {
+			rawdb.WriteTrieNode(batch, n.Owner, n.Path, common.Hash{}, n.Blob, rawdb.PathScheme)
+		}
+	}
+	rawdb.DeleteTrieHistory(batch, dl.id)
+	if id := rawdb.ReadStateID(dl.db.diskdb, dl.root); id != nil && *id == dl.id {
+		rawdb.DeleteStateID(batch, dl.root)
+	}
+	rawdb.WritePersistentStateID(batch, dl.id-1)
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	// The reverted nodes might be cached, reset the cache entirely.
+	if dl.cleans != nil {
+		dl.cleans.Reset()
+	}
+	dl.stale = true
+
+	rollbackTimeTimer.UpdateSince(start)
+	rollbackNodesMeter.Mark(int64(len(h.Nodes)))
+	log.Debug("Reverted trie layer", "id", dl.id, "root", h.Parent)
+
+	return newDiskLayer(h.Parent, dl.id-1, dl.db, dl.cleans), nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// State history records the trie nodes overwritten by each state transition
+// persisted into the disk layer, keyed by the id of the state the transition
+// produces. Applying the history of the persistent state id in reverse rolls the
+// disk layer back to its parent state, allowing the chain to be rewound within
+// the retained window of histories.
+//
+// Each state root is also mapped to its state id, so that the number of
+// transitions to revert for reaching a historic state can be determined.
+
+// historyNode is the original value of a trie node overwritten by a state
+// transition, with an empty blob denoting a node absent before.
+type historyNode struct {
+	Owner common.Hash // Owner of the trie, zero for the account trie
+	Path  []byte      // Path of the node in the trie
+	Blob  []byte      // Original node blob, empty if the node was absent
+}
+
+// history is the reverse diff of a single state transition.
+type history struct {
+	Root   common.Hash   // State root after the transition
+	Parent common.Hash   // State root before the transition
+	Nodes  []historyNode // Overwritten trie nodes
+}
+
+// newHistory creates an empty history for the transition from parent to root.
+func newHistory(root common.Hash, parent common.Hash) *history {
+	return &history{Root: root, Parent: parent}
+}
+
+// add tracks the original value of a trie node.
+func (h *history) add(owner common.Hash, path []byte, blob []byte) {
+	h.Nodes = append(h.Nodes, historyNode{
+		Owner: owner,
+		Path:  common.CopyBytes(path),
+		Blob:  common.CopyBytes(blob),
+	})
+}
+
+// encode sorts the nodes for a deterministic encoding and returns the RLP
+// encoded history.
+func (h *history) encode() ([]byte, error) {
+	sort.Slice(h.Nodes, func(i, j int) bool {
+		if c := bytes.Compare(h.Nodes[i].Owner[:], h.Nodes[j].Owner[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(h.Nodes[i].Path, h.Nodes[j].Path) < 0
+	})
+	return rlp.EncodeToBytes(h)
+}
+
+// readHistory reads and decodes the history of the given state id.
+func readHistory(db ethdb.KeyValueReader, id uint64) (*history, error) {
+	blob := rawdb.ReadTrieHistory(db, id)
+	if len(blob) == 0 {
+		return nil, fmt.Errorf("trie history %d is not found", id)
+	}
+	h := new(history)
+	if err := rlp.DecodeBytes(blob, h); err != nil {
+		return nil, fmt.Errorf("invalid trie history %d: %v", id, err)
+	}
+	return h, nil
+}
+
+// pruneHistory deletes the histories with ids up to and including the given one,
+// along with the lookups of the states which become unrecoverable.
+func pruneHistory(db ethdb.KeyValueReader, batch ethdb.KeyValueWriter, tail uint64) error {
+	for id := tail; id > 0 && rawdb.HasTrieHistory(db, id); id-- {
+		h, err := readHistory(db, id)
+		if err != nil {
+			return err
+		}
+		rawdb.DeleteTrieHistory(batch, id)
+
+		// The same state might be reached again later, only drop the lookup
+		// if it points to the now unrecoverable state.
+		if prev := rawdb.ReadStateID(db, h.Parent); prev != nil && *prev == id-1 {
+			rawdb.DeleteStateID(batch, h.Parent)
+		}
+		historyPruneMeter.Mark(1)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import "github.com/ethereum/go-ethereum/metrics"
+
+var (
+	cleanHitMeter   = metrics.NewRegisteredMeter("pathdb/clean/hit", nil)
+	cleanMissMeter  = metrics.NewRegisteredMeter("pathdb/clean/miss", nil)
+	cleanReadMeter  = metrics.NewRegisteredMeter("pathdb/clean/read", nil)
+	cleanWriteMeter = metrics.NewRegisteredMeter("pathdb/clean/write", nil)
+
+	dirtyHitMeter   = metrics.NewRegisteredMeter("pathdb/dirty/hit", nil)
+	dirtyMissMeter  = metrics.NewRegisteredMeter("pathdb/dirty/miss", nil)
+	dirtyReadMeter  = metrics.NewRegisteredMeter("pathdb/dirty/read", nil)
+	dirtyFalseMeter = metrics.NewRegisteredMeter("pathdb/dirty/false", nil)
+	dirtyWriteMeter = metrics.NewRegisteredMeter("pathdb/dirty/write", nil)
+	dirtyDepthHist  = metrics.NewRegisteredHistogram("pathdb/dirty/depth", nil, metrics.NewExpDecaySample(1028, 0.015))
+
+	diskReadMeter = metrics.NewRegisteredMeter("pathdb/disk/read", nil)
+
+	commitTimeTimer  = metrics.NewRegisteredResettingTimer("pathdb/commit/time", nil)
+	commitNodesMeter = metrics.NewRegisteredMeter("pathdb/commit/nodes", nil)
+	commitSizeMeter  = metrics.NewRegisteredMeter("pathdb/commit/size", nil)
+
+	historySizeMeter   = metrics.NewRegisteredMeter("pathdb/history/size", nil)
+	historyPruneMeter  = metrics.NewRegisteredMeter("pathdb/history/prune", nil)
+	rollbackTimeTimer  = metrics.NewRegisteredResettingTimer("pathdb/rollback/time", nil)
+	rollbackNodesMeter = metrics.NewRegisteredMeter("pathdb/rollback/nodes", nil)
+)
@@ -0,0 +1,164 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"sync"
+
+	"github.com/gballet/go-verkle"
+	"github.com/holiman/uint256"
+)
+
+// The leaves of the account header, stored under the stem derived from the
+// account address and tree index zero.
+const (
+	VersionLeafKey    = 0
+	BalanceLeafKey    = 1
+	NonceLeafKey      = 2
+	CodeKeccakLeafKey = 3
+	CodeSizeLeafKey   = 4
+)
+
+const (
+	push1  = byte(0x60)
+	push32 = byte(0x7f)
+
+	// headerStorageOffset is the leaf index of the first storage slot kept
+	// in the account header.
+	headerStorageOffset = 64
+
+	// codeOffset is the leaf index of the first code chunk, also the number
+	// of storage slots that fit into the account header.
+	codeOffset = 128
+
+	// nodeWidthLog2 is the number of bits addressing the leaves of a stem.
+	nodeWidthLog2 = 8
+)
+
+var (
+	// mainStorageOffset is the tree index of the first stem holding storage
+	// slots that don't fit into the account header, 256**31 / 256.
+	mainStorageOffset = new(uint256.Int).Lsh(uint256.NewInt(1), 248-nodeWidthLog2)
+
+	configOnce sync.Once
+	config     *verkle.Config
+)
+
+// getConfig returns the committer configuration, generating the precomputed
+// tables on first use. Building them is expensive, so it's done lazily and
+// exactly once even if keys are derived concurrently.
+func getConfig() *verkle.Config {
+	configOnce.Do(func() { config = verkle.GetConfig() })
+	return config
+}
+
+// GetTreeKey derives the key of the leaf at subIndex, within the stem at the
+// given tree index of an address. The stem is the pedersen hash of the address
+// and the tree index, both interpreted as little endian 16-byte limbs.
+func GetTreeKey(address []byte, treeIndex *uint256.Int, subIndex byte) []byte {
+	var addr [32]byte
+	copy(addr[32-len(address):], address)
+
+	index := treeIndex.Bytes32()
+	for i := 0; i < len(index)/2; i++ {
+		index[i], index[len(index)-1-i] = index[len(index)-1-i], index[i]
+	}
+	// poly = [2+256*64, address_low, address_high, index_low, index_high]
+	var poly [5]verkle.Fr
+	poly[0].SetUint64(2 + 256*64)
+	verkle.FromLEBytes(&poly[1], addr[:16])
+	verkle.FromLEBytes(&poly[2], addr[16:])
+	verkle.FromLEBytes(&poly[3], index[:16])
+	verkle.FromLEBytes(&poly[4], index[16:])
+
+	var hash verkle.Fr
+	getConfig().CommitToPoly(poly[:], 0).MapToScalarField(&hash)
+
+	key := hash.BytesLE()
+	key[verkle.StemSize] = subIndex
+	return key[:]
+}
+
+// GetTreeKeyVersion returns the key of the account version leaf.
+func GetTreeKeyVersion(address []byte) []byte {
+	return GetTreeKey(address, new(uint256.Int), VersionLeafKey)
+}
+
+// GetTreeKeyCodeSize returns the key of the account code size leaf.
+func GetTreeKeyCodeSize(address []byte) []byte {
+	return GetTreeKey(address, new(uint256.Int), CodeSizeLeafKey)
+}
+
+// GetTreeKeyStorageSlot returns the key of a storage slot. The first slots are
+// kept in the account header, the rest are spread out over the main storage
+// area of the tree.
+func GetTreeKeyStorageSlot(address []byte, slot []byte) []byte {
+	pos := new(uint256.Int).SetBytes(slot)
+	if pos.LtUint64(codeOffset - headerStorageOffset) {
+		return GetTreeKey(address, new(uint256.Int), byte(headerStorageOffset+pos.Uint64()))
+	}
+	// The main storage offset is a multiple of the node width, so it only
+	// shifts the tree index and never carries into the leaf index.
+	subIndex := byte(pos.Uint64())
+	treeIndex := pos.Rsh(pos, nodeWidthLog2)
+	treeIndex.Add(treeIndex, mainStorageOffset)
+	return GetTreeKey(address, treeIndex, subIndex)
+}
+
+// GetTreeKeyCodeChunk returns the key of the given 31-byte code chunk.
+func GetTreeKeyCodeChunk(address []byte, chunk uint64) []byte {
+	pos := codeOffset + chunk
+	return GetTreeKey(address, uint256.NewInt(pos>>nodeWidthLog2), byte(pos))
+}
+
+// CodeChunkLeaf returns the leaf index of the given code chunk within its stem.
+func CodeChunkLeaf(chunk uint64) byte {
+	return byte(codeOffset + chunk)
+}
+
+// ChunkifyCode splits contract code into 32-byte chunks, each holding up to 31
+// bytes of code prefixed by the number of leading bytes that are push data of
+// an instruction in a previous chunk.
+func ChunkifyCode(code []byte) []byte {
+	var (
+		count  = (len(code) + 30) / 31
+		chunks = make([]byte, count*32)
+		pc     int // offset of the next instruction in the code
+	)
+	for i := 0; i < count; i++ {
+		start, end := i*31, (i+1)*31
+		if end > len(code) {
+			end = len(code)
+		}
+		copy(chunks[i*32+1:], code[start:end])
+
+		if lead := pc - start; lead > 0 {
+			if lead > 31 {
+				lead = 31
+			}
+			chunks[i*32] = byte(lead)
+		}
+		for pc < end {
+			op := code[pc]
+			pc++
+			if op >= push1 && op <= push32 {
+				pc += int(op-push1) + 1
+			}
+		}
+	}
+	return chunks
+}
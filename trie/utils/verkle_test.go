@@ -0,0 +1,51 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChunkifyCode(t *testing.T) {
+	tests := []struct {
+		code   []byte
+		chunks []byte
+	}{
+		{nil, []byte{}},
+		{
+			// STOP, single chunk
+			[]byte{0x00},
+			append([]byte{0x00, 0x00}, make([]byte, 30)...),
+		},
+		{
+			// PUSH32 at the end of the first chunk spills 32 bytes of data into
+			// the second chunk and one byte into the third.
+			append(append(bytes.Repeat([]byte{0x5b}, 30), 0x7f), bytes.Repeat([]byte{0xaa}, 33)...),
+			append(append(append(
+				append(append([]byte{0x00}, bytes.Repeat([]byte{0x5b}, 30)...), 0x7f),
+				append([]byte{31}, bytes.Repeat([]byte{0xaa}, 31)...)...),
+				[]byte{1, 0xaa, 0xaa}...),
+				make([]byte, 29)...),
+		},
+	}
+	for i, tt := range tests {
+		if have := ChunkifyCode(tt.code); !bytes.Equal(have, tt.chunks) {
+			t.Errorf("test %d: chunks mismatch\nhave %x\nwant %x", i, have, tt.chunks)
+		}
+	}
+}
@@ -0,0 +1,448 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/trie/utils"
+	"github.com/gballet/go-verkle"
+)
+
+var (
+	// errVerkleUnsupported is returned for the trie operations that have no
+	// verkle counterpart yet.
+	errVerkleUnsupported = errors.New("not supported by verkle trie")
+
+	zeroLeaf [32]byte
+)
+
+// VerkleTrie is a wrapper around a verkle tree that implements the state.Trie
+// interface. Unlike the merkle patricia state, accounts, storage slots and code
+// chunks all live in one tree, keyed by the pedersen hash of their address.
+//
+// The implementation is experimental: tree nodes are persisted keyed by their
+// commitment as soon as the trie is committed and are never garbage collected.
+type VerkleTrie struct {
+	root     *verkle.InternalNode
+	db       *Database
+	accessed map[string]struct{} // Leaves read or written since the trie was opened or committed
+}
+
+// NewVerkleTrie opens the verkle tree with the given root commitment.
+func NewVerkleTrie(root common.Hash, db *Database) (*VerkleTrie, error) {
+	t := &VerkleTrie{
+		db:       db,
+		accessed: make(map[string]struct{}),
+	}
+	if root == (common.Hash{}) || root == types.EmptyRootHash {
+		t.root = verkle.New().(*verkle.InternalNode)
+		return t, nil
+	}
+	blob, err := t.resolve(root[:])
+	if err != nil {
+		return nil, err
+	}
+	node, err := verkle.ParseNode(blob, 0, root[:])
+	if err != nil {
+		return nil, err
+	}
+	internal, ok := node.(*verkle.InternalNode)
+	if !ok {
+		return nil, fmt.Errorf("invalid verkle root type %T", node)
+	}
+	t.root = internal
+	return t, nil
+}
+
+// resolve retrieves a serialized tree node by its commitment.
+func (t *VerkleTrie) resolve(commitment []byte) ([]byte, error) {
+	hash := common.BytesToHash(commitment)
+	blob := rawdb.ReadLegacyTrieNode(t.db.diskdb, hash)
+	if len(blob) == 0 {
+		return nil, &MissingNodeError{NodeHash: hash}
+	}
+	return blob, nil
+}
+
+// touch marks the given leaves of a stem as accessed.
+func (t *VerkleTrie) touch(stem []byte, leaves ...byte) {
+	key := make([]byte, verkle.StemSize+1)
+	copy(key, stem)
+	for _, leaf := range leaves {
+		key[verkle.StemSize] = leaf
+		t.accessed[string(key)] = struct{}{}
+	}
+}
+
+// touchHeader marks all the account header leaves of a stem as accessed.
+func (t *VerkleTrie) touchHeader(stem []byte) {
+	t.touch(stem, utils.VersionLeafKey, utils.BalanceLeafKey, utils.NonceLeafKey, utils.CodeKeccakLeafKey, utils.CodeSizeLeafKey)
+}
+
+// GetKey returns the key as is, since verkle keys are not reversible hashes
+// of the original key with recorded preimages.
+func (t *VerkleTrie) GetKey(key []byte) []byte {
+	return key
+}
+
+// GetAccount implements state.Trie, retrieving the account header leaves of
+// the given address. Deleted accounts are recognizable by their zeroed out
+// code hash, which is never empty for an existing account.
+func (t *VerkleTrie) GetAccount(address common.Address) (*types.StateAccount, error) {
+	stem := utils.GetTreeKeyVersion(address[:])[:verkle.StemSize]
+	t.touchHeader(stem)
+
+	values, err := t.root.GetStem(stem, t.resolve)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil || len(values[utils.CodeKeccakLeafKey]) == 0 || common.BytesToHash(values[utils.CodeKeccakLeafKey]) == (common.Hash{}) {
+		return nil, nil
+	}
+	acc := &types.StateAccount{
+		Balance:  new(big.Int),
+		Root:     types.EmptyRootHash,
+		CodeHash: common.CopyBytes(values[utils.CodeKeccakLeafKey]),
+	}
+	if nonce := values[utils.NonceLeafKey]; len(nonce) >= 8 {
+		acc.Nonce = binary.LittleEndian.Uint64(nonce)
+	}
+	if balance := values[utils.BalanceLeafKey]; len(balance) > 0 {
+		acc.Balance.SetBytes(reverseBytes(balance))
+	}
+	return acc, nil
+}
+
+// UpdateAccount implements state.Trie, writing the account header leaves of
+// the given address. The storage root is meaningless in a verkle tree and is
+// thus dropped, the code size is written along with the code.
+func (t *VerkleTrie) UpdateAccount(address common.Address, acc *types.StateAccount) error {
+	var (
+		stem   = utils.GetTreeKeyVersion(address[:])[:verkle.StemSize]
+		values = make([][]byte, verkle.NodeWidth)
+
+		nonce   [32]byte
+		balance [32]byte
+	)
+	binary.LittleEndian.PutUint64(nonce[:], acc.Nonce)
+	acc.Balance.FillBytes(balance[:])
+
+	values[utils.VersionLeafKey] = zeroLeaf[:]
+	values[utils.BalanceLeafKey] = reverseBytes(balance[:])
+	values[utils.NonceLeafKey] = nonce[:]
+	values[utils.CodeKeccakLeafKey] = common.CopyBytes(acc.CodeHash)
+
+	t.touchHeader(stem)
+	return t.root.InsertStem(stem, values, t.resolve)
+}
+
+// UpdateContractCode implements state.Trie, chunking up the code and writing
+// it into the tree along with its size.
+func (t *VerkleTrie) UpdateContractCode(address common.Address, codeHash common.Hash, code []byte) error {
+	var (
+		chunks = utils.ChunkifyCode(code)
+		count  = uint64(len(chunks) / 32)
+		stem   []byte
+		values [][]byte
+	)
+	for chunk := uint64(0); chunk < count; chunk++ {
+		leaf := utils.CodeChunkLeaf(chunk)
+		if chunk == 0 || leaf == 0 {
+			stem = utils.GetTreeKeyCodeChunk(address[:], chunk)[:verkle.StemSize]
+			values = make([][]byte, verkle.NodeWidth)
+		}
+		values[leaf] = chunks[chunk*32 : (chunk+1)*32]
+		t.touch(stem, leaf)
+
+		if leaf == verkle.NodeWidth-1 || chunk == count-1 {
+			if err := t.root.InsertStem(stem, values, t.resolve); err != nil {
+				return err
+			}
+		}
+	}
+	var size [32]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(code)))
+
+	key := utils.GetTreeKeyCodeSize(address[:])
+	t.touch(key[:verkle.StemSize], key[verkle.StemSize])
+	return t.root.Insert(key, size[:], t.resolve)
+}
+
+// DeleteAccount implements state.Trie, zeroing out the account header leaves.
+// Verkle leaves can't be removed, only overwritten.
+func (t *VerkleTrie) DeleteAccount(address common.Address) error {
+	var (
+		stem   = utils.GetTreeKeyVersion(address[:])[:verkle.StemSize]
+		values = make([][]byte, verkle.NodeWidth)
+	)
+	for i := utils.VersionLeafKey; i <= utils.CodeSizeLeafKey; i++ {
+		values[i] = zeroLeaf[:]
+	}
+	t.touchHeader(stem)
+	return t.root.InsertStem(stem, values, t.resolve)
+}
+
+// GetStorage implements state.Trie, returning the value of a storage slot
+// with its leading zeroes trimmed.
+func (t *VerkleTrie) GetStorage(address common.Address, key []byte) ([]byte, error) {
+	k := utils.GetTreeKeyStorageSlot(address[:], key)
+	t.touch(k[:verkle.StemSize], k[verkle.StemSize])
+
+	value, err := t.root.Get(k, t.resolve)
+	if err != nil {
+		return nil, err
+	}
+	return common.TrimLeftZeroes(value), nil
+}
+
+// UpdateStorage implements state.Trie, storing the value of a storage slot
+// left padded to the leaf size.
+func (t *VerkleTrie) UpdateStorage(address common.Address, key, value []byte) error {
+	var v [32]byte
+	if len(value) > len(v) {
+		return fmt.Errorf("storage value too long: %d bytes", len(value))
+	}
+	copy(v[len(v)-len(value):], value)
+
+	k := utils.GetTreeKeyStorageSlot(address[:], key)
+	t.touch(k[:verkle.StemSize], k[verkle.StemSize])
+	return t.root.Insert(k, v[:], t.resolve)
+}
+
+// DeleteStorage implements state.Trie, zeroing out a storage slot.
+func (t *VerkleTrie) DeleteStorage(address common.Address, key []byte) error {
+	return t.UpdateStorage(address, key, nil)
+}
+
+// Hash returns the root commitment of the tree. An empty tree commits to the
+// identity point, which is mapped to the empty root hash to stay compatible
+// with the emptiness checks of the merkle state.
+func (t *VerkleTrie) Hash() common.Hash {
+	root := common.Hash(t.root.Commit().Bytes())
+	if root == (common.Hash{}) {
+		return types.EmptyRootHash
+	}
+	return root
+}
+
+// Commit writes all the nodes held in memory into the database, keyed by their
+// commitment, and collapses them into hashed nodes so that memory is released.
+// Contrary to the merkle trie, the nodes are not returned as a set to be fed
+// through the trie database and the trie stays usable after commit.
+func (t *VerkleTrie) Commit(_ bool) (common.Hash, *trienode.NodeSet, error) {
+	t.accessed = make(map[string]struct{})
+
+	root := t.Hash()
+	if root == types.EmptyRootHash {
+		return root, nil, nil
+	}
+	var (
+		batch = t.db.diskdb.NewBatch()
+		err   error
+	)
+	t.root.Flush(func(node verkle.VerkleNode) {
+		if err != nil {
+			return
+		}
+		var blob []byte
+		if blob, err = node.Serialize(); err != nil {
+			return
+		}
+		rawdb.WriteLegacyTrieNode(batch, node.Commitment().Bytes(), blob)
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err = batch.Write(); err == nil {
+				batch.Reset()
+			}
+		}
+	})
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	if err := batch.Write(); err != nil {
+		return common.Hash{}, nil, err
+	}
+	return root, nil, nil
+}
+
+// NodeIterator implements state.Trie, but iterating a verkle tree is not
+// supported yet.
+func (t *VerkleTrie) NodeIterator(startKey []byte) (NodeIterator, error) {
+	return nil, errVerkleUnsupported
+}
+
+// Prove implements state.Trie, but single key merkle proofs have no verkle
+// counterpart. Use Witness to prove a set of keys instead.
+func (t *VerkleTrie) Prove(key []byte, proofDb ethdb.KeyValueWriter) error {
+	return errVerkleUnsupported
+}
+
+// Copy returns a deep copy of the trie.
+func (t *VerkleTrie) Copy() *VerkleTrie {
+	accessed := make(map[string]struct{}, len(t.accessed))
+	for key := range t.accessed {
+		accessed[key] = struct{}{}
+	}
+	return &VerkleTrie{
+		root:     t.root.Copy().(*verkle.InternalNode),
+		db:       t.db,
+		accessed: accessed,
+	}
+}
+
+// TouchCode marks all the code chunks of a contract as accessed. Code is read
+// from the code store rather than the tree, so the state has to report which
+// contracts were loaded for them to be included in a witness.
+func (t *VerkleTrie) TouchCode(address common.Address, size int) {
+	var stem []byte
+	for chunk := uint64(0); chunk < uint64(size+30)/31; chunk++ {
+		leaf := utils.CodeChunkLeaf(chunk)
+		if chunk == 0 || leaf == 0 {
+			stem = utils.GetTreeKeyCodeChunk(address[:], chunk)[:verkle.StemSize]
+		}
+		t.touch(stem, leaf)
+	}
+}
+
+// AccessedKeys returns the sorted keys of all the leaves that were read or
+// written since the trie was opened or last committed.
+func (t *VerkleTrie) AccessedKeys() [][]byte {
+	keys := make([][]byte, 0, len(t.accessed))
+	for key := range t.accessed {
+		keys = append(keys, []byte(key))
+	}
+	sort.Slice(keys, func(i, j int) bool { return string(keys[i]) < string(keys[j]) })
+	return keys
+}
+
+// Witness generates a multiproof for the given keys against the root of the
+// tree, along with the values of the proven leaves. The witness of an empty
+// set of keys carries no proof.
+func (t *VerkleTrie) Witness(keys [][]byte) (*VerkleWitness, error) {
+	if len(keys) == 0 {
+		return new(VerkleWitness), nil
+	}
+	keys = append([][]byte{}, keys...)
+	sort.Slice(keys, func(i, j int) bool { return string(keys[i]) < string(keys[j]) })
+
+	// Resolve the paths of all the keys, the proof can't be made over hashed
+	// nodes. The values are collected here too, as the proof only carries one
+	// value for all the keys of an absent subtree.
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, err := t.root.Get(key, t.resolve)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	proof, _, _, _, err := verkle.MakeVerkleMultiProof(t.root, keys)
+	if err != nil {
+		return nil, err
+	}
+	proof.Values = values
+
+	vp, diff, err := verkle.SerializeProof(proof)
+	if err != nil {
+		return nil, err
+	}
+	return &VerkleWitness{Proof: vp, StateDiff: diff}, nil
+}
+
+// VerkleWitness is a multiproof of a set of leaves against a verkle root, along
+// with the values of those leaves in the proven tree. The proof is nil if no
+// leaves are proven.
+type VerkleWitness struct {
+	Proof     *verkle.VerkleProof `json:"proof"`
+	StateDiff verkle.StateDiff    `json:"stateDiff"`
+}
+
+// verkleStemRLP is the RLP encoding of the proven leaves of one stem. Values
+// are left empty for absent leaves, present leaves always hold 32 bytes.
+type verkleStemRLP struct {
+	Stem     [verkle.StemSize]byte
+	Suffixes []byte
+	Values   [][]byte
+}
+
+// EncodeRLP implements rlp.Encoder.
+func (w *VerkleWitness) EncodeRLP(out io.Writer) error {
+	stems := make([]verkleStemRLP, len(w.StateDiff))
+	for i, diff := range w.StateDiff {
+		stems[i].Stem = diff.Stem
+		for _, suffix := range diff.SuffixDiffs {
+			stems[i].Suffixes = append(stems[i].Suffixes, suffix.Suffix)
+			if suffix.CurrentValue == nil {
+				stems[i].Values = append(stems[i].Values, nil)
+			} else {
+				stems[i].Values = append(stems[i].Values, suffix.CurrentValue[:])
+			}
+		}
+	}
+	return rlp.Encode(out, []interface{}{w.Proof, stems})
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (w *VerkleWitness) DecodeRLP(s *rlp.Stream) error {
+	var dec struct {
+		Proof *verkle.VerkleProof `rlp:"nil"`
+		Stems []verkleStemRLP
+	}
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	w.Proof, w.StateDiff = dec.Proof, make(verkle.StateDiff, len(dec.Stems))
+	for i, stem := range dec.Stems {
+		if len(stem.Suffixes) != len(stem.Values) {
+			return fmt.Errorf("stem %x: %d suffixes, %d values", stem.Stem, len(stem.Suffixes), len(stem.Values))
+		}
+		w.StateDiff[i].Stem = stem.Stem
+		for j, suffix := range stem.Suffixes {
+			diff := verkle.SuffixStateDiff{Suffix: suffix}
+			switch len(stem.Values[j]) {
+			case 0:
+			case 32:
+				diff.CurrentValue = (*[32]byte)(stem.Values[j])
+			default:
+				return fmt.Errorf("stem %x: invalid value length %d", stem.Stem, len(stem.Values[j]))
+			}
+			w.StateDiff[i].SuffixDiffs = append(w.StateDiff[i].SuffixDiffs, diff)
+		}
+	}
+	return nil
+}
+
+// reverseBytes returns a copy of the given slice in reverse byte order, used to
+// convert between big endian integers and the little endian verkle leaves.
+func reverseBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
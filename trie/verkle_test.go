@@ -0,0 +1,200 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func newTestVerkleTrie(t *testing.T) (*VerkleTrie, *Database) {
+	if testing.Short() {
+		t.Skip("verkle configuration is expensive to generate")
+	}
+	db := NewDatabaseWithConfig(rawdb.NewMemoryDatabase(), &Config{Verkle: true})
+	tr, err := NewVerkleTrie(types.EmptyRootHash, db)
+	if err != nil {
+		t.Fatalf("failed to open empty verkle trie: %v", err)
+	}
+	return tr, db
+}
+
+func TestVerkleTrieReadWrite(t *testing.T) {
+	tr, db := newTestVerkleTrie(t)
+	if root := tr.Hash(); root != types.EmptyRootHash {
+		t.Fatalf("empty root mismatch: have %x, want %x", root, types.EmptyRootHash)
+	}
+	var (
+		addr    = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		code    = bytes.Repeat([]byte{0x60, 0x01, 0x7f}, 100) // PUSH1 and PUSH32 spanning chunks
+		account = &types.StateAccount{
+			Nonce:    7,
+			Balance:  big.NewInt(1234567890),
+			Root:     types.EmptyRootHash,
+			CodeHash: crypto.Keccak256(code),
+		}
+		slots = map[common.Hash][]byte{
+			common.HexToHash("0x01"):       {0x2a},
+			common.HexToHash("0x0100"):     common.FromHex("0xdeadbeef"),
+			common.HexToHash("0xffff"):     bytes.Repeat([]byte{0xff}, 32),
+			crypto.Keccak256Hash([]byte{}): {0x01, 0x00},
+		}
+	)
+	if err := tr.UpdateAccount(addr, account); err != nil {
+		t.Fatalf("failed to update account: %v", err)
+	}
+	if err := tr.UpdateContractCode(addr, common.BytesToHash(account.CodeHash), code); err != nil {
+		t.Fatalf("failed to update code: %v", err)
+	}
+	for slot, value := range slots {
+		if err := tr.UpdateStorage(addr, slot[:], value); err != nil {
+			t.Fatalf("failed to update slot %x: %v", slot, err)
+		}
+	}
+	check := func(tr *VerkleTrie) {
+		t.Helper()
+		have, err := tr.GetAccount(addr)
+		if err != nil {
+			t.Fatalf("failed to read account: %v", err)
+		}
+		if have == nil || have.Nonce != account.Nonce || have.Balance.Cmp(account.Balance) != 0 || !bytes.Equal(have.CodeHash, account.CodeHash) {
+			t.Fatalf("account mismatch: have %+v, want %+v", have, account)
+		}
+		for slot, value := range slots {
+			have, err := tr.GetStorage(addr, slot[:])
+			if err != nil {
+				t.Fatalf("failed to read slot %x: %v", slot, err)
+			}
+			if !bytes.Equal(have, value) {
+				t.Fatalf("slot %x mismatch: have %x, want %x", slot, have, value)
+			}
+		}
+		if acc, err := tr.GetAccount(common.Address{0xff}); err != nil || acc != nil {
+			t.Fatalf("unexpected missing account: %v, %v", acc, err)
+		}
+	}
+	check(tr)
+
+	root, _, err := tr.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if root == types.EmptyRootHash {
+		t.Fatal("root of non-empty tree is empty")
+	}
+	check(tr)
+
+	reopened, err := NewVerkleTrie(root, db)
+	if err != nil {
+		t.Fatalf("failed to reopen trie: %v", err)
+	}
+	check(reopened)
+
+	// Deleted accounts are zeroed out and should read as missing
+	if err := reopened.DeleteAccount(addr); err != nil {
+		t.Fatalf("failed to delete account: %v", err)
+	}
+	if acc, err := reopened.GetAccount(addr); err != nil || acc != nil {
+		t.Fatalf("account still present after deletion: %v, %v", acc, err)
+	}
+}
+
+func TestVerkleWitnessEncoding(t *testing.T) {
+	tr, db := newTestVerkleTrie(t)
+
+	for i := byte(1); i <= 16; i++ {
+		addr := common.Address{i}
+		if err := tr.UpdateAccount(addr, &types.StateAccount{Nonce: uint64(i), Balance: big.NewInt(int64(i)), CodeHash: types.EmptyCodeHash[:]}); err != nil {
+			t.Fatalf("failed to update account: %v", err)
+		}
+		if err := tr.UpdateStorage(addr, common.Hash{i}.Bytes(), []byte{i}); err != nil {
+			t.Fatalf("failed to update storage: %v", err)
+		}
+	}
+	root, _, err := tr.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	// Access a few present accounts and slots, as well as absent ones
+	tr, err = NewVerkleTrie(root, db)
+	if err != nil {
+		t.Fatalf("failed to reopen trie: %v", err)
+	}
+	for _, i := range []byte{1, 5, 9, 0x80} {
+		if _, err := tr.GetAccount(common.Address{i}); err != nil {
+			t.Fatalf("failed to read account: %v", err)
+		}
+		if _, err := tr.GetStorage(common.Address{i}, common.Hash{i}.Bytes()); err != nil {
+			t.Fatalf("failed to read storage: %v", err)
+		}
+	}
+	keys := tr.AccessedKeys()
+	if len(keys) == 0 {
+		t.Fatal("no accessed keys tracked")
+	}
+	witness, err := tr.Witness(keys)
+	if err != nil {
+		t.Fatalf("failed to generate witness: %v", err)
+	}
+	if witness.Proof == nil {
+		t.Fatal("missing proof")
+	}
+	var leafs int
+	for _, stem := range witness.StateDiff {
+		leafs += len(stem.SuffixDiffs)
+	}
+	if leafs != len(keys) {
+		t.Fatalf("witness leaf count mismatch: have %d, want %d", leafs, len(keys))
+	}
+	blob, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	dec := new(VerkleWitness)
+	if err := rlp.DecodeBytes(blob, dec); err != nil {
+		t.Fatalf("failed to decode witness: %v", err)
+	}
+	reenc, err := rlp.EncodeToBytes(dec)
+	if err != nil {
+		t.Fatalf("failed to re-encode witness: %v", err)
+	}
+	if !bytes.Equal(blob, reenc) {
+		t.Fatalf("witness encoding mismatch:\nhave %x\nwant %x", reenc, blob)
+	}
+	// The witness of nothing carries no proof but should still roundtrip
+	empty, err := tr.Witness(nil)
+	if err != nil {
+		t.Fatalf("failed to generate empty witness: %v", err)
+	}
+	blob, err = rlp.EncodeToBytes(empty)
+	if err != nil {
+		t.Fatalf("failed to encode empty witness: %v", err)
+	}
+	if err := rlp.DecodeBytes(blob, dec); err != nil {
+		t.Fatalf("failed to decode empty witness: %v", err)
+	}
+	if dec.Proof != nil || len(dec.StateDiff) != 0 {
+		t.Fatalf("empty witness mismatch: %+v", dec)
+	}
+}